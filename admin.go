@@ -0,0 +1,249 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// adminSource is a source as listed and added through the admin API.
+// Options holds the rest of its input file line, e.g. `name="Team" count=5`.
+type adminSource struct {
+	URL     string   `json:"url"`
+	Options string   `json:"options,omitempty"`
+	Name    string   `json:"name,omitempty"`
+	Tags    []string `json:"tags,omitempty"`
+	Paused  bool     `json:"paused,omitempty"`
+	List    string   `json:"list,omitempty"`
+}
+
+// pausedOption matches a paused option on a source line, to replace it.
+var pausedOption = regexp.MustCompile(`\s+paused=\S*`)
+
+// adminHandler serves the admin API, which manages the sources in the
+// input files: listing, adding, removing, pausing and resuming them, and
+// requesting a refresh. Changes are written to the input files and take
+// effect at the next refresh.
+func (d *daemon) adminHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /admin/sources", d.listAdminSources)
+	mux.HandleFunc("POST /admin/sources", d.addAdminSource)
+	mux.HandleFunc("DELETE /admin/sources", d.removeAdminSource)
+	mux.HandleFunc("POST /admin/sources/pause", d.pauseAdminSource(true))
+	mux.HandleFunc("POST /admin/sources/resume", d.pauseAdminSource(false))
+	mux.HandleFunc("POST /admin/refresh", d.requestAdminRefresh)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(d.config.AdminToken)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+func (d *daemon) listAdminSources(w http.ResponseWriter, r *http.Request) {
+	sources, err := readSourcesFromFiles(d.config.InputFiles)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	list := make([]adminSource, 0, len(sources))
+	for _, source := range sources {
+		list = append(list, adminSource{URL: source.URL, Name: source.Name, Tags: source.Tags, Paused: source.Paused, List: source.List})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+// addAdminSource appends a source line to the list named in the request,
+// or to the first local input file.
+func (d *daemon) addAdminSource(w http.ResponseWriter, r *http.Request) {
+	var req adminSource
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	// A line break would smuggle more lines, such as an @include, into the
+	// input file.
+	if strings.ContainsAny(req.URL+req.Options, "\r\n") {
+		http.Error(w, "url and options must not contain line breaks", http.StatusBadRequest)
+		return
+	}
+	if strings.TrimSpace(req.URL) == "" {
+		http.Error(w, "url is required", http.StatusBadRequest)
+		return
+	}
+	line := strings.TrimSpace(req.URL + " " + req.Options)
+	source, err := parseSourceLine(line)
+	if err == nil {
+		if u, parseErr := url.Parse(req.URL); parseErr != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || source.URL != req.URL {
+			err = fmt.Errorf("url must be an http or https URL")
+		}
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid source %q: %v", line, err), http.StatusBadRequest)
+		return
+	}
+
+	d.adminMu.Lock()
+	defer d.adminMu.Unlock()
+
+	sources, lists, err := d.adminLists()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if len(lists) == 0 {
+		http.Error(w, "no local input file to add sources to", http.StatusConflict)
+		return
+	}
+	list := lists[0]
+	if req.List != "" {
+		if !slices.Contains(lists, req.List) {
+			http.Error(w, fmt.Sprintf("%s is not a local input file", req.List), http.StatusBadRequest)
+			return
+		}
+		list = req.List
+	}
+	if existing, ok := findSource(sources, source.URL); ok {
+		http.Error(w, fmt.Sprintf("%s is already listed as %s in %s", source.URL, existing.URL, existing.List), http.StatusConflict)
+		return
+	}
+
+	err = editInputFile(list, func(lines []string) ([]string, error) {
+		if last := len(lines) - 1; lines[last] == "" {
+			lines = lines[:last]
+		} else if !strings.HasSuffix(lines[last], "\n") {
+			lines[last] += "\n"
+		}
+		return append(lines, line+"\n"), nil
+	})
+	if err != nil {
+		http.Error(w, fmt.Sprintf("error updating %s: %v", list, err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(adminSource{URL: source.URL, Name: source.Name, Tags: source.Tags, Paused: source.Paused, List: list})
+}
+
+func (d *daemon) removeAdminSource(w http.ResponseWriter, r *http.Request) {
+	d.editAdminSource(w, r.URL.Query().Get("url"), func(line string) []string {
+		return nil
+	})
+}
+
+func (d *daemon) pauseAdminSource(paused bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		d.editAdminSource(w, r.URL.Query().Get("url"), func(line string) []string {
+			content := strings.TrimRight(line, "\r\n")
+			ending := line[len(content):]
+			content = pausedOption.ReplaceAllString(content, "")
+			if paused {
+				content += " paused=true"
+			}
+			return []string{content + ending}
+		})
+	}
+}
+
+// editAdminSource replaces the line of the source with sourceURL in its list by
+// the lines edit returns for it.
+func (d *daemon) editAdminSource(w http.ResponseWriter, sourceURL string, edit func(line string) []string) {
+	if sourceURL == "" {
+		http.Error(w, "url parameter required", http.StatusBadRequest)
+		return
+	}
+
+	d.adminMu.Lock()
+	defer d.adminMu.Unlock()
+
+	sources, lists, err := d.adminLists()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	source, ok := findSource(sources, sourceURL)
+	if !ok {
+		http.Error(w, fmt.Sprintf("%s is not listed", sourceURL), http.StatusNotFound)
+		return
+	}
+	if !slices.Contains(lists, source.List) {
+		http.Error(w, fmt.Sprintf("%s is listed in %s, which is not a local file", sourceURL, source.List), http.StatusConflict)
+		return
+	}
+
+	err = editInputFile(source.List, func(lines []string) ([]string, error) {
+		var edited []string
+		for _, line := range lines {
+			if sourceLineURL(line) == source.URL {
+				edited = append(edited, edit(line)...)
+				continue
+			}
+			edited = append(edited, line)
+		}
+		return edited, nil
+	})
+	if err != nil {
+		http.Error(w, fmt.Sprintf("error updating %s: %v", source.List, err), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// requestAdminRefresh asks the daemon to fetch every source now.
+func (d *daemon) requestAdminRefresh(w http.ResponseWriter, r *http.Request) {
+	select {
+	case d.refreshes <- true:
+	default:
+		// A refresh is already pending.
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// adminLists returns every source in the input files, paused or not, and
+// the local files among the lists they were read from, which the admin API
+// may change.
+func (d *daemon) adminLists() ([]Source, []string, error) {
+	sources, err := readSourcesFromFiles(d.config.InputFiles)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error reading input file: %v", err)
+	}
+	names, err := expandInputGlobs(d.config.InputFiles)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, source := range sources {
+		names = append(names, source.List)
+	}
+
+	var lists []string
+	for _, name := range names {
+		if name != "-" && !isRemoteList(name) && !slices.Contains(lists, name) {
+			lists = append(lists, name)
+		}
+	}
+	return sources, lists, nil
+}
+
+// findSource finds the source listed under url, or a URL that normalizes
+// to the same.
+func findSource(sources []Source, url string) (Source, bool) {
+	key := normalizeURL(url)
+	for _, source := range sources {
+		if normalizeURL(source.URL) == key {
+			return source, true
+		}
+	}
+	return Source{}, false
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDaemonAdminAPI(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "rss_admin")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	inputFile := filepath.Join(tempDir, "feeds.txt")
+	initial := "# Feeds\nhttps://example.com/a.xml name=A\nhttps://example.com/b.xml"
+	if err := os.WriteFile(inputFile, []byte(initial), 0644); err != nil {
+		t.Fatalf("Failed to write input file: %v", err)
+	}
+
+	config := &Config{
+		InputFiles: []string{inputFile},
+		OutputFile: filepath.Join(tempDir, "out.xml"),
+		Interval:   time.Hour,
+		AdminToken: "secret",
+	}
+	d := newDaemon(config)
+	server := httptest.NewServer(d.handler())
+	defer server.Close()
+
+	request := func(method, path, body string) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("NewRequest() unexpected error = %v", err)
+		}
+		req.Header.Set("Authorization", "Bearer secret")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s failed: %v", method, path, err)
+		}
+		return resp
+	}
+	readInput := func() string {
+		t.Helper()
+		data, err := os.ReadFile(inputFile)
+		if err != nil {
+			t.Fatalf("Failed to read input file: %v", err)
+		}
+		return string(data)
+	}
+	query := "?url=" + url.QueryEscape("https://example.com/b.xml")
+
+	resp, err := http.Get(server.URL + "/admin/sources")
+	if err != nil {
+		t.Fatalf("GET /admin/sources failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("GET /admin/sources without token = %d, want 401", resp.StatusCode)
+	}
+
+	resp = request("GET", "/admin/sources", "")
+	var listed []adminSource
+	if err := json.NewDecoder(resp.Body).Decode(&listed); err != nil {
+		t.Fatalf("Failed to decode sources: %v", err)
+	}
+	resp.Body.Close()
+	if len(listed) != 2 || listed[0].Name != "A" || listed[1].List != inputFile {
+		t.Errorf("GET /admin/sources = %+v, want a.xml named A and b.xml from %s", listed, inputFile)
+	}
+
+	resp = request("POST", "/admin/sources", `{"url": "https://example.com/c.xml", "options": "name=\"C feed\" count=5"}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Errorf("POST /admin/sources = %d, want 201", resp.StatusCode)
+	}
+	if got := readInput(); !strings.HasSuffix(got, "https://example.com/b.xml\nhttps://example.com/c.xml name=\"C feed\" count=5\n") {
+		t.Errorf("input file after adding = %q", got)
+	}
+
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{"duplicate", `{"url": "HTTPS://example.com/c.xml/"}`, http.StatusConflict},
+		{"bad option", `{"url": "https://example.com/d.xml", "options": "colour=blue"}`, http.StatusBadRequest},
+		{"not a URL", `{"url": "feeds.txt"}`, http.StatusBadRequest},
+		{"empty url", `{"url": ""}`, http.StatusBadRequest},
+		{"blank url", `{"url": "  ", "options": "name=x"}`, http.StatusBadRequest},
+		{"line break in url", `{"url": "https://example.com/e.xml\n@include /etc/passwd"}`, http.StatusBadRequest},
+		{"line break in options", `{"url": "https://example.com/e.xml", "options": "name=x\r\n@include /etc/passwd"}`, http.StatusBadRequest},
+		{"options in url", `{"url": "https://example.com/e.xml name=x"}`, http.StatusBadRequest},
+		{"unknown list", `{"url": "https://example.com/d.xml", "list": "/etc/passwd"}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		resp := request("POST", "/admin/sources", tt.body)
+		resp.Body.Close()
+		if resp.StatusCode != tt.want {
+			t.Errorf("POST /admin/sources %s = %d, want %d", tt.name, resp.StatusCode, tt.want)
+		}
+	}
+
+	resp = request("POST", "/admin/sources/pause"+query, "")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent || !strings.Contains(readInput(), "https://example.com/b.xml paused=true\n") {
+		t.Errorf("pause = %d, input file %q", resp.StatusCode, readInput())
+	}
+	sources, err := config.sources()
+	if err != nil {
+		t.Fatalf("sources() unexpected error = %v", err)
+	}
+	if len(sources) != 2 {
+		t.Errorf("sources() returned %d sources with one paused, want 2", len(sources))
+	}
+
+	resp = request("POST", "/admin/sources/resume"+query, "")
+	resp.Body.Close()
+	if strings.Contains(readInput(), "paused") {
+		t.Errorf("input file after resume = %q, want no paused option", readInput())
+	}
+
+	resp = request("DELETE", "/admin/sources"+query, "")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent || strings.Contains(readInput(), "b.xml") {
+		t.Errorf("delete = %d, input file %q", resp.StatusCode, readInput())
+	}
+	if !strings.HasPrefix(readInput(), "# Feeds\nhttps://example.com/a.xml name=A\n") {
+		t.Errorf("input file lost other lines: %q", readInput())
+	}
+
+	resp = request("DELETE", "/admin/sources"+query, "")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("deleting again = %d, want 404", resp.StatusCode)
+	}
+
+	resp = request("POST", "/admin/refresh", "")
+	resp.Body.Close()
+	select {
+	case force := <-d.refreshes:
+		if !force {
+			t.Errorf("refresh requested without force")
+		}
+	default:
+		t.Errorf("POST /admin/refresh = %d, no refresh requested", resp.StatusCode)
+	}
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"maps"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// OperatorAlert reports a problem with the aggregator itself, as opposed to
+// news about the items it aggregates, or with Cleared set, that the problem
+// is over.
+type OperatorAlert struct {
+	Kind string `json:"kind"`
+	// Subject is what the alert is about when a kind can be raised for
+	// several things at once, such as the directory of a full disk.
+	Subject string    `json:"subject,omitempty"`
+	Message string    `json:"message"`
+	Time    time.Time `json:"time"`
+	Cleared bool      `json:"cleared,omitempty"`
+}
+
+// key identifies the problem an alert reports across runs.
+func (a OperatorAlert) key() string {
+	if a.Subject == "" {
+		return a.Kind
+	}
+	return a.Kind + " " + a.Subject
+}
+
+func checkOperatorAlerts(config *Config, stats *RunStats, now time.Time) []OperatorAlert {
+	var alerts []OperatorAlert
+
+	if config.AlertMinSuccess > 0 && stats != nil && stats.Sources > 0 {
+		rate := float64(stats.Sources-stats.Failed) / float64(stats.Sources)
+		if rate < config.AlertMinSuccess {
+			alerts = append(alerts, OperatorAlert{
+				Kind: "low-success-rate",
+				Message: fmt.Sprintf("only %d of %d sources fetched successfully (%.0f%%, threshold %.0f%%)",
+					stats.Sources-stats.Failed, stats.Sources, rate*100, config.AlertMinSuccess*100),
+				Time: now,
+			})
+		}
+	}
+
+	if config.AlertStaleAfter > 0 {
+		info, err := os.Stat(config.OutputFile)
+		switch {
+		case err != nil:
+			alerts = append(alerts, OperatorAlert{
+				Kind:    "output-stale",
+				Message: fmt.Sprintf("output file %s is missing: %v", config.OutputFile, err),
+				Time:    now,
+			})
+		case now.Sub(info.ModTime()) > config.AlertStaleAfter:
+			alerts = append(alerts, OperatorAlert{
+				Kind: "output-stale",
+				Message: fmt.Sprintf("output file %s has not been updated since %s",
+					config.OutputFile, info.ModTime().Format(time.RFC3339)),
+				Time: now,
+			})
+		}
+	}
+
+	if config.AlertMinFree > 0 {
+		for _, dir := range storeDirs(config) {
+			free, total, err := diskSpace(dir)
+			if err != nil {
+				log.Printf("Warning: cannot check free space in %s: %v", dir, err)
+				continue
+			}
+			if total == 0 {
+				continue
+			}
+			if rate := float64(free) / float64(total); rate < config.AlertMinFree {
+				alerts = append(alerts, OperatorAlert{
+					Kind:    "store-full",
+					Subject: dir,
+					Message: fmt.Sprintf("only %.0f%% of the disk holding %s is free (%d MiB, threshold %.0f%%)",
+						rate*100, dir, free>>20, config.AlertMinFree*100),
+					Time: now,
+				})
+			}
+		}
+	}
+
+	return alerts
+}
+
+// storeDirs returns the local directories the aggregator writes to, whose
+// disks -alert-min-free watches.
+func storeDirs(config *Config) []string {
+	var dirs []string
+	add := func(dir string) {
+		if dir != "" && !slices.Contains(dirs, dir) {
+			dirs = append(dirs, dir)
+		}
+	}
+	if _, _, ok := remoteOutput(config.OutputFile); !ok && config.OutputFile != "" {
+		add(filepath.Dir(config.OutputFile))
+	}
+	if config.StateFile != "" {
+		add(filepath.Dir(config.StateFile))
+	}
+	add(config.ArchiveDir)
+	add(config.CacheDir)
+	return dirs
+}
+
+// alertsSent holds the alerts last sent for each profile, by name, when
+// there is no state file to keep them in, which only helps a daemon.
+var alertsSent = struct {
+	sync.Mutex
+	byProfile map[string]map[string]OperatorAlert
+}{byProfile: make(map[string]map[string]OperatorAlert)}
+
+// sendOperatorAlerts sends each alert when its problem starts, and a
+// cleared notice when a problem alerted about earlier is over, rather than
+// every alert on every run. The alerts sent are kept in the state file, or
+// in memory without one.
+func sendOperatorAlerts(config *Config, alerts []OperatorAlert, now time.Time) {
+	if config.StateFile == "" {
+		alertsSent.Lock()
+		defer alertsSent.Unlock()
+		alertsSent.byProfile[config.Name] = deliverAlertChanges(config, alertsSent.byProfile[config.Name], alerts, now)
+		return
+	}
+
+	state, err := loadState(config.StateFile)
+	if err != nil {
+		log.Printf("Warning: cannot read the alerts sent before: %v", err)
+		deliverAlertChanges(config, nil, alerts, now)
+		return
+	}
+	active := deliverAlertChanges(config, state.Alerts, alerts, now)
+	if maps.Equal(active, state.Alerts) {
+		return
+	}
+	state.Alerts = active
+	// As with source health, recording alerts must not make the next run
+	// think it has seen the items already.
+	if state.isNew {
+		state.Items = nil
+	}
+	if err := state.save(); err != nil {
+		log.Printf("Warning: cannot record the alerts sent: %v", err)
+	}
+}
+
+// deliverAlertChanges sends those of alerts that were not in sent, and a
+// cleared notice for each of sent not in alerts, returning the alerts now
+// active by key. An alert keeps the time it was first sent.
+func deliverAlertChanges(config *Config, sent map[string]OperatorAlert, alerts []OperatorAlert, now time.Time) map[string]OperatorAlert {
+	active := make(map[string]OperatorAlert, len(alerts))
+	for _, alert := range alerts {
+		if previous, ok := sent[alert.key()]; ok {
+			active[alert.key()] = previous
+			continue
+		}
+		active[alert.key()] = alert
+		deliverAlert(config, alert)
+	}
+	for _, key := range slices.Sorted(maps.Keys(sent)) {
+		if _, ok := active[key]; ok {
+			continue
+		}
+		previous := sent[key]
+		deliverAlert(config, OperatorAlert{
+			Kind:    previous.Kind,
+			Subject: previous.Subject,
+			Message: "cleared: " + previous.Message,
+			Time:    now,
+			Cleared: true,
+		})
+	}
+	return active
+}
+
+func deliverAlert(config *Config, alert OperatorAlert) {
+	log.Printf("Alert: %s: %s", alert.Kind, alert.Message)
+
+	if config.AlertWebhook != "" {
+		if err := postAlertWebhook(config.AlertWebhook, alert); err != nil {
+			log.Printf("Warning: failed to send alert webhook: %v", err)
+		}
+	}
+
+	if config.AlertEmail != "" {
+		if err := emailAlert(config, alert); err != nil {
+			log.Printf("Warning: failed to send alert email: %v", err)
+		}
+	}
+}
+
+func postAlertWebhook(url string, alert OperatorAlert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+
+	resp, err := httpClient.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	return nil
+}
+
+func emailAlert(config *Config, alert OperatorAlert) error {
+	var auth smtp.Auth
+	if username := os.Getenv("SMTP_USERNAME"); username != "" {
+		host, _, err := net.SplitHostPort(config.SMTPAddr)
+		if err != nil {
+			return fmt.Errorf("invalid smtp-addr: %v", err)
+		}
+		auth = smtp.PlainAuth("", username, os.Getenv("SMTP_PASSWORD"), host)
+	}
+
+	subject := alert.Kind
+	if alert.Cleared {
+		subject += " cleared"
+	}
+	msg := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: [rss-agg] %s\r\n\r\n%s\r\n",
+		config.SMTPFrom, config.AlertEmail, subject, alert.Message)
+
+	return smtp.SendMail(config.SMTPAddr, auth, config.SMTPFrom, []string{config.AlertEmail}, []byte(msg))
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestCheckOperatorAlerts(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "rss_alerts")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	outputFile := filepath.Join(tempDir, "out.xml")
+	if err := os.WriteFile(outputFile, []byte("<rss/>"), 0644); err != nil {
+		t.Fatalf("Failed to write output file: %v", err)
+	}
+	modTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(outputFile, modTime, modTime); err != nil {
+		t.Fatalf("Failed to set mtime: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		cfg   *Config
+		stats *RunStats
+		now   time.Time
+		kinds []string
+	}{
+		{
+			name:  "healthy run",
+			cfg:   &Config{OutputFile: outputFile, AlertMinSuccess: 0.5, AlertStaleAfter: 2 * time.Hour},
+			stats: &RunStats{Sources: 4, Failed: 1},
+			now:   modTime.Add(time.Hour),
+		},
+		{
+			name:  "low success rate",
+			cfg:   &Config{OutputFile: outputFile, AlertMinSuccess: 0.8},
+			stats: &RunStats{Sources: 4, Failed: 2},
+			now:   modTime,
+			kinds: []string{"low-success-rate"},
+		},
+		{
+			name:  "stale output",
+			cfg:   &Config{OutputFile: outputFile, AlertStaleAfter: 2 * time.Hour},
+			stats: &RunStats{Sources: 1},
+			now:   modTime.Add(3 * time.Hour),
+			kinds: []string{"output-stale"},
+		},
+		{
+			name:  "missing output",
+			cfg:   &Config{OutputFile: filepath.Join(tempDir, "missing.xml"), AlertStaleAfter: time.Hour},
+			now:   modTime,
+			kinds: []string{"output-stale"},
+		},
+		{
+			name:  "disk nearly full",
+			cfg:   &Config{OutputFile: outputFile, AlertMinFree: 1},
+			stats: &RunStats{Sources: 1},
+			now:   modTime,
+			kinds: []string{"store-full"},
+		},
+		{
+			name:  "alerts disabled",
+			cfg:   &Config{OutputFile: outputFile},
+			stats: &RunStats{Sources: 4, Failed: 4},
+			now:   modTime.Add(100 * time.Hour),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			alerts := checkOperatorAlerts(tt.cfg, tt.stats, tt.now)
+			if len(alerts) != len(tt.kinds) {
+				t.Fatalf("checkOperatorAlerts() got %d alerts %+v, want %v", len(alerts), alerts, tt.kinds)
+			}
+			for i, kind := range tt.kinds {
+				if alerts[i].Kind != kind {
+					t.Errorf("alert[%d].Kind = %q, want %q", i, alerts[i].Kind, kind)
+				}
+			}
+		})
+	}
+}
+
+func TestStoreDirs(t *testing.T) {
+	cfg := &Config{
+		OutputFile: "/srv/feeds/out.xml",
+		StateFile:  "/srv/feeds/state.json",
+		ArchiveDir: "/srv/archive",
+	}
+	want := []string{"/srv/feeds", "/srv/archive"}
+	if got := storeDirs(cfg); !slices.Equal(got, want) {
+		t.Errorf("storeDirs() = %v, want %v", got, want)
+	}
+
+	cfg = &Config{OutputFile: "s3://bucket/out.xml"}
+	if got := storeDirs(cfg); len(got) != 0 {
+		t.Errorf("storeDirs() with remote output = %v, want none", got)
+	}
+}
+
+func TestSendOperatorAlertsWebhook(t *testing.T) {
+	received := make(chan OperatorAlert, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var alert OperatorAlert
+		if err := json.NewDecoder(r.Body).Decode(&alert); err != nil {
+			t.Errorf("webhook body is not an alert: %v", err)
+		}
+		received <- alert
+	}))
+	defer server.Close()
+
+	alert := OperatorAlert{Kind: "output-stale", Message: "stale", Time: time.Now()}
+	sendOperatorAlerts(&Config{Name: "webhook", AlertWebhook: server.URL}, []OperatorAlert{alert}, time.Now())
+
+	select {
+	case got := <-received:
+		if got.Kind != alert.Kind || got.Message != alert.Message {
+			t.Errorf("webhook received %+v, want %+v", got, alert)
+		}
+	default:
+		t.Errorf("webhook was not called")
+	}
+}
+
+func TestSendOperatorAlertsOnce(t *testing.T) {
+	var received []OperatorAlert
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var alert OperatorAlert
+		if err := json.NewDecoder(r.Body).Decode(&alert); err != nil {
+			t.Errorf("webhook body is not an alert: %v", err)
+		}
+		received = append(received, alert)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name string
+		cfg  *Config
+	}{
+		{"state file", &Config{StateFile: filepath.Join(t.TempDir(), "state.json"), AlertWebhook: server.URL, AlertMinSuccess: 0.5}},
+		{"in memory", &Config{Name: "once", AlertWebhook: server.URL, AlertMinSuccess: 0.5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			received = nil
+			now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+			failing := &RunStats{Sources: 4, Failed: 3}
+			for range 2 {
+				sendOperatorAlerts(tt.cfg, checkOperatorAlerts(tt.cfg, failing, now), now)
+				now = now.Add(time.Hour)
+			}
+			if len(received) != 1 || received[0].Kind != "low-success-rate" || received[0].Cleared {
+				t.Fatalf("two failing refreshes sent %+v, want one low-success-rate alert", received)
+			}
+
+			healthy := &RunStats{Sources: 4}
+			for range 2 {
+				sendOperatorAlerts(tt.cfg, checkOperatorAlerts(tt.cfg, healthy, now), now)
+			}
+			if len(received) != 2 || received[1].Kind != "low-success-rate" || !received[1].Cleared {
+				t.Errorf("two healthy refreshes sent %+v, want one cleared notice", received[1:])
+			}
+		})
+	}
+
+	state, err := loadState(tests[0].cfg.StateFile)
+	if err != nil {
+		t.Fatalf("loadState() unexpected error = %v", err)
+	}
+	if !state.isNew {
+		t.Errorf("recording alerts marked the state file's items as seen")
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	apiItemsLimit    = 50
+	apiItemsMaxLimit = 500
+)
+
+// apiItem is an item as listed by /api/items: its JSON form plus the tags
+// of its source.
+type apiItem struct {
+	ItemJSON
+	Tags []string `json:"tags,omitempty"`
+}
+
+// apiItemsPage is a page of /api/items. Next is the URL of the following
+// page, if any.
+type apiItemsPage struct {
+	Items []apiItem `json:"items"`
+	Total int       `json:"total"`
+	Next  string    `json:"next,omitempty"`
+}
+
+// serveAPIItems lists the items of the aggregate as JSON, in feed order,
+// followed by those published before, newest first, filtered by the query
+// parameters:
+//
+//	tag     items from sources with this tag
+//	source  items whose source URL contains this
+//	since   items from this date (2024-01-01), time (RFC 3339) or recent
+//	        period (30d, 12h) on
+//	q       items containing every word, as in the search subcommand
+//	limit   items per page (default 50, at most 500)
+//	offset  items to skip, for the following pages
+//	page    pages of limit items to skip, counting from 1
+func (d *daemon) serveAPIItems(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	query := SearchQuery{Terms: strings.Fields(params.Get("q")), Source: params.Get("source")}
+	if since := params.Get("since"); since != "" {
+		t, err := parseSince(since, time.Now())
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid since: %v", err), http.StatusBadRequest)
+			return
+		}
+		query.Since = t
+	}
+	limit, err := apiIntParam(params, "limit", apiItemsLimit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	limit = min(max(limit, 1), apiItemsMaxLimit)
+	offset, err := apiIntParam(params, "offset", 0)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if params.Has("page") {
+		page, err := apiIntParam(params, "page", 1)
+		if err != nil || page < 1 {
+			http.Error(w, "page must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		offset += (page - 1) * limit
+		params.Del("page")
+	}
+
+	d.mu.Lock()
+	aggregatedFeed := d.feed
+	tags := make(map[string][]string)
+	for _, source := range d.sources {
+		tags[source.URL] = source.Tags
+	}
+	d.mu.Unlock()
+
+	if aggregatedFeed == nil {
+		http.Error(w, "feed not ready yet", http.StatusServiceUnavailable)
+		return
+	}
+
+	items := d.historyItems(aggregatedFeed)
+
+	var matches []apiItem
+	tag := params.Get("tag")
+	for _, item := range items {
+		matched := apiItem{ItemJSON: toItemJSON(item)}
+		if item.Source != nil {
+			matched.Tags = tags[item.Source.Href]
+		}
+		if tag != "" && !slices.Contains(matched.Tags, tag) {
+			continue
+		}
+		if query.matches(matched.ItemJSON) {
+			matches = append(matches, matched)
+		}
+	}
+
+	page := apiItemsPage{Items: []apiItem{}, Total: len(matches)}
+	if offset < len(matches) {
+		end := min(offset+limit, len(matches))
+		page.Items = matches[offset:end]
+		if end < len(matches) {
+			// RequestURI keeps the profile prefix that routing stripped.
+			path, _, _ := strings.Cut(r.RequestURI, "?")
+			params.Set("offset", strconv.Itoa(end))
+			page.Next = path + "?" + params.Encode()
+		}
+	}
+	writeJSON(w, page)
+}
+
+// parseSince parses a date, an RFC 3339 time or a period before now, for
+// search -since and /api/items.
+func parseSince(value string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	period, err := parseDays(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is not a date such as 2024-01-01 or a period such as 30d", value)
+	}
+	return now.Add(-period), nil
+}
+
+func apiIntParam(params url.Values, name string, fallback int) (int, error) {
+	value := params.Get(name)
+	if value == "" {
+		return fallback, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%s must be a non-negative integer", name)
+	}
+	return n, nil
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
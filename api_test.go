@@ -0,0 +1,137 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/feeds"
+)
+
+func TestDaemonAPIItems(t *testing.T) {
+	d := newDaemon(&Config{})
+	server := httptest.NewServer(d.handler())
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/api/items")
+	if err != nil {
+		t.Fatalf("GET /api/items failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("GET /api/items before the first update = %d, want 503", resp.StatusCode)
+	}
+
+	goBlog := &SourceFeed{URL: "https://go.example.com/feed", Tags: []string{"go"}}
+	news := &SourceFeed{URL: "https://news.example.com/feed"}
+	d.sources = map[string]*SourceFeed{goBlog.URL: goBlog, news.URL: news}
+	newItem := func(title string, source *SourceFeed, created time.Time) *feeds.Item {
+		return &feeds.Item{Title: title, Source: &feeds.Link{Href: source.URL}, Created: created}
+	}
+	d.feed = &feeds.Feed{Items: []*feeds.Item{
+		newItem("Generics in practice", goBlog, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)),
+		newItem("Go 1.22 released", goBlog, time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)),
+		newItem("Generics, a retrospective", news, time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)),
+		newItem("Go generics proposal", goBlog, time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)),
+	}}
+
+	get := func(query string) apiItemsPage {
+		t.Helper()
+		resp, err := http.Get(server.URL + "/api/items" + query)
+		if err != nil {
+			t.Fatalf("GET /api/items%s failed: %v", query, err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("GET /api/items%s = %d, want 200", query, resp.StatusCode)
+		}
+		var page apiItemsPage
+		if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+			t.Fatalf("Failed to decode /api/items%s: %v", query, err)
+		}
+		return page
+	}
+	titles := func(page apiItemsPage) string {
+		var titles []string
+		for _, item := range page.Items {
+			titles = append(titles, item.Title)
+		}
+		return fmt.Sprint(titles)
+	}
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"", "[Generics in practice Go 1.22 released Generics, a retrospective Go generics proposal]"},
+		{"?tag=go", "[Generics in practice Go 1.22 released Go generics proposal]"},
+		{"?since=2024-01-01&q=GENERICS", "[Generics in practice Generics, a retrospective]"},
+		{"?tag=go&since=2024-01-01&q=generics", "[Generics in practice]"},
+		{"?source=news.example.com", "[Generics, a retrospective]"},
+		{"?tag=rust", "[]"},
+	}
+	for _, tt := range tests {
+		if got := titles(get(tt.query)); got != tt.want {
+			t.Errorf("GET /api/items%s = %s, want %s", tt.query, got, tt.want)
+		}
+	}
+
+	page := get("?tag=go&limit=2")
+	if page.Total != 3 || titles(page) != "[Generics in practice Go 1.22 released]" {
+		t.Errorf("first page = %s of %d, want the first 2 of 3", titles(page), page.Total)
+	}
+	if page.Next != "/api/items?limit=2&offset=2&tag=go" {
+		t.Fatalf("first page next = %q, want /api/items?limit=2&offset=2&tag=go", page.Next)
+	}
+	if len(page.Items[0].Tags) != 1 || page.Items[0].Tags[0] != "go" {
+		t.Errorf("item tags = %v, want [go]", page.Items[0].Tags)
+	}
+	page = get("?limit=2&offset=2&tag=go")
+	if titles(page) != "[Go generics proposal]" || page.Next != "" {
+		t.Errorf("last page = %s, next %q, want the last item and no next page", titles(page), page.Next)
+	}
+	page = get("?tag=go&limit=2&page=2")
+	if titles(page) != "[Go generics proposal]" || page.Next != "" {
+		t.Errorf("page 2 = %s, next %q, want the last item and no next page", titles(page), page.Next)
+	}
+
+	for _, query := range []string{"?since=yesterday", "?limit=-1", "?offset=x", "?page=0"} {
+		resp, err := http.Get(server.URL + "/api/items" + query)
+		if err != nil {
+			t.Fatalf("GET /api/items%s failed: %v", query, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("GET /api/items%s = %d, want 400", query, resp.StatusCode)
+		}
+	}
+}
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		{"2024-01-01", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), false},
+		{"2024-01-01T08:30:00Z", time.Date(2024, 1, 1, 8, 30, 0, 0, time.UTC), false},
+		{"30d", now.Add(-30 * 24 * time.Hour), false},
+		{"12h", now.Add(-12 * time.Hour), false},
+		{"last week", time.Time{}, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseSince(tt.value, now)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseSince(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("parseSince(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
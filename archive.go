@@ -0,0 +1,144 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+var unsafePathChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+const (
+	// archiveIndexFile holds a Bloom filter of the items in an archive,
+	// which spares looking on disk for items that are not archived yet.
+	archiveIndexFile = ".index.bloom"
+	// The index is sized for twice the archived items, and at least
+	// archiveIndexMinSize, at archiveIndexFPRate false positives.
+	archiveIndexMinSize = 100000
+	archiveIndexFPRate  = 0.01
+)
+
+// archiveItems stores every item of sources under dir, one JSON file per
+// item in a directory per source host. Items already archived are left
+// alone, so the archive keeps the first version of each item ever fetched,
+// long after the source has dropped it from its feed.
+//
+// Only items the archive's index may already hold are looked for on disk,
+// so false positives cost a lookup but never lose an item. Other items are
+// written without replacing any file already there, so an index that is
+// out of date, say restored from a backup, never overwrites an item either.
+func archiveItems(dir string, sources []*SourceFeed) {
+	index := loadArchiveIndex(dir)
+	added := false
+	for _, source := range sources {
+		slug := sourceSlug(source.URL)
+		sourceDir := filepath.Join(dir, slug)
+		if err := os.MkdirAll(sourceDir, 0755); err != nil {
+			log.Printf("Warning: failed to create archive directory %s: %v", sourceDir, err)
+			continue
+		}
+
+		for _, item := range source.Items {
+			sum := sha256.Sum256([]byte(itemKey(item)))
+			name := hex.EncodeToString(sum[:16]) + ".json"
+			key := slug + "/" + name
+			known := index.mayContain(key)
+			if err := archiveItem(filepath.Join(sourceDir, name), toItemJSON(item), known); err != nil {
+				log.Printf("Warning: failed to archive %q from %s: %v", item.Title, source.URL, err)
+				continue
+			}
+			if !known {
+				index.add(key)
+				added = true
+			}
+		}
+	}
+
+	if added {
+		if err := saveArchiveIndex(dir, index); err != nil {
+			log.Printf("Warning: failed to save archive index: %v", err)
+		}
+	}
+}
+
+// loadArchiveIndex reads the index of the archive in dir, and builds it
+// from the archived files when it is missing, unreadable or has outgrown
+// its size.
+func loadArchiveIndex(dir string) *bloomFilter {
+	index := &bloomFilter{}
+	data, err := os.ReadFile(filepath.Join(dir, archiveIndexFile))
+	if err == nil {
+		err = index.UnmarshalBinary(data)
+		if err != nil {
+			log.Printf("Warning: rebuilding archive index: %v", err)
+		}
+	}
+	if err == nil && !index.full() {
+		return index
+	}
+
+	var keys []string
+	sourceDirs, _ := os.ReadDir(dir)
+	for _, sourceDir := range sourceDirs {
+		if !sourceDir.IsDir() {
+			continue
+		}
+		files, _ := os.ReadDir(filepath.Join(dir, sourceDir.Name()))
+		for _, file := range files {
+			if filepath.Ext(file.Name()) == ".json" {
+				keys = append(keys, sourceDir.Name()+"/"+file.Name())
+			}
+		}
+	}
+	index = newBloomFilter(max(2*len(keys), archiveIndexMinSize), archiveIndexFPRate)
+	for _, key := range keys {
+		index.add(key)
+	}
+	return index
+}
+
+func saveArchiveIndex(dir string, index *bloomFilter) error {
+	data, err := index.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(filepath.Join(dir, archiveIndexFile), data, 0644); err != nil {
+		return fmt.Errorf("error writing archive index: %v", err)
+	}
+	return nil
+}
+
+// sourceSlug names a source in file names: its host and path with anything
+// but letters, digits, dots, dashes and underscores replaced.
+func sourceSlug(sourceURL string) string {
+	name := sourceURL
+	if u, err := url.Parse(sourceURL); err == nil && u.Host != "" {
+		name = u.Host + u.Path
+	}
+	return unsafePathChars.ReplaceAllString(name, "_")
+}
+
+// archiveItem writes item to path unless it is already there, which is
+// only looked for when mayExist.
+func archiveItem(path string, item ItemJSON, mayExist bool) error {
+	if mayExist {
+		if _, err := os.Stat(path); err == nil {
+			return nil
+		}
+	}
+
+	data, err := json.MarshalIndent(item, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := createFileAtomic(path, data, 0644); err != nil {
+		return fmt.Errorf("error writing archive file: %v", err)
+	}
+	return nil
+}
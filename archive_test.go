@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gorilla/feeds"
+)
+
+func TestArchiveItems(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "rss_archive")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	source := &SourceFeed{
+		URL: "https://example.com/feed.xml",
+		Items: []*feeds.Item{
+			{Title: "First", Link: &feeds.Link{Href: "https://example.com/1"}, Content: "<p>Full text</p>"},
+			{Title: "Second", Link: &feeds.Link{Href: "https://example.com/2"}},
+		},
+	}
+	archiveItems(tempDir, []*SourceFeed{source})
+
+	// A later fetch with an edited first item and a new third one.
+	source.Items = []*feeds.Item{
+		{Title: "First (edited)", Link: &feeds.Link{Href: "https://example.com/1"}},
+		{Title: "Third", Link: &feeds.Link{Href: "https://example.com/3"}},
+	}
+	archiveItems(tempDir, []*SourceFeed{source})
+
+	files, err := filepath.Glob(filepath.Join(tempDir, "example.com_feed.xml", "*.json"))
+	if err != nil {
+		t.Fatalf("Glob() unexpected error = %v", err)
+	}
+	if len(files) != 3 {
+		t.Fatalf("archive has %d items, want 3", len(files))
+	}
+
+	titles := make(map[string]string)
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("ReadFile() unexpected error = %v", err)
+		}
+		var item ItemJSON
+		if err := json.Unmarshal(data, &item); err != nil {
+			t.Fatalf("archived item is not valid JSON: %v", err)
+		}
+		titles[item.Link] = item.Title
+		if item.Link == "https://example.com/1" && item.Content != "<p>Full text</p>" {
+			t.Errorf("archived content = %q, want full text", item.Content)
+		}
+	}
+	if titles["https://example.com/1"] != "First" {
+		t.Errorf("archived title = %q, want the first version kept", titles["https://example.com/1"])
+	}
+}
+
+func TestArchiveIndex(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "rss_archive_index")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	source := &SourceFeed{
+		URL:   "https://example.com/feed.xml",
+		Items: []*feeds.Item{{Title: "First", Link: &feeds.Link{Href: "https://example.com/1"}}},
+	}
+	archiveItems(tempDir, []*SourceFeed{source})
+
+	files, _ := filepath.Glob(filepath.Join(tempDir, "example.com_feed.xml", "*.json"))
+	if len(files) != 1 {
+		t.Fatalf("archive has %d items, want 1", len(files))
+	}
+	key := "example.com_feed.xml/" + filepath.Base(files[0])
+	if index := loadArchiveIndex(tempDir); !index.mayContain(key) {
+		t.Errorf("archive index does not hold the archived item %s", key)
+	}
+
+	// An item the index holds but that is missing from disk is written
+	// again, and an unreadable index is rebuilt from the archived files.
+	if err := os.Remove(files[0]); err != nil {
+		t.Fatalf("Remove() unexpected error = %v", err)
+	}
+	archiveItems(tempDir, []*SourceFeed{source})
+	if _, err := os.Stat(files[0]); err != nil {
+		t.Errorf("item missing from disk was not archived again: %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(tempDir, archiveIndexFile), []byte("garbage"), 0644); err != nil {
+		t.Fatalf("WriteFile() unexpected error = %v", err)
+	}
+	if index := loadArchiveIndex(tempDir); !index.mayContain(key) || index.count != 1 {
+		t.Errorf("rebuilt archive index holds %d items, want the archived item", index.count)
+	}
+}
+
+func TestArchiveStaleIndex(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "rss_archive_stale")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	source := &SourceFeed{
+		URL:   "https://example.com/feed.xml",
+		Items: []*feeds.Item{{Title: "First", Link: &feeds.Link{Href: "https://example.com/1"}}},
+	}
+	archiveItems(tempDir, []*SourceFeed{source})
+
+	// An index from before the item was archived, as after restoring it
+	// from a backup, must not let a later version overwrite it.
+	stale, err := newBloomFilter(archiveIndexMinSize, archiveIndexFPRate).MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary() unexpected error = %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, archiveIndexFile), stale, 0644); err != nil {
+		t.Fatalf("WriteFile() unexpected error = %v", err)
+	}
+	source.Items[0].Title = "First (edited)"
+	archiveItems(tempDir, []*SourceFeed{source})
+
+	files, _ := filepath.Glob(filepath.Join(tempDir, "example.com_feed.xml", "*.json"))
+	if len(files) != 1 {
+		t.Fatalf("archive has %d items, want 1", len(files))
+	}
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatalf("ReadFile() unexpected error = %v", err)
+	}
+	var item ItemJSON
+	if err := json.Unmarshal(data, &item); err != nil || item.Title != "First" {
+		t.Errorf("archived title = %q (%v), want the first version kept", item.Title, err)
+	}
+	if index := loadArchiveIndex(tempDir); !index.mayContain("example.com_feed.xml/" + filepath.Base(files[0])) {
+		t.Errorf("stale archive index was not updated with the archived item")
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// writeFileAtomic replaces path with data through a temporary file in the
+// same directory, so that readers, and concurrent runs, never see a
+// partial file, and a crash never leaves one behind.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := writeTempFile(path, data, perm)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+	return os.Rename(tmp, path)
+}
+
+// createFileAtomic is writeFileAtomic, but leaves path alone if it exists.
+func createFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := writeTempFile(path, data, perm)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+	// Unlike a rename, a link never replaces an existing file.
+	if err := os.Link(tmp, path); err != nil && !errors.Is(err, fs.ErrExist) {
+		return err
+	}
+	return nil
+}
+
+// writeTempFile writes data to a new hidden file next to path, flushed to
+// disk, and returns its name.
+func writeTempFile(path string, data []byte, perm os.FileMode) (string, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return "", err
+	}
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Chmod(perm)
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	return tmp.Name(), nil
+}
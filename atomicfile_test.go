@@ -0,0 +1,44 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "rss_atomic")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+	path := filepath.Join(tempDir, "state.json")
+
+	for _, data := range []string{"first", "second"} {
+		if err := writeFileAtomic(path, []byte(data), 0600); err != nil {
+			t.Fatalf("writeFileAtomic() unexpected error = %v", err)
+		}
+		if got, err := os.ReadFile(path); err != nil || string(got) != data {
+			t.Errorf("writeFileAtomic() wrote %q (%v), want %q", got, err, data)
+		}
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("writeFileAtomic() mode = %v (%v), want 0600", info.Mode().Perm(), err)
+	}
+
+	if err := createFileAtomic(path, []byte("third"), 0644); err != nil {
+		t.Fatalf("createFileAtomic() unexpected error = %v", err)
+	}
+	if got, _ := os.ReadFile(path); string(got) != "second" {
+		t.Errorf("createFileAtomic() replaced an existing file with %q", got)
+	}
+
+	// No temporary files are left behind.
+	if files, _ := os.ReadDir(tempDir); len(files) != 1 {
+		t.Errorf("directory holds %d files, want 1", len(files))
+	}
+
+	if err := writeFileAtomic(filepath.Join(tempDir, "missing", "state.json"), []byte("x"), 0600); err == nil {
+		t.Errorf("writeFileAtomic() into a missing directory expected error")
+	}
+}
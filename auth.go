@@ -0,0 +1,40 @@
+package main
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// requireAuth lets requests through to next only with the credentials of
+// -auth-basic or -auth-token, when either is set. Without them, every
+// request is let through.
+func requireAuth(config *Config, next http.Handler) http.Handler {
+	if config.AuthBasic == "" && config.AuthToken == "" {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if authorized(config, r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if config.AuthBasic != "" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="rss-agg", charset="UTF-8"`)
+		} else {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="rss-agg"`)
+		}
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+	})
+}
+
+// authorized reports whether r carries the -auth-basic user and password or
+// the -auth-token bearer token.
+func authorized(config *Config, r *http.Request) bool {
+	if user, password, ok := r.BasicAuth(); ok && config.AuthBasic != "" {
+		return subtle.ConstantTimeCompare([]byte(user+":"+password), []byte(config.AuthBasic)) == 1
+	}
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok && config.AuthToken != "" {
+		return subtle.ConstantTimeCompare([]byte(token), []byte(config.AuthToken)) == 1
+	}
+	return false
+}
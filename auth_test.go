@@ -0,0 +1,67 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/feeds"
+)
+
+func TestDaemonAuth(t *testing.T) {
+	d := newDaemon(&Config{AuthBasic: "reader:secret", AuthToken: "token"})
+	d.feed = &feeds.Feed{Title: "Aggregate", Link: &feeds.Link{Href: "https://example.com"}}
+	d.lastSuccess = time.Now()
+	server := httptest.NewServer(d.handler())
+	defer server.Close()
+
+	tests := []struct {
+		name       string
+		path       string
+		auth       func(r *http.Request)
+		wantStatus int
+	}{
+		{"feed without credentials", "/feed.xml", nil, http.StatusUnauthorized},
+		{"feed with basic auth", "/feed.xml", func(r *http.Request) { r.SetBasicAuth("reader", "secret") }, http.StatusOK},
+		{"feed with a wrong password", "/feed.xml", func(r *http.Request) { r.SetBasicAuth("reader", "wrong") }, http.StatusUnauthorized},
+		{"feed with the bearer token", "/feed.xml", func(r *http.Request) { r.Header.Set("Authorization", "Bearer token") }, http.StatusOK},
+		{"feed with a wrong token", "/feed.xml", func(r *http.Request) { r.Header.Set("Authorization", "Bearer other") }, http.StatusUnauthorized},
+		{"web UI without credentials", "/", nil, http.StatusUnauthorized},
+		{"items API without credentials", "/api/items", nil, http.StatusUnauthorized},
+		{"items API with the bearer token", "/api/items", func(r *http.Request) { r.Header.Set("Authorization", "Bearer token") }, http.StatusOK},
+		{"health check stays open", "/healthz", nil, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest("GET", server.URL+tt.path, nil)
+			if err != nil {
+				t.Fatalf("Failed to create request: %v", err)
+			}
+			if tt.auth != nil {
+				tt.auth(req)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("GET %s failed: %v", tt.path, err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("GET %s = %d, want %d", tt.path, resp.StatusCode, tt.wantStatus)
+			}
+			if resp.StatusCode == http.StatusUnauthorized && resp.Header.Get("WWW-Authenticate") == "" {
+				t.Errorf("GET %s unauthorized without a WWW-Authenticate challenge", tt.path)
+			}
+		})
+	}
+}
+
+func TestRequireAuthDisabled(t *testing.T) {
+	handler := requireAuth(&Config{}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/feed.xml", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("requireAuth() without credentials configured = %d, want 200", rec.Code)
+	}
+}
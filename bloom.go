@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"math"
+)
+
+// bloomMagic starts a serialized bloomFilter.
+const bloomMagic = "rss-agg bloom 1\n"
+
+// bloomFilter is a Bloom filter over strings: mayContain never misses a
+// key that was added, and wrongly reports others with about the false
+// positive rate the filter was sized for, until more than capacity keys
+// have been added.
+type bloomFilter struct {
+	bits     []uint64
+	hashes   uint32
+	count    uint64
+	capacity uint64
+}
+
+// newBloomFilter sizes a filter for capacity keys at a false positive rate
+// of fpRate.
+func newBloomFilter(capacity int, fpRate float64) *bloomFilter {
+	capacity = max(capacity, 1)
+	bits := math.Ceil(-float64(capacity) * math.Log(fpRate) / (math.Ln2 * math.Ln2))
+	hashes := max(uint32(math.Round(bits/float64(capacity)*math.Ln2)), 1)
+	return &bloomFilter{
+		bits:     make([]uint64, (uint64(bits)+63)/64),
+		hashes:   hashes,
+		capacity: uint64(capacity),
+	}
+}
+
+// positions derives the filter's bit positions for key from two 32-bit
+// halves of its FNV-1a hash, by double hashing.
+func (f *bloomFilter) positions(key string, visit func(bit uint64)) {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	sum := h.Sum64()
+	h1, h2 := sum&math.MaxUint32, sum>>32|1
+	size := uint64(len(f.bits)) * 64
+	for i := range uint64(f.hashes) {
+		visit((h1 + i*h2) % size)
+	}
+}
+
+func (f *bloomFilter) add(key string) {
+	f.positions(key, func(bit uint64) {
+		f.bits[bit/64] |= 1 << (bit % 64)
+	})
+	f.count++
+}
+
+func (f *bloomFilter) mayContain(key string) bool {
+	found := true
+	f.positions(key, func(bit uint64) {
+		found = found && f.bits[bit/64]&(1<<(bit%64)) != 0
+	})
+	return found
+}
+
+// full reports whether more keys were added than the filter was sized for,
+// so that its false positive rate has grown past what was asked.
+func (f *bloomFilter) full() bool {
+	return f.count > f.capacity
+}
+
+func (f *bloomFilter) MarshalBinary() ([]byte, error) {
+	data := []byte(bloomMagic)
+	data = binary.LittleEndian.AppendUint32(data, f.hashes)
+	data = binary.LittleEndian.AppendUint64(data, f.count)
+	data = binary.LittleEndian.AppendUint64(data, f.capacity)
+	for _, word := range f.bits {
+		data = binary.LittleEndian.AppendUint64(data, word)
+	}
+	return data, nil
+}
+
+func (f *bloomFilter) UnmarshalBinary(data []byte) error {
+	header := len(bloomMagic) + 4 + 8 + 8
+	if len(data) < header+8 || string(data[:len(bloomMagic)]) != bloomMagic || (len(data)-header)%8 != 0 {
+		return fmt.Errorf("not a bloom filter")
+	}
+	data = data[len(bloomMagic):]
+	f.hashes = binary.LittleEndian.Uint32(data)
+	f.count = binary.LittleEndian.Uint64(data[4:])
+	f.capacity = binary.LittleEndian.Uint64(data[12:])
+	if f.hashes == 0 {
+		return fmt.Errorf("not a bloom filter")
+	}
+	data = data[20:]
+	f.bits = make([]uint64, len(data)/8)
+	for i := range f.bits {
+		f.bits[i] = binary.LittleEndian.Uint64(data[i*8:])
+	}
+	return nil
+}
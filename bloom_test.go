@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestBloomFilter(t *testing.T) {
+	filter := newBloomFilter(1000, 0.01)
+	for i := range 1000 {
+		filter.add(fmt.Sprintf("added-%d", i))
+	}
+	for i := range 1000 {
+		if !filter.mayContain(fmt.Sprintf("added-%d", i)) {
+			t.Fatalf("mayContain(added-%d) = false for an added key", i)
+		}
+	}
+
+	falsePositives := 0
+	for i := range 10000 {
+		if filter.mayContain(fmt.Sprintf("other-%d", i)) {
+			falsePositives++
+		}
+	}
+	if falsePositives > 300 {
+		t.Errorf("mayContain() reported %d of 10000 other keys, want about 1%%", falsePositives)
+	}
+	if filter.full() {
+		t.Errorf("full() = true at capacity, want false")
+	}
+	filter.add("one more")
+	if !filter.full() {
+		t.Errorf("full() = false past capacity, want true")
+	}
+}
+
+func TestBloomFilterBinary(t *testing.T) {
+	filter := newBloomFilter(100, 0.01)
+	filter.add("kept")
+	data, err := filter.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary() unexpected error = %v", err)
+	}
+
+	var loaded bloomFilter
+	if err := loaded.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary() unexpected error = %v", err)
+	}
+	if !loaded.mayContain("kept") || loaded.count != 1 || loaded.capacity != 100 {
+		t.Errorf("UnmarshalBinary() = %d keys of %d, want the filter written", loaded.count, loaded.capacity)
+	}
+
+	for _, data := range [][]byte{nil, []byte("not a filter"), data[:len(data)-3]} {
+		if err := (&bloomFilter{}).UnmarshalBinary(data); err == nil {
+			t.Errorf("UnmarshalBinary(%q) expected error but got none", data)
+		}
+	}
+}
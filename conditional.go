@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/feeds"
+)
+
+// servedFeed is the aggregate as rendered for /feed.xml, with the validators
+// clients send back to poll it conditionally.
+type servedFeed struct {
+	feed     *feeds.Feed
+	body     []byte
+	etag     string
+	modified time.Time
+	maxAge   time.Duration
+
+	// pages holds the archive pages linked from the feed, by file name.
+	pages map[string]*servedFeed
+}
+
+// newServedFeed keeps body, feed as written in the output format. The ETag
+// is a hash of body, so it changes exactly when the served bytes do, and
+// Last-Modified is when the newest of its items was last updated, so that
+// rebuilding the feed without new items does not make clients fetch it
+// again. maxAge, the feed's <ttl>, tells caches how long to keep it.
+func newServedFeed(feed *feeds.Feed, body []byte, maxAge time.Duration) *servedFeed {
+	sum := sha256.Sum256(body)
+	return &servedFeed{
+		feed:     feed,
+		body:     body,
+		etag:     `"` + hex.EncodeToString(sum[:16]) + `"`,
+		modified: lastModified(feed),
+		maxAge:   maxAge,
+	}
+}
+
+// lastModified returns the latest Updated or Created date of feed's items,
+// no later than the feed's build time, or the build time when no item is
+// dated.
+func lastModified(feed *feeds.Feed) time.Time {
+	var modified time.Time
+	for _, item := range feed.Items {
+		if date := cmp.Or(item.Updated, item.Created); date.After(modified) {
+			modified = date
+		}
+	}
+	if modified.IsZero() || (!feed.Created.IsZero() && modified.After(feed.Created)) {
+		return feed.Created
+	}
+	return modified
+}
+
+// serve writes the feed, or 304 Not Modified when the request's
+// If-None-Match or If-Modified-Since shows the client has it already.
+func (s *servedFeed) serve(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("ETag", s.etag)
+	if s.maxAge > 0 {
+		w.Header().Set("Cache-Control", "max-age="+strconv.Itoa(int(s.maxAge/time.Second)))
+	}
+	http.ServeContent(w, r, "", s.modified, bytes.NewReader(s.body))
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/feeds"
+)
+
+func TestDaemonFeedConditional(t *testing.T) {
+	d := newDaemon(&Config{})
+	built := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	d.feed = &feeds.Feed{Title: "Aggregate", Link: &feeds.Link{Href: "https://example.com"}, Created: built}
+	server := httptest.NewServer(d.handler())
+	defer server.Close()
+
+	get := func(header, value string) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest("GET", server.URL+"/feed.xml", nil)
+		if err != nil {
+			t.Fatalf("Failed to create request: %v", err)
+		}
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("GET /feed.xml failed: %v", err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	resp := get("", "")
+	etag := resp.Header.Get("ETag")
+	if resp.StatusCode != http.StatusOK || etag == "" {
+		t.Fatalf("GET /feed.xml = %d with ETag %q, want 200 with an ETag", resp.StatusCode, etag)
+	}
+	if got, want := resp.Header.Get("Last-Modified"), built.Format(http.TimeFormat); got != want {
+		t.Errorf("Last-Modified = %q, want %q", got, want)
+	}
+	if got := resp.Header.Get("Content-Type"); got != d.config.format().ContentType {
+		t.Errorf("Content-Type = %q, want %q", got, d.config.format().ContentType)
+	}
+
+	tests := []struct {
+		name       string
+		header     string
+		value      string
+		wantStatus int
+	}{
+		{"matching ETag", "If-None-Match", etag, http.StatusNotModified},
+		{"other ETag", "If-None-Match", `"stale"`, http.StatusOK},
+		{"not modified since", "If-Modified-Since", built.Format(http.TimeFormat), http.StatusNotModified},
+		{"modified since", "If-Modified-Since", built.Add(-time.Hour).Format(http.TimeFormat), http.StatusOK},
+	}
+	for _, tt := range tests {
+		if resp := get(tt.header, tt.value); resp.StatusCode != tt.wantStatus {
+			t.Errorf("%s: GET /feed.xml = %d, want %d", tt.name, resp.StatusCode, tt.wantStatus)
+		}
+	}
+
+	d.mu.Lock()
+	d.feed = &feeds.Feed{Title: "Updated", Link: &feeds.Link{Href: "https://example.com"}, Created: built.Add(time.Hour)}
+	d.mu.Unlock()
+	resp = get("If-None-Match", etag)
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("ETag") == etag {
+		t.Errorf("GET /feed.xml after an update = %d with ETag %q, want 200 with a new ETag", resp.StatusCode, resp.Header.Get("ETag"))
+	}
+}
+
+func TestDaemonFeedCacheControl(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  *Config
+		want string
+	}{
+		{"no interval", &Config{}, ""},
+		{"interval", &Config{Interval: 15 * time.Minute}, "max-age=900"},
+		{"ttl overrides interval", &Config{Interval: 15 * time.Minute, TTL: time.Hour}, "max-age=3600"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newDaemon(tt.cfg)
+			d.feed = &feeds.Feed{Title: "Aggregate", Link: &feeds.Link{Href: "https://example.com"}, Created: time.Now()}
+			rec := httptest.NewRecorder()
+			d.handler().ServeHTTP(rec, httptest.NewRequest("GET", "/feed.xml", nil))
+			if got := rec.Header().Get("Cache-Control"); got != tt.want {
+				t.Errorf("Cache-Control = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLastModified(t *testing.T) {
+	built := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	item := func(created, updated time.Time) *feeds.Item {
+		return &feeds.Item{Title: "Item", Created: created, Updated: updated}
+	}
+	tests := []struct {
+		name  string
+		items []*feeds.Item
+		want  time.Time
+	}{
+		{"no items", nil, built},
+		{"newest created", []*feeds.Item{item(built.Add(-3*time.Hour), time.Time{}), item(built.Add(-2*time.Hour), time.Time{})}, built.Add(-2 * time.Hour)},
+		{"updated later", []*feeds.Item{item(built.Add(-3*time.Hour), built.Add(-time.Hour)), item(built.Add(-2*time.Hour), time.Time{})}, built.Add(-time.Hour)},
+		{"dated in the future", []*feeds.Item{item(built.Add(time.Hour), time.Time{})}, built},
+		{"undated", []*feeds.Item{item(time.Time{}, time.Time{})}, built},
+	}
+	for _, tt := range tests {
+		feed := &feeds.Feed{Created: built, Items: tt.items}
+		if got := lastModified(feed); !got.Equal(tt.want) {
+			t.Errorf("%s: lastModified() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
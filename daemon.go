@@ -0,0 +1,741 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/SlyMarbo/rss"
+	"github.com/gorilla/feeds"
+	"go.opentelemetry.io/otel/attribute"
+	"golang.org/x/net/netutil"
+)
+
+const (
+	webSubCallbackPath = "/websub/callback/"
+	maxWebSubBody      = 10 << 20
+)
+
+// daemon keeps the most recent version of every source so the aggregate can
+// be rebuilt when a single source changes, and serves it over HTTP.
+type daemon struct {
+	config *Config
+
+	// publishMu serializes publishing, which writes the output file.
+	publishMu sync.Mutex
+	// adminMu serializes changes to the input files through the admin API.
+	adminMu sync.Mutex
+	// refreshes requests an immediate refresh; true fetches every source.
+	refreshes chan bool
+
+	mu            sync.Mutex
+	sources       map[string]*SourceFeed
+	order         []string
+	entries       map[string]Source
+	subscriptions map[string]*webSubSubscription
+	feed          *feeds.Feed
+	served        *servedFeed
+	lastSuccess   time.Time
+	lastError     string
+	// started is when the daemon started, which stands in for the last
+	// success until the first one.
+	started time.Time
+	// history holds the items published since the daemon started, newest
+	// first, for paging through history. It is not persisted.
+	history []*feeds.Item
+	// favicons holds the -favicons of the sources' sites, by origin.
+	favicons map[string]string
+
+	// reader is set when the reader APIs are served.
+	reader *readerStore
+}
+
+// daemonHealth is served at /healthz for the healthcheck subcommand and
+// other monitoring.
+type daemonHealth struct {
+	Status      string    `json:"status"`
+	LastSuccess time.Time `json:"last_success,omitzero"`
+	LastError   string    `json:"last_error,omitempty"`
+}
+
+func newDaemon(config *Config) *daemon {
+	return &daemon{
+		config:        config,
+		sources:       make(map[string]*SourceFeed),
+		subscriptions: make(map[string]*webSubSubscription),
+		refreshes:     make(chan bool, 1),
+		started:       time.Now(),
+	}
+}
+
+// watchdogGrace is how long a refresh may run late before the watchdog
+// stops being pinged.
+const watchdogGrace = 30 * time.Minute
+
+// overdue reports whether the daemon has not refreshed successfully since
+// well after it was due to, as when its refresh loop hangs.
+func (d *daemon) overdue(now time.Time) bool {
+	d.mu.Lock()
+	last := d.lastSuccess
+	if last.IsZero() {
+		last = d.started
+	}
+	d.mu.Unlock()
+
+	due := last.Add(d.config.Interval)
+	if d.config.Schedule != nil {
+		if due = d.config.Schedule.next(last); due.IsZero() {
+			return false
+		}
+	}
+	return now.After(due.Add(watchdogGrace))
+}
+
+// runDaemon runs a daemon for each profile, serving them all from one
+// HTTP server with -listen, until ctx is cancelled. It returns early only
+// when the daemons cannot be set up.
+func runDaemon(ctx context.Context, config *Config) error {
+	var daemons []*daemon
+	for _, profile := range config.profiles() {
+		profile.done = ctx.Done()
+		d := newDaemon(profile)
+		if profile.ReaderLogin != "" {
+			store, err := loadReaderStore(profile.ReaderState)
+			if err != nil {
+				return fmt.Errorf("%s%v", profile.logPrefix(), err)
+			}
+			d.reader = store
+		}
+		daemons = append(daemons, d)
+	}
+
+	if config.Pprof != "" {
+		server, err := servePprof(config.Pprof)
+		if err != nil {
+			return fmt.Errorf("error serving pprof: %v", err)
+		}
+		defer server.Close()
+	}
+
+	if config.Listen != "" {
+		listener, err := daemonListener(config.Listen)
+		if err != nil {
+			return fmt.Errorf("error serving HTTP: %v", err)
+		}
+		if config.MaxConnections > 0 {
+			listener = netutil.LimitListener(listener, config.MaxConnections)
+		}
+		if len(config.TLSDomains) > 0 {
+			if listener, err = tlsListener(config, listener); err != nil {
+				return fmt.Errorf("error serving HTTPS: %v", err)
+			}
+		}
+		handler := daemonsHandler(daemons)
+		if config.RateLimit > 0 {
+			handler = limitRequests(newRateLimiter(config.RateLimit, time.Minute), handler)
+		}
+		// The timeouts keep slow or idle clients from holding connections,
+		// which matters most under -max-connections.
+		server := &http.Server{
+			Handler:           handler,
+			ReadHeaderTimeout: 10 * time.Second,
+			IdleTimeout:       2 * time.Minute,
+		}
+		go func() {
+			if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Printf("Error serving HTTP: %v", err)
+			}
+		}()
+		defer server.Shutdown(context.Background())
+	}
+
+	// Report ready before the first refresh, which may take longer than
+	// systemd waits for a service to start.
+	sdNotify("READY=1")
+	defer sdNotify("STOPPING=1")
+	go sdWatchdog(ctx, func() bool {
+		for _, d := range daemons {
+			if d.overdue(time.Now()) {
+				log.Printf("%sWarning: no successful refresh for too long, not pinging the systemd watchdog", d.config.logPrefix())
+				return false
+			}
+		}
+		return true
+	})
+
+	var wg sync.WaitGroup
+	for _, d := range daemons {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d.run(ctx)
+		}()
+	}
+	wg.Wait()
+	return nil
+}
+
+// daemonsHandler serves a single daemon at the root, and each of several
+// profiles under its name, e.g. /tech/feed.xml.
+func daemonsHandler(daemons []*daemon) http.Handler {
+	if len(daemons) == 1 && daemons[0].config.Name == "" {
+		return daemons[0].handler()
+	}
+	mux := http.NewServeMux()
+	var names []string
+	for _, d := range daemons {
+		prefix := "/" + d.config.Name
+		mux.Handle(prefix+"/", http.StripPrefix(prefix, d.handler()))
+		names = append(names, d.config.Name)
+	}
+	mux.Handle("GET /{$}", requireAuth(daemons[0].config, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serveWebUIIndex(w, names)
+	})))
+	return mux
+}
+
+// run aggregates immediately and then once per interval or at each
+// scheduled time, or as soon as an input file changes, until ctx is
+// cancelled. Failed runs are logged and retried on the next tick.
+//
+// SIGHUP reloads the feed lists and rebuilds the aggregate at once, reusing
+// cached sources; SIGUSR1 fetches every source again at once.
+func (d *daemon) run(ctx context.Context) {
+	config := d.config
+	changes, err := watchInputFiles(ctx, config.InputFiles)
+	if err != nil {
+		log.Printf("%sWarning: cannot watch input files, changes apply at the next interval: %v", config.logPrefix(), err)
+	}
+
+	signals := make(chan os.Signal, 1)
+	notifyControlSignals(signals)
+	defer signal.Stop(signals)
+
+	var tick <-chan time.Time
+	if config.Interval > 0 {
+		ticker := time.NewTicker(config.Interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	force := false
+	for {
+		d.refresh(force)
+		force = false
+
+		if config.Schedule != nil {
+			next := config.Schedule.next(time.Now())
+			if next.IsZero() {
+				log.Printf("%sWarning: schedule never matches again, no more scheduled refreshes", config.logPrefix())
+				tick = nil
+			} else {
+				tick = time.After(time.Until(next))
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick:
+		case <-changes:
+			log.Printf("%sInput changed, reloading sources", config.logPrefix())
+		case force = <-d.refreshes:
+			log.Printf("%sRefresh requested through the admin API", config.logPrefix())
+		case sig := <-signals:
+			switch sig {
+			case reloadSignal:
+				log.Printf("%sReceived %v, reloading sources", config.logPrefix(), sig)
+			case refreshSignal:
+				log.Printf("%sReceived %v, fetching all sources", config.logPrefix(), sig)
+				force = true
+			}
+		}
+	}
+}
+
+// daemonListener listens on addr, or on the socket systemd passed for
+// -listen systemd.
+func daemonListener(addr string) (net.Listener, error) {
+	if addr == systemdListen {
+		return systemdListener()
+	}
+	return net.Listen("tcp", addr)
+}
+
+// refresh fetches the sources and publishes the aggregate. force fetches
+// even sources cached less than -cache-ttl ago.
+func (d *daemon) refresh(force bool) {
+	config, span := startRun(d.config, "refresh")
+	if force {
+		config.CacheTTL = 0
+	}
+
+	stats, err := d.refreshSources(config)
+	if err == nil {
+		err = d.publish(config)
+	}
+	endSpan(span, err)
+
+	d.mu.Lock()
+	if err != nil {
+		d.lastError = err.Error()
+	} else {
+		d.lastSuccess = time.Now()
+		d.lastError = ""
+	}
+	d.mu.Unlock()
+
+	d.publishMu.Lock()
+	recordSourceHealth(d.config, stats, time.Now())
+	sendOperatorAlerts(d.config, checkOperatorAlerts(d.config, stats, time.Now()), time.Now())
+	d.publishMu.Unlock()
+	if stats != nil && d.config.Name == "" {
+		sdNotify(fmt.Sprintf("STATUS=Fetched %d of %d sources at %s", stats.Sources-stats.Failed, stats.Sources, time.Now().Format(time.Kitchen)))
+	}
+	if err != nil {
+		log.Printf("%sError %v", d.config.logPrefix(), err)
+	}
+}
+
+// refreshSources polls every source, except those whose updates are
+// currently pushed to us over WebSub.
+func (d *daemon) refreshSources(config *Config) (*RunStats, error) {
+	if config.WebSubCallback == "" {
+		sources, stats, err := collectSources(config)
+		if err != nil {
+			return stats, fmt.Errorf("aggregating feeds: %v", err)
+		}
+
+		d.mu.Lock()
+		d.sources = make(map[string]*SourceFeed)
+		d.order = nil
+		for _, source := range sources {
+			d.sources[source.URL] = source
+			d.order = append(d.order, source.URL)
+		}
+		d.mu.Unlock()
+
+		return stats, nil
+	}
+
+	entries, err := config.sources()
+	if err != nil {
+		return &RunStats{}, fmt.Errorf("aggregating feeds: %v", err)
+	}
+
+	now := time.Now()
+	var urls []string
+	var toFetch []Source
+	d.mu.Lock()
+	d.entries = make(map[string]Source)
+	for _, entry := range entries {
+		urls = append(urls, entry.URL)
+		d.entries[entry.URL] = entry
+		if !d.pushed(entry.URL, now) {
+			toFetch = append(toFetch, entry)
+		}
+	}
+	d.mu.Unlock()
+
+	fetched, stats := fetchSources(config, toFetch)
+	stats.Sources = len(urls)
+
+	d.mu.Lock()
+	next := make(map[string]*SourceFeed)
+	for _, url := range urls {
+		if source, ok := d.sources[url]; ok && d.pushed(url, now) {
+			next[url] = source
+		}
+	}
+	for _, source := range fetched {
+		next[source.URL] = source
+	}
+	d.sources = next
+	d.order = urls
+	d.mu.Unlock()
+
+	d.manageSubscriptions(fetched, now)
+
+	return stats, nil
+}
+
+// pushed reports whether url has a verified, unexpired WebSub subscription.
+// d.mu must be held.
+func (d *daemon) pushed(url string, now time.Time) bool {
+	for _, sub := range d.subscriptions {
+		if sub.SourceURL == url && sub.Active && sub.Expires.After(now) {
+			return true
+		}
+	}
+	return false
+}
+
+// manageSubscriptions subscribes to hubs advertised by freshly fetched
+// sources and renews subscriptions that would lapse before the next tick.
+func (d *daemon) manageSubscriptions(fetched []*SourceFeed, now time.Time) {
+	// A schedule may leave most of a day between refreshes.
+	renewBefore := now.Add(2 * cmp.Or(d.config.Interval, 24*time.Hour))
+
+	var requests []*webSubSubscription
+	d.mu.Lock()
+	for _, source := range fetched {
+		if source.WebSubHub == "" {
+			continue
+		}
+		sub := newWebSubSubscription(source)
+		existing, ok := d.subscriptions[sub.ID]
+		if ok && existing.Hub == sub.Hub && existing.Topic == sub.Topic {
+			if !existing.Active || existing.Expires.Before(renewBefore) {
+				requests = append(requests, existing)
+			}
+			continue
+		}
+		d.subscriptions[sub.ID] = sub
+		requests = append(requests, sub)
+	}
+	for _, sub := range d.subscriptions {
+		if sub.Active && sub.Expires.Before(renewBefore) && !slices.Contains(requests, sub) {
+			requests = append(requests, sub)
+		}
+	}
+	d.mu.Unlock()
+
+	for _, sub := range requests {
+		if err := sub.request("subscribe", d.config.WebSubCallback); err != nil {
+			log.Printf("Warning: failed to subscribe to %s via %s: %v", sub.Topic, sub.Hub, err)
+		}
+	}
+}
+
+// publish rebuilds and publishes the aggregate from the sources fetched
+// so far, within config's run.
+func (d *daemon) publish(config *Config) error {
+	d.publishMu.Lock()
+	defer d.publishMu.Unlock()
+
+	d.mu.Lock()
+	sources := make([]*SourceFeed, 0, len(d.order))
+	for _, url := range d.order {
+		if source, ok := d.sources[url]; ok {
+			sources = append(sources, source)
+		}
+	}
+	d.mu.Unlock()
+
+	_, span := config.startSpan("build", attribute.Int("sources", len(sources)))
+	aggregatedFeed := buildFeed(config, sources)
+	span.End()
+	if config.SourceOutputDir != "" {
+		writeSourceFeeds(config, sources)
+	}
+
+	d.mu.Lock()
+	d.feed = aggregatedFeed
+	d.remember(aggregatedFeed.Items)
+	d.mu.Unlock()
+
+	err := publishFeed(config, aggregatedFeed, sources)
+	if config.Favicons {
+		d.updateFavicons(config, sources)
+	}
+	return err
+}
+
+// updateFavicons fetches the favicons of the sites of sources that are not
+// known yet, for the web UI.
+func (d *daemon) updateFavicons(config *Config, sources []*SourceFeed) {
+	var origins []string
+	for _, source := range sources {
+		if origin := siteOrigin(source); origin != "" {
+			origins = append(origins, origin)
+		}
+	}
+
+	d.mu.Lock()
+	known := d.favicons
+	d.mu.Unlock()
+
+	icons := fetchFavicons(origins, known, config.CacheDir, config.Offline)
+
+	d.mu.Lock()
+	d.favicons = icons
+	d.mu.Unlock()
+}
+
+// handler serves the daemon's endpoints. The feed, web UI, items API and
+// aggregate proxy require the -auth-basic or -auth-token credentials when
+// set; the health check and WebSub callbacks stay open for monitors and
+// hubs, and the admin and reader APIs check their own credentials.
+func (d *daemon) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("GET /{$}", requireAuth(d.config, http.HandlerFunc(d.serveWebUI)))
+	mux.Handle("GET /feed.xml", requireAuth(d.config, http.HandlerFunc(d.serveFeed)))
+	if d.config.PageSize > 0 {
+		mux.Handle("GET /{page}", requireAuth(d.config, http.HandlerFunc(d.serveArchivePage)))
+	}
+	mux.Handle("GET /api/items", requireAuth(d.config, http.HandlerFunc(d.serveAPIItems)))
+	if d.config.AggregateProxy {
+		mux.Handle("GET /aggregate", requireAuth(d.config, newFeedProxy(d.config)))
+	}
+	mux.HandleFunc("GET /healthz", d.serveHealth)
+	mux.HandleFunc("GET "+webSubCallbackPath+"{id}", d.verifyWebSub)
+	mux.HandleFunc("POST "+webSubCallbackPath+"{id}", d.receiveWebSub)
+	if d.config.AdminToken != "" {
+		mux.Handle("/admin/", d.adminHandler())
+	}
+	if d.reader != nil {
+		mux.HandleFunc(feverPath+"{$}", d.serveFever)
+		greader := d.greaderHandler()
+		mux.Handle("/accounts/ClientLogin", greader)
+		mux.Handle("/reader/api/0/", greader)
+	}
+	return mux
+}
+
+func (d *daemon) serveFeed(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseFeedFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	d.mu.Lock()
+	aggregatedFeed := d.feed
+	sources := slices.Collect(maps.Values(d.sources))
+	d.mu.Unlock()
+
+	if aggregatedFeed == nil {
+		http.Error(w, "feed not ready yet", http.StatusServiceUnavailable)
+		return
+	}
+
+	var served *servedFeed
+
+	// The aggregate is rendered once per update, so polling clients, and
+	// 304 responses in particular, cost little. Filtered views are rendered
+	// for each request, without archive pages.
+	if filter != nil {
+		tags := make(map[string][]string)
+		for _, source := range sources {
+			tags[source.URL] = source.Tags
+		}
+		history := *aggregatedFeed
+		if filter.paged() {
+			history.Items = d.historyItems(aggregatedFeed)
+		}
+		page, more := filter.apply(&history, tags, d.config.Count)
+		setPageLinks(w, r, filter, d.config.Count, more)
+		served, err = d.renderFeed(page, sources, false)
+	} else {
+		served, err = d.renderServed()
+	}
+	if err != nil {
+		log.Printf("Warning: failed to serve feed: %v", err)
+		http.Error(w, "error rendering feed", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", d.config.format().ContentType)
+	served.serve(w, r)
+}
+
+// renderServed returns the current aggregate as served, with its archive
+// pages, rendering it once per update. It is nil before the first update.
+func (d *daemon) renderServed() (*servedFeed, error) {
+	d.mu.Lock()
+	aggregatedFeed := d.feed
+	served := d.served
+	sources := slices.Collect(maps.Values(d.sources))
+	d.mu.Unlock()
+
+	if aggregatedFeed == nil || (served != nil && served.feed == aggregatedFeed) {
+		return served, nil
+	}
+	served, err := d.renderFeed(aggregatedFeed, sources, true)
+	if err != nil {
+		return nil, err
+	}
+	d.mu.Lock()
+	if d.feed == aggregatedFeed {
+		d.served = served
+	}
+	d.mu.Unlock()
+	return served, nil
+}
+
+// renderFeed writes feed in the output format, split into archive pages
+// with -page-size when paged is set.
+func (d *daemon) renderFeed(feed *feeds.Feed, sources []*SourceFeed, paged bool) (*servedFeed, error) {
+	opts := withCategories(d.config.rssOptions(), sources)
+	written := feed
+	var pages []archivePage
+	if paged {
+		written, pages = pageFeed(d.config, feed)
+	}
+	var buf bytes.Buffer
+	if err := d.config.format().Write(&buf, written, subscriptionOptions(d.config, opts, pages)); err != nil {
+		return nil, err
+	}
+	served := newServedFeed(feed, buf.Bytes(), opts.TTL)
+
+	// Archive pages are cached as long as the feed: the newest one fills up
+	// as items leave the feed, and every page links to its neighbours.
+	served.pages = make(map[string]*servedFeed, len(pages))
+	for i, archive := range pages {
+		page := *feed
+		page.Items = archive.Items
+		var buf bytes.Buffer
+		if err := d.config.format().Write(&buf, &page, archivePageOptions(d.config, opts, pages, i)); err != nil {
+			return nil, err
+		}
+		served.pages[path.Base(archivePageName(d.config.SelfURL, archive.Key))] = newServedFeed(&page, buf.Bytes(), opts.TTL)
+	}
+	return served, nil
+}
+
+// serveArchivePage serves one of the archive pages the current feed links
+// to; pages it no longer links to are gone.
+func (d *daemon) serveArchivePage(w http.ResponseWriter, r *http.Request) {
+	served, err := d.renderServed()
+	if err != nil {
+		log.Printf("Warning: failed to serve feed: %v", err)
+		http.Error(w, "error rendering feed", http.StatusInternalServerError)
+		return
+	}
+
+	var page *servedFeed
+	if served != nil {
+		page = served.pages[r.PathValue("page")]
+	}
+	if page == nil {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", d.config.format().ContentType)
+	page.serve(w, r)
+}
+
+// serveHealth reports when the aggregate was last updated. It fails until
+// the first update, so a container is not considered healthy before it
+// serves a feed.
+func (d *daemon) serveHealth(w http.ResponseWriter, r *http.Request) {
+	d.mu.Lock()
+	health := daemonHealth{Status: "ok", LastSuccess: d.lastSuccess, LastError: d.lastError}
+	d.mu.Unlock()
+
+	status := http.StatusOK
+	if health.LastSuccess.IsZero() {
+		health.Status = "starting"
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(health)
+}
+
+// verifyWebSub answers a hub's intent verification for one of our
+// subscriptions.
+func (d *daemon) verifyWebSub(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	sub, ok := d.subscriptions[r.PathValue("id")]
+	if !ok || query.Get("hub.topic") != sub.Topic {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch query.Get("hub.mode") {
+	case "subscribe":
+		lease, err := strconv.Atoi(query.Get("hub.lease_seconds"))
+		if err != nil || lease <= 0 {
+			lease = int(webSubLease / time.Second)
+		}
+		sub.Active = true
+		sub.Expires = time.Now().Add(time.Duration(lease) * time.Second)
+	case "unsubscribe":
+		sub.Active = false
+	case "denied":
+		sub.Active = false
+		log.Printf("Warning: hub %s denied subscription to %s: %s", sub.Hub, sub.Topic, query.Get("hub.reason"))
+		return
+	default:
+		http.Error(w, "unknown hub.mode", http.StatusBadRequest)
+		return
+	}
+
+	io.WriteString(w, query.Get("hub.challenge"))
+}
+
+// receiveWebSub handles content pushed by a hub: the body is the updated
+// feed, which replaces that source before the aggregate is rebuilt.
+func (d *daemon) receiveWebSub(w http.ResponseWriter, r *http.Request) {
+	d.mu.Lock()
+	sub, ok := d.subscriptions[r.PathValue("id")]
+	d.mu.Unlock()
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxWebSubBody))
+	if err != nil {
+		http.Error(w, "error reading body", http.StatusBadRequest)
+		return
+	}
+
+	// Hubs expect a 2xx even when the signature does not match; the content
+	// is just ignored.
+	w.WriteHeader(http.StatusAccepted)
+
+	if !sub.verifySignature(r.Header.Get("X-Hub-Signature"), body) {
+		log.Printf("Warning: ignoring WebSub delivery for %s with invalid signature", sub.Topic)
+		return
+	}
+
+	parsed, err := rss.Parse(body)
+	if err != nil {
+		log.Printf("Warning: failed to parse WebSub delivery for %s: %v", sub.Topic, err)
+		return
+	}
+
+	source := newSourceFeed(sub.SourceURL, parsed)
+	source.Fetched = time.Now()
+	source.readDates(body)
+	source.readThumbnails(body)
+	source.Language = cmp.Or(source.Language, documentLanguage(body))
+	fillMissingDates(source, d.config.MissingDate)
+	source.WebSubHub, source.WebSubTopic = sub.Hub, sub.Topic
+
+	d.mu.Lock()
+	d.entries[sub.SourceURL].apply(source)
+	d.sources[sub.SourceURL] = source
+	d.mu.Unlock()
+
+	go func() {
+		config, span := startRun(d.config, "push")
+		span.SetAttributes(attribute.String("url", sub.SourceURL))
+		err := d.publish(config)
+		endSpan(span, err)
+		if err != nil {
+			log.Printf("Error %v", err)
+		}
+	}()
+}
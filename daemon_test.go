@@ -0,0 +1,213 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const pushedFeed = `<?xml version="1.0"?>
+<rss version="2.0"><channel><title>Pushed</title><link>https://example.com/</link>
+<item><title>Pushed Item</title><link>https://example.com/pushed</link><pubDate>Mon, 02 Jan 2006 15:04:05 GMT</pubDate></item>
+</channel></rss>`
+
+func TestDaemonWebSubCallback(t *testing.T) {
+	var form url.Values
+	hub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		form = r.PostForm
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer hub.Close()
+
+	tempDir, err := os.MkdirTemp("", "rss_daemon")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	config := &Config{
+		Count:          10,
+		OutputFile:     filepath.Join(tempDir, "out.xml"),
+		Interval:       time.Hour,
+		WebSubCallback: "https://agg.example.com",
+	}
+	d := newDaemon(config)
+	server := httptest.NewServer(d.handler())
+	defer server.Close()
+
+	source := &SourceFeed{URL: "https://example.com/feed", WebSubHub: hub.URL, WebSubTopic: "https://example.com/feed"}
+	d.order = []string{source.URL}
+	d.sources[source.URL] = source
+	d.manageSubscriptions([]*SourceFeed{source}, time.Now())
+
+	if form.Get("hub.mode") != "subscribe" || form.Get("hub.topic") != source.WebSubTopic {
+		t.Fatalf("hub received mode=%q topic=%q", form.Get("hub.mode"), form.Get("hub.topic"))
+	}
+	callback := form.Get("hub.callback")
+	if !strings.HasPrefix(callback, config.WebSubCallback+webSubCallbackPath) {
+		t.Fatalf("hub.callback = %q, want prefix %q", callback, config.WebSubCallback+webSubCallbackPath)
+	}
+	callbackPath := strings.TrimPrefix(callback, config.WebSubCallback)
+
+	// Intent verification.
+	query := url.Values{
+		"hub.mode":          {"subscribe"},
+		"hub.topic":         {source.WebSubTopic},
+		"hub.challenge":     {"abc123"},
+		"hub.lease_seconds": {"3600"},
+	}
+	resp, err := http.Get(server.URL + callbackPath + "?" + query.Encode())
+	if err != nil {
+		t.Fatalf("verification request failed: %v", err)
+	}
+	challenge := readBody(t, resp)
+	if resp.StatusCode != http.StatusOK || challenge != "abc123" {
+		t.Errorf("verification = %d %q, want 200 %q", resp.StatusCode, challenge, "abc123")
+	}
+	if !d.pushed(source.URL, time.Now()) {
+		t.Errorf("pushed() = false after verification, want true")
+	}
+
+	// Content distribution updates the served feed.
+	mac := hmac.New(sha256.New, []byte(form.Get("hub.secret")))
+	mac.Write([]byte(pushedFeed))
+	req, _ := http.NewRequest(http.MethodPost, server.URL+callbackPath, strings.NewReader(pushedFeed))
+	req.Header.Set("X-Hub-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("content distribution request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		t.Errorf("content distribution status = %d, want %d", resp.StatusCode, http.StatusAccepted)
+	}
+
+	var served string
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(20 * time.Millisecond) {
+		resp, err := http.Get(server.URL + "/feed.xml")
+		if err != nil {
+			t.Fatalf("feed request failed: %v", err)
+		}
+		served = readBody(t, resp)
+		if strings.Contains(served, "Pushed Item") {
+			break
+		}
+	}
+	if !strings.Contains(served, "Pushed Item") {
+		t.Errorf("/feed.xml = %q, want pushed item", served)
+	}
+}
+
+func TestDaemonWebSubCallbackUnknown(t *testing.T) {
+	d := newDaemon(&Config{})
+	server := httptest.NewServer(d.handler())
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + webSubCallbackPath + "missing?hub.mode=subscribe&hub.challenge=x")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("unknown subscription status = %d, want %d", resp.StatusCode, http.StatusNotFound)
+	}
+}
+
+func readBody(t *testing.T, resp *http.Response) string {
+	t.Helper()
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("reading body: %v", err)
+	}
+	return string(body)
+}
+
+func TestDaemonRefreshForce(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.Write([]byte(cacheTestFeed))
+	}))
+	defer server.Close()
+
+	tempDir, err := os.MkdirTemp("", "rss_daemon")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	inputFile := filepath.Join(tempDir, "feeds.txt")
+	if err := os.WriteFile(inputFile, []byte(server.URL+"\n"), 0644); err != nil {
+		t.Fatalf("Failed to write input file: %v", err)
+	}
+
+	config := &Config{
+		InputFiles: []string{inputFile},
+		Count:      10,
+		OutputFile: filepath.Join(tempDir, "out.xml"),
+		Interval:   time.Hour,
+		CacheDir:   filepath.Join(tempDir, "cache"),
+		CacheTTL:   time.Hour,
+	}
+	d := newDaemon(config)
+
+	d.refresh(false)
+	d.refresh(false)
+	if got := atomic.LoadInt32(&hits); got != 1 {
+		t.Errorf("refresh() fetched %d times within the cache TTL, want 1", got)
+	}
+
+	d.refresh(true)
+	if got := atomic.LoadInt32(&hits); got != 2 {
+		t.Errorf("refresh(force) fetched %d times, want 2", got)
+	}
+	if config.CacheTTL != time.Hour {
+		t.Errorf("refresh(force) changed the cache TTL to %v", config.CacheTTL)
+	}
+}
+
+func TestDaemonOverdue(t *testing.T) {
+	nightly, err := parseSchedule("0 3 * * *")
+	if err != nil {
+		t.Fatalf("parseSchedule() unexpected error = %v", err)
+	}
+	last := time.Date(2024, 3, 1, 3, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		name string
+		cfg  *Config
+		now  time.Time
+		want bool
+	}{
+		{"interval on time", &Config{Interval: time.Hour}, last.Add(time.Hour + time.Minute), false},
+		{"interval late", &Config{Interval: time.Hour}, last.Add(2 * time.Hour), true},
+		{"schedule waiting for the next night", &Config{Schedule: nightly}, last.Add(20 * time.Hour), false},
+		{"schedule missed a night", &Config{Schedule: nightly}, last.Add(25 * time.Hour), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newDaemon(tt.cfg)
+			d.lastSuccess = last
+			if got := d.overdue(tt.now); got != tt.want {
+				t.Errorf("overdue() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	d := newDaemon(&Config{Interval: time.Hour})
+	if d.overdue(time.Now()) {
+		t.Errorf("overdue() right after starting = true, want false")
+	}
+}
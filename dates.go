@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/xml"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/SlyMarbo/rss"
+	"github.com/gorilla/feeds"
+)
+
+// itemDates are the publication and last update dates a feed gives an
+// item, either of which may be missing.
+type itemDates struct {
+	Published time.Time
+	Updated   time.Time
+}
+
+// parseFeedDates reads the date of a fetched feed itself and the dates of
+// each of its items or entries, in document order. The feed parser keeps a
+// single date per item, Atom's updated or RSS's pubDate or dc:date, and
+// none for the feed. This reads Atom's published and updated (or issued and
+// modified) and, in RSS, atom:updated or dcterms:modified alongside
+// pubDate.
+func parseFeedDates(body []byte) (time.Time, []itemDates) {
+	var feedDate time.Time
+	var dates []itemDates
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	decoder.Strict = false
+	inItem := false
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			break
+		}
+		switch token := token.(type) {
+		case xml.StartElement:
+			name := token.Name.Local
+			if name == "item" || name == "entry" {
+				inItem = true
+				dates = append(dates, itemDates{})
+				continue
+			}
+			if !isDateElement(name) {
+				continue
+			}
+			var text string
+			if decoder.DecodeElement(&text, &token) != nil {
+				continue
+			}
+			date, ok := parseFeedDate(text)
+			if !ok {
+				continue
+			}
+			if !inItem {
+				if feedDate.IsZero() {
+					feedDate = date
+				}
+				continue
+			}
+			current := &dates[len(dates)-1]
+			switch name {
+			case "published", "issued", "pubDate", "date":
+				if current.Published.IsZero() {
+					current.Published = date
+				}
+			default:
+				if current.Updated.IsZero() {
+					current.Updated = date
+				}
+			}
+		case xml.EndElement:
+			if token.Name.Local == "item" || token.Name.Local == "entry" {
+				inItem = false
+			}
+		}
+	}
+	return feedDate, dates
+}
+
+func isDateElement(name string) bool {
+	switch name {
+	case "published", "issued", "pubDate", "date", "updated", "modified", "lastBuildDate":
+		return true
+	}
+	return false
+}
+
+// parseFeedDate parses a date in any of the layouts the feed parser knows.
+func parseFeedDate(s string) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+	for _, layouts := range [][]string{rss.TimeLayouts, rss.TimeLayoutsLoadLocation} {
+		for _, layout := range layouts {
+			if t, err := time.Parse(layout, s); err == nil {
+				return t, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// readDates dates source, and its items, from body, the document it was
+// parsed from.
+func (source *SourceFeed) readDates(body []byte) {
+	var dates []itemDates
+	source.Date, dates = parseFeedDates(body)
+	applyItemDates(source.Items, dates)
+}
+
+// applyItemDates dates items by their publication date, and by their last
+// update when the feed gives one apart from it. Items dated only by an
+// update are taken to have been published then. dates come from
+// parseFeedDates; when they do not line up with items, because the parser
+// dropped duplicate entries, items keep the date the parser gave them.
+func applyItemDates(items []*feeds.Item, dates []itemDates) {
+	if len(dates) != len(items) {
+		return
+	}
+	for i, item := range items {
+		if !dates[i].Published.IsZero() {
+			item.Created = dates[i].Published
+		}
+		if !dates[i].Updated.IsZero() {
+			item.Updated = dates[i].Updated
+		}
+		if item.Created.IsZero() || item.Updated.Equal(item.Created) {
+			item.Created = cmp.Or(item.Created, item.Updated)
+			item.Updated = time.Time{}
+		}
+	}
+}
+
+// fillMissingDates dates the items of source that have no date by mode:
+// "feed" gives them the date of the feed itself, or the time it was fetched
+// when it has none, "fetch" the time it was fetched, and "drop" drops them.
+// Any other mode leaves them undated, to sort after every dated item.
+func fillMissingDates(source *SourceFeed, mode string) {
+	date := source.Fetched
+	switch mode {
+	case "feed":
+		date = cmp.Or(source.Date, source.Fetched)
+	case "fetch":
+	case "drop":
+		source.Items = slices.DeleteFunc(source.Items, func(item *feeds.Item) bool {
+			return item.Created.IsZero()
+		})
+		return
+	default:
+		return
+	}
+	for _, item := range source.Items {
+		if item.Created.IsZero() {
+			item.Created = date
+		}
+	}
+}
+
+// inZone gives the dates of feed and its items in loc, naming the same
+// instants, so that readers showing them as given do not mix time zones.
+func inZone(feed *feeds.Feed, loc *time.Location) {
+	feed.Created = feed.Created.In(loc)
+	feed.Updated = feed.Updated.In(loc)
+	for _, item := range feed.Items {
+		item.Created = item.Created.In(loc)
+		item.Updated = item.Updated.In(loc)
+	}
+}
+
+// updatedDate is when item last changed: its update date, or its
+// publication date when it was never updated.
+func updatedDate(item *feeds.Item) time.Time {
+	if item.Updated.After(item.Created) {
+		return item.Updated
+	}
+	return item.Created
+}
+
+// now is the current time in -timezone, or the local time zone.
+func (config *Config) now() time.Time {
+	if config.Timezone != nil {
+		return time.Now().In(config.Timezone)
+	}
+	return time.Now()
+}
+
+// itemDate is the date of item that -sort-by picks.
+func (config *Config) itemDate(item *feeds.Item) time.Time {
+	if config.SortBy == "updated" {
+		return updatedDate(item)
+	}
+	return item.Created
+}
+
+// newer returns how items are ordered in the output: newest first by the
+// date -sort-by picks. Items of the same date, common with feeds published
+// in batches, are ordered by the URL of their source, then title, then
+// link, so that the order does not change between runs or depend on which
+// source happened to be fetched first.
+func (config *Config) newer() func(a, b *feeds.Item) bool {
+	return func(a, b *feeds.Item) bool {
+		if da, db := config.itemDate(a), config.itemDate(b); !da.Equal(db) {
+			return da.After(db)
+		}
+		return cmp.Or(
+			strings.Compare(linkHref(a.Source), linkHref(b.Source)),
+			strings.Compare(a.Title, b.Title),
+			strings.Compare(linkHref(a.Link), linkHref(b.Link)),
+		) < 0
+	}
+}
+
+func linkHref(link *feeds.Link) string {
+	if link == nil {
+		return ""
+	}
+	return link.Href
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/gorilla/feeds"
+)
+
+func TestParseFeedDates(t *testing.T) {
+	published := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	updated := time.Date(2024, 2, 3, 4, 5, 6, 0, time.UTC)
+	feedDate := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		body     string
+		wantFeed time.Time
+		want     []itemDates
+	}{
+		{
+			name: "atom",
+			body: `<feed xmlns="http://www.w3.org/2005/Atom"><updated>2025-01-01T00:00:00Z</updated>
+<entry><title>Edited</title><published>2024-01-02T03:04:05Z</published><updated>2024-02-03T04:05:06Z</updated></entry>
+<entry><title>Plain</title><updated>2024-01-02T03:04:05Z</updated></entry>
+</feed>`,
+			wantFeed: feedDate,
+			want:     []itemDates{{Published: published, Updated: updated}, {Updated: published}},
+		},
+		{
+			name: "rss",
+			body: `<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom"><channel><pubDate>Wed, 01 Jan 2025 00:00:00 GMT</pubDate>
+<item><title>Edited</title><pubDate>Tue, 02 Jan 2024 03:04:05 GMT</pubDate><atom:updated>2024-02-03T04:05:06Z</atom:updated></item>
+<item><title>Undated</title></item>
+</channel></rss>`,
+			wantFeed: feedDate,
+			want:     []itemDates{{Published: published, Updated: updated}, {}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotFeed, got := parseFeedDates([]byte(tt.body))
+			if !gotFeed.Equal(tt.wantFeed) {
+				t.Errorf("parseFeedDates() feed date = %v, want %v", gotFeed, tt.wantFeed)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("parseFeedDates() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if !got[i].Published.Equal(tt.want[i].Published) || !got[i].Updated.Equal(tt.want[i].Updated) {
+					t.Errorf("parseFeedDates()[%d] = %v, want %v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestFetchSourceItemDates(t *testing.T) {
+	atomContent := `<?xml version="1.0" encoding="UTF-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+<title>Test Feed</title>
+<id>urn:test</id>
+<updated>2024-03-01T00:00:00Z</updated>
+<entry><title>Edited</title><id>urn:1</id><link href="http://example.com/1"/><published>2024-01-01T00:00:00Z</published><updated>2024-03-01T00:00:00Z</updated></entry>
+<entry><title>Unedited</title><id>urn:2</id><link href="http://example.com/2"/><published>2024-02-01T00:00:00Z</published><updated>2024-02-01T00:00:00Z</updated></entry>
+</feed>`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(atomContent))
+	}))
+	defer server.Close()
+
+	source, err := fetchSourceFeed(Source{URL: server.URL})
+	if err != nil {
+		t.Fatalf("fetchSourceFeed() unexpected error = %v", err)
+	}
+	if len(source.Items) != 2 {
+		t.Fatalf("fetchSourceFeed() got %d items, want 2", len(source.Items))
+	}
+
+	edited, unedited := source.Items[0], source.Items[1]
+	if want := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC); !edited.Created.Equal(want) {
+		t.Errorf("edited item Created = %v, want the published date %v", edited.Created, want)
+	}
+	if want := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC); !edited.Updated.Equal(want) {
+		t.Errorf("edited item Updated = %v, want %v", edited.Updated, want)
+	}
+	if !unedited.Updated.IsZero() {
+		t.Errorf("unedited item Updated = %v, want none", unedited.Updated)
+	}
+}
+
+func TestNewerSortBy(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+	old := &feeds.Item{Title: "Old but edited", Created: day(1), Updated: day(10)}
+	recent := &feeds.Item{Title: "Recent", Created: day(5)}
+
+	tests := []struct {
+		sortBy string
+		want   bool
+	}{
+		{"", false},
+		{"published", false},
+		{"updated", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sortBy, func(t *testing.T) {
+			newer := (&Config{SortBy: tt.sortBy}).newer()
+			if got := newer(old, recent); got != tt.want {
+				t.Errorf("newer(edited, recent) = %v, want %v", got, tt.want)
+			}
+			if got := newer(recent, old); got == tt.want {
+				t.Errorf("newer(recent, edited) = %v, want %v", got, !tt.want)
+			}
+		})
+	}
+}
+
+func TestNewerTies(t *testing.T) {
+	date := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	item := func(source, title, link string) *feeds.Item {
+		return &feeds.Item{Title: title, Link: &feeds.Link{Href: link}, Source: &feeds.Link{Href: source}, Created: date}
+	}
+	// Each item sorts before the next only by a later tiebreaker.
+	ordered := []*feeds.Item{
+		item("https://a.example/feed", "B", "https://a.example/2"),
+		item("https://b.example/feed", "A", "https://b.example/1"),
+		item("https://b.example/feed", "B", "https://b.example/1"),
+		item("https://b.example/feed", "B", "https://b.example/2"),
+		{Title: "Older", Created: date.Add(-time.Hour)},
+	}
+
+	newer := (&Config{}).newer()
+	for i := 1; i < len(ordered); i++ {
+		if !newer(ordered[i-1], ordered[i]) || newer(ordered[i], ordered[i-1]) {
+			t.Errorf("newer() should order %q from %s before %q from %s", ordered[i-1].Title, linkHref(ordered[i-1].Source), ordered[i].Title, linkHref(ordered[i].Source))
+		}
+	}
+}
+
+func TestFillMissingDates(t *testing.T) {
+	dated := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	feedDate := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	fetched := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		mode     string
+		feedDate time.Time
+		want     []time.Time
+	}{
+		{"", feedDate, []time.Time{dated, {}}},
+		{"feed", feedDate, []time.Time{dated, feedDate}},
+		{"feed", time.Time{}, []time.Time{dated, fetched}},
+		{"fetch", feedDate, []time.Time{dated, fetched}},
+		{"drop", feedDate, []time.Time{dated}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			source := &SourceFeed{Date: tt.feedDate, Fetched: fetched, Items: []*feeds.Item{
+				{Title: "Dated", Created: dated},
+				{Title: "Undated"},
+			}}
+			fillMissingDates(source, tt.mode)
+			var got []time.Time
+			for _, item := range source.Items {
+				got = append(got, item.Created)
+			}
+			if !slices.EqualFunc(got, tt.want, time.Time.Equal) {
+				t.Errorf("fillMissingDates(%q) dates = %v, want %v", tt.mode, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildFeedTimezone(t *testing.T) {
+	lisbon := time.FixedZone("WEST", 3600)
+	tokyo := time.FixedZone("JST", 9*3600)
+	sources := []*SourceFeed{{URL: "https://a.example/feed", Items: []*feeds.Item{
+		{Title: "Lisbon", Created: time.Date(2024, 6, 1, 12, 0, 0, 0, lisbon)},
+		{Title: "Tokyo", Created: time.Date(2024, 6, 1, 19, 0, 0, 0, tokyo), Updated: time.Date(2024, 6, 2, 9, 0, 0, 0, tokyo)},
+	}}}
+
+	feed := buildFeed(&Config{Count: 10, Timezone: time.UTC}, sources)
+	if feed.Created.Location() != time.UTC {
+		t.Errorf("buildFeed() feed dated %v, want UTC", feed.Created)
+	}
+	for _, item := range feed.Items {
+		if item.Created.Location() != time.UTC || !item.Updated.IsZero() && item.Updated.Location() != time.UTC {
+			t.Errorf("buildFeed() item %q dated %v, %v, want UTC", item.Title, item.Created, item.Updated)
+		}
+	}
+	if len(feed.Items) != 2 || feed.Items[0].Title != "Lisbon" || feed.Items[0].Created.Hour() != 11 {
+		t.Errorf("buildFeed() should keep the instants and their order, got %v", feed.Items)
+	}
+	if !sources[0].Items[0].Created.Equal(feed.Items[0].Created) || sources[0].Items[0].Created.Location() != lisbon {
+		t.Errorf("buildFeed() changed the source's own item")
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/gorilla/feeds"
+)
+
+// deadLinkMarker starts the titles of items whose link is dead with
+// -check-links flag.
+const deadLinkMarker = "[dead link] "
+
+// checkLinks requests the link of each of items, at most workers at once,
+// and finds those answering 404 Not Found or 410 Gone, such as the posts
+// of a defunct site whose archive is still published. With mode "drop"
+// they are dropped, and with "flag" their titles start with
+// deadLinkMarker. Links that fail in any other way are kept, since their
+// site may only be down for now. What a link answered is trusted for
+// linkCacheTTL, shared with -resolve-links.
+func checkLinks(items []*feeds.Item, mode string, workers int) []*feeds.Item {
+	var mu sync.Mutex
+	dead := make(map[string]bool)
+	queued := make(map[string]bool)
+	var pending []string
+	for _, item := range items {
+		if item.Link == nil || item.Link.Href == "" || queued[item.Link.Href] {
+			continue
+		}
+		queued[item.Link.Href] = true
+		if result, ok := linkResults.get(item.Link.Href); ok && time.Since(result.Checked) < linkCacheTTL {
+			dead[item.Link.Href] = deadStatus(result.Status)
+		} else {
+			pending = append(pending, item.Link.Href)
+		}
+	}
+
+	sem := make(chan struct{}, max(workers, 1))
+	var wg sync.WaitGroup
+	for _, href := range pending {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			result, err := linkResults.request(href)
+			if err != nil {
+				log.Printf("Warning: failed to check link %s: %v", href, err)
+				return
+			}
+			mu.Lock()
+			dead[href] = deadStatus(result.Status)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	isDead := func(item *feeds.Item) bool {
+		return item.Link != nil && dead[item.Link.Href]
+	}
+	if mode == "drop" {
+		return slices.DeleteFunc(items, isDead)
+	}
+	for _, item := range items {
+		if isDead(item) {
+			item.Title = deadLinkMarker + item.Title
+		}
+	}
+	return items
+}
+
+func deadStatus(status int) bool {
+	return status == http.StatusNotFound || status == http.StatusGone
+}
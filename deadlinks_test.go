@@ -0,0 +1,104 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/feeds"
+)
+
+func TestCheckLinks(t *testing.T) {
+	var requests atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/live", func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+	})
+	mux.HandleFunc("/gone", func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusGone)
+	})
+	mux.HandleFunc("/moved", func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		http.Redirect(w, r, "/missing", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/nohead", func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/down", func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	newItems := func() []*feeds.Item {
+		var items []*feeds.Item
+		for _, path := range []string{"live", "gone", "moved", "nohead", "down", "live"} {
+			items = append(items, &feeds.Item{Title: path, Link: &feeds.Link{Href: server.URL + "/" + path}})
+		}
+		return append(items, &feeds.Item{Title: "no link"})
+	}
+	titles := func(items []*feeds.Item) string {
+		var titles []string
+		for _, item := range items {
+			titles = append(titles, item.Title)
+		}
+		return strings.Join(titles, ", ")
+	}
+
+	if got, want := titles(checkLinks(newItems(), "drop", 2)), "live, nohead, down, live, no link"; got != want {
+		t.Errorf("checkLinks() drop = %q, want %q", got, want)
+	}
+	// The twice listed link is requested once, and /nohead twice.
+	if got := requests.Load(); got != 6 {
+		t.Errorf("checkLinks() made %d requests, want 6", got)
+	}
+
+	if got, want := titles(checkLinks(newItems(), "flag", 2)), "live, [dead link] gone, [dead link] moved, nohead, down, live, no link"; got != want {
+		t.Errorf("checkLinks() flag = %q, want %q", got, want)
+	}
+	// Links are not requested again within linkCacheTTL.
+	if got := requests.Load(); got != 6 {
+		t.Errorf("checkLinks() made %d requests after checking again, want 6", got)
+	}
+}
+
+func TestBuildFeedChecksSelectedLinks(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.URL.Path == "/dead" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	base := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	source := &SourceFeed{URL: server.URL + "/feed"}
+	for i, path := range []string{"dead", "new", "old", "older"} {
+		source.Items = append(source.Items, &feeds.Item{Title: path, Link: &feeds.Link{Href: server.URL + "/" + path}, Created: base.Add(-time.Duration(i) * time.Hour)})
+	}
+
+	config := &Config{Count: 2, CheckLinks: "drop", CheckLinksWorkers: 2}
+	for range 2 {
+		var got []string
+		for _, item := range buildFeed(config, []*SourceFeed{source}).Items {
+			got = append(got, item.Title)
+		}
+		if strings.Join(got, ", ") != "new, old" {
+			t.Errorf("buildFeed() with a dead link = %v, want new, old", got)
+		}
+	}
+	// Only the links of the selected items and the one making room are
+	// requested, and only once.
+	if got := requests.Load(); got != 3 {
+		t.Errorf("buildFeed() made %d requests, want 3", got)
+	}
+}
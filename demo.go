@@ -0,0 +1,71 @@
+package main
+
+import (
+	"github.com/SlyMarbo/rss"
+)
+
+// demoFeeds are bundled sample sources used by -demo. They cover RSS and
+// Atom, HTML content, images and relative links so that every stage of the
+// pipeline has something to work on without touching the network.
+var demoFeeds = []string{
+	`<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:content="http://purl.org/rss/1.0/modules/content/">
+<channel>
+<title>The Gopher Gazette</title>
+<link>https://gazette.example.com/</link>
+<description>News from the Go community</description>
+<item>
+<title>Go 1.24 Released with Generic Type Aliases</title>
+<link>https://gazette.example.com/2024/go-1-24</link>
+<description>The Go team has released Go 1.24, completing support for generic type aliases and bringing Swiss Tables to the map implementation.</description>
+<content:encoded><![CDATA[<p>The Go team has released <b>Go 1.24</b>. Highlights include generic type aliases, a new map implementation based on Swiss Tables, and the <code>tool</code> directive in go.mod.</p><p><img src="https://gazette.example.com/img/go124.png" alt="Go 1.24"></p><p>Upgrading is recommended for all users.</p>]]></content:encoded>
+<pubDate>Tue, 11 Feb 2025 17:00:00 GMT</pubDate>
+</item>
+<item>
+<title>Profiling Allocation Hot Spots in Practice</title>
+<link>https://gazette.example.com/2025/profiling</link>
+<description>A walkthrough of using pprof to find and remove needless allocations in a JSON-heavy service.</description>
+<pubDate>Mon, 03 Mar 2025 09:30:00 GMT</pubDate>
+</item>
+<item>
+<title>Community Spotlight: Small Tools, Big Impact</title>
+<link>https://gazette.example.com/2025/spotlight</link>
+<description>This month we talk to maintainers of three tiny command-line tools that quietly power thousands of pipelines.</description>
+<pubDate>Fri, 14 Mar 2025 12:00:00 GMT</pubDate>
+</item>
+</channel>
+</rss>`,
+	`<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+<title>Weekend Engineering Notes</title>
+<link href="https://notes.example.org/"/>
+<id>https://notes.example.org/</id>
+<updated>2025-03-15T08:00:00Z</updated>
+<entry>
+<title>Why We Moved Our Feeds to Object Storage</title>
+<link href="https://notes.example.org/posts/object-storage"/>
+<id>https://notes.example.org/posts/object-storage</id>
+<updated>2025-03-15T08:00:00Z</updated>
+<summary>Serving static feeds from a bucket behind a CDN cut our hosting bill and removed a whole class of outages.</summary>
+</entry>
+<entry>
+<title>Notes on Polite Crawling</title>
+<link href="https://notes.example.org/posts/polite-crawling"/>
+<id>https://notes.example.org/posts/polite-crawling</id>
+<updated>2025-03-08T08:00:00Z</updated>
+<summary>Conditional requests, sensible intervals and a descriptive User-Agent go a long way toward being a good citizen.</summary>
+</entry>
+</feed>`,
+}
+
+func demoSources() ([]*SourceFeed, error) {
+	var sources []*SourceFeed
+	for _, data := range demoFeeds {
+		feed, err := rss.Parse([]byte(data))
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, newSourceFeed(feed.Link, feed))
+	}
+	return sources, nil
+}
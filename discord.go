@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/feeds"
+)
+
+const (
+	discordEmbedsPerMessage  = 10
+	discordTitleLimit        = 256
+	discordDescriptionLimit  = 300
+	discordMaxRateLimitRetry = 3
+)
+
+// DiscordNotifier posts new items to a Discord webhook as embeds.
+type DiscordNotifier struct {
+	WebhookURL string
+}
+
+type discordEmbed struct {
+	Title       string            `json:"title"`
+	URL         string            `json:"url,omitempty"`
+	Description string            `json:"description,omitempty"`
+	Timestamp   string            `json:"timestamp,omitempty"`
+	Thumbnail   *discordThumbnail `json:"thumbnail,omitempty"`
+}
+
+type discordThumbnail struct {
+	URL string `json:"url"`
+}
+
+func (n *DiscordNotifier) Name() string {
+	return "discord"
+}
+
+func (n *DiscordNotifier) Notify(items []*feeds.Item) error {
+	for start := 0; start < len(items); start += discordEmbedsPerMessage {
+		end := min(start+discordEmbedsPerMessage, len(items))
+
+		var embeds []discordEmbed
+		for _, item := range items[start:end] {
+			embeds = append(embeds, newDiscordEmbed(item))
+		}
+
+		if err := n.post(embeds); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func newDiscordEmbed(item *feeds.Item) discordEmbed {
+	embed := discordEmbed{Title: truncateRunes(item.Title, discordTitleLimit)}
+	if item.Link != nil {
+		embed.URL = item.Link.Href
+	}
+	if !item.Created.IsZero() {
+		embed.Timestamp = item.Created.Format(time.RFC3339)
+	}
+
+	description := item.Description
+	if description == "" {
+		description = item.Content
+	}
+	if text, truncated := truncateText(htmlToText(description), 0, discordDescriptionLimit); text != "" {
+		if truncated {
+			text += "…"
+		}
+		embed.Description = text
+	}
+
+	if thumbnail := itemThumbnail(item); thumbnail != "" {
+		embed.Thumbnail = &discordThumbnail{URL: thumbnail}
+	}
+
+	return embed
+}
+
+func truncateRunes(s string, limit int) string {
+	runes := []rune(s)
+	if len(runes) <= limit {
+		return s
+	}
+	return string(runes[:limit-1]) + "…"
+}
+
+// post sends one message, waiting and retrying when Discord answers with a
+// rate limit.
+func (n *DiscordNotifier) post(embeds []discordEmbed) error {
+	body, err := json.Marshal(map[string]any{"embeds": embeds})
+	if err != nil {
+		return err
+	}
+
+	for attempt := 0; ; attempt++ {
+		resp, err := httpClient.Post(n.WebhookURL, "application/json", bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+
+		if resp.StatusCode == http.StatusTooManyRequests && attempt < discordMaxRateLimitRetry {
+			wait := discordRetryAfter(resp)
+			resp.Body.Close()
+			time.Sleep(wait)
+			continue
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return fmt.Errorf("unexpected status %s", resp.Status)
+		}
+
+		// Pace consecutive messages when the bucket is exhausted.
+		if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+			if seconds, err := strconv.ParseFloat(resp.Header.Get("X-RateLimit-Reset-After"), 64); err == nil {
+				time.Sleep(time.Duration(seconds * float64(time.Second)))
+			}
+		}
+
+		return nil
+	}
+}
+
+func discordRetryAfter(resp *http.Response) time.Duration {
+	var payload struct {
+		RetryAfter float64 `json:"retry_after"`
+	}
+	if data, err := io.ReadAll(resp.Body); err == nil && json.Unmarshal(data, &payload) == nil && payload.RetryAfter > 0 {
+		return time.Duration(payload.RetryAfter * float64(time.Second))
+	}
+	if seconds, err := strconv.ParseFloat(resp.Header.Get("Retry-After"), 64); err == nil {
+		return time.Duration(seconds * float64(time.Second))
+	}
+	return time.Second
+}
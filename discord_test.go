@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/feeds"
+)
+
+func TestNewDiscordEmbed(t *testing.T) {
+	item := &feeds.Item{
+		Title:       "Launch",
+		Link:        &feeds.Link{Href: "http://example.com/posts/launch"},
+		Description: `<p>We <b>launched</b>.</p><img src="/img/launch.png">`,
+	}
+
+	embed := newDiscordEmbed(item)
+
+	if embed.Title != "Launch" || embed.URL != "http://example.com/posts/launch" {
+		t.Errorf("embed title/url = %q/%q", embed.Title, embed.URL)
+	}
+	if embed.Description != "We launched." {
+		t.Errorf("embed description = %q, want plain text", embed.Description)
+	}
+	if embed.Thumbnail == nil || embed.Thumbnail.URL != "http://example.com/img/launch.png" {
+		t.Errorf("embed thumbnail = %+v, want resolved first image", embed.Thumbnail)
+	}
+}
+
+func TestDiscordNotifierBatchesAndRetriesRateLimits(t *testing.T) {
+	var requests int
+	var embedCounts []int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			fmt.Fprint(w, `{"retry_after": 0.01}`)
+			return
+		}
+		var payload struct {
+			Embeds []discordEmbed `json:"embeds"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("invalid Discord payload: %v", err)
+		}
+		embedCounts = append(embedCounts, len(payload.Embeds))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	var items []*feeds.Item
+	for i := 0; i < 12; i++ {
+		items = append(items, &feeds.Item{Title: fmt.Sprintf("Item %d", i)})
+	}
+
+	notifier := &DiscordNotifier{WebhookURL: server.URL}
+	if err := notifier.Notify(items); err != nil {
+		t.Fatalf("Notify() unexpected error = %v", err)
+	}
+
+	if len(embedCounts) != 2 || embedCounts[0] != 10 || embedCounts[1] != 2 {
+		t.Errorf("embeds per message = %v, want [10 2]", embedCounts)
+	}
+	if requests != 3 {
+		t.Errorf("got %d requests, want 3 (one rate-limited retry)", requests)
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !(linux || darwin || freebsd)
+
+package main
+
+import "errors"
+
+// diskSpace is not implemented on this platform, so -alert-min-free never
+// fires here.
+func diskSpace(path string) (free, total uint64, err error) {
+	return 0, 0, errors.ErrUnsupported
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// dohClient talks to DNS-over-HTTPS servers. It resolves their own names
+// through the system resolver, so -dns-server may use a host name.
+var dohClient = &http.Client{Timeout: 10 * time.Second}
+
+// dnsCacheTTL is how long -dns-cache keeps a lookup: long enough to cover
+// a run, short enough for a daemon to notice hosts moving.
+const dnsCacheTTL = 5 * time.Minute
+
+// dnsCacheSize is how many host names -dns-cache keeps at most.
+const dnsCacheSize = 1000
+
+// dnsDialer resolves host names itself before dialing, so that lookups can
+// go to -dns-server and be cached for the run with -dns-cache.
+type dnsDialer struct {
+	lookup func(ctx context.Context, host string) ([]net.IPAddr, error)
+	dialer net.Dialer
+	cache  bool
+
+	mu    sync.Mutex
+	hosts map[string]cachedLookup
+}
+
+type cachedLookup struct {
+	ips      []net.IPAddr
+	expires  time.Time
+	lastUsed time.Time
+}
+
+func newDNSDialer(server string, cache bool) (*dnsDialer, error) {
+	resolver, err := newResolver(server)
+	if err != nil {
+		return nil, err
+	}
+	return &dnsDialer{
+		lookup: resolver.LookupIPAddr,
+		dialer: net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second},
+		cache:  cache,
+		hosts:  make(map[string]cachedLookup),
+	}, nil
+}
+
+func (d *dnsDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || net.ParseIP(host) != nil {
+		return d.dialer.DialContext(ctx, network, addr)
+	}
+
+	ips, err := d.resolve(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	var lastErr error
+	for _, ip := range ips {
+		conn, err := d.dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+func (d *dnsDialer) resolve(ctx context.Context, host string) ([]net.IPAddr, error) {
+	host = strings.ToLower(host)
+	if d.cache {
+		d.mu.Lock()
+		cached, ok := d.hosts[host]
+		if now := time.Now(); ok && now.Before(cached.expires) {
+			cached.lastUsed = now
+			d.hosts[host] = cached
+			d.mu.Unlock()
+			return cached.ips, nil
+		}
+		d.mu.Unlock()
+	}
+
+	ips, err := d.lookup(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("no addresses found for %s", host)
+	}
+
+	if d.cache {
+		d.mu.Lock()
+		if _, ok := d.hosts[host]; !ok && len(d.hosts) >= dnsCacheSize {
+			d.evict()
+		}
+		now := time.Now()
+		d.hosts[host] = cachedLookup{ips: ips, expires: now.Add(dnsCacheTTL), lastUsed: now}
+		d.mu.Unlock()
+	}
+	return ips, nil
+}
+
+// evict drops expired lookups, or the least recently used one when none has
+// expired. d.mu must be held.
+func (d *dnsDialer) evict() {
+	now := time.Now()
+	var oldest string
+	for host, cached := range d.hosts {
+		if !now.Before(cached.expires) {
+			delete(d.hosts, host)
+		} else if oldest == "" || cached.lastUsed.Before(d.hosts[oldest].lastUsed) {
+			oldest = host
+		}
+	}
+	if len(d.hosts) >= dnsCacheSize {
+		delete(d.hosts, oldest)
+	}
+}
+
+// newResolver returns a resolver that asks server: "host[:port]" over UDP,
+// "tls://host[:port]" over DNS-over-TLS or an https:// URL over
+// DNS-over-HTTPS. An empty server means the system resolver.
+func newResolver(server string) (*net.Resolver, error) {
+	if server == "" {
+		return net.DefaultResolver, nil
+	}
+
+	var dial func(ctx context.Context) (net.Conn, error)
+	var dialer net.Dialer
+	switch {
+	case strings.HasPrefix(server, "https://"):
+		dial = func(ctx context.Context) (net.Conn, error) {
+			return &dohConn{ctx: ctx, url: server, client: dohClient}, nil
+		}
+	case strings.HasPrefix(server, "tls://"):
+		addr := withDefaultPort(strings.TrimPrefix(server, "tls://"), "853")
+		host, _, _ := net.SplitHostPort(addr)
+		dial = func(ctx context.Context) (net.Conn, error) {
+			tlsDialer := &tls.Dialer{NetDialer: &dialer, Config: &tls.Config{ServerName: host}}
+			return tlsDialer.DialContext(ctx, "tcp", addr)
+		}
+	case strings.Contains(server, "://"):
+		return nil, fmt.Errorf("dns-server must be host[:port], tls://host[:port] or an https:// URL")
+	default:
+		addr := withDefaultPort(server, "53")
+		dial = func(ctx context.Context) (net.Conn, error) {
+			return dialer.DialContext(ctx, "udp", addr)
+		}
+	}
+
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			return dial(ctx)
+		},
+	}, nil
+}
+
+// dnsTransport is the default transport, dialing through dialer.
+func dnsTransport(dialer *dnsDialer) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	return transport
+}
+
+func withDefaultPort(host, port string) string {
+	if _, _, err := net.SplitHostPort(host); err == nil {
+		return host
+	}
+	return net.JoinHostPort(strings.Trim(host, "[]"), port)
+}
+
+// dohConn carries the resolver's DNS messages, framed with a length prefix
+// as over TCP, to a DNS-over-HTTPS server (RFC 8484), one POST per query.
+type dohConn struct {
+	ctx    context.Context
+	url    string
+	client *http.Client
+
+	query  bytes.Buffer
+	answer bytes.Reader
+}
+
+func (c *dohConn) Write(b []byte) (int, error) {
+	return c.query.Write(b)
+}
+
+func (c *dohConn) Read(b []byte) (int, error) {
+	if c.answer.Len() == 0 {
+		if err := c.exchange(); err != nil {
+			return 0, err
+		}
+	}
+	return c.answer.Read(b)
+}
+
+func (c *dohConn) exchange() error {
+	framed := c.query.Bytes()
+	if len(framed) < 2 || len(framed) < 2+int(binary.BigEndian.Uint16(framed)) {
+		return io.EOF
+	}
+	message := framed[2 : 2+binary.BigEndian.Uint16(framed)]
+
+	req, err := http.NewRequestWithContext(c.ctx, http.MethodPost, c.url, bytes.NewReader(message))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/dns-message")
+	req.Header.Set("Accept", "application/dns-message")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("DNS-over-HTTPS server returned %s", resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 65535))
+	if err != nil {
+		return err
+	}
+
+	c.query.Next(2 + len(message))
+	c.answer.Reset(append(binary.BigEndian.AppendUint16(nil, uint16(len(body))), body...))
+	return nil
+}
+
+func (c *dohConn) Close() error                       { return nil }
+func (c *dohConn) LocalAddr() net.Addr                { return &net.TCPAddr{} }
+func (c *dohConn) RemoteAddr() net.Addr               { return &net.TCPAddr{} }
+func (c *dohConn) SetDeadline(t time.Time) error      { return nil }
+func (c *dohConn) SetReadDeadline(t time.Time) error  { return nil }
+func (c *dohConn) SetWriteDeadline(t time.Time) error { return nil }
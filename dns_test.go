@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDNSDialerCache(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	for _, cache := range []bool{false, true} {
+		var lookups int32
+		dialer := &dnsDialer{
+			lookup: func(ctx context.Context, host string) ([]net.IPAddr, error) {
+				atomic.AddInt32(&lookups, 1)
+				return []net.IPAddr{{IP: net.ParseIP("127.0.0.1")}}, nil
+			},
+			cache: cache,
+			hosts: make(map[string]cachedLookup),
+		}
+		client := &http.Client{Transport: dnsTransport(dialer)}
+
+		for i := 0; i < 2; i++ {
+			resp, err := client.Get("http://feeds.test:" + port + "/")
+			if err != nil {
+				t.Fatalf("Get() through dnsDialer unexpected error = %v", err)
+			}
+			resp.Body.Close()
+			client.CloseIdleConnections()
+		}
+
+		want := int32(2)
+		if cache {
+			want = 1
+		}
+		if got := atomic.LoadInt32(&lookups); got != want {
+			t.Errorf("dnsDialer with cache=%v looked up %d times, want %d", cache, got, want)
+		}
+	}
+}
+
+func TestDNSDialerCacheEviction(t *testing.T) {
+	dialer := &dnsDialer{
+		lookup: func(ctx context.Context, host string) ([]net.IPAddr, error) {
+			return []net.IPAddr{{IP: net.ParseIP("127.0.0.1")}}, nil
+		},
+		cache: true,
+		hosts: make(map[string]cachedLookup),
+	}
+	now := time.Now()
+	for i := range dnsCacheSize {
+		dialer.hosts[fmt.Sprintf("host%d.test", i)] = cachedLookup{
+			expires:  now.Add(time.Minute),
+			lastUsed: now.Add(time.Duration(i-dnsCacheSize) * time.Millisecond),
+		}
+	}
+
+	// Using the least recently used host makes the next one go instead.
+	for _, host := range []string{"host0.test", "new.test"} {
+		if _, err := dialer.resolve(context.Background(), host); err != nil {
+			t.Fatalf("resolve(%q) unexpected error = %v", host, err)
+		}
+	}
+	if len(dialer.hosts) != dnsCacheSize {
+		t.Errorf("cache holds %d hosts, want %d", len(dialer.hosts), dnsCacheSize)
+	}
+	for host, want := range map[string]bool{"host0.test": true, "host1.test": false, "new.test": true} {
+		if _, ok := dialer.hosts[host]; ok != want {
+			t.Errorf("cache has %s = %v, want %v", host, ok, want)
+		}
+	}
+}
+
+func TestNewResolver(t *testing.T) {
+	tests := []struct {
+		server  string
+		wantErr bool
+	}{
+		{"", false},
+		{"1.1.1.1", false},
+		{"[2606:4700:4700::1111]:53", false},
+		{"tls://dns.example", false},
+		{"https://dns.example/dns-query", false},
+		{"quic://dns.example", true},
+	}
+
+	for _, tt := range tests {
+		if _, err := newResolver(tt.server); (err != nil) != tt.wantErr {
+			t.Errorf("newResolver(%q) error = %v, wantErr %v", tt.server, err, tt.wantErr)
+		}
+	}
+}
+
+func TestDNSOverHTTPS(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/dns-message" {
+			http.Error(w, "bad content type", http.StatusUnsupportedMediaType)
+			return
+		}
+		query, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/dns-message")
+		w.Write(dnsAnswer(query, net.IPv4(192, 0, 2, 1)))
+	}))
+	defer server.Close()
+
+	defer func(client *http.Client) { dohClient = client }(dohClient)
+	dohClient = server.Client()
+
+	resolver, err := newResolver(server.URL + "/dns-query")
+	if err != nil {
+		t.Fatalf("newResolver() unexpected error = %v", err)
+	}
+	ips, err := resolver.LookupIPAddr(context.Background(), "feeds.example")
+	if err != nil {
+		t.Fatalf("LookupIPAddr() over DNS-over-HTTPS unexpected error = %v", err)
+	}
+	if len(ips) != 1 || !ips[0].IP.Equal(net.IPv4(192, 0, 2, 1)) {
+		t.Errorf("LookupIPAddr() = %v, want 192.0.2.1", ips)
+	}
+}
+
+// dnsAnswer answers an A query with ip, and any other query with no
+// records.
+func dnsAnswer(query []byte, ip net.IP) []byte {
+	end := 12
+	for query[end] != 0 {
+		end += int(query[end]) + 1
+	}
+	end += 5
+	qtype := binary.BigEndian.Uint16(query[end-4:])
+
+	answer := append([]byte{}, query[:2]...)
+	answer = append(answer, 0x81, 0x80, 0, 1, 0, 0, 0, 0, 0, 0)
+	answer = append(answer, query[12:end]...)
+	if qtype == 1 {
+		answer[7] = 1
+		answer = append(answer, 0xc0, 12, 0, 1, 0, 1, 0, 0, 0, 60, 0, 4)
+		answer = append(answer, ip.To4()...)
+	}
+	return answer
+}
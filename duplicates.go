@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sync"
+)
+
+// feedClaims records which source fetches each feed during a run, so that
+// sources redirecting to the same feed fetch it only once.
+type feedClaims struct {
+	mu     sync.Mutex
+	owners map[string]string
+}
+
+func newFeedClaims() *feedClaims {
+	return &feedClaims{owners: make(map[string]string)}
+}
+
+// claim records that source fetches target, unless another source already
+// does, in which case it returns that source.
+func (c *feedClaims) claim(target, source string) (string, bool) {
+	if c == nil {
+		return "", true
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := normalizeURL(target)
+	if owner, ok := c.owners[key]; ok && owner != source {
+		return owner, false
+	}
+	c.owners[key] = source
+	return "", true
+}
+
+// duplicateFeedError stops fetching a source that redirects to a feed
+// another source fetches.
+type duplicateFeedError struct {
+	target string
+	owner  string
+}
+
+func (e *duplicateFeedError) Error() string {
+	return fmt.Sprintf("redirects to %s, which %s already fetches", e.target, e.owner)
+}
+
+// dropDuplicateSources removes sources that ended up at the same feed as an
+// earlier one, such as cached copies fetched through different redirects.
+func dropDuplicateSources(sources []*SourceFeed) []*SourceFeed {
+	owners := make(map[string]string)
+	var kept []*SourceFeed
+	for _, source := range sources {
+		key := normalizeURL(source.feedURL())
+		if owner, ok := owners[key]; ok {
+			log.Printf("Warning: feed %s is the same feed as %s, skipping it", source.URL, owner)
+			continue
+		}
+		owners[key] = source.URL
+		kept = append(kept, source)
+	}
+	return kept
+}
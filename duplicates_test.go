@@ -0,0 +1,78 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+func TestReadSourcesFromFilesNormalizedDuplicates(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "rss_duplicates")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	input := filepath.Join(tempDir, "feeds.txt")
+	os.WriteFile(input, []byte("https://example.com/feed owner=first\nHTTPS://EXAMPLE.com:443/feed/ owner=second\n"), 0644)
+
+	sources, err := readSourcesFromFiles([]string{input})
+	if err != nil {
+		t.Fatalf("readSourcesFromFiles() unexpected error = %v", err)
+	}
+	if len(sources) != 1 || sources[0].Owner != "first" {
+		t.Errorf("readSourcesFromFiles() = %+v, want only the first line", sources)
+	}
+}
+
+func TestFetchSourcesSameFeedOnce(t *testing.T) {
+	var hits int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/a", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/feed", http.StatusFound)
+	})
+	mux.HandleFunc("/b", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/feed", http.StatusFound)
+	})
+	mux.HandleFunc("/feed", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.Write([]byte(cacheTestFeed))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	entries := []Source{{URL: server.URL + "/a"}, {URL: server.URL + "/b"}}
+	sources, stats := fetchSources(&Config{}, entries)
+
+	if got := atomic.LoadInt32(&hits); got != 1 {
+		t.Errorf("fetchSources() fetched the shared feed %d times, want 1", got)
+	}
+	if len(sources) != 1 || stats.Failed != 0 {
+		t.Errorf("fetchSources() got %d sources and %d failed, want 1 and 0", len(sources), stats.Failed)
+	}
+
+	// A source listed under its own URL keeps it, and the one redirecting
+	// to it is skipped.
+	atomic.StoreInt32(&hits, 0)
+	entries = []Source{{URL: server.URL + "/a"}, {URL: server.URL + "/feed"}}
+	sources, _ = fetchSources(&Config{}, entries)
+	if got := atomic.LoadInt32(&hits); got != 1 || len(sources) != 1 || sources[0].URL != server.URL+"/feed" {
+		t.Errorf("fetchSources() fetched the feed %d times into %d sources, want once from its own URL", got, len(sources))
+	}
+}
+
+func TestDropDuplicateSources(t *testing.T) {
+	sources := []*SourceFeed{
+		{URL: "http://a.example/feed"},
+		{URL: "http://b.example/feed", FinalURL: "http://a.example/feed/"},
+		{URL: "http://c.example/feed"},
+	}
+
+	got := dropDuplicateSources(sources)
+	if len(got) != 2 || got[0].URL != "http://a.example/feed" || got[1].URL != "http://c.example/feed" {
+		t.Errorf("dropDuplicateSources() kept %d sources, want a and c", len(got))
+	}
+}
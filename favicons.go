@@ -0,0 +1,218 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+const (
+	// faviconsFile remembers, in -cache-dir, the favicon of each site.
+	faviconsFile = "favicons.json"
+	// faviconMaxBytes is the largest favicon kept; bigger icons are not
+	// worth embedding next to every item.
+	faviconMaxBytes = 64 * 1024
+	// faviconWorkers is how many sites are asked for their favicon at once.
+	faviconWorkers = 8
+)
+
+// htmlTag is an element of an HTML document, with its attribute names
+// lowercased.
+type htmlTag struct {
+	Name  string
+	Attrs map[string]string
+}
+
+// headTags returns the <link> and <meta> elements in the head of the HTML
+// document r, stopping at its body.
+func headTags(r io.Reader) []htmlTag {
+	decoder := xml.NewDecoder(r)
+	decoder.Strict = false
+	decoder.AutoClose = xml.HTMLAutoClose
+	decoder.Entity = xml.HTMLEntity
+
+	var tags []htmlTag
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return tags
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		name := strings.ToLower(start.Name.Local)
+		if name == "body" {
+			return tags
+		}
+		if name != "link" && name != "meta" {
+			continue
+		}
+		tag := htmlTag{Name: name, Attrs: make(map[string]string, len(start.Attr))}
+		for _, attr := range start.Attr {
+			tag.Attrs[strings.ToLower(attr.Name.Local)] = attr.Value
+		}
+		tags = append(tags, tag)
+	}
+}
+
+// siteOrigin is the scheme and host of the site source belongs to: its
+// website, or the feed itself when it names none.
+func siteOrigin(source *SourceFeed) string {
+	for _, link := range []string{source.Link, source.URL} {
+		u, err := url.Parse(link)
+		if err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" {
+			return u.Scheme + "://" + u.Host
+		}
+	}
+	return ""
+}
+
+// fetchFavicons returns the favicon of each of origins as a data URI, or ""
+// for sites without one. known holds favicons fetched before, which are not
+// fetched again. With cacheDir, the favicons found are also remembered
+// across restarts, and offline only uses what was remembered.
+func fetchFavicons(origins []string, known map[string]string, cacheDir string, offline bool) map[string]string {
+	cached := loadFavicons(cacheDir)
+	icons := make(map[string]string, len(origins))
+	var pending []string
+	for _, origin := range origins {
+		if _, ok := icons[origin]; ok {
+			continue
+		}
+		if icon, ok := known[origin]; ok {
+			icons[origin] = icon
+		} else if icon, ok := cached[origin]; ok {
+			icons[origin] = icon
+		} else {
+			icons[origin] = ""
+			if !offline {
+				pending = append(pending, origin)
+			}
+		}
+	}
+
+	var mu sync.Mutex
+	sem := make(chan struct{}, faviconWorkers)
+	var wg sync.WaitGroup
+	for _, origin := range pending {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			icon, err := fetchFavicon(origin)
+			if err != nil {
+				log.Printf("Warning: failed to fetch favicon of %s: %v", origin, err)
+				return
+			}
+			mu.Lock()
+			icons[origin] = icon
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	if cacheDir != "" && len(pending) > 0 {
+		if err := saveFavicons(cacheDir, icons); err != nil {
+			log.Printf("Warning: failed to cache favicons: %v", err)
+		}
+	}
+	return icons
+}
+
+// fetchFavicon returns the icon the home page at origin links to, or its
+// /favicon.ico, as a data URI.
+func fetchFavicon(origin string) (string, error) {
+	candidates := []string{origin + "/favicon.ico"}
+	if href, err := faviconLink(origin); err == nil && href != "" {
+		candidates = slices.Insert(candidates, 0, href)
+	}
+
+	var err error
+	for _, candidate := range candidates {
+		var icon string
+		if icon, err = fetchDataURI(candidate, faviconMaxBytes); err == nil {
+			return icon, nil
+		}
+	}
+	return "", err
+}
+
+// faviconLink returns the absolute URL of the first icon the home page at
+// origin links to, if any.
+func faviconLink(origin string) (string, error) {
+	resp, err := httpClient.Get(origin + "/")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	for _, tag := range headTags(io.LimitReader(resp.Body, 1<<20)) {
+		if tag.Name != "link" || tag.Attrs["href"] == "" {
+			continue
+		}
+		if !slices.Contains(strings.Fields(strings.ToLower(tag.Attrs["rel"])), "icon") {
+			continue
+		}
+		ref, err := url.Parse(tag.Attrs["href"])
+		if err != nil {
+			continue
+		}
+		return resp.Request.URL.ResolveReference(ref).String(), nil
+	}
+	return "", nil
+}
+
+func loadFavicons(cacheDir string) map[string]string {
+	icons := make(map[string]string)
+	if cacheDir == "" {
+		return icons
+	}
+	data, err := os.ReadFile(filepath.Join(cacheDir, faviconsFile))
+	if err != nil {
+		return icons
+	}
+	if err := json.Unmarshal(data, &icons); err != nil {
+		log.Printf("Warning: ignoring unreadable favicons cache: %v", err)
+		return make(map[string]string)
+	}
+	return icons
+}
+
+// saveFavicons replaces the cache with the favicons found in icons, so that
+// it only keeps those of current sources, and sites that had none are asked
+// again after a restart.
+func saveFavicons(cacheDir string, icons map[string]string) error {
+	found := make(map[string]string, len(icons))
+	for origin, icon := range icons {
+		if icon != "" {
+			found[origin] = icon
+		}
+	}
+	data, err := json.MarshalIndent(found, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		return fmt.Errorf("error creating cache dir: %v", err)
+	}
+	if err := writeFileAtomic(filepath.Join(cacheDir, faviconsFile), data, 0644); err != nil {
+		return fmt.Errorf("error writing cache file: %v", err)
+	}
+	return nil
+}
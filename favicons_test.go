@@ -0,0 +1,100 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestHeadTags(t *testing.T) {
+	page := `<!DOCTYPE html><html><head><meta charset="utf-8">
+<link REL="Shortcut Icon" href="/icon.png?a=1&amp;b=2"><title>Site</title>
+</head><body><link rel="icon" href="/late.png"></body></html>`
+
+	tags := headTags(strings.NewReader(page))
+	if len(tags) != 2 {
+		t.Fatalf("headTags() = %d tags, want 2: %v", len(tags), tags)
+	}
+	if tags[1].Name != "link" || tags[1].Attrs["rel"] != "Shortcut Icon" || tags[1].Attrs["href"] != "/icon.png?a=1&b=2" {
+		t.Errorf("headTags()[1] = %v, want the shortcut icon link", tags[1])
+	}
+}
+
+func TestFetchFavicons(t *testing.T) {
+	var requests atomic.Int32
+	linked := http.NewServeMux()
+	linked.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Write([]byte(`<html><head><link rel="apple-touch-icon" href="/touch.png"><link rel="shortcut icon" href="static/icon.png"></head></html>`))
+	})
+	linked.HandleFunc("/static/icon.png", func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte("png"))
+	})
+	linkedServer := httptest.NewServer(linked)
+	defer linkedServer.Close()
+
+	plain := http.NewServeMux()
+	plain.HandleFunc("/favicon.ico", func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Content-Type", "image/x-icon")
+		w.Write([]byte("ico"))
+	})
+	plainServer := httptest.NewServer(plain)
+	defer plainServer.Close()
+
+	noneServer := httptest.NewServer(http.NotFoundHandler())
+	defer noneServer.Close()
+
+	cacheDir, err := os.MkdirTemp("", "rss_favicons")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(cacheDir)
+
+	origins := []string{linkedServer.URL, plainServer.URL, noneServer.URL}
+	icons := fetchFavicons(origins, nil, cacheDir, false)
+	want := map[string]string{
+		linkedServer.URL: "data:image/png;base64,cG5n",
+		plainServer.URL:  "data:image/x-icon;base64,aWNv",
+		noneServer.URL:   "",
+	}
+	for origin, icon := range want {
+		if icons[origin] != icon {
+			t.Errorf("fetchFavicons() icon of %s = %q, want %q", origin, icons[origin], icon)
+		}
+	}
+
+	before := requests.Load()
+	if again := fetchFavicons(origins, icons, "", false); again[linkedServer.URL] != want[linkedServer.URL] {
+		t.Errorf("fetchFavicons() with known icons = %q, want %q", again[linkedServer.URL], want[linkedServer.URL])
+	}
+	offline := fetchFavicons(origins, nil, cacheDir, true)
+	if offline[plainServer.URL] != want[plainServer.URL] {
+		t.Errorf("fetchFavicons() offline icon = %q, want the cached %q", offline[plainServer.URL], want[plainServer.URL])
+	}
+	if got := requests.Load(); got != before {
+		t.Errorf("fetchFavicons() made %d requests for known icons, want none", got-before)
+	}
+}
+
+func TestSiteOrigin(t *testing.T) {
+	tests := []struct {
+		source *SourceFeed
+		want   string
+	}{
+		{&SourceFeed{URL: "https://feeds.example.com/rss", Link: "https://blog.example.com/posts/"}, "https://blog.example.com"},
+		{&SourceFeed{URL: "http://example.org:8080/feed.xml"}, "http://example.org:8080"},
+		{&SourceFeed{URL: "file:///tmp/feed.xml"}, ""},
+	}
+
+	for _, tt := range tests {
+		if got := siteOrigin(tt.source); got != tt.want {
+			t.Errorf("siteOrigin(%q, %q) = %q, want %q", tt.source.URL, tt.source.Link, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gorilla/feeds"
+)
+
+// cachedFeed is a source as it was fetched, before the input file's
+// options were applied, so changing those takes effect within the TTL.
+type cachedFeed struct {
+	FetchedAt time.Time   `json:"fetched_at"`
+	Source    *SourceFeed `json:"source"`
+}
+
+// offlineTransport replaces httpClient's transport with -offline, so that
+// nothing the run does can reach the network.
+type offlineTransport struct{}
+
+func (offlineTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return nil, fmt.Errorf("no network access in offline mode")
+}
+
+// fetchCachedSource returns entry from -cache-dir when it was fetched less
+// than -cache-ttl ago, and fetches and caches it otherwise. With -offline
+// the cached copy is used however old it is, and with -stale-if-error it
+// stands in for the source when fetching fails.
+func fetchCachedSource(config *Config, entry Source) (*SourceFeed, error) {
+	source, err := cachedOrDownloaded(config, entry)
+	if err != nil {
+		return nil, err
+	}
+	fillMissingDates(source, config.MissingDate)
+	entry.apply(source)
+	return source, nil
+}
+
+// cachedOrDownloaded is fetchCachedSource before -missing-date and the
+// input file's options are applied.
+func cachedOrDownloaded(config *Config, entry Source) (*SourceFeed, error) {
+	if config.CacheDir == "" {
+		return downloadSourceFeed(entry, config.claims)
+	}
+
+	cached, err := readCachedFeed(config.CacheDir, entry.URL)
+	if config.Offline {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("not in cache")
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err == nil && (config.Offline || time.Since(cached.FetchedAt) < config.CacheTTL) {
+		return cached.Source, nil
+	}
+
+	source, fetchErr := downloadSourceFeed(entry, config.claims)
+	if fetchErr != nil {
+		if err == nil && time.Since(cached.FetchedAt) < config.StaleIfError {
+			log.Printf("Warning: failed to fetch feed %s, using cached copy from %s: %v", entry.URL, cached.FetchedAt.Format(time.RFC3339), fetchErr)
+			return cached.Source, nil
+		}
+		return nil, fetchErr
+	}
+	if err := writeCachedFeed(config.CacheDir, source, time.Now()); err != nil {
+		log.Printf("Warning: failed to cache feed %s: %v", entry.URL, err)
+	}
+	return source, nil
+}
+
+func feedCachePath(dir, url string) string {
+	sum := sha256.Sum256([]byte(normalizeURL(url)))
+	return filepath.Join(dir, hex.EncodeToString(sum[:])+".json")
+}
+
+func readCachedFeed(dir, url string) (*cachedFeed, error) {
+	data, err := os.ReadFile(feedCachePath(dir, url))
+	if err != nil {
+		return nil, err
+	}
+
+	var cached cachedFeed
+	if err := json.Unmarshal(data, &cached); err != nil {
+		return nil, fmt.Errorf("error parsing cached feed: %v", err)
+	}
+	if cached.Source == nil || normalizeURL(cached.Source.URL) != normalizeURL(url) {
+		return nil, fmt.Errorf("cached feed is not for %s", url)
+	}
+
+	// The URL may have been written differently when the feed was cached.
+	cached.Source.URL = url
+	for _, item := range cached.Source.Items {
+		item.Source = &feeds.Link{Href: url}
+	}
+	return &cached, nil
+}
+
+// writeCachedFeed stores source atomically, like the state file, so
+// concurrent runs never read a partial entry.
+func writeCachedFeed(dir string, source *SourceFeed, fetchedAt time.Time) error {
+	data, err := json.Marshal(cachedFeed{FetchedAt: fetchedAt, Source: source})
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("error creating cache dir: %v", err)
+	}
+
+	if err := writeFileAtomic(feedCachePath(dir, source.URL), data, 0644); err != nil {
+		return fmt.Errorf("error writing cache file: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/feeds"
+)
+
+const cacheTestFeed = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0">
+<channel>
+<title>Test Feed</title>
+<link>http://example.com</link>
+<item><title>Older</title><link>http://example.com/1</link><pubDate>Wed, 01 Jan 2020 00:00:00 GMT</pubDate></item>
+<item><title>Newer</title><link>http://example.com/2</link><pubDate>Thu, 02 Jan 2020 00:00:00 GMT</pubDate></item>
+</channel>
+</rss>`
+
+func TestFetchCachedSource(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.Write([]byte(cacheTestFeed))
+	}))
+	defer server.Close()
+
+	cacheDir, err := os.MkdirTemp("", "rss_cache")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(cacheDir)
+
+	config := &Config{CacheDir: cacheDir, CacheTTL: time.Hour}
+
+	source, err := fetchCachedSource(config, Source{URL: server.URL, Count: 1})
+	if err != nil {
+		t.Fatalf("fetchCachedSource() unexpected error = %v", err)
+	}
+	if source.Metrics == nil || source.Metrics.Bytes != len(cacheTestFeed) || source.Metrics.Duration <= 0 {
+		t.Errorf("fetchCachedSource() downloaded source metrics = %+v, want %d bytes", source.Metrics, len(cacheTestFeed))
+	}
+
+	// Options apply to the cached copy, not only to what was fetched.
+	source, err = fetchCachedSource(config, Source{URL: server.URL, Name: "Renamed"})
+	if err != nil {
+		t.Fatalf("fetchCachedSource() unexpected error = %v", err)
+	}
+	if got := atomic.LoadInt32(&hits); got != 1 {
+		t.Errorf("fetchCachedSource() fetched %d times within the TTL, want 1", got)
+	}
+	if source.Title != "Renamed" || len(source.Items) != 2 {
+		t.Errorf("fetchCachedSource() cached source title = %q with %d items, want Renamed with 2", source.Title, len(source.Items))
+	}
+	if source.Items[0].Source == nil || source.Items[0].Source.Href != server.URL {
+		t.Errorf("fetchCachedSource() cached item source = %v, want %s", source.Items[0].Source, server.URL)
+	}
+	if source.Metrics != nil {
+		t.Errorf("fetchCachedSource() cached source metrics = %+v, want none", source.Metrics)
+	}
+
+	config.CacheTTL = 0
+	if _, err := fetchCachedSource(config, Source{URL: server.URL}); err != nil {
+		t.Fatalf("fetchCachedSource() unexpected error = %v", err)
+	}
+	if got := atomic.LoadInt32(&hits); got != 2 {
+		t.Errorf("fetchCachedSource() fetched %d times after the TTL, want 2", got)
+	}
+}
+
+func TestFetchCachedSourceOffline(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.Write([]byte(cacheTestFeed))
+	}))
+	defer server.Close()
+
+	cacheDir, err := os.MkdirTemp("", "rss_cache")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(cacheDir)
+
+	source, err := downloadSourceFeed(Source{URL: server.URL}, nil)
+	if err != nil {
+		t.Fatalf("downloadSourceFeed() unexpected error = %v", err)
+	}
+	if err := writeCachedFeed(cacheDir, source, time.Now().Add(-24*time.Hour)); err != nil {
+		t.Fatalf("writeCachedFeed() unexpected error = %v", err)
+	}
+
+	config := &Config{CacheDir: cacheDir, CacheTTL: time.Minute, Offline: true}
+
+	cached, err := fetchCachedSource(config, Source{URL: server.URL})
+	if err != nil {
+		t.Fatalf("fetchCachedSource() offline unexpected error = %v", err)
+	}
+	if len(cached.Items) != 2 {
+		t.Errorf("fetchCachedSource() offline got %d items, want 2 from the expired cache", len(cached.Items))
+	}
+	if got := atomic.LoadInt32(&hits); got != 1 {
+		t.Errorf("fetchCachedSource() offline fetched %d times, want only the initial download", got)
+	}
+
+	if _, err := fetchCachedSource(config, Source{URL: server.URL + "/other"}); err == nil {
+		t.Errorf("fetchCachedSource() offline expected error for an uncached source")
+	}
+}
+
+func TestOfflineTransport(t *testing.T) {
+	client := &http.Client{Transport: offlineTransport{}}
+	if _, err := client.Get("http://example.com"); err == nil {
+		t.Errorf("offlineTransport expected error, got none")
+	}
+}
+
+func TestFetchCachedSourceStaleIfError(t *testing.T) {
+	var failing atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing.Load() {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(cacheTestFeed))
+	}))
+	defer server.Close()
+
+	cacheDir, err := os.MkdirTemp("", "rss_cache")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(cacheDir)
+
+	config := &Config{CacheDir: cacheDir, StaleIfError: time.Hour}
+	if _, err := fetchCachedSource(config, Source{URL: server.URL}); err != nil {
+		t.Fatalf("fetchCachedSource() unexpected error = %v", err)
+	}
+
+	failing.Store(true)
+	source, err := fetchCachedSource(config, Source{URL: server.URL})
+	if err != nil {
+		t.Fatalf("fetchCachedSource() with stale-if-error unexpected error = %v", err)
+	}
+	if len(source.Items) != 2 {
+		t.Errorf("fetchCachedSource() with stale-if-error got %d items, want 2 from the cache", len(source.Items))
+	}
+
+	config.StaleIfError = 0
+	if _, err := fetchCachedSource(config, Source{URL: server.URL}); err == nil {
+		t.Errorf("fetchCachedSource() without stale-if-error expected error from failing source")
+	}
+}
+
+func TestReadCachedFeedNormalizedURL(t *testing.T) {
+	cacheDir, err := os.MkdirTemp("", "rss_cache")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(cacheDir)
+
+	source := &SourceFeed{URL: "http://example.com/feed", Items: []*feeds.Item{
+		{Title: "Item", Source: &feeds.Link{Href: "http://example.com/feed"}},
+	}}
+	if err := writeCachedFeed(cacheDir, source, time.Now()); err != nil {
+		t.Fatalf("writeCachedFeed() unexpected error = %v", err)
+	}
+
+	cached, err := readCachedFeed(cacheDir, "HTTP://Example.com/feed/")
+	if err != nil {
+		t.Fatalf("readCachedFeed() unexpected error = %v", err)
+	}
+	if cached.Source.URL != "HTTP://Example.com/feed/" || cached.Source.Items[0].Source.Href != "HTTP://Example.com/feed/" {
+		t.Errorf("readCachedFeed() source URL = %q, want it as now written", cached.Source.URL)
+	}
+}
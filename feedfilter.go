@@ -0,0 +1,191 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/feeds"
+)
+
+// feedFilter carves a view out of the aggregate for one consumer of
+// /feed.xml, from its query parameters.
+type feedFilter struct {
+	// Tags keeps items from sources with any of these tags.
+	Tags []string
+	// Exclude drops items from sources with any of these tags, or
+	// containing any of these words.
+	Exclude []string
+	Query   SearchQuery
+	Count   int
+	// Offset skips items, and Page skips Page-1 pages of Count items.
+	Offset int
+	Page   int
+}
+
+// parseFeedFilter reads the tag, exclude, q, source and count (or limit)
+// parameters, and page or offset, returning nil when there are none. tag
+// and exclude may be repeated or comma-separated.
+func parseFeedFilter(params url.Values) (*feedFilter, error) {
+	if !slices.ContainsFunc([]string{"tag", "exclude", "q", "source", "count", "limit", "page", "offset"}, params.Has) {
+		return nil, nil
+	}
+
+	filter := &feedFilter{
+		Tags:    splitParams(params["tag"]),
+		Exclude: splitParams(params["exclude"]),
+		Query:   SearchQuery{Terms: strings.Fields(params.Get("q")), Source: params.Get("source")},
+	}
+	if value := cmp.Or(params.Get("count"), params.Get("limit")); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("count must be a positive integer")
+		}
+		filter.Count = n
+	}
+	if value := params.Get("page"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("page must be a positive integer")
+		}
+		filter.Page = n
+	}
+	var err error
+	if filter.Offset, err = apiIntParam(params, "offset", 0); err != nil {
+		return nil, err
+	}
+	return filter, nil
+}
+
+// paged reports whether the filter asks for a page beyond the newest items,
+// which comes from the daemon's history.
+func (f *feedFilter) paged() bool {
+	return f.Page > 0 || f.Offset > 0
+}
+
+func splitParams(values []string) []string {
+	var fields []string
+	for _, value := range values {
+		for _, field := range strings.Split(value, ",") {
+			if field = strings.TrimSpace(field); field != "" {
+				fields = append(fields, field)
+			}
+		}
+	}
+	return fields
+}
+
+// apply returns a copy of feed with the page of items the filter keeps, at
+// most Count of them, or count when Count is not set, and whether more
+// items follow. tags maps source URLs to their tags.
+func (f *feedFilter) apply(feed *feeds.Feed, tags map[string][]string, count int) (*feeds.Feed, bool) {
+	count = cmp.Or(f.Count, count)
+	skip := f.Offset + max(f.Page-1, 0)*count
+	filtered := *feed
+	filtered.Items = nil
+	for _, item := range feed.Items {
+		var itemTags []string
+		if item.Source != nil {
+			itemTags = tags[item.Source.Href]
+		}
+		if len(f.Tags) > 0 && !slices.ContainsFunc(f.Tags, func(tag string) bool { return slices.Contains(itemTags, tag) }) {
+			continue
+		}
+		itemJSON := toItemJSON(item)
+		if !f.Query.matches(itemJSON) || slices.ContainsFunc(f.Exclude, func(word string) bool {
+			return slices.Contains(itemTags, word) || SearchQuery{Terms: []string{word}}.matches(itemJSON)
+		}) {
+			continue
+		}
+		if skip > 0 {
+			skip--
+			continue
+		}
+		if len(filtered.Items) == count {
+			return &filtered, true
+		}
+		filtered.Items = append(filtered.Items, item)
+	}
+	return &filtered, false
+}
+
+// historyMaxItems is how many published items the daemon remembers for
+// paging through history. The history is only held in memory: it starts
+// empty again when the daemon restarts, and neither the archive nor the
+// reader store, which keep items as fetched, backs it.
+const historyMaxItems = 10000
+
+// remember adds the items of a newly published aggregate to the history,
+// replacing earlier versions of the same items, and drops the oldest beyond
+// historyMaxItems. d.mu must be held.
+func (d *daemon) remember(items []*feeds.Item) {
+	seen := make(map[string]bool, len(items))
+	var history []*feeds.Item
+	for _, item := range items {
+		if key := itemKey(item); !isHeading(item) && !seen[key] {
+			seen[key] = true
+			history = append(history, item)
+		}
+	}
+	for _, item := range d.history {
+		if key := itemKey(item); !seen[key] {
+			seen[key] = true
+			history = append(history, item)
+		}
+	}
+	slices.SortStableFunc(history, func(a, b *feeds.Item) int {
+		return b.Created.Compare(a.Created)
+	})
+	d.history = history[:min(len(history), historyMaxItems)]
+}
+
+// historyItems returns every item to page through: those of the aggregate,
+// in feed order, followed by the items published since the daemon started
+// that have left it, newest first. Like the aggregate, and unlike the
+// archive, they have been through every filter and transformation. Pages
+// count from the newest item, so they shift as new items are published.
+func (d *daemon) historyItems(aggregatedFeed *feeds.Feed) []*feeds.Item {
+	d.mu.Lock()
+	history := d.history
+	d.mu.Unlock()
+
+	items := slices.Clone(aggregatedFeed.Items)
+	current := make(map[string]bool, len(items))
+	for _, item := range items {
+		current[itemKey(item)] = true
+	}
+	for _, item := range history {
+		if !current[itemKey(item)] {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// setPageLinks links a page of the feed to the pages before and after it
+// with a Link header, as offset or page parameters like the request's.
+func setPageLinks(w http.ResponseWriter, r *http.Request, filter *feedFilter, count int, more bool) {
+	count = cmp.Or(filter.Count, count)
+	// RequestURI keeps the profile prefix that routing stripped.
+	path, _, _ := strings.Cut(r.RequestURI, "?")
+	link := func(rel string, step int) string {
+		params := r.URL.Query()
+		if filter.Page > 0 {
+			params.Set("page", strconv.Itoa(filter.Page+step))
+		} else {
+			params.Set("offset", strconv.Itoa(max(filter.Offset+step*count, 0)))
+		}
+		return fmt.Sprintf("<%s?%s>; rel=%q", path, params.Encode(), rel)
+	}
+
+	if more {
+		w.Header().Add("Link", link("next", 1))
+	}
+	if filter.Page > 1 || filter.Page == 0 && filter.Offset > 0 {
+		w.Header().Add("Link", link("prev", -1))
+	}
+}
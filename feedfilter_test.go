@@ -0,0 +1,199 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/feeds"
+)
+
+func TestFeedFilter(t *testing.T) {
+	tech := &SourceFeed{URL: "https://tech.example.com/feed", Tags: []string{"tech"}}
+	ads := &SourceFeed{URL: "https://ads.example.com/feed", Tags: []string{"tech", "sponsored"}}
+	news := &SourceFeed{URL: "https://news.example.com/feed", Tags: []string{"news"}}
+	tags := map[string][]string{tech.URL: tech.Tags, ads.URL: ads.Tags, news.URL: news.Tags}
+	newItem := func(title string, source *SourceFeed) *feeds.Item {
+		return &feeds.Item{Title: title, Source: &feeds.Link{Href: source.URL}}
+	}
+	feed := &feeds.Feed{Title: "Aggregate", Items: []*feeds.Item{
+		newItem("Go generics", tech),
+		newItem("Buy our cloud", ads),
+		newItem("Election results", news),
+		newItem("Rust 2024 (Sponsored)", tech),
+		newItem("Go 1.22", tech),
+	}}
+
+	tests := []struct {
+		query    string
+		count    int
+		want     string
+		wantMore bool
+	}{
+		{"tag=tech", 10, "[Go generics Buy our cloud Rust 2024 (Sponsored) Go 1.22]", false},
+		{"tag=tech", 2, "[Go generics Buy our cloud]", true},
+		{"tag=tech&exclude=sponsored", 10, "[Go generics Go 1.22]", false},
+		{"tag=tech&exclude=sponsored&count=1", 10, "[Go generics]", true},
+		{"tag=news,sponsored", 10, "[Buy our cloud Election results]", false},
+		{"tag=news&tag=sponsored", 10, "[Buy our cloud Election results]", false},
+		{"q=go", 10, "[Go generics Go 1.22]", false},
+		{"source=news.example.com", 10, "[Election results]", false},
+		{"count=3", 10, "[Go generics Buy our cloud Election results]", true},
+		{"limit=3", 10, "[Go generics Buy our cloud Election results]", true},
+		{"page=2", 2, "[Election results Rust 2024 (Sponsored)]", true},
+		{"page=3&count=2", 10, "[Go 1.22]", false},
+		{"offset=3&limit=1", 10, "[Rust 2024 (Sponsored)]", true},
+		{"tag=tech&exclude=sponsored&page=2&count=1", 10, "[Go 1.22]", false},
+	}
+
+	for _, tt := range tests {
+		params, _ := url.ParseQuery(tt.query)
+		filter, err := parseFeedFilter(params)
+		if err != nil || filter == nil {
+			t.Fatalf("parseFeedFilter(%s) = %v, %v, want a filter", tt.query, filter, err)
+		}
+		page, more := filter.apply(feed, tags, tt.count)
+		var titles []string
+		for _, item := range page.Items {
+			titles = append(titles, item.Title)
+		}
+		if got := fmt.Sprint(titles); got != tt.want || more != tt.wantMore {
+			t.Errorf("apply(%s) with count %d = %s, %v, want %s, %v", tt.query, tt.count, got, more, tt.want, tt.wantMore)
+		}
+	}
+	if len(feed.Items) != 5 {
+		t.Errorf("apply() changed the aggregate to %d items, want 5", len(feed.Items))
+	}
+
+	for _, query := range []string{"", "format=rss"} {
+		params, _ := url.ParseQuery(query)
+		if filter, err := parseFeedFilter(params); filter != nil || err != nil {
+			t.Errorf("parseFeedFilter(%q) = %v, %v, want no filter", query, filter, err)
+		}
+	}
+	for _, query := range []string{"count=0", "count=ten", "page=0", "offset=-1"} {
+		params, _ := url.ParseQuery(query)
+		if _, err := parseFeedFilter(params); err == nil {
+			t.Errorf("parseFeedFilter(%q) expected error but got none", query)
+		}
+	}
+}
+
+func TestDaemonFilteredFeed(t *testing.T) {
+	d := newDaemon(&Config{Count: 10})
+	tech := &SourceFeed{URL: "https://tech.example.com/feed", Tags: []string{"tech"}}
+	d.sources = map[string]*SourceFeed{tech.URL: tech}
+	d.feed = &feeds.Feed{Title: "Aggregate", Link: &feeds.Link{Href: "https://example.com"}, Created: time.Now(), Items: []*feeds.Item{
+		{Title: "Tagged", Link: &feeds.Link{Href: "https://example.com/1"}, Source: &feeds.Link{Href: tech.URL}},
+		{Title: "Untagged", Link: &feeds.Link{Href: "https://example.com/2"}, Source: &feeds.Link{Href: "https://other.example.com/feed"}},
+	}}
+	server := httptest.NewServer(d.handler())
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/feed.xml?tag=tech")
+	if err != nil {
+		t.Fatalf("GET /feed.xml?tag=tech failed: %v", err)
+	}
+	body := readBody(t, resp)
+	if !strings.Contains(body, "Tagged") || strings.Contains(body, "Untagged") {
+		t.Errorf("GET /feed.xml?tag=tech = %s, want only the tagged item", body)
+	}
+	if resp.Header.Get("ETag") == "" {
+		t.Errorf("GET /feed.xml?tag=tech has no ETag")
+	}
+
+	resp, err = http.Get(server.URL + "/feed.xml?count=x")
+	if err != nil {
+		t.Fatalf("GET /feed.xml?count=x failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("GET /feed.xml?count=x = %d, want 400", resp.StatusCode)
+	}
+}
+
+func TestDaemonFeedPagesFromHistory(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	source := &SourceFeed{URL: "https://example.com/feed"}
+	for i := range 5 {
+		source.Items = append(source.Items, &feeds.Item{
+			Title:   fmt.Sprintf("Item %d", i),
+			Link:    &feeds.Link{Href: fmt.Sprintf("https://example.com/%d", i)},
+			Source:  &feeds.Link{Href: source.URL},
+			Created: base.Add(time.Duration(i) * time.Hour),
+		})
+	}
+
+	// Each update publishes the newest item; the aggregate only holds the
+	// last one, and the rest are remembered.
+	d := newDaemon(&Config{Count: 2})
+	for i := range 5 {
+		d.feed = &feeds.Feed{Title: "Aggregate", Link: &feeds.Link{Href: "https://example.com"}, Items: source.Items[i : i+1]}
+		d.remember(d.feed.Items)
+	}
+	server := httptest.NewServer(d.handler())
+	defer server.Close()
+
+	tests := []struct {
+		query     string
+		want      []string
+		wantLinks string
+	}{
+		{"page=1", []string{"Item 4", "Item 3"}, `</feed.xml?page=2>; rel="next"`},
+		{"page=2", []string{"Item 2", "Item 1"}, `</feed.xml?page=3>; rel="next" </feed.xml?page=1>; rel="prev"`},
+		{"page=3", []string{"Item 0"}, `</feed.xml?page=2>; rel="prev"`},
+		{"offset=1&limit=3", []string{"Item 3", "Item 2", "Item 1"}, `</feed.xml?limit=3&offset=4>; rel="next" </feed.xml?limit=3&offset=0>; rel="prev"`},
+	}
+	for _, tt := range tests {
+		resp, err := http.Get(server.URL + "/feed.xml?" + tt.query)
+		if err != nil {
+			t.Fatalf("GET /feed.xml?%s failed: %v", tt.query, err)
+		}
+		body := readBody(t, resp)
+		if got := strings.Count(body, "<item>"); got != len(tt.want) {
+			t.Errorf("GET /feed.xml?%s returned %d items, want %d", tt.query, got, len(tt.want))
+		}
+		for _, title := range tt.want {
+			if !strings.Contains(body, "<title>"+title+"</title>") {
+				t.Errorf("GET /feed.xml?%s is missing %s", tt.query, title)
+			}
+		}
+		if got := strings.Join(resp.Header.Values("Link"), " "); got != tt.wantLinks {
+			t.Errorf("GET /feed.xml?%s Link = %s, want %s", tt.query, got, tt.wantLinks)
+		}
+	}
+}
+
+func TestDaemonRemember(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	item := func(title string, hours int) *feeds.Item {
+		return &feeds.Item{Title: title, Link: &feeds.Link{Href: "https://example.com/" + title}, Created: base.Add(time.Duration(hours) * time.Hour)}
+	}
+	heading := &feeds.Item{Title: "Example", Source: &feeds.Link{Href: "https://example.com/feed", Rel: headingRel}}
+
+	d := newDaemon(&Config{})
+	d.remember([]*feeds.Item{heading, item("a", 0), item("b", 1)})
+	updated := item("a", 0)
+	updated.Description = "updated"
+	d.remember([]*feeds.Item{item("c", 2), updated})
+
+	var titles []string
+	for _, item := range d.history {
+		titles = append(titles, item.Title)
+	}
+	if strings.Join(titles, ",") != "c,b,a" {
+		t.Errorf("remember() history = %v, want c,b,a", titles)
+	}
+	if d.history[2] != updated {
+		t.Errorf("remember() kept the earlier version of a")
+	}
+
+	history := d.historyItems(&feeds.Feed{Items: []*feeds.Item{updated}})
+	if len(history) != 3 || history[0] != updated || history[1].Title != "c" {
+		t.Errorf("historyItems() = %d items starting with %s, want the aggregate's first", len(history), history[0].Title)
+	}
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"cmp"
+	"crypto/md5"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	feverPath       = "/fever/"
+	feverAPIVersion = 3
+	// feverItemsLimit is how many items the Fever API returns at a time.
+	feverItemsLimit = 50
+)
+
+// feverAPIKey is the key Fever clients send for a login of the form
+// email:password.
+func feverAPIKey(login string) string {
+	sum := md5.Sum([]byte(login))
+	return hex.EncodeToString(sum[:])
+}
+
+// serveFever implements the Fever API, which mobile clients such as Reeder
+// and Unread speak to hosted readers. Groups are the sources' tags; sparks,
+// links and favicons are not supported and always empty.
+func (d *daemon) serveFever(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	resp := map[string]any{"api_version": feverAPIVersion}
+	w.Header().Set("Content-Type", "application/json")
+
+	key := strings.ToLower(r.PostForm.Get("api_key"))
+	if subtle.ConstantTimeCompare([]byte(key), []byte(feverAPIKey(d.config.ReaderLogin))) != 1 {
+		resp["auth"] = 0
+		json.NewEncoder(w).Encode(resp)
+		return
+	}
+	resp["auth"] = 1
+
+	sources, items := d.readerItems()
+	d.mu.Lock()
+	resp["last_refreshed_on_time"] = d.lastSuccess.Unix()
+	d.mu.Unlock()
+
+	store := d.reader
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	query := r.URL.Query()
+	if query.Has("mark") {
+		store.mark(r.Form, items, feverGroupsByFeed(store, sources))
+	}
+	if query.Has("groups") || query.Has("feeds") {
+		resp["feeds_groups"] = feverFeedsGroups(store, sources)
+	}
+	if query.Has("groups") {
+		resp["groups"] = feverGroups(sources)
+	}
+	if query.Has("feeds") {
+		resp["feeds"] = feverFeeds(store, sources)
+	}
+	if query.Has("favicons") {
+		resp["favicons"] = []any{}
+	}
+	if query.Has("links") {
+		resp["links"] = []any{}
+	}
+	if query.Has("items") {
+		resp["total_items"] = len(items)
+		resp["items"] = store.itemsPage(items, r.Form)
+	}
+	if query.Has("unread_item_ids") || query.Has("mark") {
+		resp["unread_item_ids"] = feverIDList(items, func(item readerItem) bool { return !store.Read[item.ID] })
+	}
+	if query.Has("saved_item_ids") || query.Has("mark") {
+		resp["saved_item_ids"] = feverIDList(items, func(item readerItem) bool { return store.Saved[item.ID] })
+	}
+
+	json.NewEncoder(w).Encode(resp)
+}
+
+// mark applies a mark=item|feed|group request. groups has the ids of the
+// groups each feed is in.
+func (s *readerStore) mark(form url.Values, items []readerItem, groups map[int64][]int64) {
+	id, _ := strconv.ParseInt(form.Get("id"), 10, 64)
+	as := form.Get("as")
+
+	switch form.Get("mark") {
+	case "item":
+		switch as {
+		case "read":
+			s.Read[id] = true
+		case "unread":
+			delete(s.Read, id)
+		case "saved":
+			s.Saved[id] = true
+		case "unsaved":
+			delete(s.Saved, id)
+		}
+	case "feed", "group":
+		if as != "read" {
+			return
+		}
+		// Items fetched after the client last refreshed stay unread.
+		before, _ := strconv.ParseInt(form.Get("before"), 10, 64)
+		for _, item := range items {
+			if form.Get("mark") == "feed" && item.FeedID != id {
+				continue
+			}
+			// Group 0 is every feed.
+			if form.Get("mark") == "group" && id > 0 && !slices.Contains(groups[item.FeedID], id) {
+				continue
+			}
+			if before == 0 || item.Created.Unix() < before {
+				s.Read[item.ID] = true
+			}
+		}
+	}
+
+	if err := s.save(); err != nil {
+		log.Printf("Warning: failed to save reader state: %v", err)
+	}
+}
+
+// itemsPage returns up to feverItemsLimit items: those listed in with_ids,
+// or those after since_id or before max_id, oldest first or newest first
+// respectively.
+func (s *readerStore) itemsPage(items []readerItem, form url.Values) []map[string]any {
+	var page []readerItem
+	switch {
+	case form.Get("with_ids") != "":
+		for _, field := range strings.Split(form.Get("with_ids"), ",") {
+			id, _ := strconv.ParseInt(strings.TrimSpace(field), 10, 64)
+			if i := slices.IndexFunc(items, func(item readerItem) bool { return item.ID == id }); i >= 0 {
+				page = append(page, items[i])
+			}
+		}
+	case form.Get("max_id") != "":
+		maxID, _ := strconv.ParseInt(form.Get("max_id"), 10, 64)
+		for i := len(items) - 1; i >= 0; i-- {
+			if items[i].ID < maxID {
+				page = append(page, items[i])
+			}
+		}
+	default:
+		sinceID, _ := strconv.ParseInt(form.Get("since_id"), 10, 64)
+		for _, item := range items {
+			if item.ID > sinceID {
+				page = append(page, item)
+			}
+		}
+	}
+	if len(page) > feverItemsLimit {
+		page = page[:feverItemsLimit]
+	}
+
+	result := make([]map[string]any, 0, len(page))
+	for _, item := range page {
+		link, author := "", ""
+		if item.Link != nil {
+			link = item.Link.Href
+		}
+		if item.Author != nil {
+			author = item.Author.Name
+		}
+		result = append(result, map[string]any{
+			"id":              item.ID,
+			"feed_id":         item.FeedID,
+			"title":           item.Title,
+			"author":          author,
+			"html":            cmp.Or(item.Content, item.Description),
+			"url":             link,
+			"is_saved":        feverBool(s.Saved[item.ID]),
+			"is_read":         feverBool(s.Read[item.ID]),
+			"created_on_time": item.Created.Unix(),
+		})
+	}
+	return result
+}
+
+func feverFeeds(store *readerStore, sources []*SourceFeed) []map[string]any {
+	feeds := make([]map[string]any, 0, len(sources))
+	for _, source := range sources {
+		var updated time.Time
+		for _, item := range source.Items {
+			if item.Created.After(updated) {
+				updated = item.Created
+			}
+		}
+		feeds = append(feeds, map[string]any{
+			"id":                   store.Feeds[normalizeURL(source.URL)],
+			"favicon_id":           0,
+			"title":                source.Title,
+			"url":                  source.URL,
+			"site_url":             source.Link,
+			"is_spark":             0,
+			"last_updated_on_time": updated.Unix(),
+		})
+	}
+	return feeds
+}
+
+// feverGroups returns a group for each tag, numbered in order of first use.
+func feverGroups(sources []*SourceFeed) []map[string]any {
+	groups := []map[string]any{}
+	for i, tag := range feverTags(sources) {
+		groups = append(groups, map[string]any{"id": i + 1, "title": tag})
+	}
+	return groups
+}
+
+func feverFeedsGroups(store *readerStore, sources []*SourceFeed) []map[string]any {
+	feedsGroups := []map[string]any{}
+	for i, tag := range feverTags(sources) {
+		var ids []string
+		for _, source := range sources {
+			if slices.Contains(source.Tags, tag) {
+				ids = append(ids, strconv.FormatInt(store.Feeds[normalizeURL(source.URL)], 10))
+			}
+		}
+		feedsGroups = append(feedsGroups, map[string]any{"group_id": i + 1, "feed_ids": strings.Join(ids, ",")})
+	}
+	return feedsGroups
+}
+
+// feverGroupsByFeed returns the ids of the groups each feed is in.
+func feverGroupsByFeed(store *readerStore, sources []*SourceFeed) map[int64][]int64 {
+	tags := feverTags(sources)
+	groups := make(map[int64][]int64)
+	for _, source := range sources {
+		id := store.Feeds[normalizeURL(source.URL)]
+		for _, tag := range source.Tags {
+			groups[id] = append(groups[id], int64(slices.Index(tags, tag)+1))
+		}
+	}
+	return groups
+}
+
+func feverTags(sources []*SourceFeed) []string {
+	var tags []string
+	for _, source := range sources {
+		for _, tag := range source.Tags {
+			if !slices.Contains(tags, tag) {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	return tags
+}
+
+func feverIDList(items []readerItem, include func(readerItem) bool) string {
+	var ids []string
+	for _, item := range items {
+		if include(item) {
+			ids = append(ids, strconv.FormatInt(item.ID, 10))
+		}
+	}
+	return strings.Join(ids, ",")
+}
+
+func feverBool(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
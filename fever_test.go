@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/feeds"
+)
+
+func TestDaemonFeverAPI(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "rss_fever")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	statePath := filepath.Join(tempDir, "fever.json")
+	store, err := loadReaderStore(statePath)
+	if err != nil {
+		t.Fatalf("loadReaderStore() unexpected error = %v", err)
+	}
+
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	newItem := func(link string, age int) *feeds.Item {
+		return &feeds.Item{Title: link, Link: &feeds.Link{Href: "https://example.com/" + link}, Created: base.Add(time.Duration(age) * time.Hour)}
+	}
+	tech := &SourceFeed{URL: "https://tech.example.com/feed", Title: "Tech", Tags: []string{"tech"}, Items: []*feeds.Item{newItem("t2", 2), newItem("t1", 1)}}
+	news := &SourceFeed{URL: "https://news.example.com/feed", Title: "News", Items: []*feeds.Item{newItem("n3", 3)}}
+
+	d := newDaemon(&Config{ReaderLogin: "me@example.com:secret"})
+	d.reader = store
+	d.sources = map[string]*SourceFeed{tech.URL: tech, news.URL: news}
+	d.order = []string{tech.URL, news.URL}
+	server := httptest.NewServer(d.handler())
+	defer server.Close()
+
+	call := func(query, key string, form url.Values) map[string]any {
+		t.Helper()
+		if form == nil {
+			form = url.Values{}
+		}
+		form.Set("api_key", key)
+		resp, err := http.PostForm(server.URL+"/fever/?api&"+query, form)
+		if err != nil {
+			t.Fatalf("Fever request %s failed: %v", query, err)
+		}
+		defer resp.Body.Close()
+		var result map[string]any
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			t.Fatalf("Failed to decode Fever response: %v", err)
+		}
+		return result
+	}
+	key := feverAPIKey("me@example.com:secret")
+
+	if result := call("items", "wrong", nil); result["auth"] != 0.0 || result["items"] != nil {
+		t.Errorf("Fever with a wrong key = %v, want auth 0 and no items", result)
+	}
+
+	result := call("groups&feeds", key, nil)
+	if result["auth"] != 1.0 || result["api_version"] != 3.0 {
+		t.Errorf("Fever auth = %v, api_version = %v, want 1 and 3", result["auth"], result["api_version"])
+	}
+	groups := result["groups"].([]any)
+	if len(groups) != 1 || groups[0].(map[string]any)["title"] != "tech" {
+		t.Errorf("Fever groups = %v, want one tech group", groups)
+	}
+	if feeds := result["feeds"].([]any); len(feeds) != 2 {
+		t.Errorf("Fever feeds = %v, want 2", feeds)
+	}
+	techID := int64(result["feeds"].([]any)[0].(map[string]any)["id"].(float64))
+
+	// Items are numbered oldest first.
+	result = call("items", key, nil)
+	items := result["items"].([]any)
+	var titles []string
+	for _, item := range items {
+		titles = append(titles, item.(map[string]any)["title"].(string))
+	}
+	if strings.Join(titles, ",") != "t1,t2,n3" || result["total_items"] != 3.0 {
+		t.Errorf("Fever items = %v (total %v), want t1,t2,n3", titles, result["total_items"])
+	}
+	firstID := int64(items[0].(map[string]any)["id"].(float64))
+
+	result = call("items", key, url.Values{"since_id": {formatID(firstID)}})
+	if items := result["items"].([]any); len(items) != 2 {
+		t.Errorf("Fever items since %d = %d items, want 2", firstID, len(items))
+	}
+
+	result = call("mark", key, url.Values{"mark": {"item"}, "as": {"read"}, "id": {formatID(firstID)}})
+	if result["unread_item_ids"] != formatID(firstID+1)+","+formatID(firstID+2) {
+		t.Errorf("Fever unread after marking %d read = %v", firstID, result["unread_item_ids"])
+	}
+
+	result = call("mark", key, url.Values{"mark": {"feed"}, "as": {"read"}, "id": {formatID(techID)}, "before": {formatID(base.Add(10 * time.Hour).Unix())}})
+	if result["unread_item_ids"] != formatID(firstID+2) {
+		t.Errorf("Fever unread after marking the tech feed read = %v, want only n3", result["unread_item_ids"])
+	}
+
+	call("mark", key, url.Values{"mark": {"item"}, "as": {"saved"}, "id": {formatID(firstID + 2)}})
+
+	// Ids and flags survive a restart.
+	reloaded, err := loadReaderStore(statePath)
+	if err != nil {
+		t.Fatalf("loadReaderStore() unexpected error = %v", err)
+	}
+	reloadedItems := reloaded.sync([]*SourceFeed{tech, news}, time.Now(), true)
+	if reloadedItems[0].ID != firstID || !reloaded.Read[firstID] || !reloaded.Saved[firstID+2] {
+		t.Errorf("reloaded fever state = ids %d.., read %v, saved %v", reloadedItems[0].ID, reloaded.Read, reloaded.Saved)
+	}
+
+	// A new item gets the next id even though it is older.
+	news.Items = append(news.Items, newItem("n0", 0))
+	result = call("items", key, url.Values{"since_id": {formatID(firstID + 2)}})
+	if items := result["items"].([]any); len(items) != 1 || items[0].(map[string]any)["title"] != "n0" {
+		t.Errorf("Fever items after a new one = %v, want n0", items)
+	}
+}
+
+func formatID(i int64) string {
+	return strconv.FormatInt(i, 10)
+}
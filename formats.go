@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bufio"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/gorilla/feeds"
+)
+
+// feedFormat renders the aggregate for one -format.
+type feedFormat struct {
+	ContentType string
+	// Extension names files the aggregator picks names for itself, such
+	// as the per-source outputs.
+	Extension string
+	Write     func(w io.Writer, feed *feeds.Feed, opts RssOptions) error
+}
+
+var formats = map[string]feedFormat{
+	"rss": {
+		ContentType: "application/rss+xml; charset=utf-8",
+		Extension:   ".xml",
+		Write:       writeRss,
+	},
+	"gemtext": {
+		ContentType: "text/gemini; charset=utf-8",
+		Extension:   ".gmi",
+		Write:       writeGemtext,
+	},
+	"json": {
+		ContentType: "application/feed+json",
+		Extension:   ".json",
+		Write:       writeJSONFeed,
+	},
+	"ndjson": {
+		ContentType: "application/x-ndjson",
+		Extension:   ".ndjson",
+		Write:       writeNDJSON,
+	},
+	"sqlite": {
+		ContentType: "application/vnd.sqlite3",
+		Extension:   ".sqlite",
+		Write:       writeSQLite,
+	},
+}
+
+// writeNDJSON writes one JSON object per item and line, with the same fields
+// as -transform-cmd.
+func writeNDJSON(w io.Writer, feed *feeds.Feed, opts RssOptions) error {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	enc.SetEscapeHTML(false)
+
+	for _, item := range feed.Items {
+		if err := enc.Encode(toItemJSON(item)); err != nil {
+			return err
+		}
+	}
+
+	return bw.Flush()
+}
+
+// writeJSONFeed writes a JSON Feed, with each item's itemThumbnail as its
+// image.
+func writeJSONFeed(w io.Writer, feed *feeds.Feed, opts RssOptions) error {
+	jsonFeed := (&feeds.JSON{Feed: feed}).JSONFeed()
+	jsonFeed.FeedUrl = opts.SelfURL
+	for i, item := range feed.Items {
+		jsonItem := jsonFeed.Items[i]
+		jsonItem.Image = itemThumbnail(item)
+		// JSON Feed requires an id; gorilla/feeds leaves it empty for items
+		// without a guid.
+		jsonItem.Id = cmp.Or(jsonItem.Id, jsonItem.Url)
+		// gorilla/feeds gives the source feed as the item's external URL,
+		// which JSON Feed means for the page the item is about.
+		jsonItem.ExternalUrl = ""
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	if !opts.Compact {
+		enc.SetIndent("", cmp.Or(opts.Indent, "  "))
+	}
+	return enc.Encode(jsonFeed)
+}
+
+// writeGemtext writes a gemtext index of the items. Link lines start with
+// the item date, which is the Gemini subscription convention, so the page
+// can be followed as a feed in Gemini clients.
+func writeGemtext(w io.Writer, feed *feeds.Feed, opts RssOptions) error {
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintf(bw, "# %s\n\n", gemtextLine(feed.Title))
+	if feed.Description != "" {
+		fmt.Fprintf(bw, "%s\n\n", gemtextLine(feed.Description))
+	}
+
+	for _, item := range feed.Items {
+		if isHeading(item) {
+			fmt.Fprintf(bw, "\n## %s\n\n", gemtextLine(item.Title))
+			if item.Link != nil && item.Link.Href != "" {
+				fmt.Fprintf(bw, "=> %s\n", item.Link.Href)
+			}
+			continue
+		}
+
+		title := gemtextLine(item.Title)
+		if source := itemSourceHost(item); source != "" {
+			title += " (" + source + ")"
+		}
+		if !item.Created.IsZero() {
+			title = item.Created.Format("2006-01-02") + " " + title
+		}
+
+		if item.Link == nil || item.Link.Href == "" {
+			fmt.Fprintf(bw, "* %s\n", title)
+			continue
+		}
+		fmt.Fprintf(bw, "=> %s %s\n", item.Link.Href, title)
+	}
+
+	return bw.Flush()
+}
+
+// gemtextLine collapses text onto one line so it cannot start a new gemtext
+// line type.
+func gemtextLine(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
+
+func itemSourceHost(item *feeds.Item) string {
+	if item.Source == nil {
+		return ""
+	}
+	u, err := url.Parse(item.Source.Href)
+	if err != nil {
+		return ""
+	}
+	return u.Hostname()
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/gorilla/feeds"
+)
+
+func TestWriteGemtext(t *testing.T) {
+	feed := &feeds.Feed{
+		Title:       "RSS Aggregator Feed",
+		Description: "Aggregated RSS feed",
+		Items: []*feeds.Item{
+			{
+				Title:   "First\nPost",
+				Link:    &feeds.Link{Href: "https://example.com/first"},
+				Source:  &feeds.Link{Href: "https://example.com/feed.xml"},
+				Created: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+			},
+			{Title: "No link"},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := writeGemtext(&buf, feed, RssOptions{}); err != nil {
+		t.Fatalf("writeGemtext() unexpected error = %v", err)
+	}
+
+	want := "# RSS Aggregator Feed\n\n" +
+		"Aggregated RSS feed\n\n" +
+		"=> https://example.com/first 2024-03-01 First Post (example.com)\n" +
+		"* No link\n"
+	if got := buf.String(); got != want {
+		t.Errorf("writeGemtext() = %q, want %q", got, want)
+	}
+}
+
+func TestWriteGemtextHeadings(t *testing.T) {
+	feed := &feeds.Feed{
+		Title: "Digest",
+		Items: []*feeds.Item{
+			{Title: "Today", Source: &feeds.Link{Rel: headingRel}},
+			{Title: "Post", Link: &feeds.Link{Href: "https://example.com/post"}},
+			{Title: "Blog", Link: &feeds.Link{Href: "https://blog.example/"}, Source: &feeds.Link{Href: "https://blog.example/feed", Rel: headingRel}},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := writeGemtext(&buf, feed, RssOptions{}); err != nil {
+		t.Fatalf("writeGemtext() unexpected error = %v", err)
+	}
+
+	want := "# Digest\n\n" +
+		"\n## Today\n\n" +
+		"=> https://example.com/post Post\n" +
+		"\n## Blog\n\n" +
+		"=> https://blog.example/\n"
+	if got := buf.String(); got != want {
+		t.Errorf("writeGemtext() = %q, want %q", got, want)
+	}
+}
+
+func TestWriteNDJSON(t *testing.T) {
+	feed := &feeds.Feed{
+		Items: []*feeds.Item{
+			{Title: "A & B", Link: &feeds.Link{Href: "https://example.com/a?x=1&y=2"}, Created: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)},
+			{Title: "Second", Description: "<p>Hi</p>"},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := writeNDJSON(&buf, feed, RssOptions{}); err != nil {
+		t.Fatalf("writeNDJSON() unexpected error = %v", err)
+	}
+
+	want := `{"title":"A & B","link":"https://example.com/a?x=1&y=2","created":"2024-03-01T12:00:00Z"}` + "\n" +
+		`{"title":"Second","description":"<p>Hi</p>"}` + "\n"
+	if got := buf.String(); got != want {
+		t.Errorf("writeNDJSON() = %q, want %q", got, want)
+	}
+}
+
+func TestWriteJSONFeed(t *testing.T) {
+	feed := &feeds.Feed{
+		Title: "Aggregate",
+		Link:  &feeds.Link{Href: "https://agg.example.com/"},
+		Items: []*feeds.Item{
+			{
+				Title:   "Pictured",
+				Link:    &feeds.Link{Href: "https://example.com/a"},
+				Source:  &feeds.Link{Href: "https://example.com/feed"},
+				Id:      "tag:example.com,2024:a",
+				Content: `<p><img src="/a.png"></p>`,
+				Created: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+			},
+			{Title: "Plain", Link: &feeds.Link{Href: "https://example.com/b"}},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := writeJSONFeed(&buf, feed, RssOptions{Compact: true, SelfURL: "https://agg.example.com/feed.json"}); err != nil {
+		t.Fatalf("writeJSONFeed() unexpected error = %v", err)
+	}
+
+	want := `{"version":"https://jsonfeed.org/version/1.1","title":"Aggregate","home_page_url":"https://agg.example.com/","feed_url":"https://agg.example.com/feed.json","items":[` +
+		`{"id":"tag:example.com,2024:a","url":"https://example.com/a","title":"Pictured","content_html":"<p><img src=\"/a.png\"></p>","image":"https://example.com/a.png","date_published":"2024-03-01T12:00:00Z"},` +
+		`{"id":"https://example.com/b","url":"https://example.com/b","title":"Plain"}]}` + "\n"
+	if got := buf.String(); got != want {
+		t.Errorf("writeJSONFeed() = %s, want %s", got, want)
+	}
+}
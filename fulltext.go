@@ -0,0 +1,247 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/feeds"
+)
+
+var httpClient = &http.Client{Timeout: 30 * time.Second, Transport: userAgentTransport{}}
+
+// Elements whose contents never belong to the article body.
+var skippedElements = map[string]bool{
+	"script": true, "style": true, "noscript": true, "nav": true, "header": true,
+	"footer": true, "aside": true, "form": true, "iframe": true, "svg": true,
+}
+
+// Block elements kept when rendering the extracted body.
+var keptElements = map[string]bool{
+	"p": true, "h2": true, "h3": true, "h4": true, "pre": true, "blockquote": true,
+}
+
+type htmlNode struct {
+	tag      string
+	parent   *htmlNode
+	children []*htmlNode
+	text     strings.Builder
+	score    float64
+}
+
+func enrichFullText(items []*feeds.Item, config *Config) {
+	workers := config.FullTextWorkers
+	if workers <= 0 {
+		workers = 1
+	}
+
+	var robots *robotsChecker
+	if config.FullTextRobots {
+		robots = newRobotsChecker()
+	}
+
+	sem := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+	for _, item := range items {
+		if item.Link == nil || item.Link.Href == "" {
+			continue
+		}
+		wg.Add(1)
+		go func(item *feeds.Item) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			content, err := fetchFullText(item.Link.Href, config.FullTextCacheDir, robots)
+			if err != nil {
+				log.Printf("Warning: failed to extract full text for %s: %v", item.Link.Href, err)
+				return
+			}
+			if content != "" {
+				item.Content = content
+			}
+		}(item)
+	}
+	wg.Wait()
+}
+
+// fetchFullText returns the extracted article at url, from cacheDir if it
+// was fetched before. With robots, pages robots.txt disallows are skipped.
+func fetchFullText(url, cacheDir string, robots *robotsChecker) (string, error) {
+	var cachePath string
+	if cacheDir != "" {
+		sum := sha256.Sum256([]byte(normalizeURL(url)))
+		cachePath = filepath.Join(cacheDir, hex.EncodeToString(sum[:])+".html")
+		if data, err := os.ReadFile(cachePath); err == nil {
+			return string(data), nil
+		}
+	}
+
+	if robots != nil && !robots.allowed(url) {
+		return "", fmt.Errorf("disallowed by robots.txt")
+	}
+
+	resp, err := httpClient.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	content, err := extractArticle(resp.Body)
+	if err != nil {
+		return "", err
+	}
+
+	if cachePath != "" {
+		if err := os.MkdirAll(cacheDir, 0755); err != nil {
+			return "", fmt.Errorf("error creating cache dir: %v", err)
+		}
+		if err := writeFileAtomic(cachePath, []byte(content), 0644); err != nil {
+			return "", fmt.Errorf("error writing cache file: %v", err)
+		}
+	}
+
+	return content, nil
+}
+
+// extractArticle picks the element whose paragraphs carry the most text,
+// readability-style, and renders its block-level children as clean HTML.
+func extractArticle(r io.Reader) (string, error) {
+	root, err := parseHTML(r)
+	if err != nil {
+		return "", err
+	}
+
+	var best *htmlNode
+	var walk func(n *htmlNode)
+	walk = func(n *htmlNode) {
+		if n.tag == "p" && n.parent != nil {
+			length := float64(len(strings.TrimSpace(nodeText(n))))
+			if length >= 25 {
+				n.parent.score += length
+				if n.parent.parent != nil {
+					n.parent.parent.score += length / 2
+				}
+			}
+		}
+		for _, c := range n.children {
+			walk(c)
+		}
+		if n.tag == "article" {
+			n.score *= 1.5
+		}
+		if n.score > 0 && (best == nil || n.score > best.score) {
+			best = n
+		}
+	}
+	walk(root)
+
+	if best == nil {
+		return "", nil
+	}
+
+	var sb strings.Builder
+	renderBlocks(&sb, best)
+	return sb.String(), nil
+}
+
+func parseHTML(r io.Reader) (*htmlNode, error) {
+	decoder := xml.NewDecoder(r)
+	decoder.Strict = false
+	decoder.AutoClose = xml.HTMLAutoClose
+	decoder.Entity = xml.HTMLEntity
+
+	root := &htmlNode{tag: "#root"}
+	current := root
+	skipDepth := 0
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			// Real-world HTML is rarely well formed; keep what was parsed so far.
+			break
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			tag := strings.ToLower(t.Name.Local)
+			if skipDepth > 0 || skippedElements[tag] {
+				skipDepth++
+				continue
+			}
+			node := &htmlNode{tag: tag, parent: current}
+			current.children = append(current.children, node)
+			current = node
+		case xml.EndElement:
+			if skipDepth > 0 {
+				skipDepth--
+				continue
+			}
+			tag := strings.ToLower(t.Name.Local)
+			for n := current; n != root; n = n.parent {
+				if n.tag == tag {
+					current = n.parent
+					break
+				}
+			}
+		case xml.CharData:
+			if skipDepth == 0 {
+				text := &htmlNode{tag: "#text", parent: current}
+				text.text.Write(t)
+				current.children = append(current.children, text)
+			}
+		}
+	}
+
+	return root, nil
+}
+
+func nodeText(n *htmlNode) string {
+	if n.tag == "#text" {
+		return n.text.String()
+	}
+	var sb strings.Builder
+	for _, c := range n.children {
+		sb.WriteString(nodeText(c))
+	}
+	return sb.String()
+}
+
+// htmlToText strips markup from an HTML fragment and collapses whitespace.
+func htmlToText(fragment string) string {
+	root, err := parseHTML(strings.NewReader(fragment))
+	if err != nil {
+		return fragment
+	}
+	return strings.Join(strings.Fields(nodeText(root)), " ")
+}
+
+func renderBlocks(sb *strings.Builder, n *htmlNode) {
+	for _, c := range n.children {
+		if keptElements[c.tag] {
+			text := strings.Join(strings.Fields(nodeText(c)), " ")
+			if text != "" {
+				fmt.Fprintf(sb, "<%s>%s</%s>\n", c.tag, html.EscapeString(text), c.tag)
+			}
+			continue
+		}
+		renderBlocks(sb, c)
+	}
+}
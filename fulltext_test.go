@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gorilla/feeds"
+)
+
+const articleHTML = `<!DOCTYPE html>
+<html>
+<head><title>Article</title><script>var x = "<p>not content</p>";</script></head>
+<body>
+<nav><p>Home | About | A navigation link that is long enough to count</p></nav>
+<div class="sidebar"><p>Short</p></div>
+<article>
+<h2>The Headline</h2>
+<p>This is the first paragraph of the article body, long enough to score.</p>
+<p>This is the second paragraph &amp; it also carries plenty of text.</p>
+</article>
+<footer><p>Copyright notice that is long enough to be a paragraph</p></footer>
+</body>
+</html>`
+
+func TestExtractArticle(t *testing.T) {
+	content, err := extractArticle(strings.NewReader(articleHTML))
+	if err != nil {
+		t.Fatalf("extractArticle() unexpected error = %v", err)
+	}
+
+	if !strings.Contains(content, "<h2>The Headline</h2>") {
+		t.Errorf("extractArticle() missing headline, got %q", content)
+	}
+	if !strings.Contains(content, "first paragraph of the article body") {
+		t.Errorf("extractArticle() missing first paragraph, got %q", content)
+	}
+	if !strings.Contains(content, "second paragraph &amp; it also") {
+		t.Errorf("extractArticle() missing escaped second paragraph, got %q", content)
+	}
+	for _, unwanted := range []string{"navigation", "Copyright", "not content"} {
+		if strings.Contains(content, unwanted) {
+			t.Errorf("extractArticle() should not contain %q, got %q", unwanted, content)
+		}
+	}
+}
+
+func TestEnrichFullText(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, articleHTML)
+	}))
+	defer server.Close()
+
+	cacheDir, err := os.MkdirTemp("", "rss_fulltext")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(cacheDir)
+
+	config := &Config{FullTextWorkers: 2, FullTextCacheDir: cacheDir}
+
+	for run := 0; run < 2; run++ {
+		items := []*feeds.Item{
+			{Title: "Item", Link: &feeds.Link{Href: server.URL + "/article"}, Description: "Summary"},
+		}
+		enrichFullText(items, config)
+
+		if !strings.Contains(items[0].Content, "The Headline") {
+			t.Errorf("enrichFullText() run %d content = %q, want extracted article", run, items[0].Content)
+		}
+	}
+
+	if got := atomic.LoadInt32(&hits); got != 1 {
+		t.Errorf("enrichFullText() fetched article %d times, want 1 (second run should hit cache)", got)
+	}
+}
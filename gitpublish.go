@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// publishGit commits files, which must be inside the working tree at repo,
+// and pushes them to the branch's upstream. Nothing is committed when the
+// files are unchanged.
+func publishGit(repo string, files []string, message string) error {
+	paths := []string{"--"}
+	for _, file := range files {
+		rel, err := gitRelPath(repo, file)
+		if err != nil {
+			return err
+		}
+		paths = append(paths, rel)
+	}
+
+	if err := runGit(repo, append([]string{"add"}, paths...)...); err != nil {
+		return err
+	}
+
+	if err := runGit(repo, append([]string{"diff", "--cached", "--quiet"}, paths...)...); err == nil {
+		return nil
+	}
+
+	if err := runGit(repo, append([]string{"commit", "--quiet", "-m", message}, paths...)...); err != nil {
+		return err
+	}
+
+	return runGit(repo, "push", "--quiet")
+}
+
+// gitRelPath checks that file lies inside repo, as publishGit requires.
+func gitRelPath(repo, file string) (string, error) {
+	absRepo, err := filepath.Abs(repo)
+	if err != nil {
+		return "", err
+	}
+	absFile, err := filepath.Abs(file)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(absRepo, absFile)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("output %s is not inside git-repo %s", file, repo)
+	}
+	return rel, nil
+}
+
+func runGit(repo string, args ...string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", repo}, args...)...)
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("git %s: %v: %s", args[0], err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPublishGit(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "rss_git")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	for _, env := range []string{"GIT_AUTHOR_NAME", "GIT_COMMITTER_NAME"} {
+		t.Setenv(env, "rss-agg")
+	}
+	for _, env := range []string{"GIT_AUTHOR_EMAIL", "GIT_COMMITTER_EMAIL"} {
+		t.Setenv(env, "rss-agg@example.com")
+	}
+
+	remote := filepath.Join(tempDir, "remote.git")
+	clone := filepath.Join(tempDir, "site")
+	for _, args := range [][]string{
+		{"init", "--quiet", "--bare", remote},
+		{"clone", "--quiet", remote, clone},
+		{"-C", clone, "commit", "--quiet", "--allow-empty", "-m", "Initial commit"},
+		{"-C", clone, "push", "--quiet", "-u", "origin", "HEAD"},
+	} {
+		if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+
+	output := filepath.Join(clone, "feed.xml")
+
+	for run, content := range []string{"<rss>1</rss>", "<rss>1</rss>", "<rss>2</rss>"} {
+		if err := os.WriteFile(output, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write output: %v", err)
+		}
+		if err := publishGit(clone, []string{output}, "Update aggregated feed"); err != nil {
+			t.Fatalf("publishGit() run %d unexpected error = %v", run, err)
+		}
+	}
+
+	out, err := exec.Command("git", "-C", remote, "log", "--format=%s").Output()
+	if err != nil {
+		t.Fatalf("git log: %v", err)
+	}
+	want := "Update aggregated feed\nUpdate aggregated feed\nInitial commit"
+	if got := strings.TrimSpace(string(out)); got != want {
+		t.Errorf("remote history = %q, want %q (unchanged output should not be committed)", got, want)
+	}
+}
+
+func TestGitRelPath(t *testing.T) {
+	tests := []struct {
+		repo, file string
+		want       string
+		wantErr    bool
+	}{
+		{"site", "site/feed.xml", "feed.xml", false},
+		{"site", "site/feeds/all.xml", filepath.Join("feeds", "all.xml"), false},
+		{"site", "aggregated.xml", "", true},
+		{"site", "site/../other/feed.xml", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := gitRelPath(tt.repo, tt.file)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("gitRelPath(%q, %q) = %q, %v, want %q, wantErr %v", tt.repo, tt.file, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
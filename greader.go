@@ -0,0 +1,404 @@
+package main
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	greaderItemPrefix  = "tag:google.com,2005:reader/item/"
+	greaderReadingList = "user/-/state/com.google/reading-list"
+	greaderRead        = "user/-/state/com.google/read"
+	greaderStarred     = "user/-/state/com.google/starred"
+	greaderLabelPrefix = "user/-/label/"
+	greaderFeedPrefix  = "feed/"
+
+	// greaderItemsLimit is the most items a stream request returns.
+	greaderItemsLimit = 1000
+)
+
+// greaderToken is the auth token ClientLogin hands out for login. It is
+// derived from the login, so it stays valid across restarts and changes
+// with the password.
+func greaderToken(login string) string {
+	sum := sha256.Sum256([]byte("greader\x00" + login))
+	return hex.EncodeToString(sum[:])
+}
+
+// greaderHandler serves the subset of the Google Reader API that clients
+// need to read the aggregate: login, subscriptions and labels, streams,
+// unread counts and marking items read or starred. Subscriptions are the
+// sources and labels their tags; they cannot be changed through this API.
+func (d *daemon) greaderHandler() http.Handler {
+	api := http.NewServeMux()
+	api.HandleFunc("GET /reader/api/0/token", d.greaderTokenHandler)
+	api.HandleFunc("GET /reader/api/0/user-info", d.greaderUserInfo)
+	api.HandleFunc("GET /reader/api/0/subscription/list", d.greaderSubscriptions)
+	api.HandleFunc("GET /reader/api/0/tag/list", d.greaderTags)
+	api.HandleFunc("GET /reader/api/0/unread-count", d.greaderUnreadCount)
+	api.HandleFunc("GET /reader/api/0/stream/items/ids", d.greaderItemIDs)
+	api.HandleFunc("/reader/api/0/stream/items/contents", d.greaderItemContents)
+	api.HandleFunc("GET /reader/api/0/stream/contents/{stream...}", d.greaderStreamContents)
+	api.HandleFunc("POST /reader/api/0/edit-tag", d.greaderEditTag)
+	api.HandleFunc("POST /reader/api/0/mark-all-as-read", d.greaderMarkAllAsRead)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/accounts/ClientLogin", d.greaderClientLogin)
+	mux.Handle("/reader/api/0/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "GoogleLogin auth=")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(greaderToken(d.config.ReaderLogin))) != 1 {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		r.ParseForm()
+		api.ServeHTTP(w, r)
+	}))
+	return mux
+}
+
+func (d *daemon) greaderClientLogin(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	login := r.Form.Get("Email") + ":" + r.Form.Get("Passwd")
+	if subtle.ConstantTimeCompare([]byte(login), []byte(d.config.ReaderLogin)) != 1 {
+		http.Error(w, "Error=BadAuthentication", http.StatusUnauthorized)
+		return
+	}
+
+	token := greaderToken(d.config.ReaderLogin)
+	w.Header().Set("Content-Type", "text/plain")
+	fmt.Fprintf(w, "SID=%s\nLSID=%s\nAuth=%s\n", token, token, token)
+}
+
+func (d *daemon) greaderTokenHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain")
+	io.WriteString(w, greaderToken(d.config.ReaderLogin))
+}
+
+func (d *daemon) greaderUserInfo(w http.ResponseWriter, r *http.Request) {
+	email, _, _ := strings.Cut(d.config.ReaderLogin, ":")
+	writeJSON(w, map[string]any{
+		"userId":        "1",
+		"userName":      email,
+		"userProfileId": "1",
+		"userEmail":     email,
+	})
+}
+
+func (d *daemon) greaderSubscriptions(w http.ResponseWriter, r *http.Request) {
+	sources, _ := d.readerItems()
+
+	subscriptions := []map[string]any{}
+	for _, source := range sources {
+		categories := []map[string]any{}
+		for _, tag := range source.Tags {
+			categories = append(categories, map[string]any{"id": greaderLabelPrefix + tag, "label": tag})
+		}
+		subscriptions = append(subscriptions, map[string]any{
+			"id":         greaderFeedPrefix + source.URL,
+			"title":      source.Title,
+			"categories": categories,
+			"url":        source.URL,
+			"htmlUrl":    source.Link,
+			"iconUrl":    "",
+		})
+	}
+	writeJSON(w, map[string]any{"subscriptions": subscriptions})
+}
+
+func (d *daemon) greaderTags(w http.ResponseWriter, r *http.Request) {
+	sources, _ := d.readerItems()
+
+	tags := []map[string]any{{"id": greaderStarred}}
+	for _, tag := range feverTags(sources) {
+		tags = append(tags, map[string]any{"id": greaderLabelPrefix + tag, "type": "folder"})
+	}
+	writeJSON(w, map[string]any{"tags": tags})
+}
+
+func (d *daemon) greaderUnreadCount(w http.ResponseWriter, r *http.Request) {
+	_, items := d.readerItems()
+
+	type unreadCount struct {
+		count  int
+		newest time.Time
+	}
+	counts := make(map[string]*unreadCount)
+	var streams []string
+	d.reader.mu.Lock()
+	for _, item := range items {
+		if d.reader.Read[item.ID] {
+			continue
+		}
+		ids := []string{greaderReadingList, greaderFeedPrefix + item.Source.URL}
+		for _, tag := range item.Source.Tags {
+			ids = append(ids, greaderLabelPrefix+tag)
+		}
+		for _, id := range ids {
+			count, ok := counts[id]
+			if !ok {
+				count = &unreadCount{}
+				counts[id] = count
+				streams = append(streams, id)
+			}
+			count.count++
+			if item.Created.After(count.newest) {
+				count.newest = item.Created
+			}
+		}
+	}
+	d.reader.mu.Unlock()
+
+	unreadCounts := []map[string]any{}
+	for _, id := range streams {
+		unreadCounts = append(unreadCounts, map[string]any{
+			"id":                      id,
+			"count":                   counts[id].count,
+			"newestItemTimestampUsec": strconv.FormatInt(counts[id].newest.UnixMicro(), 10),
+		})
+	}
+	writeJSON(w, map[string]any{"max": greaderItemsLimit, "unreadcounts": unreadCounts})
+}
+
+func (d *daemon) greaderItemIDs(w http.ResponseWriter, r *http.Request) {
+	items, continuation := d.greaderStream(r.Form.Get("s"), r.Form)
+
+	refs := []map[string]any{}
+	for _, item := range items {
+		refs = append(refs, map[string]any{
+			"id":              strconv.FormatInt(item.ID, 10),
+			"directStreamIds": []string{},
+			"timestampUsec":   strconv.FormatInt(item.Created.UnixMicro(), 10),
+		})
+	}
+	resp := map[string]any{"itemRefs": refs}
+	if continuation != "" {
+		resp["continuation"] = continuation
+	}
+	writeJSON(w, resp)
+}
+
+func (d *daemon) greaderItemContents(w http.ResponseWriter, r *http.Request) {
+	_, items := d.readerItems()
+
+	var selected []readerItem
+	for _, value := range r.Form["i"] {
+		id, ok := parseGReaderItemID(value)
+		if !ok {
+			continue
+		}
+		if i := slices.IndexFunc(items, func(item readerItem) bool { return item.ID == id }); i >= 0 {
+			selected = append(selected, items[i])
+		}
+	}
+	writeJSON(w, d.greaderStreamResponse(greaderReadingList, selected, ""))
+}
+
+func (d *daemon) greaderStreamContents(w http.ResponseWriter, r *http.Request) {
+	stream := cmp.Or(r.PathValue("stream"), r.Form.Get("s"))
+	items, continuation := d.greaderStream(stream, r.Form)
+	writeJSON(w, d.greaderStreamResponse(stream, items, continuation))
+}
+
+// greaderStream returns a page of the items in stream, filtered by the
+// request's xt (exclude), it (include), ot and nt (time range) parameters,
+// newest first unless r=o, and the continuation for the next page.
+func (d *daemon) greaderStream(stream string, form url.Values) ([]readerItem, string) {
+	_, items := d.readerItems()
+	stream = cmp.Or(stream, greaderReadingList)
+	oldest, _ := strconv.ParseInt(form.Get("ot"), 10, 64)
+	newest, _ := strconv.ParseInt(form.Get("nt"), 10, 64)
+
+	d.reader.mu.Lock()
+	var matching []readerItem
+	for _, item := range items {
+		if !d.greaderInStream(item, stream) {
+			continue
+		}
+		if exclude := form.Get("xt"); exclude != "" && d.greaderInStream(item, exclude) {
+			continue
+		}
+		if include := form.Get("it"); include != "" && !d.greaderInStream(item, include) {
+			continue
+		}
+		if oldest > 0 && item.Created.Unix() < oldest || newest > 0 && item.Created.Unix() > newest {
+			continue
+		}
+		matching = append(matching, item)
+	}
+	d.reader.mu.Unlock()
+
+	slices.SortStableFunc(matching, func(a, b readerItem) int {
+		if form.Get("r") == "o" {
+			return a.Created.Compare(b.Created)
+		}
+		return b.Created.Compare(a.Created)
+	})
+
+	count, err := strconv.Atoi(form.Get("n"))
+	if err != nil || count <= 0 {
+		count = 20
+	}
+	count = min(count, greaderItemsLimit)
+	offset, _ := strconv.Atoi(form.Get("c"))
+	offset = min(max(offset, 0), len(matching))
+
+	end := min(offset+count, len(matching))
+	continuation := ""
+	if end < len(matching) {
+		continuation = strconv.Itoa(end)
+	}
+	return matching[offset:end], continuation
+}
+
+// greaderInStream reports whether item belongs to stream. d.reader.mu must
+// be held.
+func (d *daemon) greaderInStream(item readerItem, stream string) bool {
+	switch {
+	case stream == greaderReadingList:
+		return true
+	case stream == greaderRead:
+		return d.reader.Read[item.ID]
+	case stream == greaderStarred:
+		return d.reader.Saved[item.ID]
+	case strings.HasPrefix(stream, greaderFeedPrefix):
+		return normalizeURL(strings.TrimPrefix(stream, greaderFeedPrefix)) == normalizeURL(item.Source.URL)
+	case strings.HasPrefix(stream, greaderLabelPrefix):
+		return slices.Contains(item.Source.Tags, strings.TrimPrefix(stream, greaderLabelPrefix))
+	}
+	return false
+}
+
+func (d *daemon) greaderStreamResponse(stream string, items []readerItem, continuation string) map[string]any {
+	d.reader.mu.Lock()
+	defer d.reader.mu.Unlock()
+
+	entries := []map[string]any{}
+	for _, item := range items {
+		categories := []string{greaderReadingList}
+		if d.reader.Read[item.ID] {
+			categories = append(categories, greaderRead)
+		}
+		if d.reader.Saved[item.ID] {
+			categories = append(categories, greaderStarred)
+		}
+		for _, tag := range item.Source.Tags {
+			categories = append(categories, greaderLabelPrefix+tag)
+		}
+
+		link, author := "", ""
+		if item.Link != nil {
+			link = item.Link.Href
+		}
+		if item.Author != nil {
+			author = item.Author.Name
+		}
+		entries = append(entries, map[string]any{
+			"id":            fmt.Sprintf("%s%016x", greaderItemPrefix, item.ID),
+			"crawlTimeMsec": strconv.FormatInt(item.Created.UnixMilli(), 10),
+			"timestampUsec": strconv.FormatInt(item.Created.UnixMicro(), 10),
+			"published":     item.Created.Unix(),
+			"updated":       item.Created.Unix(),
+			"title":         item.Title,
+			"author":        author,
+			"canonical":     []map[string]string{{"href": link}},
+			"alternate":     []map[string]string{{"href": link, "type": "text/html"}},
+			"summary":       map[string]string{"content": cmp.Or(item.Content, item.Description)},
+			"categories":    categories,
+			"origin": map[string]string{
+				"streamId": greaderFeedPrefix + item.Source.URL,
+				"title":    item.Source.Title,
+				"htmlUrl":  item.Source.Link,
+			},
+		})
+	}
+
+	resp := map[string]any{
+		"id":      stream,
+		"updated": time.Now().Unix(),
+		"items":   entries,
+	}
+	if continuation != "" {
+		resp["continuation"] = continuation
+	}
+	return resp
+}
+
+// greaderEditTag adds (a) and removes (r) the read and starred states of
+// the items listed in i. Labels belong to sources and cannot be edited.
+func (d *daemon) greaderEditTag(w http.ResponseWriter, r *http.Request) {
+	d.reader.mu.Lock()
+	defer d.reader.mu.Unlock()
+
+	for _, value := range r.Form["i"] {
+		id, ok := parseGReaderItemID(value)
+		if !ok {
+			continue
+		}
+		for _, tag := range r.Form["a"] {
+			switch tag {
+			case greaderRead:
+				d.reader.Read[id] = true
+			case greaderStarred:
+				d.reader.Saved[id] = true
+			}
+		}
+		for _, tag := range r.Form["r"] {
+			switch tag {
+			case greaderRead:
+				delete(d.reader.Read, id)
+			case greaderStarred:
+				delete(d.reader.Saved, id)
+			}
+		}
+	}
+	if err := d.reader.save(); err != nil {
+		log.Printf("Warning: failed to save reader state: %v", err)
+	}
+	io.WriteString(w, "OK")
+}
+
+// greaderMarkAllAsRead marks the items in stream s read, up to ts
+// (microseconds) when given.
+func (d *daemon) greaderMarkAllAsRead(w http.ResponseWriter, r *http.Request) {
+	_, items := d.readerItems()
+	before, _ := strconv.ParseInt(r.Form.Get("ts"), 10, 64)
+	stream := cmp.Or(r.Form.Get("s"), greaderReadingList)
+
+	d.reader.mu.Lock()
+	defer d.reader.mu.Unlock()
+	for _, item := range items {
+		if d.greaderInStream(item, stream) && (before == 0 || item.Created.UnixMicro() <= before) {
+			d.reader.Read[item.ID] = true
+		}
+	}
+	if err := d.reader.save(); err != nil {
+		log.Printf("Warning: failed to save reader state: %v", err)
+	}
+	io.WriteString(w, "OK")
+}
+
+// parseGReaderItemID accepts both the long tag:google.com form of an item
+// id, in hexadecimal, and the short decimal form.
+func parseGReaderItemID(value string) (int64, bool) {
+	var id int64
+	var err error
+	if hexID, ok := strings.CutPrefix(value, greaderItemPrefix); ok {
+		var u uint64
+		u, err = strconv.ParseUint(hexID, 16, 64)
+		id = int64(u)
+	} else {
+		id, err = strconv.ParseInt(value, 10, 64)
+	}
+	return id, err == nil
+}
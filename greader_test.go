@@ -0,0 +1,208 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/feeds"
+)
+
+func TestDaemonGoogleReaderAPI(t *testing.T) {
+	store, err := loadReaderStore("")
+	if err != nil {
+		t.Fatalf("loadReaderStore() unexpected error = %v", err)
+	}
+
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	newItem := func(link string, age int) *feeds.Item {
+		return &feeds.Item{Title: link, Link: &feeds.Link{Href: "https://example.com/" + link}, Created: base.Add(time.Duration(age) * time.Hour)}
+	}
+	tech := &SourceFeed{URL: "https://tech.example.com/feed", Title: "Tech", Tags: []string{"tech"}, Items: []*feeds.Item{newItem("t2", 2), newItem("t1", 1)}}
+	news := &SourceFeed{URL: "https://news.example.com/feed", Title: "News", Items: []*feeds.Item{newItem("n3", 3)}}
+
+	d := newDaemon(&Config{ReaderLogin: "me@example.com:secret"})
+	d.reader = store
+	d.sources = map[string]*SourceFeed{tech.URL: tech, news.URL: news}
+	d.order = []string{tech.URL, news.URL}
+	server := httptest.NewServer(d.handler())
+	defer server.Close()
+
+	resp, err := http.PostForm(server.URL+"/accounts/ClientLogin", url.Values{"Email": {"me@example.com"}, "Passwd": {"wrong"}})
+	if err != nil {
+		t.Fatalf("ClientLogin request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("ClientLogin with a wrong password status = %d, want %d", resp.StatusCode, http.StatusUnauthorized)
+	}
+
+	resp, err = http.PostForm(server.URL+"/accounts/ClientLogin", url.Values{"Email": {"me@example.com"}, "Passwd": {"secret"}})
+	if err != nil {
+		t.Fatalf("ClientLogin request failed: %v", err)
+	}
+	body := readBody(t, resp)
+	_, token, ok := strings.Cut(body, "Auth=")
+	token = strings.TrimSpace(token)
+	if !ok || token == "" {
+		t.Fatalf("ClientLogin body = %q, want an Auth token", body)
+	}
+
+	call := func(method, path string, form url.Values) string {
+		t.Helper()
+		req, err := http.NewRequest(method, server.URL+"/reader/api/0/"+path, strings.NewReader(form.Encode()))
+		if err != nil {
+			t.Fatalf("Failed to create request: %v", err)
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("Authorization", "GoogleLogin auth="+token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Request %s failed: %v", path, err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Request %s status = %d, want %d", path, resp.StatusCode, http.StatusOK)
+		}
+		return readBody(t, resp)
+	}
+	streamIDs := func(query string) []string {
+		t.Helper()
+		var result struct {
+			ItemRefs []struct {
+				ID string `json:"id"`
+			} `json:"itemRefs"`
+		}
+		if err := json.Unmarshal([]byte(call("GET", "stream/items/ids?"+query, url.Values{})), &result); err != nil {
+			t.Fatalf("Failed to decode item ids: %v", err)
+		}
+		var ids []string
+		for _, ref := range result.ItemRefs {
+			ids = append(ids, ref.ID)
+		}
+		return ids
+	}
+
+	resp, err = http.Get(server.URL + "/reader/api/0/user-info")
+	if err != nil {
+		t.Fatalf("user-info request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("user-info without a token status = %d, want %d", resp.StatusCode, http.StatusUnauthorized)
+	}
+
+	var subscriptions struct {
+		Subscriptions []struct {
+			ID         string `json:"id"`
+			Categories []struct {
+				ID string `json:"id"`
+			} `json:"categories"`
+		} `json:"subscriptions"`
+	}
+	if err := json.Unmarshal([]byte(call("GET", "subscription/list?output=json", url.Values{})), &subscriptions); err != nil {
+		t.Fatalf("Failed to decode subscriptions: %v", err)
+	}
+	if len(subscriptions.Subscriptions) != 2 || subscriptions.Subscriptions[0].ID != "feed/"+tech.URL {
+		t.Fatalf("subscriptions = %+v, want tech and news", subscriptions.Subscriptions)
+	}
+	if categories := subscriptions.Subscriptions[0].Categories; len(categories) != 1 || categories[0].ID != "user/-/label/tech" {
+		t.Errorf("tech categories = %+v, want the tech label", categories)
+	}
+
+	// Feeds take ids 1 and 2, so items are numbered 3 to 5 oldest first,
+	// and listed newest first.
+	if got, want := fmt.Sprint(streamIDs("s=user/-/state/com.google/reading-list")), "[5 4 3]"; got != want {
+		t.Errorf("reading list ids = %s, want %s", got, want)
+	}
+	if got, want := fmt.Sprint(streamIDs("s=user/-/label/tech&r=o")), "[3 4]"; got != want {
+		t.Errorf("tech label ids oldest first = %s, want %s", got, want)
+	}
+	if got, want := fmt.Sprint(streamIDs("s=feed/"+news.URL)), "[5]"; got != want {
+		t.Errorf("news feed ids = %s, want %s", got, want)
+	}
+
+	var page struct {
+		ItemRefs     []any  `json:"itemRefs"`
+		Continuation string `json:"continuation"`
+	}
+	json.Unmarshal([]byte(call("GET", "stream/items/ids?n=2", url.Values{})), &page)
+	if len(page.ItemRefs) != 2 || page.Continuation != "2" {
+		t.Errorf("first page = %d items, continuation %q, want 2 items and %q", len(page.ItemRefs), page.Continuation, "2")
+	}
+	if got, want := fmt.Sprint(streamIDs("n=2&c=2")), "[3]"; got != want {
+		t.Errorf("second page ids = %s, want %s", got, want)
+	}
+
+	call("POST", "edit-tag", url.Values{
+		"i": {"tag:google.com,2005:reader/item/0000000000000004", "5"},
+		"a": {"user/-/state/com.google/read", "user/-/state/com.google/starred"},
+	})
+	if got, want := fmt.Sprint(streamIDs("xt=user/-/state/com.google/read")), "[3]"; got != want {
+		t.Errorf("unread ids = %s, want %s", got, want)
+	}
+	call("POST", "edit-tag", url.Values{"i": {"5"}, "r": {"user/-/state/com.google/starred"}})
+	if got, want := fmt.Sprint(streamIDs("s=user/-/state/com.google/starred")), "[4]"; got != want {
+		t.Errorf("starred ids = %s, want %s", got, want)
+	}
+
+	var contents struct {
+		Items []struct {
+			ID         string   `json:"id"`
+			Title      string   `json:"title"`
+			Categories []string `json:"categories"`
+			Origin     struct {
+				StreamID string `json:"streamId"`
+			} `json:"origin"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal([]byte(call("POST", "stream/items/contents", url.Values{"i": {"4"}})), &contents); err != nil {
+		t.Fatalf("Failed to decode item contents: %v", err)
+	}
+	if len(contents.Items) != 1 {
+		t.Fatalf("item contents = %+v, want one item", contents.Items)
+	}
+	item := contents.Items[0]
+	if item.ID != "tag:google.com,2005:reader/item/0000000000000004" || item.Title != "t2" || item.Origin.StreamID != "feed/"+tech.URL {
+		t.Errorf("item = %+v, want t2 from tech", item)
+	}
+	if !strings.Contains(strings.Join(item.Categories, " "), "user/-/state/com.google/starred") {
+		t.Errorf("item categories = %v, want starred", item.Categories)
+	}
+
+	call("POST", "mark-all-as-read", url.Values{"s": {"user/-/label/tech"}})
+	var counts struct {
+		UnreadCounts []struct {
+			ID    string `json:"id"`
+			Count int    `json:"count"`
+		} `json:"unreadcounts"`
+	}
+	json.Unmarshal([]byte(call("GET", "unread-count?output=json", url.Values{})), &counts)
+	if len(counts.UnreadCounts) != 0 {
+		t.Errorf("unread counts = %+v, want none", counts.UnreadCounts)
+	}
+}
+
+func TestParseGReaderItemID(t *testing.T) {
+	tests := []struct {
+		value  string
+		wantID int64
+		wantOK bool
+	}{
+		{"42", 42, true},
+		{"tag:google.com,2005:reader/item/000000000000002a", 42, true},
+		{"tag:google.com,2005:reader/item/zz", 0, false},
+		{"", 0, false},
+	}
+
+	for _, tt := range tests {
+		id, ok := parseGReaderItemID(tt.value)
+		if id != tt.wantID || ok != tt.wantOK {
+			t.Errorf("parseGReaderItemID(%q) = %d, %v, want %d, %v", tt.value, id, ok, tt.wantID, tt.wantOK)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"time"
+
+	"github.com/gorilla/feeds"
+)
+
+// headingRel marks the Source link of heading items inserted by
+// groupBySource and groupByDate, which are not items of any feed.
+const headingRel = "heading"
+
+// groupBySource reorders items, sorted newest first, into one run of items
+// per source, each introduced by a heading item linking to the source.
+// Sources are ordered by their newest item.
+func groupBySource(items []*feeds.Item, sources []*SourceFeed) []*feeds.Item {
+	byURL := make(map[string]*SourceFeed, len(sources))
+	for _, source := range sources {
+		byURL[source.URL] = source
+	}
+
+	var order []string
+	groups := make(map[string][]*feeds.Item)
+	for _, item := range items {
+		key := ""
+		if item.Source != nil {
+			key = item.Source.Href
+		}
+		if _, ok := groups[key]; !ok {
+			order = append(order, key)
+		}
+		groups[key] = append(groups[key], item)
+	}
+
+	grouped := make([]*feeds.Item, 0, len(items)+len(order))
+	for _, key := range order {
+		grouped = append(grouped, sourceHeading(key, byURL[key], groups[key]))
+		grouped = append(grouped, groups[key]...)
+	}
+	return grouped
+}
+
+func sourceHeading(sourceURL string, source *SourceFeed, items []*feeds.Item) *feeds.Item {
+	title, link := "Other items", ""
+	if source != nil {
+		title = cmp.Or(source.Title, source.URL)
+		link = cmp.Or(source.Link, source.URL)
+	}
+
+	heading := &feeds.Item{
+		Title:       title,
+		Description: fmt.Sprintf("%d items from %s", len(items), title),
+		Source:      &feeds.Link{Href: sourceURL, Rel: headingRel},
+		Created:     items[0].Created,
+	}
+	if link != "" {
+		heading.Link = &feeds.Link{Href: link}
+		heading.Id = sourceURL
+		heading.IsPermaLink = "false"
+	}
+	return heading
+}
+
+// groupByDate reorders items into one run of items per calendar day, or
+// week from Monday, in the time zone of now, dating items by date. Each run
+// is introduced by a heading item titled with its date, and keeps the order
+// of its items in items. Runs are ordered by their first item.
+func groupByDate(items []*feeds.Item, period string, now time.Time, date func(*feeds.Item) time.Time) []*feeds.Item {
+	var order []time.Time
+	groups := make(map[int64][]*feeds.Item)
+	for _, item := range items {
+		start := periodStart(date(item).In(now.Location()), period)
+		if _, ok := groups[start.Unix()]; !ok {
+			order = append(order, start)
+		}
+		groups[start.Unix()] = append(groups[start.Unix()], item)
+	}
+
+	grouped := make([]*feeds.Item, 0, len(items)+len(order))
+	for _, start := range order {
+		group := groups[start.Unix()]
+		grouped = append(grouped, &feeds.Item{
+			Title:       periodTitle(start, period),
+			Description: fmt.Sprintf("%d items", len(group)),
+			Source:      &feeds.Link{Rel: headingRel},
+			Created:     group[0].Created,
+		})
+		grouped = append(grouped, group...)
+	}
+	return grouped
+}
+
+// periodStart is the midnight starting the day or week of t.
+func periodStart(t time.Time, period string) time.Time {
+	year, month, day := t.Date()
+	if period == "week" {
+		day -= (int(t.Weekday()) + 6) % 7
+	}
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
+
+// periodTitle names the day or week starting at start by its date, which
+// stays true however long the feed is cached or read after it is written.
+func periodTitle(start time.Time, period string) string {
+	if period == "week" {
+		return "Week of " + start.Format("2 January 2006")
+	}
+	return start.Format("Monday, 2 January 2006")
+}
+
+// relativePeriodTitle names the day or week starting at start relative to
+// the one starting at current, such as "Today" or "Last Week", for pages
+// rendered as they are viewed.
+func relativePeriodTitle(start, current time.Time, period string) string {
+	if period == "week" {
+		switch {
+		case start.Equal(current):
+			return "This Week"
+		case start.Equal(current.AddDate(0, 0, -7)):
+			return "Last Week"
+		}
+		return periodTitle(start, period)
+	}
+	switch {
+	case start.Equal(current):
+		return "Today"
+	case start.Equal(current.AddDate(0, 0, -1)):
+		return "Yesterday"
+	}
+	return periodTitle(start, period)
+}
+
+func isHeading(item *feeds.Item) bool {
+	return item.Source != nil && item.Source.Rel == headingRel
+}
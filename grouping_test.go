@@ -0,0 +1,120 @@
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/gorilla/feeds"
+)
+
+func TestBuildFeedGroupBySource(t *testing.T) {
+	now := time.Now()
+	item := func(title, source string, age time.Duration) *feeds.Item {
+		return &feeds.Item{Title: title, Source: &feeds.Link{Href: source}, Created: now.Add(-age)}
+	}
+	sources := []*SourceFeed{
+		{URL: "https://a.example/feed", Title: "Blog A", Link: "https://a.example/", Items: []*feeds.Item{
+			item("A1", "https://a.example/feed", time.Hour),
+			item("A2", "https://a.example/feed", 3*time.Hour),
+		}},
+		{URL: "https://b.example/feed", Items: []*feeds.Item{
+			item("B1", "https://b.example/feed", 0),
+			item("B2", "https://b.example/feed", 2*time.Hour),
+			item("B3", "https://b.example/feed", 4*time.Hour),
+		}},
+	}
+
+	feed := buildFeed(&Config{Count: 4, GroupBy: "source"}, sources)
+
+	var titles []string
+	for _, item := range feed.Items {
+		titles = append(titles, item.Title)
+	}
+	want := []string{"https://b.example/feed", "B1", "B2", "Blog A", "A1", "A2"}
+	if len(titles) != len(want) {
+		t.Fatalf("buildFeed() titles = %v, want %v", titles, want)
+	}
+	for i := range want {
+		if titles[i] != want[i] {
+			t.Fatalf("buildFeed() titles = %v, want %v", titles, want)
+		}
+	}
+
+	heading := feed.Items[3]
+	if !isHeading(heading) || heading.Link.Href != "https://a.example/" || heading.Description != "2 items from Blog A" {
+		t.Errorf("heading = %+v, want a heading linking to Blog A", heading)
+	}
+	if isHeading(feed.Items[4]) {
+		t.Errorf("isHeading(%q) = true, want false", feed.Items[4].Title)
+	}
+}
+
+func TestGroupByDate(t *testing.T) {
+	// A Wednesday.
+	now := time.Date(2024, 5, 15, 9, 0, 0, 0, time.UTC)
+	at := func(day, hour int) time.Time { return time.Date(2024, 5, day, hour, 0, 0, 0, time.UTC) }
+	items := []*feeds.Item{
+		{Title: "wed", Created: at(15, 8)},
+		{Title: "tue", Created: at(14, 20)},
+		{Title: "mon", Created: at(13, 1)},
+		{Title: "sun", Created: at(12, 23)},
+		{Title: "prev-mon", Created: at(6, 0)},
+		{Title: "older", Created: at(1, 12)},
+	}
+	date := func(item *feeds.Item) time.Time { return item.Created }
+
+	tests := []struct {
+		period string
+		want   []string
+	}{
+		{"day", []string{"Wednesday, 15 May 2024", "wed", "Tuesday, 14 May 2024", "tue", "Monday, 13 May 2024", "mon", "Sunday, 12 May 2024", "sun", "Monday, 6 May 2024", "prev-mon", "Wednesday, 1 May 2024", "older"}},
+		{"week", []string{"Week of 13 May 2024", "wed", "tue", "mon", "Week of 6 May 2024", "sun", "prev-mon", "Week of 29 April 2024", "older"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.period, func(t *testing.T) {
+			var titles []string
+			for _, item := range groupByDate(items, tt.period, now, date) {
+				titles = append(titles, item.Title)
+			}
+			if !slices.Equal(titles, tt.want) {
+				t.Errorf("groupByDate(%q) = %v, want %v", tt.period, titles, tt.want)
+			}
+		})
+	}
+
+	grouped := groupByDate(items, "week", now, date)
+	if !isHeading(grouped[0]) || grouped[0].Description != "3 items" || isHeading(grouped[1]) {
+		t.Errorf("groupByDate() heading = %+v, want a heading for 3 items", grouped[0])
+	}
+}
+
+func TestRelativePeriodTitle(t *testing.T) {
+	// A Wednesday and the Monday starting its week.
+	day := time.Date(2024, 5, 15, 0, 0, 0, 0, time.UTC)
+	week := time.Date(2024, 5, 13, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		start  time.Time
+		period string
+		want   string
+	}{
+		{day, "day", "Today"},
+		{day.AddDate(0, 0, -1), "day", "Yesterday"},
+		{day.AddDate(0, 0, -2), "day", "Monday, 13 May 2024"},
+		{week, "week", "This Week"},
+		{week.AddDate(0, 0, -7), "week", "Last Week"},
+		{week.AddDate(0, 0, -14), "week", "Week of 29 April 2024"},
+	}
+
+	for _, tt := range tests {
+		current := day
+		if tt.period == "week" {
+			current = week
+		}
+		if got := relativePeriodTitle(tt.start, current, tt.period); got != tt.want {
+			t.Errorf("relativePeriodTitle(%v, %q) = %q, want %q", tt.start, tt.period, got, tt.want)
+		}
+	}
+}
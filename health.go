@@ -0,0 +1,252 @@
+package main
+
+import (
+	"cmp"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"slices"
+	"text/tabwriter"
+	"time"
+)
+
+// SourceHealth is the fetch history of one source, kept in the state file.
+type SourceHealth struct {
+	LastSuccess   time.Time `json:"last_success,omitzero"`
+	LastFailure   time.Time `json:"last_failure,omitzero"`
+	LastError     string    `json:"last_error,omitempty"`
+	FailureStreak int       `json:"failure_streak,omitempty"`
+	Fetches       int       `json:"fetches"`
+	TotalItems    int       `json:"total_items"`
+	NewestItem    time.Time `json:"newest_item,omitzero"`
+
+	// Downloads counts the successful fetches that downloaded the source
+	// rather than reading it from the cache, which the totals below add up.
+	Downloads      int           `json:"downloads,omitempty"`
+	TotalFetchTime time.Duration `json:"total_fetch_time,omitempty"`
+	TotalParseTime time.Duration `json:"total_parse_time,omitempty"`
+	TotalBytes     int64         `json:"total_bytes,omitempty"`
+}
+
+// recordResults adds one run's fetch results to the sources' history.
+func (s *State) recordResults(results map[string]SourceResult, now time.Time) {
+	if s.Sources == nil {
+		s.Sources = make(map[string]*SourceHealth)
+	}
+	for url, result := range results {
+		key := normalizeURL(url)
+		health, ok := s.Sources[key]
+		if !ok {
+			health = &SourceHealth{}
+			s.Sources[key] = health
+		}
+
+		if result.Err != nil {
+			health.LastFailure = now
+			health.LastError = result.Err.Error()
+			health.FailureStreak++
+			continue
+		}
+		health.LastSuccess = now
+		health.FailureStreak = 0
+		health.Fetches++
+		health.TotalItems += result.Items
+		if result.Newest.After(health.NewestItem) {
+			health.NewestItem = result.Newest
+		}
+		if result.Metrics != nil {
+			health.Downloads++
+			health.TotalFetchTime += result.Metrics.Duration
+			health.TotalParseTime += result.Metrics.Parse
+			health.TotalBytes += int64(result.Metrics.Bytes)
+		}
+	}
+}
+
+// averageMetrics returns the average download of the source, and false
+// when it has never been downloaded.
+func (h *SourceHealth) averageMetrics() (FetchMetrics, bool) {
+	if h.Downloads == 0 {
+		return FetchMetrics{}, false
+	}
+	n := h.Downloads
+	return FetchMetrics{
+		Duration: h.TotalFetchTime / time.Duration(n),
+		Parse:    h.TotalParseTime / time.Duration(n),
+		Bytes:    int(h.TotalBytes / int64(n)),
+	}, true
+}
+
+// recordSourceHealth adds the run's fetch results to the history in the
+// state file. The history is only informational, so failures are logged.
+func recordSourceHealth(config *Config, stats *RunStats, now time.Time) {
+	if config.StateFile == "" || config.Offline || stats == nil || len(stats.Results) == 0 {
+		return
+	}
+
+	state, err := loadState(config.StateFile)
+	if err != nil {
+		log.Printf("Warning: cannot record source health: %v", err)
+		return
+	}
+	state.recordResults(stats.Results, now)
+	// Recording health must not make the next run think it has seen the
+	// items already.
+	if state.isNew {
+		state.Items = nil
+	}
+	if err := state.save(); err != nil {
+		log.Printf("Warning: cannot record source health: %v", err)
+	}
+}
+
+// status describes a source as "dead" when it has not been fetched, or its
+// newest item is older than deadAfter, as "failing" while its latest
+// fetches fail, and as "ok" otherwise. The reason explains the first two.
+func (h *SourceHealth) status(now time.Time, deadAfter time.Duration) (string, string) {
+	cutoff := now.Add(-deadAfter)
+	switch {
+	case h.FailureStreak > 0 && h.LastSuccess.Before(cutoff):
+		if h.LastSuccess.IsZero() {
+			return "dead", "never fetched: " + h.LastError
+		}
+		return "dead", "no successful fetch since " + h.LastSuccess.Format(time.DateOnly) + ": " + h.LastError
+	case !h.NewestItem.IsZero() && h.NewestItem.Before(cutoff):
+		return "dead", "nothing published since " + h.NewestItem.Format(time.DateOnly)
+	case h.FailureStreak > 0:
+		return "failing", h.LastError
+	}
+	return "ok", ""
+}
+
+func runHealth(args []string) error {
+	flags := flag.NewFlagSet("health", flag.ExitOnError)
+	stateFile := flags.String("state", "", "State file written by -state")
+	inputFiles := stringsFlag(flags, "input", "Only report the feeds in this input file; may be repeated")
+	deadAfter := flags.String("dead-after", "30d", "Flag feeds as dead after this long without a successful fetch or a new item, e.g. 30d")
+	deadOnly := flags.Bool("dead", false, "Only show feeds that appear dead")
+	sortBy := flags.String("sort", "url", "Order feeds by url, fetch-time, parse-time or size, the slowest or largest first")
+	flags.Parse(args)
+
+	if *stateFile == "" {
+		return fmt.Errorf("state file must be provided")
+	}
+	period, err := parseDays(*deadAfter)
+	if err != nil {
+		return fmt.Errorf("dead-after: %v", err)
+	}
+	if !slices.Contains([]string{"url", "fetch-time", "parse-time", "size"}, *sortBy) {
+		return fmt.Errorf("sort must be url, fetch-time, parse-time or size")
+	}
+
+	state, err := loadState(*stateFile)
+	if err != nil {
+		return err
+	}
+
+	var urls []string
+	if len(*inputFiles) > 0 {
+		sources, err := readSourcesFromFiles(*inputFiles)
+		if err != nil {
+			return fmt.Errorf("error reading input file: %v", err)
+		}
+		for _, source := range sources {
+			urls = append(urls, source.URL)
+		}
+	} else {
+		for url := range state.Sources {
+			urls = append(urls, url)
+		}
+		slices.Sort(urls)
+	}
+	if *sortBy != "url" {
+		sortByMetric(state, urls, *sortBy)
+	}
+
+	return writeHealthReport(os.Stdout, state, urls, period, *deadOnly, time.Now())
+}
+
+func writeHealthReport(w io.Writer, state *State, urls []string, deadAfter time.Duration, deadOnly bool, now time.Time) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "URL\tSTATUS\tLAST-SUCCESS\tFAILURES\tAVG-ITEMS\tNEWEST-ITEM\tAVG-FETCH\tAVG-PARSE\tAVG-SIZE\tNOTES")
+
+	for _, url := range urls {
+		health, ok := state.Sources[normalizeURL(url)]
+		if !ok {
+			if !deadOnly {
+				fmt.Fprintf(tw, "%s\tunknown\t\t\t\t\t\t\t\tno fetches recorded\n", url)
+			}
+			continue
+		}
+
+		status, reason := health.status(now, deadAfter)
+		if deadOnly && status != "dead" {
+			continue
+		}
+
+		average := ""
+		if health.Fetches > 0 {
+			average = fmt.Sprintf("%.1f", float64(health.TotalItems)/float64(health.Fetches))
+		}
+		var fetchTime, parseTime, size string
+		if metrics, ok := health.averageMetrics(); ok {
+			fetchTime = metrics.Duration.Round(time.Millisecond).String()
+			parseTime = metrics.Parse.Round(time.Millisecond).String()
+			size = formatBytes(metrics.Bytes)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\t%s\t%s\t%s\t%s\t%s\n", url, status,
+			formatHealthDate(health.LastSuccess), health.FailureStreak, average,
+			formatHealthDate(health.NewestItem), fetchTime, parseTime, size, reason)
+	}
+
+	return tw.Flush()
+}
+
+func formatHealthDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.DateOnly)
+}
+
+// sortByMetric orders urls by their average fetch-time, parse-time or size,
+// largest first, so the sources worth pruning come first. Sources never
+// downloaded go last.
+func sortByMetric(state *State, urls []string, metric string) {
+	value := func(url string) int64 {
+		health, ok := state.Sources[normalizeURL(url)]
+		if !ok {
+			return -1
+		}
+		metrics, ok := health.averageMetrics()
+		if !ok {
+			return -1
+		}
+		switch metric {
+		case "fetch-time":
+			return int64(metrics.Duration)
+		case "parse-time":
+			return int64(metrics.Parse)
+		}
+		return int64(metrics.Bytes)
+	}
+	slices.SortStableFunc(urls, func(a, b string) int {
+		return cmp.Compare(value(b), value(a))
+	})
+}
+
+// formatBytes writes n with a binary unit, e.g. 12.3 KiB.
+func formatBytes(n int) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	value, prefix := float64(n)/unit, 0
+	for value >= unit && prefix < 2 {
+		value /= unit
+		prefix++
+	}
+	return fmt.Sprintf("%.1f %ciB", value, "KMG"[prefix])
+}
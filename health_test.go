@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecordSourceHealth(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "rss_health")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	config := &Config{StateFile: filepath.Join(tempDir, "state.json")}
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	published := time.Date(2024, 5, 30, 0, 0, 0, 0, time.UTC)
+
+	runs := []map[string]SourceResult{
+		{"http://a.example/feed": {Items: 4, Newest: published}},
+		{"http://a.example/feed": {Items: 2, Newest: published.Add(-time.Hour), Metrics: &FetchMetrics{Duration: 300 * time.Millisecond, Parse: 10 * time.Millisecond, Bytes: 2000}}},
+		{"http://a.example/feed": {Items: 2, Newest: published.Add(-time.Hour), Metrics: &FetchMetrics{Duration: 100 * time.Millisecond, Parse: 30 * time.Millisecond, Bytes: 4000}}},
+		{"http://a.example/feed": {Err: errors.New("timeout")}},
+	}
+	for i, results := range runs {
+		recordSourceHealth(config, &RunStats{Results: results}, now.Add(time.Duration(i)*time.Hour))
+	}
+
+	state, err := loadState(config.StateFile)
+	if err != nil {
+		t.Fatalf("loadState() unexpected error = %v", err)
+	}
+	if !state.isNew {
+		t.Errorf("loadState() of a health-only state should report a new state, so the first run still seeds")
+	}
+
+	health := state.Sources["http://a.example/feed"]
+	want := SourceHealth{
+		LastSuccess:   now.Add(2 * time.Hour),
+		LastFailure:   now.Add(3 * time.Hour),
+		LastError:     "timeout",
+		FailureStreak: 1,
+		Fetches:       3,
+		TotalItems:    8,
+		NewestItem:    published,
+	}
+	if health == nil || !health.LastSuccess.Equal(want.LastSuccess) || !health.LastFailure.Equal(want.LastFailure) ||
+		!health.NewestItem.Equal(want.NewestItem) || health.LastError != want.LastError ||
+		health.FailureStreak != want.FailureStreak || health.Fetches != want.Fetches || health.TotalItems != want.TotalItems {
+		t.Errorf("recorded health = %+v, want %+v", health, want)
+	}
+
+	// The first run came from the cache, so only two downloads count.
+	metrics, ok := health.averageMetrics()
+	wantMetrics := FetchMetrics{Duration: 200 * time.Millisecond, Parse: 20 * time.Millisecond, Bytes: 3000}
+	if !ok || metrics != wantMetrics {
+		t.Errorf("averageMetrics() = %+v, %v, want %+v, true", metrics, ok, wantMetrics)
+	}
+}
+
+func TestSourceHealthStatus(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	recent := now.Add(-24 * time.Hour)
+	old := now.Add(-60 * 24 * time.Hour)
+
+	tests := []struct {
+		name   string
+		health SourceHealth
+		want   string
+	}{
+		{"healthy", SourceHealth{LastSuccess: recent, Fetches: 3, NewestItem: recent}, "ok"},
+		{"undated items", SourceHealth{LastSuccess: recent, Fetches: 3}, "ok"},
+		{"recent failures", SourceHealth{LastSuccess: recent, FailureStreak: 2, LastError: "timeout", NewestItem: recent}, "failing"},
+		{"failing for long", SourceHealth{LastSuccess: old, FailureStreak: 40, LastError: "404", NewestItem: old}, "dead"},
+		{"never fetched", SourceHealth{FailureStreak: 1, LastError: "no such host"}, "dead"},
+		{"quiet", SourceHealth{LastSuccess: recent, Fetches: 10, NewestItem: old}, "dead"},
+	}
+
+	for _, tt := range tests {
+		if got, _ := tt.health.status(now, 30*24*time.Hour); got != tt.want {
+			t.Errorf("status() for %s = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestWriteHealthReport(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	state := &State{Sources: map[string]*SourceHealth{
+		"http://a.example/feed": {LastSuccess: now, Fetches: 4, TotalItems: 10, NewestItem: now,
+			Downloads: 2, TotalFetchTime: 3 * time.Second, TotalParseTime: 50 * time.Millisecond, TotalBytes: 3 << 20},
+		"http://b.example/feed": {LastSuccess: now, Fetches: 2, TotalItems: 2, NewestItem: now.Add(-90 * 24 * time.Hour)},
+	}}
+	urls := []string{"http://a.example/feed", "http://b.example/feed", "http://c.example/feed"}
+
+	var all bytes.Buffer
+	if err := writeHealthReport(&all, state, urls, 30*24*time.Hour, false, now); err != nil {
+		t.Fatalf("writeHealthReport() unexpected error = %v", err)
+	}
+	for _, want := range []string{"2.5", "nothing published since 2024-03-03", "http://c.example/feed", "no fetches recorded", "1.5s", "25ms", "1.5 MiB"} {
+		if !strings.Contains(all.String(), want) {
+			t.Errorf("writeHealthReport() output missing %q:\n%s", want, all.String())
+		}
+	}
+
+	var dead bytes.Buffer
+	if err := writeHealthReport(&dead, state, urls, 30*24*time.Hour, true, now); err != nil {
+		t.Fatalf("writeHealthReport() unexpected error = %v", err)
+	}
+	if !strings.Contains(dead.String(), "http://b.example/feed") ||
+		strings.Contains(dead.String(), "http://a.example/feed") || strings.Contains(dead.String(), "http://c.example/feed") {
+		t.Errorf("writeHealthReport() dead only should list just b:\n%s", dead.String())
+	}
+}
+
+func TestSortByMetric(t *testing.T) {
+	state := &State{Sources: map[string]*SourceHealth{
+		"http://slow.example/feed":   {Downloads: 1, TotalFetchTime: 5 * time.Second, TotalBytes: 1000},
+		"http://large.example/feed":  {Downloads: 2, TotalFetchTime: 2 * time.Second, TotalBytes: 4 << 20},
+		"http://cached.example/feed": {Fetches: 3},
+	}}
+	tests := []struct {
+		metric string
+		want   string
+	}{
+		{"fetch-time", "[http://slow.example/feed http://large.example/feed http://cached.example/feed http://unknown.example/feed]"},
+		{"size", "[http://large.example/feed http://slow.example/feed http://cached.example/feed http://unknown.example/feed]"},
+	}
+
+	for _, tt := range tests {
+		urls := []string{"http://cached.example/feed", "http://large.example/feed", "http://slow.example/feed", "http://unknown.example/feed"}
+		sortByMetric(state, urls, tt.metric)
+		if got := fmt.Sprint(urls); got != tt.want {
+			t.Errorf("sortByMetric(%s) = %s, want %s", tt.metric, got, tt.want)
+		}
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		n    int
+		want string
+	}{
+		{512, "512 B"},
+		{1536, "1.5 KiB"},
+		{3 << 20, "3.0 MiB"},
+		{5 << 30, "5.0 GiB"},
+	}
+
+	for _, tt := range tests {
+		if got := formatBytes(tt.n); got != tt.want {
+			t.Errorf("formatBytes(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
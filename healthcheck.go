@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+)
+
+// runHealthcheck checks a running daemon through its /healthz endpoint, or
+// a run-once setup through the age of its output file, and returns an
+// error when it is unhealthy. It is meant for container HEALTHCHECK lines.
+func runHealthcheck(args []string) error {
+	flags := flag.NewFlagSet("healthcheck", flag.ExitOnError)
+	url := flags.String("url", "", "Health endpoint of the daemon, e.g. http://localhost:8080/healthz")
+	outputFile := flags.String("output", "", "Output file to check the age of instead of querying the daemon")
+	maxAge := flags.Duration("max-age", 0, "Unhealthy when the feed was last updated longer ago than this (0 = any age; required with -output)")
+	timeout := flags.Duration("timeout", 5*time.Second, "Timeout for querying -url")
+	flags.Parse(args)
+
+	switch {
+	case (*url == "") == (*outputFile == ""):
+		return fmt.Errorf("exactly one of url and output must be provided")
+	case *maxAge < 0:
+		return fmt.Errorf("max-age must not be negative")
+	case *outputFile != "" && *maxAge == 0:
+		return fmt.Errorf("output requires max-age")
+	}
+
+	var updated time.Time
+	if *url != "" {
+		health, err := fetchDaemonHealth(&http.Client{Timeout: *timeout}, *url)
+		if err != nil {
+			return err
+		}
+		updated = health.LastSuccess
+	} else {
+		info, err := os.Stat(*outputFile)
+		if err != nil {
+			return fmt.Errorf("error checking output file: %v", err)
+		}
+		updated = info.ModTime()
+	}
+
+	if *maxAge > 0 {
+		if age := time.Since(updated); age > *maxAge {
+			return fmt.Errorf("feed last updated %v ago, more than %v", age.Round(time.Second), *maxAge)
+		}
+	}
+	return nil
+}
+
+func fetchDaemonHealth(client *http.Client, url string) (*daemonHealth, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("error querying daemon: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var health daemonHealth
+	if err := json.NewDecoder(resp.Body).Decode(&health); err != nil {
+		return nil, fmt.Errorf("error querying daemon: %s", resp.Status)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("daemon is %s", health.Status)
+	}
+	return &health, nil
+}
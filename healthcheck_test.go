@@ -0,0 +1,86 @@
+package main
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunHealthcheckDaemon(t *testing.T) {
+	d := newDaemon(&Config{})
+	server := httptest.NewServer(d.handler())
+	defer server.Close()
+	url := server.URL + "/healthz"
+
+	if err := runHealthcheck([]string{"-url", url}); err == nil || !strings.Contains(err.Error(), "starting") {
+		t.Errorf("runHealthcheck() before the first update error = %v, want starting", err)
+	}
+
+	d.lastSuccess = time.Now().Add(-2 * time.Hour)
+	d.lastError = "aggregating feeds: boom"
+	if err := runHealthcheck([]string{"-url", url}); err != nil {
+		t.Errorf("runHealthcheck() unexpected error = %v", err)
+	}
+	if err := runHealthcheck([]string{"-url", url, "-max-age", "1h"}); err == nil {
+		t.Errorf("runHealthcheck() expected error for an update older than max-age")
+	}
+	if err := runHealthcheck([]string{"-url", url, "-max-age", "3h"}); err != nil {
+		t.Errorf("runHealthcheck() unexpected error = %v", err)
+	}
+
+	server.Close()
+	if err := runHealthcheck([]string{"-url", url}); err == nil {
+		t.Errorf("runHealthcheck() expected error when the daemon is down")
+	}
+}
+
+func TestRunHealthcheckOutput(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "rss_healthcheck")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	output := filepath.Join(tempDir, "out.xml")
+	if err := runHealthcheck([]string{"-output", output, "-max-age", "1h"}); err == nil {
+		t.Errorf("runHealthcheck() expected error for a missing output file")
+	}
+
+	if err := os.WriteFile(output, []byte("<rss/>"), 0644); err != nil {
+		t.Fatalf("Failed to write output file: %v", err)
+	}
+	if err := runHealthcheck([]string{"-output", output, "-max-age", "1h"}); err != nil {
+		t.Errorf("runHealthcheck() unexpected error = %v", err)
+	}
+
+	old := time.Now().Add(-2 * time.Hour)
+	if err := os.Chtimes(output, old, old); err != nil {
+		t.Fatalf("Failed to age output file: %v", err)
+	}
+	if err := runHealthcheck([]string{"-output", output, "-max-age", "1h"}); err == nil {
+		t.Errorf("runHealthcheck() expected error for a stale output file")
+	}
+}
+
+func TestRunHealthcheckOptions(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"neither", nil},
+		{"both", []string{"-url", "http://localhost/healthz", "-output", "out.xml"}},
+		{"output without max-age", []string{"-output", "out.xml"}},
+		{"negative max-age", []string{"-url", "http://localhost/healthz", "-max-age", "-1h"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := runHealthcheck(tt.args); err == nil {
+				t.Errorf("runHealthcheck(%q) expected error", tt.args)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"html/template"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+)
+
+// highlightTerms are the terms the web UI marks in items, so readers see at
+// a glance why an item was selected: the keywords -score-keywords favours.
+func (config *Config) highlightTerms() []string {
+	scorer, ok := config.Scorer.(keywordScorer)
+	if !ok {
+		return nil
+	}
+	var terms []string
+	for keyword, weight := range scorer.Keywords {
+		if weight > 0 {
+			terms = append(terms, keyword)
+		}
+	}
+	// Longer terms first, so that a term inside another is not marked
+	// instead of it.
+	slices.SortFunc(terms, func(a, b string) int { return len(b) - len(a) })
+	return terms
+}
+
+// highlightText escapes text as HTML with each occurrence of terms, in any
+// case, wrapped in <mark>.
+func highlightText(text string, terms []string) template.HTML {
+	return template.HTML(markTerms(text, terms, html.EscapeString))
+}
+
+// highlightHTML wraps occurrences of terms in the text of an HTML fragment
+// in <mark>. Only text is marked: tags, attributes, comments and the
+// contents of scripts and styles are left alone, and so are entities, which
+// are matched as the characters they stand for.
+func highlightHTML(fragment string, terms []string) template.HTML {
+	if len(terms) == 0 {
+		return template.HTML(fragment)
+	}
+	var b strings.Builder
+	z := html.NewTokenizer(strings.NewReader(fragment))
+	// rawText is the element whose contents are not text, if in one.
+	rawText := ""
+	for {
+		tokenType := z.Next()
+		if tokenType == html.ErrorToken {
+			return template.HTML(b.String())
+		}
+		// Text and TagName may change what Raw returns.
+		raw := string(z.Raw())
+		switch tokenType {
+		case html.TextToken:
+			if rawText == "" {
+				b.WriteString(markTerms(string(z.Text()), terms, html.EscapeString))
+				continue
+			}
+		case html.StartTagToken:
+			name, _ := z.TagName()
+			if slices.Contains(rawTextElements, string(name)) {
+				rawText = string(name)
+			}
+		case html.EndTagToken:
+			if name, _ := z.TagName(); string(name) == rawText {
+				rawText = ""
+			}
+		}
+		b.WriteString(raw)
+	}
+}
+
+// rawTextElements hold text that is not rendered as such.
+var rawTextElements = []string{"script", "style", "textarea", "title", "xmp", "iframe", "noembed", "noframes", "noscript", "plaintext"}
+
+// markTerms wraps each occurrence of terms in text in <mark>, passing the
+// rest of text and the marked occurrences through escape.
+func markTerms(text string, terms []string, escape func(string) string) string {
+	if len(terms) == 0 {
+		return escape(text)
+	}
+	lower := strings.ToLower(text)
+	if len(lower) != len(text) {
+		// Lowercasing changed byte offsets; match case-sensitively.
+		lower = text
+	}
+
+	var b strings.Builder
+	start := 0
+	for i := 0; i < len(text); {
+		matched := ""
+		for _, term := range terms {
+			if term != "" && strings.HasPrefix(lower[i:], term) {
+				matched = term
+				break
+			}
+		}
+		if matched == "" {
+			_, size := utf8.DecodeRuneInString(text[i:])
+			i += size
+			continue
+		}
+		b.WriteString(escape(text[start:i]))
+		b.WriteString("<mark>" + escape(text[i:i+len(matched)]) + "</mark>")
+		i += len(matched)
+		start = i
+	}
+	b.WriteString(escape(text[start:]))
+	return b.String()
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"html/template"
+	"reflect"
+	"testing"
+)
+
+func TestHighlightText(t *testing.T) {
+	tests := []struct {
+		text  string
+		terms []string
+		want  template.HTML
+	}{
+		{"Go & Rust", nil, "Go &amp; Rust"},
+		{"Go & Rust", []string{"go"}, "<mark>Go</mark> &amp; Rust"},
+		{"Golang, go, GO", []string{"go"}, "<mark>Go</mark>lang, <mark>go</mark>, <mark>GO</mark>"},
+		{"<b>rust</b>", []string{"rust"}, "&lt;b&gt;<mark>rust</mark>&lt;/b&gt;"},
+		{"rust-lang and rust", []string{"rust-lang", "rust"}, "<mark>rust-lang</mark> and <mark>rust</mark>"},
+	}
+
+	for _, tt := range tests {
+		if got := highlightText(tt.text, tt.terms); got != tt.want {
+			t.Errorf("highlightText(%q, %v) = %q, want %q", tt.text, tt.terms, got, tt.want)
+		}
+	}
+}
+
+func TestHighlightHTML(t *testing.T) {
+	tests := []struct {
+		fragment string
+		want     template.HTML
+	}{
+		{`<p>Learn <a href="https://go.dev/" title="go">Go</a> today</p>`, `<p>Learn <a href="https://go.dev/" title="go"><mark>Go</mark></a> today</p>`},
+		{`<p title="a > go">Go</p>`, `<p title="a > go"><mark>Go</mark></p>`},
+		{`<p>Go &amp; &lt;go&gt;</p>`, `<p><mark>Go</mark> &amp; &lt;<mark>go</mark>&gt;</p>`},
+		{`<p>&#103;o &gopher;</p>`, `<p><mark>go</mark> &amp;<mark>go</mark>pher;</p>`},
+		{`<!-- go --><style>.go {}</style><p>go</p>`, `<!-- go --><style>.go {}</style><p><mark>go</mark></p>`},
+	}
+
+	for _, tt := range tests {
+		if got := highlightHTML(tt.fragment, []string{"go"}); got != tt.want {
+			t.Errorf("highlightHTML(%q) = %q, want %q", tt.fragment, got, tt.want)
+		}
+	}
+}
+
+func TestHighlightTerms(t *testing.T) {
+	config := &Config{Scorer: keywordScorer{Keywords: map[string]float64{"go": 2, "golang": 1, "ads": -3}}}
+	if got, want := config.highlightTerms(), []string{"golang", "go"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("highlightTerms() = %v, want %v", got, want)
+	}
+	if got := (&Config{}).highlightTerms(); got != nil {
+		t.Errorf("highlightTerms() without a keyword scorer = %v, want none", got)
+	}
+}
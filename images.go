@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/gorilla/feeds"
+)
+
+var imgSrcPattern = regexp.MustCompile(`(?i)(<img\b[^>]*?\bsrc\s*=\s*)("[^"]*"|'[^']*')`)
+
+// rewriteImageSources calls rewrite for the src attribute of every <img> tag
+// in content and substitutes the returned value.
+func rewriteImageSources(content string, rewrite func(src string) string) string {
+	return imgSrcPattern.ReplaceAllStringFunc(content, func(match string) string {
+		parts := imgSrcPattern.FindStringSubmatch(match)
+		quoted := parts[2]
+		src := html.UnescapeString(quoted[1 : len(quoted)-1])
+		return parts[1] + `"` + html.EscapeString(rewrite(src)) + `"`
+	})
+}
+
+// resolveImageURL makes src absolute relative to the item link.
+func resolveImageURL(src string, item *feeds.Item) string {
+	if item.Link == nil || item.Link.Href == "" {
+		return src
+	}
+	base, err := url.Parse(item.Link.Href)
+	if err != nil {
+		return src
+	}
+	ref, err := url.Parse(src)
+	if err != nil {
+		return src
+	}
+	return base.ResolveReference(ref).String()
+}
+
+func inlineImages(items []*feeds.Item, maxBytes int64) {
+	cache := make(map[string]string)
+
+	inline := func(item *feeds.Item) func(string) string {
+		return func(src string) string {
+			if strings.HasPrefix(src, "data:") {
+				return src
+			}
+			abs := resolveImageURL(src, item)
+			if dataURI, ok := cache[abs]; ok {
+				if dataURI == "" {
+					return src
+				}
+				return dataURI
+			}
+
+			dataURI, err := fetchDataURI(abs, maxBytes)
+			if err != nil {
+				log.Printf("Warning: failed to inline image %s: %v", abs, err)
+			}
+			cache[abs] = dataURI
+			if dataURI == "" {
+				return src
+			}
+			return dataURI
+		}
+	}
+
+	for _, item := range items {
+		item.Description = rewriteImageSources(item.Description, inline(item))
+		item.Content = rewriteImageSources(item.Content, inline(item))
+	}
+}
+
+func fetchDataURI(imageURL string, maxBytes int64) (string, error) {
+	resp, err := httpClient.Get(imageURL)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil || !strings.HasPrefix(mediaType, "image/") {
+		return "", fmt.Errorf("not an image: %q", resp.Header.Get("Content-Type"))
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxBytes+1))
+	if err != nil {
+		return "", err
+	}
+	if int64(len(data)) > maxBytes {
+		return "", fmt.Errorf("image larger than %d bytes", maxBytes)
+	}
+
+	return "data:" + mediaType + ";base64," + base64.StdEncoding.EncodeToString(data), nil
+}
+
+func proxyImages(items []*feeds.Item, proxyTemplate string) {
+	proxy := func(item *feeds.Item) func(string) string {
+		return func(src string) string {
+			if strings.HasPrefix(src, "data:") {
+				return src
+			}
+			return strings.ReplaceAll(proxyTemplate, "{url}", url.QueryEscape(resolveImageURL(src, item)))
+		}
+	}
+
+	for _, item := range items {
+		item.Description = rewriteImageSources(item.Description, proxy(item))
+		item.Content = rewriteImageSources(item.Content, proxy(item))
+	}
+}
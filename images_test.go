@@ -0,0 +1,80 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/feeds"
+)
+
+func TestRewriteImageSources(t *testing.T) {
+	content := `<p>Hi</p><img alt="a" src="/a.png"><IMG SRC='b.jpg?x=1&amp;y=2'/>`
+	got := rewriteImageSources(content, func(src string) string {
+		return "[" + src + "]"
+	})
+
+	want := `<p>Hi</p><img alt="a" src="[/a.png]"><IMG SRC="[b.jpg?x=1&amp;y=2]"/>`
+	if got != want {
+		t.Errorf("rewriteImageSources() = %q, want %q", got, want)
+	}
+}
+
+func TestInlineImages(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/small.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte("png"))
+		case "/big.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte(strings.Repeat("x", 100)))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	items := []*feeds.Item{
+		{
+			Link:    &feeds.Link{Href: server.URL + "/posts/1"},
+			Content: `<img src="/small.png"><img src="/big.png"><img src="/missing.png">`,
+		},
+	}
+
+	inlineImages(items, 10)
+
+	content := items[0].Content
+	if !strings.Contains(content, `src="data:image/png;base64,cG5n"`) {
+		t.Errorf("small image was not inlined: %q", content)
+	}
+	if !strings.Contains(content, `src="/big.png"`) {
+		t.Errorf("oversized image should keep its URL: %q", content)
+	}
+	if !strings.Contains(content, `src="/missing.png"`) {
+		t.Errorf("missing image should keep its URL: %q", content)
+	}
+}
+
+func TestProxyImages(t *testing.T) {
+	items := []*feeds.Item{
+		{
+			Link:        &feeds.Link{Href: "https://blog.example/posts/1"},
+			Description: `<img src="/a.png?s=1">`,
+			Content:     `<img src="data:image/png;base64,AAAA"><img src="https://cdn.example/b.jpg">`,
+		},
+	}
+
+	proxyImages(items, "https://camo.example/{url}")
+
+	if want := `<img src="https://camo.example/https%3A%2F%2Fblog.example%2Fa.png%3Fs%3D1">`; items[0].Description != want {
+		t.Errorf("proxyImages() description = %q, want %q", items[0].Description, want)
+	}
+	if !strings.Contains(items[0].Content, `src="data:image/png;base64,AAAA"`) {
+		t.Errorf("proxyImages() should leave data URIs alone: %q", items[0].Content)
+	}
+	if !strings.Contains(items[0].Content, `src="https://camo.example/https%3A%2F%2Fcdn.example%2Fb.jpg"`) {
+		t.Errorf("proxyImages() did not rewrite absolute image: %q", items[0].Content)
+	}
+}
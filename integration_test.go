@@ -134,6 +134,28 @@ func TestCLIIntegration(t *testing.T) {
 		}
 	})
 
+	t.Run("input from stdin", func(t *testing.T) {
+		outputFile := filepath.Join(tempDir, "stdin_output.xml")
+
+		cmd := exec.Command(binaryPath,
+			"-input", "-",
+			"-output", outputFile)
+		cmd.Stdin = strings.NewReader(server.URL + "\n")
+
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("CLI command failed: %v\nOutput: %s", err, output)
+		}
+
+		content, err := os.ReadFile(outputFile)
+		if err != nil {
+			t.Fatalf("Failed to read output file: %v", err)
+		}
+		if !strings.Contains(string(content), "Integration Test Item 1") {
+			t.Errorf("Output file does not contain the items of the feed listed on stdin")
+		}
+	})
+
 	t.Run("error cases", func(t *testing.T) {
 		// Test missing input file in all mode
 		cmd := exec.Command(binaryPath,
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// publishIPFS adds data to the IPFS node whose RPC API is at api, pins it,
+// and points the IPNS name of key at it unless key is empty. It returns the
+// CID of the added file.
+func publishIPFS(api, key string, data []byte) (string, error) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, err := mw.CreateFormFile("file", "feed.xml")
+	if err != nil {
+		return "", err
+	}
+	part.Write(data)
+	mw.Close()
+
+	var added struct {
+		Hash string
+	}
+	if err := ipfsCall(api, "add", url.Values{"pin": {"true"}}, mw.FormDataContentType(), &body, &added); err != nil {
+		return "", err
+	}
+
+	if key != "" {
+		params := url.Values{"arg": {"/ipfs/" + added.Hash}, "key": {key}}
+		if err := ipfsCall(api, "name/publish", params, "", nil, nil); err != nil {
+			return added.Hash, err
+		}
+	}
+
+	return added.Hash, nil
+}
+
+func ipfsCall(api, command string, params url.Values, contentType string, body io.Reader, result any) error {
+	endpoint := strings.TrimSuffix(api, "/") + "/api/v0/" + command + "?" + params.Encode()
+	req, err := http.NewRequest(http.MethodPost, endpoint, body)
+	if err != nil {
+		return err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("ipfs %s: %v", command, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("ipfs %s: unexpected status %s: %s", command, resp.Status, bytes.TrimSpace(msg))
+	}
+
+	if result == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("ipfs %s: error decoding response: %v", command, err)
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPublishIPFS(t *testing.T) {
+	var added, published string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v0/add":
+			file, _, err := r.FormFile("file")
+			if err != nil {
+				t.Errorf("add request without file: %v", err)
+				return
+			}
+			data, _ := io.ReadAll(file)
+			added = string(data)
+			fmt.Fprint(w, `{"Name":"feed.xml","Hash":"bafytest","Size":"6"}`)
+		case "/api/v0/name/publish":
+			published = r.URL.Query().Get("key") + " " + r.URL.Query().Get("arg")
+			fmt.Fprint(w, `{"Name":"k51test","Value":"/ipfs/bafytest"}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	cid, err := publishIPFS(server.URL, "feeds", []byte("<rss/>"))
+	if err != nil {
+		t.Fatalf("publishIPFS() unexpected error = %v", err)
+	}
+	if cid != "bafytest" {
+		t.Errorf("publishIPFS() = %q, want %q", cid, "bafytest")
+	}
+	if added != "<rss/>" {
+		t.Errorf("added content = %q, want %q", added, "<rss/>")
+	}
+	if published != "feeds /ipfs/bafytest" {
+		t.Errorf("name/publish = %q, want %q", published, "feeds /ipfs/bafytest")
+	}
+
+	published = ""
+	if _, err := publishIPFS(server.URL, "", []byte("<rss/>")); err != nil {
+		t.Fatalf("publishIPFS() without key unexpected error = %v", err)
+	}
+	if published != "" {
+		t.Errorf("publishIPFS() without key published IPNS name %q", published)
+	}
+}
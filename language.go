@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/xml"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/gorilla/feeds"
+)
+
+// stopwords are frequent words of the languages that can be told from an
+// item's text, chosen to overlap little between them.
+var stopwords = map[string][]string{
+	"en": {"the", "and", "of", "to", "is", "that", "with", "for", "this", "are", "was", "you", "have", "it", "on"},
+	"pt": {"não", "uma", "com", "mais", "como", "mas", "foi", "também", "você", "são", "isso", "pelo", "pela", "ao", "os", "dos", "das", "em", "um"},
+	"es": {"el", "los", "las", "del", "por", "pero", "más", "está", "también", "muy", "sus", "fue", "es", "y", "lo", "en"},
+	"fr": {"le", "les", "des", "est", "une", "pour", "dans", "qui", "pas", "sur", "avec", "sont", "mais", "du", "au", "ce"},
+	"de": {"der", "die", "und", "das", "ist", "nicht", "mit", "sich", "auf", "ein", "eine", "für", "auch", "den", "von", "zu"},
+	"it": {"il", "della", "che", "non", "per", "sono", "gli", "anche", "più", "nel", "alla", "è", "di", "questo"},
+	"nl": {"het", "een", "van", "niet", "dat", "zijn", "voor", "ook", "maar", "wordt", "naar", "bij", "deze", "en", "met"},
+}
+
+// minLanguageHits is how many stopwords text needs before detectLanguage
+// trusts it over the feed's language.
+const minLanguageHits = 3
+
+// detectLanguage guesses the language of text from the stopwords it uses,
+// returning "" when too few of them tell any language apart.
+func detectLanguage(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool { return !unicode.IsLetter(r) })
+	hits := make(map[string]int)
+	for _, word := range words {
+		for language, list := range stopwords {
+			if slices.Contains(list, word) {
+				hits[language]++
+			}
+		}
+	}
+
+	best, bestHits, secondHits := "", 0, 0
+	for language, n := range hits {
+		switch {
+		case n > bestHits:
+			best, bestHits, secondHits = language, n, bestHits
+		case n > secondHits:
+			secondHits = n
+		}
+	}
+	if bestHits < minLanguageHits || bestHits == secondHits {
+		return ""
+	}
+	return best
+}
+
+// documentLanguage returns the xml:lang of the root element of a fetched
+// feed, which is where Atom feeds give their language.
+func documentLanguage(body []byte) string {
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	decoder.Strict = false
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return ""
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			for _, attr := range start.Attr {
+				if attr.Name.Local == "lang" {
+					return attr.Value
+				}
+			}
+			return ""
+		}
+	}
+}
+
+// primaryLanguage reduces a language tag such as "en-US" to its primary
+// language, "en".
+func primaryLanguage(tag string) string {
+	tag, _, _ = strings.Cut(strings.ReplaceAll(tag, "_", "-"), "-")
+	return strings.ToLower(strings.TrimSpace(tag))
+}
+
+// parseLanguages parses a comma-separated list of languages for -languages.
+func parseLanguages(value string) ([]string, error) {
+	var languages []string
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		language := primaryLanguage(field)
+		if len(language) < 2 || len(language) > 3 || strings.ContainsFunc(language, func(r rune) bool { return r < 'a' || r > 'z' }) {
+			return nil, fmt.Errorf("%q is not a language code such as en or pt", field)
+		}
+		languages = append(languages, language)
+	}
+	return languages, nil
+}
+
+// itemLanguage is the language of item: the one its text is detected in,
+// or else the one its source declares, or "" when neither is known.
+func itemLanguage(item *feeds.Item, sourceLanguage string) string {
+	text := item.Title + " " + htmlToText(cmp.Or(item.Description, item.Content))
+	if language := detectLanguage(text); language != "" {
+		return language
+	}
+	return primaryLanguage(sourceLanguage)
+}
+
+// filterLanguages keeps the items of sources in one of languages. Items
+// whose language is unknown are kept, so that untagged sources writing
+// briefly do not go silent.
+func filterLanguages(items []*feeds.Item, sources []*SourceFeed, languages []string) []*feeds.Item {
+	sourceLanguages := make(map[string]string, len(sources))
+	for _, source := range sources {
+		sourceLanguages[source.URL] = source.Language
+	}
+	return slices.DeleteFunc(items, func(item *feeds.Item) bool {
+		language := itemLanguage(item, sourceLanguages[linkHref(item.Source)])
+		return language != "" && !slices.Contains(languages, language)
+	})
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gorilla/feeds"
+)
+
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"The release of the new version is out, and it is faster than ever", "en"},
+		{"A nova versão já está disponível e é mais rápida do que nunca, mas não para todos os utilizadores", "pt"},
+		{"Die neue Version ist da und sie ist schneller als je zuvor, auch für alte Rechner", "de"},
+		{"La nouvelle version est sortie et elle est plus rapide que jamais pour les utilisateurs", "fr"},
+		{"Release notes", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := detectLanguage(tt.text); got != tt.want {
+			t.Errorf("detectLanguage(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestParseLanguages(t *testing.T) {
+	got, err := parseLanguages("en, pt-BR,,DE")
+	if err != nil {
+		t.Fatalf("parseLanguages() unexpected error = %v", err)
+	}
+	if want := []string{"en", "pt", "de"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("parseLanguages() = %v, want %v", got, want)
+	}
+	if _, err := parseLanguages("english"); err == nil {
+		t.Errorf("parseLanguages(%q) expected error", "english")
+	}
+}
+
+func TestDocumentLanguage(t *testing.T) {
+	body := []byte(`<?xml version="1.0"?><feed xmlns="http://www.w3.org/2005/Atom" xml:lang="pt-PT"><title>Blog</title></feed>`)
+	if got := documentLanguage(body); got != "pt-PT" {
+		t.Errorf("documentLanguage() = %q, want pt-PT", got)
+	}
+	if got := documentLanguage([]byte(`<rss version="2.0"><channel/></rss>`)); got != "" {
+		t.Errorf("documentLanguage() without xml:lang = %q, want none", got)
+	}
+}
+
+func TestBuildFeedLanguages(t *testing.T) {
+	source := func(url, language string, titles ...string) *SourceFeed {
+		feed := &SourceFeed{URL: url, Language: language}
+		for _, title := range titles {
+			feed.Items = append(feed.Items, &feeds.Item{Title: title, Source: &feeds.Link{Href: url}})
+		}
+		return feed
+	}
+	sources := []*SourceFeed{
+		source("https://en.example/feed", "en-us",
+			"Notes",
+			"Uma nota sobre o que não foi dito e como isso mudou com o tempo"),
+		source("https://de.example/feed", "de", "Kurz notiert"),
+		source("https://untagged.example/feed", "",
+			"Untitled",
+			"What the team is working on for this release and why it is late"),
+	}
+
+	feed := buildFeed(&Config{Count: 10, Languages: []string{"en"}}, sources)
+	var titles []string
+	for _, item := range feed.Items {
+		titles = append(titles, item.Title)
+	}
+	want := []string{"Notes", "Untitled", "What the team is working on for this release and why it is late"}
+	if !reflect.DeepEqual(titles, want) {
+		t.Errorf("buildFeed() with languages en = %v, want %v", titles, want)
+	}
+}
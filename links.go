@@ -0,0 +1,240 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/gorilla/feeds"
+)
+
+const (
+	// resolvedLinksFile remembers, in -cache-dir, where item links lead.
+	resolvedLinksFile = "resolved-links.json"
+	// resolveWorkers is how many links are resolved at once.
+	resolveWorkers = 8
+	// linkCacheSize is how many links linkResults remembers at most.
+	linkCacheSize = 10000
+	// linkCacheTTL is how long -check-links trusts the status a link
+	// answered with; where a link leads is trusted for good.
+	linkCacheTTL = 6 * time.Hour
+)
+
+// linkResult is what requesting a link found: where its redirects end, and
+// the status answered there. Links read from -cache-dir only have a target.
+type linkResult struct {
+	Target  string
+	Status  int
+	Checked time.Time
+}
+
+// linkCache remembers what requesting links found for the life of the
+// process, so that a daemon requests each item link once, or once per
+// linkCacheTTL, rather than on every refresh.
+type linkCache struct {
+	mu      sync.Mutex
+	results map[string]linkResult
+	// dir is the -cache-dir the results were last loaded from.
+	dir string
+}
+
+var linkResults = &linkCache{results: make(map[string]linkResult)}
+
+func (c *linkCache) get(link string) (linkResult, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	result, ok := c.results[link]
+	return result, ok
+}
+
+// put remembers result for link. c.mu must be held.
+func (c *linkCache) put(link string, result linkResult) {
+	if _, ok := c.results[link]; !ok && len(c.results) >= linkCacheSize {
+		c.evict()
+	}
+	c.results[link] = result
+}
+
+// evict drops the link checked longest ago, those read from -cache-dir
+// first. c.mu must be held.
+func (c *linkCache) evict() {
+	var oldest string
+	for link, result := range c.results {
+		if oldest == "" || result.Checked.Before(c.results[oldest].Checked) {
+			oldest = link
+		}
+	}
+	delete(c.results, oldest)
+}
+
+// request requests link and remembers what it found, for link and for
+// where it leads.
+func (c *linkCache) request(link string) (linkResult, error) {
+	resp, err := headLink(link)
+	if err != nil {
+		return linkResult{}, err
+	}
+	result := linkResult{Target: resp.Request.URL.String(), Status: resp.StatusCode, Checked: time.Now()}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.put(link, result)
+	c.put(result.Target, result)
+	return result, nil
+}
+
+// load adds the links resolved in cacheDir by an earlier run to those
+// remembered, unless they were loaded from there already.
+func (c *linkCache) load(cacheDir string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if cacheDir == "" || c.dir == cacheDir {
+		return
+	}
+	c.dir = cacheDir
+	for link, target := range loadResolvedLinks(cacheDir) {
+		if _, ok := c.results[link]; !ok {
+			c.put(link, linkResult{Target: target})
+		}
+	}
+}
+
+// save writes where the links remembered lead to cacheDir.
+func (c *linkCache) save(cacheDir string) error {
+	c.mu.Lock()
+	links := make(map[string]string, len(c.results))
+	for link, result := range c.results {
+		if result.resolved() {
+			links[link] = result.Target
+		}
+	}
+	c.mu.Unlock()
+	return saveResolvedLinks(cacheDir, links)
+}
+
+// resolved reports whether the link led somewhere, rather than failing.
+func (r linkResult) resolved() bool {
+	return r.Target != "" && r.Status < 400
+}
+
+// resolveLinks replaces the links of items with where they redirect to,
+// such as the article behind a feedproxy, feedburner or t.co link, so that
+// links are direct and a story links the same way from every source. Links
+// that fail to resolve are kept as they are.
+//
+// Each link is only requested once: what it led to is remembered for as
+// long as the process runs, and with cacheDir for the next run too.
+// Offline, only remembered links are resolved.
+func resolveLinks(items []*feeds.Item, cacheDir string, offline bool) {
+	linkResults.load(cacheDir)
+	queued := make(map[string]bool)
+	var pending []string
+	for _, item := range items {
+		if item.Link == nil || item.Link.Href == "" || queued[item.Link.Href] {
+			continue
+		}
+		if _, ok := linkResults.get(item.Link.Href); !ok && !offline {
+			queued[item.Link.Href] = true
+			pending = append(pending, item.Link.Href)
+		}
+	}
+
+	sem := make(chan struct{}, resolveWorkers)
+	var wg sync.WaitGroup
+	for _, href := range pending {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			result, err := linkResults.request(href)
+			if err == nil && !result.resolved() {
+				err = fmt.Errorf("unexpected status %d", result.Status)
+			}
+			if err != nil {
+				log.Printf("Warning: failed to resolve link %s: %v", href, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, item := range items {
+		if item.Link == nil {
+			continue
+		}
+		if result, ok := linkResults.get(item.Link.Href); ok && result.resolved() && result.Target != item.Link.Href {
+			// Links are shared with the sources the items were cloned from.
+			link := *item.Link
+			link.Href = result.Target
+			item.Link = &link
+		}
+	}
+
+	if cacheDir != "" && len(pending) > 0 {
+		if err := linkResults.save(cacheDir); err != nil {
+			log.Printf("Warning: failed to cache resolved links: %v", err)
+		}
+	}
+}
+
+// resolveKnownLinks replaces the links of sources' items with where they
+// were found to lead before, without requesting any, so that items are
+// known by the same keys before and after -resolve-links resolves the links
+// of those selected.
+func resolveKnownLinks(sources []*SourceFeed, cacheDir string) {
+	for _, source := range sources {
+		resolveLinks(source.Items, cacheDir, true)
+	}
+}
+
+// headLink requests link, following redirects, without its body. Servers
+// that do not answer HEAD requests are asked with GET.
+func headLink(link string) (*http.Response, error) {
+	resp, err := httpClient.Head(link)
+	if err == nil && (resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusNotImplemented) {
+		resp.Body.Close()
+		resp, err = httpClient.Get(link)
+	}
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	return resp, nil
+}
+
+func loadResolvedLinks(cacheDir string) map[string]string {
+	links := make(map[string]string)
+	if cacheDir == "" {
+		return links
+	}
+	data, err := os.ReadFile(filepath.Join(cacheDir, resolvedLinksFile))
+	if err != nil {
+		return links
+	}
+	if err := json.Unmarshal(data, &links); err != nil {
+		log.Printf("Warning: ignoring unreadable resolved links cache: %v", err)
+		return make(map[string]string)
+	}
+	return links
+}
+
+// saveResolvedLinks replaces the cache with links.
+func saveResolvedLinks(cacheDir string, links map[string]string) error {
+	data, err := json.MarshalIndent(links, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		return fmt.Errorf("error creating cache dir: %v", err)
+	}
+	if err := writeFileAtomic(filepath.Join(cacheDir, resolvedLinksFile), data, 0644); err != nil {
+		return fmt.Errorf("error writing cache file: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/feeds"
+)
+
+func TestResolveLinks(t *testing.T) {
+	var requests atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/short", func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		http.Redirect(w, r, "/article", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/nohead", func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		http.Redirect(w, r, "/article", http.StatusFound)
+	})
+	mux.HandleFunc("/article", func(w http.ResponseWriter, r *http.Request) {})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	cacheDir, err := os.MkdirTemp("", "rss_links")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(cacheDir)
+
+	shared := &feeds.Link{Href: server.URL + "/short"}
+	newItems := func() []*feeds.Item {
+		return []*feeds.Item{
+			{Title: "Short", Link: shared},
+			{Title: "No HEAD", Link: &feeds.Link{Href: server.URL + "/nohead"}},
+			{Title: "Gone", Link: &feeds.Link{Href: server.URL + "/gone"}},
+			{Title: "No link"},
+		}
+	}
+
+	items := newItems()
+	resolveLinks(items, cacheDir, false)
+	want := []string{server.URL + "/article", server.URL + "/article", server.URL + "/gone"}
+	for i, href := range want {
+		if items[i].Link.Href != href {
+			t.Errorf("resolveLinks() link of %q = %s, want %s", items[i].Title, items[i].Link.Href, href)
+		}
+	}
+	if shared.Href != server.URL+"/short" {
+		t.Errorf("resolveLinks() changed a link shared with the source to %s", shared.Href)
+	}
+
+	before := requests.Load()
+	items = newItems()
+	resolveLinks(items, cacheDir, true)
+	if items[0].Link.Href != server.URL+"/article" || items[2].Link.Href != server.URL+"/gone" {
+		t.Errorf("resolveLinks() offline links = %s, %s, want the cached resolution and the unresolved link", items[0].Link.Href, items[2].Link.Href)
+	}
+	if got := requests.Load(); got != before {
+		t.Errorf("resolveLinks() offline made %d requests, want none", got-before)
+	}
+
+	// Links are remembered without a cache directory too, failed ones
+	// included.
+	items = newItems()
+	resolveLinks(items, "", false)
+	if items[1].Link.Href != server.URL+"/article" {
+		t.Errorf("resolveLinks() remembered link = %s, want %s", items[1].Link.Href, server.URL+"/article")
+	}
+	if got := requests.Load(); got != before {
+		t.Errorf("resolveLinks() made %d requests for remembered links, want none", got-before)
+	}
+}
+
+func TestBuildFeedResolvesSelectedLinks(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.URL.Path != "/article" {
+			http.Redirect(w, r, "/article", http.StatusMovedPermanently)
+		}
+	}))
+	defer server.Close()
+
+	base := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	source := &SourceFeed{URL: server.URL + "/feed", Items: []*feeds.Item{
+		{Title: "New", Link: &feeds.Link{Href: server.URL + "/new"}, Created: base},
+		{Title: "Old", Link: &feeds.Link{Href: server.URL + "/old"}, Created: base.Add(-time.Hour)},
+	}}
+
+	feed := buildFeed(&Config{Count: 1, ResolveLinks: true}, []*SourceFeed{source})
+	if len(feed.Items) != 1 || feed.Items[0].Link.Href != server.URL+"/article" {
+		t.Fatalf("buildFeed() items = %v, want New resolved", feed.Items)
+	}
+	// HEAD follows one redirect: two requests for the selected item only.
+	if got := requests.Load(); got != 2 {
+		t.Errorf("buildFeed() made %d requests, want 2 for the selected item", got)
+	}
+
+	// Items are known by their resolved links from then on.
+	resolved := cloneSources([]*SourceFeed{source})
+	resolveKnownLinks(resolved, "")
+	if hashes := contentHashes(resolved); hashes[itemKey(feed.Items[0])] == "" {
+		t.Errorf("contentHashes() of resolved sources = %v, want the output item's key", hashes)
+	}
+}
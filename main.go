@@ -1,58 +1,736 @@
 package main
 
 import (
-	"bufio"
+	"bytes"
+	"cmp"
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
+	"syscall"
+	"text/template"
 	"time"
 
 	"github.com/SlyMarbo/rss"
 	"github.com/gorilla/feeds"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type Config struct {
-	InputFile  string
+	// Name is the profile's name, empty without -profiles.
+	Name     string
+	Profiles []*Config
+
+	InputFiles []string
 	Count      int
 	Mode       string // "single" or "all"
 	SingleURL  string
 	OutputFile string
+	Format     string
+	Interval   time.Duration
+	Schedule   *cronSchedule
+	Spread     time.Duration
+	Demo       bool
+	SelfURL    string
+	SiteURL    string
+	WebSubHub  string
+	GitRepo    string
+	GitMessage string
+	IPFSAPI    string
+	IPNSKey    string
+
+	RssOmitContent   bool
+	RssGUID          string
+	RssLastBuildDate bool
+	Indent           int
+	Stylesheet       string
+	Deterministic    bool
+	GroupBy          string
+	MergeDuplicates  bool
+	ResolveLinks     bool
+	Languages        []string
+	Select           string
+	Days             int
+	SortBy           string
+	MissingDate      string
+	Order            string
+	Seed             int64
+	Timezone         *time.Location
+	PageSize         int
+	SourceOutputDir  string
+
+	TTL             time.Duration
+	UpdatePeriod    string
+	UpdateFrequency int
+	SkipHours       []int
+	SkipDays        []string
+
+	Listen         string
+	WebSubCallback string
+	AdminToken     string
+	AggregateProxy bool
+	Favicons       bool
+	TLSDomains     []string
+	TLSCache       string
+	RateLimit      int
+	MaxConnections int
+	AuthBasic      string
+	AuthToken      string
+	ReaderLogin    string
+	ReaderState    string
+
+	DNSServer string
+	DNSCache  bool
+
+	OTLPEndpoint string
+	Pprof        string
+
+	CacheDir     string
+	CacheTTL     time.Duration
+	Offline      bool
+	StaleIfError time.Duration
+	RetryFailed  bool
+	RetryTimeout time.Duration
+	FixRedirects bool
+
+	FullText         bool
+	FullTextWorkers  int
+	FullTextCacheDir string
+	FullTextRobots   bool
+
+	OpenGraph         bool
+	OpenGraphWorkers  int
+	OpenGraphCacheDir string
+
+	CheckLinks        string
+	CheckLinksWorkers int
+
+	InlineImages         bool
+	InlineImagesMaxBytes int64
+	ImageProxy           string
+
+	MaxDescriptionWords int
+	MaxDescriptionChars int
+
+	TransformCmd        string
+	TitleTemplate       *template.Template
+	DescriptionTemplate *template.Template
+
+	// Scorer rates items for -select score, by default by -score-keywords
+	// and -score-half-life.
+	Scorer Scorer
+
+	StateFile      string
+	OnlyNew        bool
+	MarkUpdated    bool
+	ArchiveDir     string
+	Retain         time.Duration
+	RetainMax      int
+	SlackWebhook   string
+	DiscordWebhook string
+	TelegramToken  string
+	TelegramChat   string
+	MatrixServer   string
+	MatrixToken    string
+	MatrixRoom     string
+	NtfyURL        string
+	NtfyPriority   string
+	NtfyTags       string
+	WebhookURL     string
+	WebhookSecret  string
+
+	AlertWebhook    string
+	AlertEmail      string
+	SMTPAddr        string
+	SMTPFrom        string
+	AlertMinSuccess float64
+	AlertStaleAfter time.Duration
+	AlertMinFree    float64
+
+	// claims and stream are set while fetchSources runs.
+	claims *feedClaims
+	stream *itemStream
+	// done is closed when the daemon shuts down, to cut -spread short.
+	done <-chan struct{}
+	// traceCtx holds the span of the current run, set by startRun.
+	traceCtx context.Context
+}
+
+// RunStats summarizes how a single aggregation run went.
+type RunStats struct {
+	Sources int
+	Failed  int
+	Items   int
+
+	// Results has the outcome for each source fetched, by URL. Demo
+	// sources are not fetched and have none.
+	Results map[string]SourceResult
+}
+
+// SourceResult is the outcome of fetching one source.
+type SourceResult struct {
+	Items  int
+	Newest time.Time
+	Err    error
+
+	// Metrics is how the download went, when the source was downloaded
+	// rather than taken from the cache.
+	Metrics *FetchMetrics
+}
+
+func (stats *RunStats) recordFetch(source *SourceFeed) {
+	stats.record(source.URL, fetchResult(source))
+}
+
+func fetchResult(source *SourceFeed) SourceResult {
+	result := SourceResult{Items: len(source.Items), Metrics: source.Metrics}
+	for _, item := range source.Items {
+		if item.Created.After(result.Newest) {
+			result.Newest = item.Created
+		}
+	}
+	return result
+}
+
+func (stats *RunStats) record(url string, result SourceResult) {
+	if stats.Results == nil {
+		stats.Results = make(map[string]SourceResult)
+	}
+	stats.Results[url] = result
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "list" {
+		if err := runList(os.Args[2:]); err != nil {
+			log.Fatalf("Error listing feeds: %v", err)
+		}
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "health" {
+		if err := runHealth(os.Args[2:]); err != nil {
+			log.Fatalf("Error reporting feed health: %v", err)
+		}
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "healthcheck" {
+		if err := runHealthcheck(os.Args[2:]); err != nil {
+			log.Fatalf("Unhealthy: %v", err)
+		}
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "service" {
+		if err := runServiceCommand(os.Args[2:]); err != nil {
+			log.Fatalf("Error managing service: %v", err)
+		}
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "search" {
+		if err := runSearch(os.Args[2:]); err != nil {
+			log.Fatalf("Error searching archive: %v", err)
+		}
+		return
+	}
+
+	config, err := parseConfig(flag.CommandLine, os.Args[1:])
+	if err != nil {
+		log.Fatalf("Configuration error: %v", err)
+	}
+
+	if err := configureNetwork(config); err != nil {
+		log.Fatalf("Configuration error: %v", err)
+	}
+
+	// flushTraces sends the spans not sent yet, before exiting. Offline,
+	// no spans are exported.
+	flushTraces := func() {}
+	if config.OTLPEndpoint != "" && !config.Offline {
+		shutdown, err := setupTracing(config.OTLPEndpoint)
+		if err != nil {
+			log.Fatalf("Configuration error: %v", err)
+		}
+		flushTraces = func() {
+			if err := shutdown(context.Background()); err != nil {
+				log.Printf("Warning: failed to send traces: %v", err)
+			}
+		}
+	}
+
+	if config.daemon() && runningAsService() {
+		err := runAsService(config)
+		flushTraces()
+		if err != nil {
+			log.Fatalf("Error running service: %v", err)
+		}
+		return
+	}
+
+	if config.daemon() {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		err := runDaemon(ctx, config)
+		flushTraces()
+		if err != nil {
+			log.Fatalf("Error running daemon: %v", err)
+		}
+		return
+	}
+
+	failed := false
+	for _, profile := range config.profiles() {
+		stats, err := run(profile)
+		now := time.Now()
+		sendOperatorAlerts(profile, checkOperatorAlerts(profile, stats, now), now)
+		if err != nil {
+			log.Printf("%sError %v", profile.logPrefix(), err)
+			failed = true
+		}
+	}
+	flushTraces()
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// parseConfig parses the command-line options in args into a validated
+// Config. With -profiles, each profile is parsed and validated in turn as
+// args followed by its own options.
+func parseConfig(flags *flag.FlagSet, args []string) (*Config, error) {
 	var (
-		inputFile = flag.String("input", "", "Input file containing RSS feed URLs (one per line)")
-		count     = flag.Int("count", 10, "Number of items to include")
-		mode      = flag.String("mode", "all", "Mode: 'single' for one source, 'all' for all sources")
-		singleURL = flag.String("single-url", "", "Single RSS feed URL (when mode=single)")
-		outputFile = flag.String("output", "aggregated.xml", "Output file path")
+		inputFiles = stringsFlag(flags, "input", "Input file containing RSS feed URLs (one per line); may be repeated")
+		count      = flags.Int("count", 10, "Number of items to include")
+		mode       = flags.String("mode", "all", "Mode: 'single' for one source, 'all' for all sources")
+		singleURL  = flags.String("single-url", "", "Single RSS feed URL (when mode=single)")
+		outputFile = flags.String("output", "aggregated.xml", "Output file path")
+		format     = flags.String("format", "rss", "Output format: 'rss', 'json', 'gemtext', 'ndjson' or 'sqlite'")
+		profiles   = flags.String("profiles", "", "File of named profiles, each aggregating its own sources to its own output")
+		interval   = flags.Duration("interval", 0, "Run as a daemon, re-aggregating at this interval (0 = run once)")
+		schedule   = flags.String("schedule", "", "Run as a daemon, re-aggregating at the times of this cron expression, e.g. \"*/20 7-23 * * *\"")
+		spread     = flags.Duration("spread", 0, "Spread each run's source fetches over this long, at jittered times, instead of fetching all at once")
+		demo       = flags.Bool("demo", false, "Aggregate bundled sample feeds instead of fetching anything")
+		selfURL    = flags.String("self-url", "", "Public URL the output feed is served from")
+		siteURL    = flags.String("site-url", "", "Website the feed belongs to, used as the channel link (default: -self-url)")
+		webSubHub  = flags.String("websub-hub", "", "WebSub hub to advertise and ping after each update (requires -self-url)")
+		gitRepo    = flags.String("git-repo", "", "Git working tree containing -output; commit and push the feed after each update")
+		gitMessage = flags.String("git-message", "Update aggregated feed", "Commit message used with -git-repo")
+		ipfsAPI    = flags.String("ipfs-api", "", "RPC API of an IPFS node to add the feed to after each update, e.g. http://127.0.0.1:5001")
+		ipnsKey    = flags.String("ipns-key", "self", "IPNS key to point at the added feed (empty to skip IPNS)")
+
+		rssOmitContent   = flags.Bool("rss-omit-content", false, "Leave content:encoded out of RSS output, keeping only descriptions")
+		rssGUID          = flags.String("rss-guid", "source", "RSS item guids: 'source' as published, 'link' to use the item link as a permalink, or 'none'")
+		rssLastBuildDate = flags.Bool("rss-last-build-date", false, "Add the build time as the RSS channel lastBuildDate")
+		indent           = flags.Int("indent", 2, "Spaces per nesting level in XML and JSON Feed output (0 = compact, on one line)")
+		stylesheet       = flags.String("xsl", "", "URL of an XSL (or CSS) stylesheet for browsers to render RSS output with")
+		pageSize         = flags.Int("page-size", 0, "Keep items beyond -count in RFC 5005 archive feeds of this many items, linked from the output (requires -self-url)")
+		sourceOutputDir  = flags.String("source-output-dir", "", "Also write each source, cleaned up like the aggregate, to its own file in this directory")
+		selection        = flags.String("select", "newest", "How -count items are chosen: 'newest' overall, 'round-robin' taking the newest of each source in turn, 'daily' taking -count items from each of the last -days days, or the highest 'score'")
+		scoreKeywords    = flags.String("score-keywords", "", "Comma-separated keyword:weight pairs adding to the score of items mentioning them, for -select score")
+		scoreHalfLife    = flags.Duration("score-half-life", 24*time.Hour, "Age at which an item's score for -select score is halved; 0 ignores age")
+		days             = flags.Int("days", 7, "Number of calendar days, today included, that -select daily takes items from")
+		sortBy           = flags.String("sort-by", "published", "Order items newest first by the date they were 'published' or last 'updated'")
+		order            = flags.String("order", "desc", "Order items 'desc' (newest first), 'asc' (oldest first) or 'shuffle'd, before -count items are selected")
+		seed             = flags.Int64("seed", 0, "Seed for -order shuffle, to repeat the same order (default: a new order on every run)")
+		missingDate      = flags.String("missing-date", "", "Date items that have none by the 'feed' date, the 'fetch' time, or 'drop' them (default: leave them undated, sorted last)")
+		timezone         = flags.String("timezone", "", "Give every date in the output in this time zone: 'UTC', 'Local' or an IANA name such as 'Europe/Lisbon' (default: as each source gave it)")
+		languages        = flags.String("languages", "", "Comma-separated languages to keep items in, such as 'en,pt', detected from their text or the feed's language")
+		resolveLinks     = flags.Bool("resolve-links", false, "Replace item links with where they redirect to, such as the articles behind feedproxy or t.co links (cached in -cache-dir)")
+		mergeDuplicates  = flags.Bool("merge-duplicates", false, "Merge items several sources published for the same story, by link or guid, into the first published, linking to the other sources")
+		groupBy          = flags.String("group-by", "", "Group output items under a heading item per 'source', 'day' or 'week' instead of one chronological stream")
+		deterministic    = flags.Bool("deterministic", false, "Make the output depend only on item content: pin feed dates to the newest item")
+
+		ttl             = flags.Duration("ttl", 0, "How long readers may cache the feed, advertised as <ttl> (default: -interval)")
+		updatePeriod    = flags.String("update-period", "", "Advertise sy:updatePeriod: hourly, daily, weekly, monthly or yearly")
+		updateFrequency = flags.Int("update-frequency", 0, "Advertise sy:updateFrequency, the number of updates per -update-period")
+		skipHours       = flags.String("skip-hours", "", "Comma-separated GMT hours (0-23) readers need not poll, advertised as <skipHours>")
+		skipDays        = flags.String("skip-days", "", "Comma-separated weekdays readers need not poll, e.g. Saturday,Sunday")
+
+		listen         = flags.String("listen", "", "In daemon mode, serve the aggregate at /feed.xml, and as a web page at /, on this address, e.g. :8080")
+		webSubCallback = flags.String("websub-callback", "", "Public base URL of -listen; subscribes to sources' WebSub hubs and refreshes them on push instead of polling")
+		tlsDomain      = flags.String("tls-domain", "", "Serve -listen over HTTPS with Let's Encrypt certificates for these comma-separated domains, e.g. feed.example.com")
+		tlsCache       = flags.String("tls-cache", "", "Directory keeping -tls-domain certificates across restarts (default: rss-agg/autocert in the user cache directory)")
+		rateLimit      = flags.Int("rate-limit", 0, "Answer 429 to clients making more than this many requests a minute to -listen, per IP address")
+		maxConnections = flags.Int("max-connections", 0, "Accept at most this many simultaneous connections on -listen")
+		authBasic      = flags.String("auth-basic", "", "Require this user:password, with HTTP basic authentication, for the feed, web page, items API and aggregate proxy on -listen")
+		authToken      = flags.String("auth-token", "", "Require this bearer token for the feed, web page, items API and aggregate proxy on -listen")
+		favicons       = flags.Bool("favicons", false, "Show each source's favicon next to its items on the web page served by -listen (cached in -cache-dir)")
+		aggregateProxy = flags.Bool("aggregate-proxy", false, "Serve /aggregate?url=...&url=...&count=20 on -listen, merging any public feeds given in the request")
+		adminToken     = flags.String("admin-token", "", "Serve an API for managing sources under /admin/ on -listen, to requests bearing this token")
+		readerLogin    = flags.String("reader-login", "", "Serve the Fever and Google Reader APIs for feed reader apps on -listen, for this email:password login")
+		readerState    = flags.String("reader-state", "", "JSON file keeping reader API item ids and read and saved items across restarts")
+
+		dnsServer = flags.String("dns-server", "", "Resolve host names through this DNS server: host[:port], tls://host[:port] or a DNS-over-HTTPS URL")
+		dnsCache  = flags.Bool("dns-cache", false, "Cache DNS lookups for the duration of a run")
+
+		otlpEndpoint = flags.String("otlp-endpoint", "", "Send OpenTelemetry traces of each run to this OTLP/HTTP collector, e.g. http://localhost:4318")
+		pprofAddr    = flags.String("pprof", "", "In daemon mode, serve net/http/pprof profiles under /debug/pprof/ on this address, e.g. localhost:6060")
+
+		cacheDir     = flags.String("cache-dir", "", "Directory for caching fetched feeds between runs, e.g. ~/.cache/rss-agg")
+		cacheTTL     = flags.Duration("cache-ttl", 30*time.Minute, "How long a feed in -cache-dir is reused instead of fetched again")
+		offline      = flags.Bool("offline", false, "Aggregate only from -cache-dir, however old, without any network access")
+		staleIfError = flags.Duration("stale-if-error", 0, "Use a source's copy in -cache-dir up to this old when fetching it fails, instead of dropping it (0 = never)")
+		retryFailed  = flags.Bool("retry-failed", false, "Retry sources that failed once more after all others were fetched")
+		retryTimeout = flags.Duration("retry-timeout", 0, "Timeout for the -retry-failed attempt (default: the source's usual timeout)")
+		fixRedirects = flags.Bool("fix-redirects", false, "Rewrite sources that permanently redirect to their new URL in the local input files")
+
+		fullText         = flags.Bool("fulltext", false, "Fetch each item's link and use the extracted article body as content")
+		fullTextWorkers  = flags.Int("fulltext-workers", 4, "Number of concurrent full-text fetches")
+		fullTextCacheDir = flags.String("fulltext-cache", "", "Directory for caching extracted article bodies")
+		fullTextRobots   = flags.Bool("fulltext-robots", false, "Only fetch article pages for -fulltext that the site's robots.txt allows")
+
+		openGraph         = flags.Bool("opengraph", false, "Fill in items without a description or content from the og:description and og:image of their link")
+		openGraphWorkers  = flags.Int("opengraph-workers", 4, "Number of concurrent -opengraph fetches")
+		openGraphCacheDir = flags.String("opengraph-cache", "", "Directory for caching the OpenGraph tags of item links")
+
+		checkLinks        = flags.String("check-links", "", "Request each item's link and 'drop' the items whose link answers 404 or 410, or 'flag' them by starting their titles with [dead link]")
+		checkLinksWorkers = flags.Int("check-links-workers", 8, "Number of concurrent -check-links requests")
+
+		inlineImages         = flags.Bool("inline-images", false, "Embed images referenced in item content as data URIs")
+		inlineImagesMaxBytes = flags.Int64("inline-images-max-bytes", 512*1024, "Largest image to inline; bigger images keep their remote URL")
+		imageProxy           = flags.String("image-proxy", "", "Rewrite <img src> through a proxy URL template, e.g. https://camo.example/{url}")
+
+		maxDescriptionWords = flags.Int("max-description-words", 0, "Truncate item descriptions to this many words and add a read-more link (0 = no limit)")
+		maxDescriptionChars = flags.Int("max-description-chars", 0, "Truncate item descriptions to this many characters and add a read-more link (0 = no limit)")
+
+		transformCmd        = flags.String("transform-cmd", "", "Shell command that receives the items as JSON lines on stdin and prints each transformed item in order (or null to drop it)")
+		titleTemplate       = flags.String("title-template", "", "Go template for item titles, e.g. '{{.Source.Title}}: {{.Title}}'")
+		descriptionTemplate = flags.String("description-template", "", "Go template for item descriptions")
+
+		stateFile      = flags.String("state", "", "JSON file recording items seen in previous runs")
+		archiveDir     = flags.String("archive", "", "Directory where every fetched item is kept, independent of the output feed")
+		onlyNew        = flags.Bool("only-new", false, "Only output items not seen in previous runs (requires -state)")
+		markUpdates    = flags.Bool("mark-updated", false, "Start the titles of items edited since they were first seen with [updated] (requires -state)")
+		retain         = flags.String("retain", "", "Forget seen items after this long, e.g. 90d or 720h (default: keep forever)")
+		retainMax      = flags.Int("retain-max", 0, "Keep at most this many seen items, forgetting the oldest first (0 = unlimited)")
+		slackWebhook   = flags.String("slack-webhook", "", "Slack incoming webhook URL to post new items to (requires -state)")
+		discordWebhook = flags.String("discord-webhook", "", "Discord webhook URL to post new items to (requires -state)")
+		telegramToken  = flags.String("telegram-token", "", "Telegram bot token for posting new items (requires -state and -telegram-chat)")
+		telegramChat   = flags.String("telegram-chat", "", "Telegram chat or channel id (e.g. @mychannel) to post new items to")
+		matrixServer   = flags.String("matrix-homeserver", "", "Matrix homeserver URL for posting new items (requires -state, -matrix-token and -matrix-room)")
+		matrixToken    = flags.String("matrix-token", "", "Matrix access token")
+		matrixRoom     = flags.String("matrix-room", "", "Matrix room id, e.g. !abc123:example.org")
+		ntfyURL        = flags.String("ntfy-url", "", "ntfy topic URL to push new items to, e.g. https://ntfy.sh/mytopic (requires -state)")
+		ntfyPriority   = flags.String("ntfy-priority", "", "ntfy priority: 1-5 or min, low, default, high, max")
+		ntfyTags       = flags.String("ntfy-tags", "", "Comma-separated ntfy tags/emoji shortcodes")
+		webhookURL     = flags.String("webhook-url", "", "URL to POST each batch of new items to as JSON (requires -state)")
+		webhookSecret  = flags.String("webhook-secret", "", "Secret for signing webhook bodies with HMAC-SHA256 (X-Signature-256 header)")
+
+		alertWebhook    = flags.String("alert-webhook", "", "URL to POST operator alerts to as JSON")
+		alertEmail      = flags.String("alert-email", "", "Address to email operator alerts to")
+		smtpAddr        = flags.String("smtp-addr", "", "SMTP server host:port for -alert-email (credentials from SMTP_USERNAME/SMTP_PASSWORD)")
+		smtpFrom        = flags.String("smtp-from", "", "Sender address for -alert-email")
+		alertMinSuccess = flags.Float64("alert-min-success", 0, "Alert when the fraction of sources fetched successfully falls below this (0-1)")
+		alertStaleAfter = flags.Duration("alert-stale-after", 0, "Alert when the output file has not been updated for this long")
+		alertMinFree    = flags.Float64("alert-min-free", 0, "Alert when the fraction of free space on the disks holding the output, state, archive or cache falls below this (0-1)")
 	)
-	flag.Parse()
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
 
 	config := &Config{
-		InputFile:  *inputFile,
+		InputFiles: *inputFiles,
 		Count:      *count,
 		Mode:       *mode,
 		SingleURL:  *singleURL,
 		OutputFile: *outputFile,
+		Format:     *format,
+
+		Interval:   *interval,
+		Spread:     *spread,
+		Demo:       *demo,
+		SelfURL:    *selfURL,
+		SiteURL:    *siteURL,
+		WebSubHub:  *webSubHub,
+		GitRepo:    *gitRepo,
+		GitMessage: *gitMessage,
+		IPFSAPI:    *ipfsAPI,
+		IPNSKey:    *ipnsKey,
+
+		RssOmitContent:   *rssOmitContent,
+		RssGUID:          *rssGUID,
+		RssLastBuildDate: *rssLastBuildDate,
+		Indent:           *indent,
+		Stylesheet:       *stylesheet,
+		Deterministic:    *deterministic,
+		GroupBy:          *groupBy,
+		MergeDuplicates:  *mergeDuplicates,
+		ResolveLinks:     *resolveLinks,
+		Select:           *selection,
+		Days:             *days,
+		SortBy:           *sortBy,
+		MissingDate:      *missingDate,
+		Order:            *order,
+		Seed:             *seed,
+		PageSize:         *pageSize,
+		SourceOutputDir:  *sourceOutputDir,
+
+		TTL:             *ttl,
+		UpdatePeriod:    *updatePeriod,
+		UpdateFrequency: *updateFrequency,
+
+		Listen:         *listen,
+		WebSubCallback: *webSubCallback,
+		AdminToken:     *adminToken,
+		AggregateProxy: *aggregateProxy,
+		TLSCache:       *tlsCache,
+		RateLimit:      *rateLimit,
+		MaxConnections: *maxConnections,
+		Favicons:       *favicons,
+		AuthBasic:      *authBasic,
+		AuthToken:      *authToken,
+		ReaderLogin:    *readerLogin,
+		ReaderState:    *readerState,
+
+		DNSServer: *dnsServer,
+		DNSCache:  *dnsCache,
+
+		OTLPEndpoint: *otlpEndpoint,
+		Pprof:        *pprofAddr,
+
+		CacheDir:     *cacheDir,
+		CacheTTL:     *cacheTTL,
+		Offline:      *offline,
+		StaleIfError: *staleIfError,
+		RetryFailed:  *retryFailed,
+		RetryTimeout: *retryTimeout,
+		FixRedirects: *fixRedirects,
+
+		FullText:         *fullText,
+		FullTextWorkers:  *fullTextWorkers,
+		FullTextCacheDir: *fullTextCacheDir,
+		FullTextRobots:   *fullTextRobots,
+
+		OpenGraph:         *openGraph,
+		OpenGraphWorkers:  *openGraphWorkers,
+		OpenGraphCacheDir: *openGraphCacheDir,
+
+		CheckLinks:        *checkLinks,
+		CheckLinksWorkers: *checkLinksWorkers,
+
+		InlineImages:         *inlineImages,
+		InlineImagesMaxBytes: *inlineImagesMaxBytes,
+		ImageProxy:           *imageProxy,
+
+		MaxDescriptionWords: *maxDescriptionWords,
+		MaxDescriptionChars: *maxDescriptionChars,
+
+		TransformCmd: *transformCmd,
+
+		StateFile:      *stateFile,
+		OnlyNew:        *onlyNew,
+		MarkUpdated:    *markUpdates,
+		ArchiveDir:     *archiveDir,
+		RetainMax:      *retainMax,
+		SlackWebhook:   *slackWebhook,
+		DiscordWebhook: *discordWebhook,
+		TelegramToken:  *telegramToken,
+		TelegramChat:   *telegramChat,
+		MatrixServer:   *matrixServer,
+		MatrixToken:    *matrixToken,
+		MatrixRoom:     *matrixRoom,
+		NtfyURL:        *ntfyURL,
+		NtfyPriority:   *ntfyPriority,
+		NtfyTags:       *ntfyTags,
+		WebhookURL:     *webhookURL,
+		WebhookSecret:  *webhookSecret,
+
+		AlertWebhook:    *alertWebhook,
+		AlertEmail:      *alertEmail,
+		SMTPAddr:        *smtpAddr,
+		SMTPFrom:        *smtpFrom,
+		AlertMinSuccess: *alertMinSuccess,
+		AlertStaleAfter: *alertStaleAfter,
+		AlertMinFree:    *alertMinFree,
+	}
+
+	var err error
+	if config.Retain, err = parseDays(*retain); err != nil {
+		return nil, fmt.Errorf("retain: %v", err)
+	}
+	if *schedule != "" {
+		if config.Schedule, err = parseSchedule(*schedule); err != nil {
+			return nil, fmt.Errorf("schedule: %v", err)
+		}
+	}
+	if *scoreKeywords != "" || config.Select == "score" {
+		keywords, err := parseScoreKeywords(*scoreKeywords)
+		if err != nil {
+			return nil, fmt.Errorf("score-keywords: %v", err)
+		}
+		config.Scorer = keywordScorer{Keywords: keywords, HalfLife: *scoreHalfLife}
+	}
+	if config.Languages, err = parseLanguages(*languages); err != nil {
+		return nil, fmt.Errorf("languages: %v", err)
+	}
+	if config.SkipHours, err = parseSkipHours(*skipHours); err != nil {
+		return nil, fmt.Errorf("skip-hours: %v", err)
+	}
+	if config.SkipDays, err = parseSkipDays(*skipDays); err != nil {
+		return nil, fmt.Errorf("skip-days: %v", err)
+	}
+	if *timezone != "" {
+		if config.Timezone, err = time.LoadLocation(*timezone); err != nil {
+			return nil, fmt.Errorf("timezone: %v", err)
+		}
+	}
+	if config.TLSDomains, err = parseTLSDomains(*tlsDomain); err != nil {
+		return nil, fmt.Errorf("tls-domain: %v", err)
+	}
+	if config.TitleTemplate, err = parseItemTemplate("title-template", *titleTemplate); err != nil {
+		return nil, err
+	}
+	if config.DescriptionTemplate, err = parseItemTemplate("description-template", *descriptionTemplate); err != nil {
+		return nil, err
+	}
+
+	if *profiles != "" {
+		if config.Profiles, err = loadProfiles(*profiles, args); err != nil {
+			return nil, err
+		}
+		return config, nil
 	}
 
 	if err := validateConfig(config); err != nil {
-		log.Fatalf("Configuration error: %v", err)
+		return nil, err
+	}
+	return config, nil
+}
+
+// daemon reports whether the aggregator keeps running, re-aggregating at an
+// interval or on a schedule.
+func (config *Config) daemon() bool {
+	return config.Interval > 0 || config.Schedule != nil
+}
+
+func (config *Config) format() feedFormat {
+	return formats[cmp.Or(config.Format, "rss")]
+}
+
+func (config *Config) rssOptions() RssOptions {
+	return RssOptions{
+		TTL:           cmp.Or(config.TTL, config.Interval),
+		SelfURL:       config.SelfURL,
+		HubURL:        config.WebSubHub,
+		OmitContent:   config.RssOmitContent,
+		GUID:          config.RssGUID,
+		LastBuildDate: config.RssLastBuildDate,
+		Compact:       config.Indent == 0,
+		Indent:        strings.Repeat(" ", config.Indent),
+		Stylesheet:    config.Stylesheet,
+
+		UpdatePeriod:    config.UpdatePeriod,
+		UpdateFrequency: config.UpdateFrequency,
+		SkipHours:       config.SkipHours,
+		SkipDays:        config.SkipDays,
+	}
+}
+
+func run(config *Config) (*RunStats, error) {
+	config, span := startRun(config, "run")
+	aggregatedFeed, sources, stats, err := aggregateFeeds(config)
+	if err == nil {
+		err = publishFeed(config, aggregatedFeed, sources)
+	} else {
+		err = fmt.Errorf("aggregating feeds: %v", err)
 	}
 
-	aggregatedFeed, err := aggregateFeeds(config)
+	recordSourceHealth(config, stats, time.Now())
+	endSpan(span, err)
+	return stats, err
+}
+
+// publishFeed writes the aggregated feed and performs everything that
+// follows a new aggregate: state updates, notifications and hub pings.
+func publishFeed(config *Config, aggregatedFeed *feeds.Feed, sources []*SourceFeed) error {
+	aggregatedFeed, outputs, opts, err := renderFeed(config, aggregatedFeed, sources)
 	if err != nil {
-		log.Fatalf("Error aggregating feeds: %v", err)
+		return fmt.Errorf("outputting feed: %v", err)
 	}
 
-	if err := outputFeed(aggregatedFeed, config.OutputFile); err != nil {
-		log.Fatalf("Error outputting feed: %v", err)
+	_, span := config.startSpan("publish")
+	err = publishOutputs(config, aggregatedFeed, sources, outputs, opts)
+	endSpan(span, err)
+	return err
+}
+
+// renderFeed writes the aggregated feed, and its archive pages with
+// -page-size, returning the feed's first page, the files written and the
+// options they were written with.
+func renderFeed(config *Config, aggregatedFeed *feeds.Feed, sources []*SourceFeed) (*feeds.Feed, []string, RssOptions, error) {
+	_, span := config.startSpan("render", attribute.Int("items", len(aggregatedFeed.Items)))
+	opts := withCategories(config.rssOptions(), sources)
+	aggregatedFeed, pages := pageFeed(config, aggregatedFeed)
+	outputs := []string{config.OutputFile}
+	if len(pages) > 0 {
+		// Archive pages go first so the output never links to a missing one.
+		written, err := writeArchivePages(config, aggregatedFeed, pages, opts)
+		if err != nil {
+			endSpan(span, err)
+			return nil, nil, opts, err
+		}
+		outputs = append(outputs, written...)
 	}
+
+	opts = subscriptionOptions(config, opts, pages)
+	err := outputFeed(aggregatedFeed, config.OutputFile, config.format(), opts)
+	endSpan(span, err)
+	return aggregatedFeed, outputs, opts, err
+}
+
+// publishOutputs performs everything that follows writing a new aggregate:
+// state updates, publishing and hub pings.
+func publishOutputs(config *Config, aggregatedFeed *feeds.Feed, sources []*SourceFeed, outputs []string, opts RssOptions) error {
+	if config.StateFile != "" {
+		items := slices.DeleteFunc(slices.Clone(aggregatedFeed.Items), isHeading)
+		// Items are known by their resolved links, as in buildFeed.
+		fetched := sources
+		if config.ResolveLinks {
+			fetched = cloneSources(sources)
+			resolveKnownLinks(fetched, config.CacheDir)
+		}
+		if err := updateState(config, items, contentHashes(fetched), time.Now()); err != nil {
+			return fmt.Errorf("updating state: %v", err)
+		}
+	}
+
+	if config.GitRepo != "" {
+		if err := publishGit(config.GitRepo, outputs, config.GitMessage); err != nil {
+			return fmt.Errorf("publishing to git: %v", err)
+		}
+	}
+
+	if config.IPFSAPI != "" {
+		var buf bytes.Buffer
+		if err := config.format().Write(&buf, aggregatedFeed, opts); err != nil {
+			return fmt.Errorf("rendering feed for IPFS: %v", err)
+		}
+		cid, err := publishIPFS(config.IPFSAPI, config.IPNSKey, buf.Bytes())
+		if err != nil {
+			return fmt.Errorf("publishing to IPFS: %v", err)
+		}
+		log.Printf("Published feed to IPFS as /ipfs/%s", cid)
+	}
+
+	if config.WebSubHub != "" {
+		if err := pingWebSubHub(config.WebSubHub, config.SelfURL); err != nil {
+			log.Printf("Warning: failed to ping WebSub hub %s: %v", config.WebSubHub, err)
+		}
+	}
+
+	return nil
 }
 
 func validateConfig(config *Config) error {
@@ -60,12 +738,99 @@ func validateConfig(config *Config) error {
 		return fmt.Errorf("mode must be 'single' or 'all'")
 	}
 
-	if config.Mode == "single" {
+	if config.TTL < 0 {
+		return fmt.Errorf("ttl must not be negative")
+	}
+
+	switch config.UpdatePeriod {
+	case "", "hourly", "daily", "weekly", "monthly", "yearly":
+	default:
+		return fmt.Errorf("update-period must be hourly, daily, weekly, monthly or yearly")
+	}
+
+	if config.UpdateFrequency < 0 {
+		return fmt.Errorf("update-frequency must not be negative")
+	}
+
+	if config.Indent < 0 {
+		return fmt.Errorf("indent must not be negative")
+	}
+
+	switch config.Select {
+	case "", "newest", "round-robin", "daily", "score":
+	default:
+		return fmt.Errorf("select must be 'newest', 'round-robin', 'daily' or 'score'")
+	}
+
+	switch config.SortBy {
+	case "", "published", "updated":
+	default:
+		return fmt.Errorf("sort-by must be 'published' or 'updated'")
+	}
+
+	if config.Select == "score" && config.Scorer == nil {
+		return fmt.Errorf("select score requires a scorer")
+	}
+	if config.Select != "score" && config.Scorer != nil {
+		return fmt.Errorf("score-keywords requires select score")
+	}
+	if config.Select == "daily" && config.Days <= 0 {
+		return fmt.Errorf("days must be positive with select daily")
+	}
+
+	switch config.Order {
+	case "", "desc", "asc", "shuffle":
+	default:
+		return fmt.Errorf("order must be 'desc', 'asc' or 'shuffle'")
+	}
+	if config.Seed != 0 && config.Order != "shuffle" {
+		return fmt.Errorf("seed requires order shuffle")
+	}
+
+	switch config.MissingDate {
+	case "", "feed", "fetch", "drop":
+	default:
+		return fmt.Errorf("missing-date must be 'feed', 'fetch' or 'drop'")
+	}
+
+	switch config.GroupBy {
+	case "", "source", "day", "week":
+	default:
+		return fmt.Errorf("group-by must be 'source', 'day' or 'week'")
+	}
+
+	if config.PageSize < 0 {
+		return fmt.Errorf("page-size must not be negative")
+	}
+	if config.PageSize > 0 {
+		if config.SelfURL == "" || cmp.Or(config.Format, "rss") != "rss" {
+			return fmt.Errorf("page-size requires self-url and rss format")
+		}
+		if config.OnlyNew || config.GroupBy != "" || cmp.Or(config.Select, "newest") != "newest" || cmp.Or(config.Order, "desc") != "desc" {
+			return fmt.Errorf("page-size cannot be combined with only-new, group-by, round-robin, daily or score selection, or another order than desc")
+		}
+	}
+
+	switch config.RssGUID {
+	case "", "source", "link", "none":
+	default:
+		return fmt.Errorf("rss-guid must be 'source', 'link' or 'none'")
+	}
+
+	if _, ok := formats[cmp.Or(config.Format, "rss")]; !ok {
+		return fmt.Errorf("format must be 'rss', 'json', 'gemtext', 'ndjson' or 'sqlite'")
+	}
+
+	if config.Demo {
+		if config.FullText || config.InlineImages || config.ResolveLinks || config.OpenGraph || config.CheckLinks != "" {
+			return fmt.Errorf("demo runs without network access and cannot be combined with fulltext, inline-images, resolve-links, opengraph or check-links")
+		}
+	} else if config.Mode == "single" {
 		if config.SingleURL == "" {
 			return fmt.Errorf("single-url must be provided when mode is 'single'")
 		}
 	} else {
-		if config.InputFile == "" {
+		if len(config.InputFiles) == 0 {
 			return fmt.Errorf("input file must be provided when mode is 'all'")
 		}
 	}
@@ -74,92 +839,822 @@ func validateConfig(config *Config) error {
 		return fmt.Errorf("count must be greater than 0")
 	}
 
+	if config.Interval < 0 {
+		return fmt.Errorf("interval must not be negative")
+	}
+
+	if config.Spread < 0 {
+		return fmt.Errorf("spread must not be negative")
+	}
+	if config.Interval > 0 && config.Spread >= config.Interval {
+		return fmt.Errorf("spread must be shorter than interval")
+	}
+
+	if config.Interval > 0 && config.Schedule != nil {
+		return fmt.Errorf("interval and schedule cannot be combined")
+	}
+
+	if config.Listen != "" && !config.daemon() {
+		return fmt.Errorf("listen requires daemon mode (interval or schedule)")
+	}
+	if config.Pprof != "" && !config.daemon() {
+		return fmt.Errorf("pprof requires daemon mode (interval or schedule)")
+	}
+	if config.Pprof != "" && !loopbackAddress(config.Pprof) {
+		return fmt.Errorf("pprof must be a loopback address such as localhost:6060")
+	}
+
+	if config.WebSubCallback != "" && (config.Listen == "" || config.Demo) {
+		return fmt.Errorf("websub-callback requires listen and cannot be used with demo")
+	}
+
+	if len(config.TLSDomains) > 0 && config.Listen == "" {
+		return fmt.Errorf("tls-domain requires listen")
+	}
+	if config.TLSCache != "" && len(config.TLSDomains) == 0 {
+		return fmt.Errorf("tls-cache requires tls-domain")
+	}
+
+	if (config.RateLimit != 0 || config.MaxConnections != 0) && config.Listen == "" {
+		return fmt.Errorf("rate-limit and max-connections require listen")
+	}
+	if config.RateLimit < 0 || config.MaxConnections < 0 {
+		return fmt.Errorf("rate-limit and max-connections must not be negative")
+	}
+
+	if config.AuthBasic != "" && (config.Listen == "" || !strings.Contains(config.AuthBasic, ":")) {
+		return fmt.Errorf("auth-basic must be user:password and requires listen")
+	}
+	if config.AuthToken != "" && config.Listen == "" {
+		return fmt.Errorf("auth-token requires listen")
+	}
+
+	if config.AggregateProxy && config.Listen == "" {
+		return fmt.Errorf("aggregate-proxy requires listen")
+	}
+
+	if config.Favicons && config.Listen == "" {
+		return fmt.Errorf("favicons requires listen")
+	}
+
+	if config.AdminToken != "" && (config.Listen == "" || len(config.InputFiles) == 0) {
+		return fmt.Errorf("admin-token requires listen and input")
+	}
+
+	if config.ReaderLogin != "" && (config.Listen == "" || !strings.Contains(config.ReaderLogin, ":")) {
+		return fmt.Errorf("reader-login must be email:password and requires listen")
+	}
+	if config.ReaderState != "" && config.ReaderLogin == "" {
+		return fmt.Errorf("reader-state requires reader-login")
+	}
+
+	if dest, _, ok := remoteOutput(config.OutputFile); ok {
+		if err := checkRemoteOutput(dest); err != nil {
+			return err
+		}
+		if config.AlertStaleAfter > 0 {
+			return fmt.Errorf("alert-stale-after requires a local output file")
+		}
+	}
+
+	if config.GitRepo != "" {
+		if _, _, ok := remoteOutput(config.OutputFile); ok {
+			return fmt.Errorf("git-repo requires a local output file")
+		}
+		if _, err := gitRelPath(config.GitRepo, config.OutputFile); err != nil {
+			return err
+		}
+	}
+
+	for _, option := range []struct{ name, value string }{
+		{"self-url", config.SelfURL},
+		{"site-url", config.SiteURL},
+	} {
+		if option.value == "" {
+			continue
+		}
+		if u, err := url.Parse(option.value); err != nil || !u.IsAbs() || u.Host == "" {
+			return fmt.Errorf("%s must be an absolute URL", option.name)
+		}
+	}
+
+	if config.WebSubHub != "" && config.SelfURL == "" {
+		return fmt.Errorf("self-url must be provided with websub-hub")
+	}
+
+	if _, err := newResolver(config.DNSServer); err != nil {
+		return err
+	}
+
+	if err := validateOTLPEndpoint(config.OTLPEndpoint); err != nil {
+		return err
+	}
+
+	if config.CacheTTL < 0 {
+		return fmt.Errorf("cache-ttl must not be negative")
+	}
+
+	if config.StaleIfError < 0 {
+		return fmt.Errorf("stale-if-error must not be negative")
+	}
+	if config.StaleIfError > 0 && config.CacheDir == "" {
+		return fmt.Errorf("stale-if-error requires cache-dir")
+	}
+
+	if config.RetryTimeout < 0 {
+		return fmt.Errorf("retry-timeout must not be negative")
+	}
+
+	if config.Offline {
+		if config.CacheDir == "" && !config.Demo {
+			return fmt.Errorf("offline requires cache-dir")
+		}
+		if config.InlineImages || config.CheckLinks != "" || config.WebSubHub != "" || config.WebSubCallback != "" || config.GitRepo != "" || config.IPFSAPI != "" || config.AggregateProxy {
+			return fmt.Errorf("offline cannot be combined with inline-images, check-links, websub-hub, websub-callback, git-repo, ipfs-api or aggregate-proxy")
+		}
+		if _, _, ok := remoteOutput(config.OutputFile); ok {
+			return fmt.Errorf("offline requires a local output file")
+		}
+		if slices.ContainsFunc(config.InputFiles, isRemoteList) {
+			return fmt.Errorf("offline requires local input files")
+		}
+	}
+
+	if config.FullText && config.FullTextWorkers <= 0 {
+		return fmt.Errorf("fulltext-workers must be greater than 0")
+	}
+
+	switch config.CheckLinks {
+	case "", "drop", "flag":
+	default:
+		return fmt.Errorf("check-links must be 'drop' or 'flag'")
+	}
+	if config.CheckLinks != "" && config.CheckLinksWorkers <= 0 {
+		return fmt.Errorf("check-links-workers must be greater than 0")
+	}
+
+	if config.OpenGraph && config.OpenGraphWorkers <= 0 {
+		return fmt.Errorf("opengraph-workers must be greater than 0")
+	}
+
+	if config.InlineImages && config.InlineImagesMaxBytes <= 0 {
+		return fmt.Errorf("inline-images-max-bytes must be greater than 0")
+	}
+
+	if config.ImageProxy != "" && !strings.Contains(config.ImageProxy, "{url}") {
+		return fmt.Errorf("image-proxy must contain a {url} placeholder")
+	}
+
+	if config.MaxDescriptionWords < 0 || config.MaxDescriptionChars < 0 {
+		return fmt.Errorf("max-description-words and max-description-chars must not be negative")
+	}
+
+	if (config.TelegramToken == "") != (config.TelegramChat == "") {
+		return fmt.Errorf("telegram-token and telegram-chat must be provided together")
+	}
+
+	if config.MatrixServer != "" && (config.MatrixToken == "" || config.MatrixRoom == "") {
+		return fmt.Errorf("matrix-token and matrix-room must be provided with matrix-homeserver")
+	}
+
+	if config.NtfyPriority != "" && !ntfyPriorities[config.NtfyPriority] {
+		return fmt.Errorf("ntfy-priority must be 1-5 or one of min, low, default, high, max")
+	}
+
+	if config.RetainMax < 0 {
+		return fmt.Errorf("retain-max must not be negative")
+	}
+
+	if (config.Retain > 0 || config.RetainMax > 0) && config.StateFile == "" {
+		return fmt.Errorf("state must be provided with retain or retain-max")
+	}
+
+	if config.OnlyNew && config.StateFile == "" {
+		return fmt.Errorf("state must be provided with only-new")
+	}
+
+	if config.MarkUpdated && config.StateFile == "" {
+		return fmt.Errorf("state must be provided with mark-updated")
+	}
+
+	if len(config.notifiers()) > 0 && config.StateFile == "" {
+		return fmt.Errorf("state must be provided when notifications are enabled")
+	}
+
+	if config.AlertMinSuccess < 0 || config.AlertMinSuccess > 1 {
+		return fmt.Errorf("alert-min-success must be between 0 and 1")
+	}
+
+	if config.AlertMinFree < 0 || config.AlertMinFree > 1 {
+		return fmt.Errorf("alert-min-free must be between 0 and 1")
+	}
+
+	if config.AlertEmail != "" && (config.SMTPAddr == "" || config.SMTPFrom == "") {
+		return fmt.Errorf("smtp-addr and smtp-from must be provided with alert-email")
+	}
+
 	return nil
 }
 
-func aggregateFeeds(config *Config) (*feeds.Feed, error) {
-	var allItems []*feeds.Item
+// configureNetwork sets up how httpClient reaches the network: not at all
+// with -offline, which also leaves -dns-server unused so that no
+// DNS-over-HTTPS query is sent, and otherwise through -dns-server and
+// -dns-cache.
+func configureNetwork(config *Config) error {
+	if config.Offline {
+		httpClient.Transport = offlineTransport{}
+		dohClient.Transport = offlineTransport{}
+		return nil
+	}
+	if config.DNSServer != "" || config.DNSCache {
+		dialer, err := newDNSDialer(config.DNSServer, config.DNSCache)
+		if err != nil {
+			return err
+		}
+		httpClient.Transport = userAgentTransport{dnsTransport(dialer)}
+	}
+	return nil
+}
+
+// aggregateFeeds collects the sources and builds the aggregate from them,
+// returning both.
+func aggregateFeeds(config *Config) (*feeds.Feed, []*SourceFeed, *RunStats, error) {
+	sources, stats, err := collectSources(config)
+	if err != nil {
+		return nil, nil, stats, err
+	}
+
+	_, span := config.startSpan("build", attribute.Int("sources", len(sources)))
+	aggregatedFeed := buildFeed(config, sources)
+	span.End()
+	stats.Items = len(aggregatedFeed.Items)
+
+	if config.SourceOutputDir != "" {
+		writeSourceFeeds(config, sources)
+	}
+
+	return aggregatedFeed, sources, stats, nil
+}
+
+func collectSources(config *Config) ([]*SourceFeed, *RunStats, error) {
+	if config.Demo {
+		demo, err := demoSources()
+		if err != nil {
+			return nil, &RunStats{}, fmt.Errorf("error loading demo feeds: %v", err)
+		}
+		return demo, &RunStats{Sources: len(demo)}, nil
+	}
 
 	if config.Mode == "single" {
-		items, err := fetchFeedItems(config.SingleURL)
+		stats := &RunStats{Sources: 1}
+		source, err := fetchTraced(config, Source{URL: config.SingleURL})
 		if err != nil {
-			return nil, fmt.Errorf("error fetching single feed: %v", err)
+			stats.Failed = 1
+			stats.record(config.SingleURL, SourceResult{Err: err})
+			return nil, stats, fmt.Errorf("error fetching single feed: %v", err)
 		}
-		allItems = items
+		stats.recordFetch(source)
+		return []*SourceFeed{source}, stats, nil
+	}
+
+	entries, err := config.sources()
+	if err != nil {
+		return nil, &RunStats{}, err
+	}
+
+	sources, stats := fetchSources(config, entries)
+	return sources, stats, nil
+}
+
+// fetchSources fetches entries concurrently. Failures are logged and counted
+// but do not stop the other sources from being fetched. With -retry-failed,
+// the sources that failed are tried once more after all others are done,
+// and only fall back to stale cached copies if that fails too.
+func fetchSources(config *Config, entries []Source) ([]*SourceFeed, *RunStats) {
+	runConfig := *config
+	runConfig.claims = newFeedClaims()
+	if config.streamable() {
+		runConfig.stream = newItemStream(config, entries)
+	}
+	for _, entry := range entries {
+		runConfig.claims.claim(entry.URL, entry.URL)
+	}
+	config = &runConfig
+
+	var sources []*SourceFeed
+	var failed []fetchFailure
+	if !config.RetryFailed {
+		sources, failed = fetchPass(config, entries, config.Spread, "Warning: failed to fetch feed")
 	} else {
-		urls, err := readURLsFromFile(config.InputFile)
-		if err != nil {
-			return nil, fmt.Errorf("error reading input file: %v", err)
+		firstConfig := *config
+		firstConfig.StaleIfError = 0
+		sources, failed = fetchPass(&firstConfig, entries, config.Spread, "Failed to fetch feed, retrying after the others")
+
+		if len(failed) > 0 {
+			var retries []Source
+			for _, failure := range failed {
+				entry := failure.entry
+				if config.RetryTimeout > 0 {
+					entry.Timeout = config.RetryTimeout
+				}
+				retries = append(retries, entry)
+			}
+			var retried []*SourceFeed
+			retried, failed = fetchPass(config, retries, 0, "Warning: failed to fetch feed on retry")
+			sources = append(sources, retried...)
 		}
+	}
 
-		var wg sync.WaitGroup
-		var mu sync.Mutex
+	order := make(map[string]int)
+	for i, entry := range entries {
+		order[entry.URL] = i
+	}
+	slices.SortFunc(sources, func(a, b *SourceFeed) int {
+		return order[a.URL] - order[b.URL]
+	})
+	sources = dropDuplicateSources(sources)
+	reportMovedSources(config, entries, sources)
 
-		for _, url := range urls {
-			wg.Add(1)
-			go func(url string) {
-				defer wg.Done()
-				items, err := fetchFeedItems(strings.TrimSpace(url))
-				if err != nil {
-					log.Printf("Warning: failed to fetch feed %s: %v", url, err)
-					return
+	stats := &RunStats{Sources: len(entries), Failed: len(failed)}
+	if config.stream != nil {
+		config.stream.finish(sources, stats)
+	} else {
+		for _, source := range sources {
+			stats.recordFetch(source)
+		}
+	}
+	for _, failure := range failed {
+		stats.record(failure.entry.URL, SourceResult{Err: failure.err})
+	}
+	return sources, stats
+}
+
+type fetchFailure struct {
+	entry Source
+	err   error
+}
+
+// fetchPass fetches entries concurrently, logging each failure after
+// message, and returns the sources fetched and the entries that failed.
+// With spread, each entry is fetched at a random time within its own equal
+// share of that period, so that fetches are not all made at once.
+func fetchPass(config *Config, entries []Source, spread time.Duration, message string) ([]*SourceFeed, []fetchFailure) {
+	var sources []*SourceFeed
+	var failed []fetchFailure
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+
+	for i, entry := range entries {
+		wg.Add(1)
+		go func(entry Source) {
+			defer wg.Done()
+			if spread > 0 {
+				slot := (float64(i) + rand.Float64()) / float64(len(entries))
+				select {
+				case <-time.After(time.Duration(slot * float64(spread))):
+				case <-config.done:
 				}
-				mu.Lock()
-				allItems = append(allItems, items...)
-				mu.Unlock()
-			}(url)
+			}
+			source, err := fetchTraced(config, entry)
+			mu.Lock()
+			defer mu.Unlock()
+			var duplicate *duplicateFeedError
+			if errors.As(err, &duplicate) {
+				log.Printf("Warning: skipping feed %s: %v", entry.URL, err)
+				return
+			}
+			if err != nil {
+				log.Printf("%s %s: %v", message, entry.URL, err)
+				failed = append(failed, fetchFailure{entry, err})
+				return
+			}
+			if config.stream != nil {
+				config.stream.add(source)
+			}
+			sources = append(sources, source)
+		}(entry)
+	}
+	wg.Wait()
+
+	return sources, failed
+}
+
+func buildFeed(config *Config, sources []*SourceFeed) *feeds.Feed {
+	if config.ArchiveDir != "" {
+		archiveItems(config.ArchiveDir, sources)
+	}
+
+	// Later stages modify items in place; work on copies so sources can be
+	// kept and rebuilt from by the daemon.
+	sources = cloneSources(sources)
+	if config.ResolveLinks {
+		resolveKnownLinks(sources, config.CacheDir)
+	}
+	hashes := contentHashes(sources)
+
+	if config.TitleTemplate != nil || config.DescriptionTemplate != nil {
+		for _, source := range sources {
+			applyItemTemplates(source, config.TitleTemplate, config.DescriptionTemplate)
 		}
-		wg.Wait()
 	}
 
-	sort.Slice(allItems, func(i, j int) bool {
-		return allItems[i].Created.After(allItems[j].Created)
+	var allItems []*feeds.Item
+	for _, source := range sources {
+		allItems = append(allItems, source.Items...)
+	}
+
+	if config.TransformCmd != "" {
+		allItems = transformItems(allItems, config.TransformCmd)
+	}
+
+	if len(config.Languages) > 0 {
+		allItems = filterLanguages(allItems, sources, config.Languages)
+	}
+
+	newer := config.newer()
+	sort.SliceStable(allItems, func(i, j int) bool {
+		return newer(allItems[i], allItems[j])
 	})
 
-	if len(allItems) > config.Count {
-		allItems = allItems[:config.Count]
+	if config.MergeDuplicates {
+		allItems = mergeDuplicates(allItems, sources)
+	}
+
+	if config.StateFile != "" {
+		now := time.Now()
+		updates, err := itemUpdates(config.StateFile, allItems, hashes, now)
+		if err != nil {
+			log.Printf("Warning: cannot detect updated items: %v", err)
+		}
+		markUpdated(allItems, updates, config.MarkUpdated)
+
+		if config.OnlyNew {
+			unseen, err := unseenItems(config.StateFile, allItems)
+			if err != nil {
+				log.Printf("Warning: cannot filter seen items: %v", err)
+			} else {
+				// Items edited since the last run are new again.
+				isUnseen := make(map[*feeds.Item]bool, len(unseen))
+				for _, item := range unseen {
+					isUnseen[item] = true
+				}
+				allItems = slices.DeleteFunc(allItems, func(item *feeds.Item) bool {
+					return !isUnseen[item] && !updates[itemKey(item)].Equal(now)
+				})
+			}
+		}
+	}
+
+	orderItems(allItems, config.Order, config.Seed)
+
+	// Links are only resolved and checked for the items selected; those
+	// already resolved in an earlier run were resolved above. Items dropped
+	// for dead links make room for others, which are checked in turn.
+	candidates := allItems
+	for {
+		allItems = selectItems(config, slices.Clone(candidates), sources)
+		if config.ResolveLinks {
+			resolveLinks(allItems, config.CacheDir, config.Offline)
+		}
+		if config.CheckLinks == "" {
+			break
+		}
+		live := checkLinks(slices.Clone(allItems), config.CheckLinks, config.CheckLinksWorkers)
+		if len(live) == len(allItems) {
+			allItems = live
+			break
+		}
+		dead := make(map[*feeds.Item]bool, len(allItems))
+		for _, item := range allItems {
+			dead[item] = true
+		}
+		for _, item := range live {
+			delete(dead, item)
+		}
+		candidates = slices.DeleteFunc(candidates, func(item *feeds.Item) bool { return dead[item] })
+	}
+	if config.ResolveLinks && config.MergeDuplicates {
+		allItems = mergeDuplicates(allItems, sources)
+	}
+
+	if config.FullText {
+		enrichFullText(allItems, config)
+	}
+
+	if config.OpenGraph {
+		enrichOpenGraph(allItems, config)
+	}
+
+	if config.InlineImages {
+		inlineImages(allItems, config.InlineImagesMaxBytes)
+	}
+
+	if config.ImageProxy != "" {
+		proxyImages(allItems, config.ImageProxy)
+	}
+
+	if config.MaxDescriptionWords > 0 || config.MaxDescriptionChars > 0 {
+		truncateDescriptions(allItems, config.MaxDescriptionWords, config.MaxDescriptionChars)
+	}
+
+	switch config.GroupBy {
+	case "source":
+		allItems = groupBySource(allItems, sources)
+	case "day", "week":
+		allItems = groupByDate(allItems, config.GroupBy, config.now(), config.itemDate)
 	}
 
 	aggregatedFeed := &feeds.Feed{
 		Title:       "RSS Aggregator Feed",
-		Link:        &feeds.Link{Href: ""},
+		Link:        &feeds.Link{Href: cmp.Or(config.SiteURL, config.SelfURL)},
 		Description: "Aggregated RSS feed",
 		Created:     time.Now(),
 		Items:       allItems,
 	}
 
-	return aggregatedFeed, nil
+	// The build time changes on every run even when no item did; use the
+	// newest item's date instead, which only moves when content does.
+	if config.Deterministic {
+		aggregatedFeed.Created = time.Time{}
+		for _, item := range allItems {
+			if item.Created.After(aggregatedFeed.Created) {
+				aggregatedFeed.Created = item.Created
+			}
+		}
+	}
+
+	if config.Timezone != nil {
+		inZone(aggregatedFeed, config.Timezone)
+	}
+
+	return aggregatedFeed
+}
+
+// selectItems picks the items of the aggregate among items, ordered as
+// they are output. With -page-size, items beyond -count are kept for
+// archive pages and split off when publishing.
+func selectItems(config *Config, items []*feeds.Item, sources []*SourceFeed) []*feeds.Item {
+	switch {
+	case config.Select == "round-robin":
+		weights := make(map[string]int)
+		for _, source := range sources {
+			weights[source.URL] = source.Weight
+		}
+		return selectRoundRobin(items, config.Count, weights)
+	case config.Select == "score":
+		return selectScored(items, config.Count, config.Scorer, sources, time.Now())
+	case config.Select == "daily":
+		return selectDaily(items, config.Count, config.Days, config.now(), config.itemDate)
+	case len(items) > config.Count && config.PageSize == 0:
+		return items[:config.Count]
+	}
+	return items
+}
+
+// sources lists the sources configured for this run, with the options
+// given for each in the input file.
+func (config *Config) sources() ([]Source, error) {
+	if config.Mode == "single" {
+		return []Source{{URL: config.SingleURL}}, nil
+	}
+	sources, err := readSourcesFromFiles(config.InputFiles)
+	if err != nil {
+		return nil, fmt.Errorf("error reading input file: %v", err)
+	}
+	return slices.DeleteFunc(sources, func(source Source) bool { return source.Paused }), nil
+}
+
+func cloneSources(sources []*SourceFeed) []*SourceFeed {
+	clones := make([]*SourceFeed, 0, len(sources))
+	for _, source := range sources {
+		clone := *source
+		clone.Items = make([]*feeds.Item, 0, len(source.Items))
+		for _, item := range source.Items {
+			itemCopy := *item
+			clone.Items = append(clone.Items, &itemCopy)
+		}
+		clones = append(clones, &clone)
+	}
+	return clones
 }
 
 func readURLsFromFile(filename string) ([]string, error) {
-	file, err := os.Open(filename)
+	sources, err := readSourcesFromFile(filename)
 	if err != nil {
-		return nil, fmt.Errorf("error opening file: %v", err)
+		return nil, err
 	}
-	defer file.Close()
 
 	var urls []string
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if line != "" && !strings.HasPrefix(line, "#") {
-			urls = append(urls, line)
+	for _, source := range sources {
+		urls = append(urls, source.URL)
+	}
+
+	return urls, nil
+}
+
+// SourceFeed is one fetched source together with its converted items. Each
+// item's Source link points back at URL.
+type SourceFeed struct {
+	URL   string
+	Title string
+	Link  string
+	Items []*feeds.Item
+
+	// Tags and Weight come from the source's options in the input file.
+	Tags   []string
+	Weight int
+
+	// MovedTo is where the source permanently redirects to, if it does,
+	// and FinalURL where it was last fetched from after any redirects.
+	MovedTo  string
+	FinalURL string
+
+	// WebSubHub and WebSubTopic are set when the source advertises push
+	// updates.
+	WebSubHub   string
+	WebSubTopic string
+
+	// Language is the language the feed declares, if any.
+	Language string
+
+	// Date is the date the feed gives itself, if any, and Fetched when it
+	// was downloaded.
+	Date    time.Time
+	Fetched time.Time
+
+	// Metrics is set when the source was just downloaded, and not kept in
+	// the feed cache.
+	Metrics *FetchMetrics `json:"-"`
+}
+
+// FetchMetrics measures one download of a source.
+type FetchMetrics struct {
+	// Duration is how long the request and the response body took, and
+	// Parse how long parsing the body took after that.
+	Duration time.Duration
+	Parse    time.Duration
+	Bytes    int
+	// Start is when the request was made.
+	Start time.Time
+}
+
+// feedURL is where the source's feed was actually fetched from.
+func (source *SourceFeed) feedURL() string {
+	return cmp.Or(source.FinalURL, source.URL)
+}
+
+func fetchSourceFeed(entry Source) (*SourceFeed, error) {
+	source, err := downloadSourceFeed(entry, nil)
+	if err != nil {
+		return nil, err
+	}
+	entry.apply(source)
+	return source, nil
+}
+
+// downloadSourceFeed fetches entry without applying its options. It gives up
+// when entry redirects to a feed that claims records another source for.
+func downloadSourceFeed(entry Source, claims *feedClaims) (*SourceFeed, error) {
+	return downloadSourceFeedWith(httpClient, entry, claims)
+}
+
+// maxFeedBytes is the largest feed downloaded, so that a misbehaving or
+// hostile server cannot exhaust memory.
+const maxFeedBytes = 50 << 20
+
+// downloadSourceFeedWith is downloadSourceFeed through base instead of
+// httpClient.
+func downloadSourceFeedWith(base *http.Client, entry Source, claims *feedClaims) (*SourceFeed, error) {
+	url := entry.URL
+	client := *base
+	if entry.Timeout > 0 {
+		client.Timeout = entry.Timeout
+	}
+
+	// Note where a chain of permanent redirects from url ends, so the input
+	// file can be updated.
+	var movedTo string
+	permanent := true
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 10 {
+			return fmt.Errorf("stopped after 10 redirects")
+		}
+		if owner, ok := claims.claim(req.URL.String(), url); !ok {
+			return &duplicateFeedError{target: req.URL.String(), owner: owner}
 		}
+		switch req.Response.StatusCode {
+		case http.StatusMovedPermanently, http.StatusPermanentRedirect:
+			if permanent {
+				movedTo = req.URL.String()
+			}
+		default:
+			permanent = false
+		}
+		return nil
 	}
 
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("error reading file: %v", err)
+	var body bytes.Buffer
+	var header http.Header
+	var finalURL string
+	// downloaded is when the body was read to the end, which separates
+	// downloading from parsing.
+	var downloaded time.Time
+	fetchFunc := func(url string) (*http.Response, error) {
+		req, err := http.NewRequest(http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		for name, values := range entry.Headers {
+			req.Header[name] = values
+		}
+		if entry.UserAgent != "" {
+			req.Header.Set("User-Agent", entry.UserAgent)
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		header = resp.Header
+		finalURL = resp.Request.URL.String()
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{&timedReader{r: io.TeeReader(io.LimitReader(resp.Body, maxFeedBytes+1), &body), done: &downloaded}, resp.Body}
+		return resp, nil
 	}
 
-	return urls, nil
+	start := time.Now()
+	feed, err := rss.FetchByFunc(fetchFunc, url)
+	if body.Len() > maxFeedBytes {
+		return nil, fmt.Errorf("feed is larger than %d bytes", maxFeedBytes)
+	}
+	if err != nil {
+		return nil, err
+	}
+	end := time.Now()
+	if downloaded.IsZero() {
+		downloaded = end
+	}
+
+	source := newSourceFeed(url, feed)
+	source.Metrics = &FetchMetrics{Duration: downloaded.Sub(start), Parse: end.Sub(downloaded), Bytes: body.Len(), Start: start}
+	source.MovedTo = movedTo
+	if finalURL != url {
+		source.FinalURL = finalURL
+	}
+	source.Fetched = start
+	source.readDates(body.Bytes())
+	source.readThumbnails(body.Bytes())
+	source.Language = cmp.Or(source.Language, documentLanguage(body.Bytes()))
+	source.WebSubHub, source.WebSubTopic = discoverWebSub(body.Bytes(), header)
+	if source.WebSubHub != "" && source.WebSubTopic == "" {
+		source.WebSubTopic = url
+	}
+
+	return source, nil
+}
+
+// timedReader notes in done when r reaches the end.
+type timedReader struct {
+	r    io.Reader
+	done *time.Time
+}
+
+func (t *timedReader) Read(p []byte) (int, error) {
+	n, err := t.r.Read(p)
+	if err == io.EOF && t.done.IsZero() {
+		*t.done = time.Now()
+	}
+	return n, err
+}
+
+func newSourceFeed(url string, feed *rss.Feed) *SourceFeed {
+	items := convertFeedItems(feed)
+	for _, item := range items {
+		item.Source = &feeds.Link{Href: url}
+	}
+
+	return &SourceFeed{
+		URL:      url,
+		Title:    feed.Title,
+		Link:     feed.Link,
+		Language: feed.Language,
+		Items:    items,
+	}
 }
 
 func fetchFeedItems(url string) ([]*feeds.Item, error) {
-	feed, err := rss.Fetch(url)
+	source, err := fetchSourceFeed(Source{URL: url})
 	if err != nil {
 		return nil, err
 	}
 
+	return source.Items, nil
+}
+
+func convertFeedItems(feed *rss.Feed) []*feeds.Item {
 	var items []*feeds.Item
 	for _, item := range feed.Items {
 		feedItem := &feeds.Item{
@@ -176,25 +1671,41 @@ func fetchFeedItems(url string) ([]*feeds.Item, error) {
 		items = append(items, feedItem)
 	}
 
-	return items, nil
+	return items
 }
 
-func outputFeed(feed *feeds.Feed, outputFile string) error {
-	rssString, err := feed.ToRss()
-	if err != nil {
-		return fmt.Errorf("error generating RSS: %v", err)
+func outputFeed(feed *feeds.Feed, outputFile string, format feedFormat, opts RssOptions) error {
+	if dest, upload, ok := remoteOutput(outputFile); ok {
+		var buf bytes.Buffer
+		if err := format.Write(&buf, feed, opts); err != nil {
+			return fmt.Errorf("error rendering feed: %v", err)
+		}
+		if err := upload(dest, buf.Bytes(), format.ContentType); err != nil {
+			return fmt.Errorf("error uploading to %s: %v", outputFile, err)
+		}
+		return nil
 	}
 
-	file, err := os.Create(outputFile)
-	if err != nil {
-		return fmt.Errorf("error creating output file: %v", err)
+	// Devices and pipes, such as /dev/stdout, are written to as they are;
+	// files are replaced in one step so readers never see a partial feed.
+	if info, err := os.Stat(outputFile); err == nil && !info.Mode().IsRegular() {
+		file, err := os.OpenFile(outputFile, os.O_WRONLY, 0)
+		if err != nil {
+			return fmt.Errorf("error creating output file: %v", err)
+		}
+		defer file.Close()
+		if err := format.Write(file, feed, opts); err != nil {
+			return fmt.Errorf("error writing to output file: %v", err)
+		}
+		return nil
 	}
-	defer file.Close()
 
-	_, err = file.WriteString(rssString)
-	if err != nil {
+	var buf bytes.Buffer
+	if err := format.Write(&buf, feed, opts); err != nil {
+		return fmt.Errorf("error rendering feed: %v", err)
+	}
+	if err := writeFileAtomic(outputFile, buf.Bytes(), 0644); err != nil {
 		return fmt.Errorf("error writing to output file: %v", err)
 	}
-
 	return nil
-}
\ No newline at end of file
+}
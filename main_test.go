@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bytes"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -23,7 +24,7 @@ func TestValidateConfig(t *testing.T) {
 		{
 			name: "valid config - all mode",
 			config: &Config{
-				InputFile:  "test.txt",
+				InputFiles: []string{"test.txt"},
 				Count:      10,
 				Mode:       "all",
 				OutputFile: "output.xml",
@@ -40,10 +41,57 @@ func TestValidateConfig(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "valid config - demo without input",
+			config: &Config{
+				Count:      10,
+				Mode:       "all",
+				Demo:       true,
+				OutputFile: "output.xml",
+			},
+			wantErr: false,
+		},
+		{
+			name: "demo with fulltext",
+			config: &Config{
+				Count:      10,
+				Mode:       "all",
+				Demo:       true,
+				FullText:   true,
+				OutputFile: "output.xml",
+			},
+			wantErr: true,
+			errMsg:  "demo runs without network access",
+		},
+		{
+			name: "invalid check-links mode",
+			config: &Config{
+				InputFiles:        []string{"test.txt"},
+				Count:             10,
+				Mode:              "all",
+				OutputFile:        "output.xml",
+				CheckLinks:        "remove",
+				CheckLinksWorkers: 8,
+			},
+			wantErr: true,
+			errMsg:  "check-links must be 'drop' or 'flag'",
+		},
+		{
+			name: "opengraph without workers",
+			config: &Config{
+				InputFiles: []string{"test.txt"},
+				Count:      10,
+				Mode:       "all",
+				OutputFile: "output.xml",
+				OpenGraph:  true,
+			},
+			wantErr: true,
+			errMsg:  "opengraph-workers must be greater than 0",
+		},
 		{
 			name: "invalid mode",
 			config: &Config{
-				InputFile:  "test.txt",
+				InputFiles: []string{"test.txt"},
 				Count:      10,
 				Mode:       "invalid",
 				OutputFile: "output.xml",
@@ -74,7 +122,7 @@ func TestValidateConfig(t *testing.T) {
 		{
 			name: "zero count",
 			config: &Config{
-				InputFile:  "test.txt",
+				InputFiles: []string{"test.txt"},
 				Count:      0,
 				Mode:       "all",
 				OutputFile: "output.xml",
@@ -85,7 +133,7 @@ func TestValidateConfig(t *testing.T) {
 		{
 			name: "negative count",
 			config: &Config{
-				InputFile:  "test.txt",
+				InputFiles: []string{"test.txt"},
 				Count:      -5,
 				Mode:       "all",
 				OutputFile: "output.xml",
@@ -93,6 +141,180 @@ func TestValidateConfig(t *testing.T) {
 			wantErr: true,
 			errMsg:  "count must be greater than 0",
 		},
+		{
+			name: "relative self url",
+			config: &Config{
+				InputFiles: []string{"test.txt"},
+				Count:      10,
+				Mode:       "all",
+				OutputFile: "output.xml",
+				SelfURL:    "/feed.xml",
+			},
+			wantErr: true,
+			errMsg:  "self-url must be an absolute URL",
+		},
+		{
+			name: "page size without self url",
+			config: &Config{
+				InputFiles: []string{"test.txt"},
+				Count:      10,
+				Mode:       "all",
+				OutputFile: "output.xml",
+				PageSize:   50,
+			},
+			wantErr: true,
+			errMsg:  "page-size requires self-url and rss format",
+		},
+		{
+			name: "offline without cache dir",
+			config: &Config{
+				InputFiles: []string{"test.txt"},
+				Count:      10,
+				Mode:       "all",
+				OutputFile: "output.xml",
+				Offline:    true,
+			},
+			wantErr: true,
+			errMsg:  "offline requires cache-dir",
+		},
+		{
+			name: "admin token without listen",
+			config: &Config{
+				InputFiles: []string{"test.txt"},
+				Count:      10,
+				Mode:       "all",
+				OutputFile: "output.xml",
+				Interval:   time.Hour,
+				AdminToken: "secret",
+			},
+			wantErr: true,
+			errMsg:  "admin-token requires listen",
+		},
+		{
+			name: "basic auth without a password",
+			config: &Config{
+				InputFiles: []string{"test.txt"},
+				Count:      10,
+				Mode:       "all",
+				OutputFile: "output.xml",
+				Interval:   time.Hour,
+				Listen:     ":8080",
+				AuthBasic:  "reader",
+			},
+			wantErr: true,
+			errMsg:  "auth-basic must be user:password",
+		},
+		{
+			name: "tls domain without listen",
+			config: &Config{
+				InputFiles: []string{"test.txt"},
+				Count:      10,
+				Mode:       "all",
+				OutputFile: "output.xml",
+				TLSDomains: []string{"feed.example.com"},
+			},
+			wantErr: true,
+			errMsg:  "tls-domain requires listen",
+		},
+		{
+			name: "auth token without listen",
+			config: &Config{
+				InputFiles: []string{"test.txt"},
+				Count:      10,
+				Mode:       "all",
+				OutputFile: "output.xml",
+				AuthToken:  "secret",
+			},
+			wantErr: true,
+			errMsg:  "auth-token requires listen",
+		},
+		{
+			name: "favicons without listen",
+			config: &Config{
+				InputFiles: []string{"test.txt"},
+				Count:      10,
+				Mode:       "all",
+				OutputFile: "output.xml",
+				Interval:   time.Hour,
+				Favicons:   true,
+			},
+			wantErr: true,
+			errMsg:  "favicons requires listen",
+		},
+		{
+			name: "otlp endpoint not a URL",
+			config: &Config{
+				InputFiles:   []string{"test.txt"},
+				Count:        10,
+				Mode:         "all",
+				OutputFile:   "output.xml",
+				OTLPEndpoint: "localhost:4318",
+			},
+			wantErr: true,
+			errMsg:  "otlp-endpoint must be an http or https URL",
+		},
+		{
+			name: "pprof without daemon mode",
+			config: &Config{
+				InputFiles: []string{"test.txt"},
+				Count:      10,
+				Mode:       "all",
+				OutputFile: "output.xml",
+				Pprof:      "localhost:6060",
+			},
+			wantErr: true,
+			errMsg:  "pprof requires daemon mode (interval or schedule)",
+		},
+		{
+			name: "pprof on a public address",
+			config: &Config{
+				InputFiles: []string{"test.txt"},
+				Count:      10,
+				Mode:       "all",
+				OutputFile: "output.xml",
+				Interval:   time.Minute,
+				Pprof:      ":6060",
+			},
+			wantErr: true,
+			errMsg:  "pprof must be a loopback address such as localhost:6060",
+		},
+		{
+			name: "unknown missing date fallback",
+			config: &Config{
+				InputFiles:  []string{"test.txt"},
+				Count:       10,
+				Mode:        "all",
+				OutputFile:  "output.xml",
+				MissingDate: "now",
+			},
+			wantErr: true,
+			errMsg:  "missing-date must be 'feed', 'fetch' or 'drop'",
+		},
+		{
+			name: "seed without shuffle",
+			config: &Config{
+				InputFiles: []string{"test.txt"},
+				Count:      10,
+				Mode:       "all",
+				OutputFile: "output.xml",
+				Order:      "asc",
+				Seed:       42,
+			},
+			wantErr: true,
+			errMsg:  "seed requires order shuffle",
+		},
+		{
+			name: "scorer without select score",
+			config: &Config{
+				InputFiles: []string{"test.txt"},
+				Count:      10,
+				Mode:       "all",
+				OutputFile: "output.xml",
+				Scorer:     keywordScorer{},
+			},
+			wantErr: true,
+			errMsg:  "score-keywords requires select score",
+		},
 	}
 
 	for _, tt := range tests {
@@ -115,6 +337,22 @@ func TestValidateConfig(t *testing.T) {
 	}
 }
 
+func TestConfigureNetworkOffline(t *testing.T) {
+	defer func(transport, doh http.RoundTripper) {
+		httpClient.Transport, dohClient.Transport = transport, doh
+	}(httpClient.Transport, dohClient.Transport)
+
+	config := &Config{Offline: true, DNSServer: "https://dns.example/dns-query", DNSCache: true}
+	if err := configureNetwork(config); err != nil {
+		t.Fatalf("configureNetwork() unexpected error = %v", err)
+	}
+	for name, transport := range map[string]http.RoundTripper{"httpClient": httpClient.Transport, "dohClient": dohClient.Transport} {
+		if _, ok := transport.(offlineTransport); !ok {
+			t.Errorf("%s.Transport = %T offline, want offlineTransport", name, transport)
+		}
+	}
+}
+
 func TestReadURLsFromFile(t *testing.T) {
 	// Create temporary directory for test files
 	tempDir, err := os.MkdirTemp("", "rss_test")
@@ -237,7 +475,7 @@ func TestOutputFeed(t *testing.T) {
 	}
 
 	outputFile := filepath.Join(tempDir, "test_output.xml")
-	err = outputFeed(feed, outputFile)
+	err = outputFeed(feed, outputFile, formats["rss"], RssOptions{})
 	if err != nil {
 		t.Errorf("outputFeed() unexpected error = %v", err)
 		return
@@ -263,6 +501,18 @@ func TestOutputFeed(t *testing.T) {
 	if !strings.Contains(contentStr, "Test Item 1") {
 		t.Errorf("Output file does not contain expected item title")
 	}
+
+	// A later run replaces the file in one step, leaving nothing behind.
+	feed.Title = "Updated Feed"
+	if err := outputFeed(feed, outputFile, formats["rss"], RssOptions{}); err != nil {
+		t.Fatalf("outputFeed() unexpected error = %v", err)
+	}
+	if content, _ := os.ReadFile(outputFile); !strings.Contains(string(content), "Updated Feed") {
+		t.Errorf("outputFeed() did not replace the output file")
+	}
+	if files, _ := os.ReadDir(tempDir); len(files) != 1 {
+		t.Errorf("output directory holds %d files, want 1", len(files))
+	}
 }
 
 func createMockRSSServer(rssContent string) *httptest.Server {
@@ -348,7 +598,7 @@ func TestAggregateFeedsSingleMode(t *testing.T) {
 		Count:     5,
 	}
 
-	feed, err := aggregateFeeds(config)
+	feed, _, _, err := aggregateFeeds(config)
 	if err != nil {
 		t.Errorf("aggregateFeeds() unexpected error = %v", err)
 		return
@@ -416,12 +666,12 @@ func TestAggregateFeedsAllMode(t *testing.T) {
 	}
 
 	config := &Config{
-		Mode:      "all",
-		InputFile: inputFile,
-		Count:     5,
+		Mode:       "all",
+		InputFiles: []string{inputFile},
+		Count:      5,
 	}
 
-	feed, err := aggregateFeeds(config)
+	feed, _, _, err := aggregateFeeds(config)
 	if err != nil {
 		t.Errorf("aggregateFeeds() unexpected error = %v", err)
 		return
@@ -441,4 +691,152 @@ func TestAggregateFeedsAllMode(t *testing.T) {
 	if feed.Items[1].Title != "Item from Feed 1" {
 		t.Errorf("aggregateFeeds() second item title = %v, want 'Item from Feed 1'", feed.Items[1].Title)
 	}
-}
\ No newline at end of file
+}
+
+func TestAggregateFeedsDemo(t *testing.T) {
+	config := &Config{Demo: true, Count: 3}
+
+	feed, _, stats, err := aggregateFeeds(config)
+	if err != nil {
+		t.Fatalf("aggregateFeeds() unexpected error = %v", err)
+	}
+
+	if stats.Sources != len(demoFeeds) || stats.Failed != 0 {
+		t.Errorf("aggregateFeeds() stats = %+v, want %d sources and no failures", stats, len(demoFeeds))
+	}
+
+	if len(feed.Items) != 3 {
+		t.Fatalf("aggregateFeeds() got %d items, want 3", len(feed.Items))
+	}
+
+	if feed.Items[0].Title != "Why We Moved Our Feeds to Object Storage" {
+		t.Errorf("aggregateFeeds() first item title = %v, want newest demo item", feed.Items[0].Title)
+	}
+}
+
+func TestBuildFeedChannelLink(t *testing.T) {
+	tests := []struct {
+		name   string
+		config *Config
+		want   string
+	}{
+		{"site url", &Config{Count: 1, SiteURL: "https://example.com/", SelfURL: "https://example.com/feed.xml"}, "https://example.com/"},
+		{"falls back to self url", &Config{Count: 1, SelfURL: "https://example.com/feed.xml"}, "https://example.com/feed.xml"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			feed := buildFeed(tt.config, nil)
+			if feed.Link.Href != tt.want {
+				t.Errorf("buildFeed() link = %q, want %q", feed.Link.Href, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildFeedDeterministic(t *testing.T) {
+	published := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	a := &SourceFeed{URL: "https://a.example/feed", Items: []*feeds.Item{
+		{Title: "A", Link: &feeds.Link{Href: "https://a.example/1"}, Created: published},
+	}}
+	b := &SourceFeed{URL: "https://b.example/feed", Items: []*feeds.Item{
+		{Title: "B", Link: &feeds.Link{Href: "https://b.example/1"}, Created: published},
+		{Title: "Older", Link: &feeds.Link{Href: "https://b.example/0"}, Created: published.Add(-time.Hour)},
+	}}
+	config := &Config{Count: 10, Deterministic: true, RssLastBuildDate: true}
+
+	var outputs []string
+	for _, sources := range [][]*SourceFeed{{a, b}, {b, a}} {
+		feed := buildFeed(config, sources)
+		if !feed.Created.Equal(published) {
+			t.Errorf("buildFeed() created = %v, want newest item date %v", feed.Created, published)
+		}
+
+		var buf bytes.Buffer
+		if err := formats["rss"].Write(&buf, feed, config.rssOptions()); err != nil {
+			t.Fatalf("Write() unexpected error = %v", err)
+		}
+		outputs = append(outputs, buf.String())
+	}
+
+	if outputs[0] != outputs[1] {
+		t.Errorf("deterministic output depends on source order:\n%s\n---\n%s", outputs[0], outputs[1])
+	}
+	if !strings.Contains(outputs[0], "<lastBuildDate>Fri, 01 Mar 2024 12:00:00 +0000</lastBuildDate>") {
+		t.Errorf("lastBuildDate should be pinned to the newest item:\n%s", outputs[0])
+	}
+}
+
+func TestFetchSourcesRetryFailed(t *testing.T) {
+	rssContent := `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0">
+<channel>
+<title>Flaky Feed</title>
+<item><title>Item</title><link>http://example.com/1</link></item>
+</channel>
+</rss>`
+
+	for _, retry := range []bool{false, true} {
+		var hits int
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			hits++
+			if hits == 1 {
+				http.Error(w, "unavailable", http.StatusServiceUnavailable)
+				return
+			}
+			w.Write([]byte(rssContent))
+		}))
+
+		config := &Config{RetryFailed: retry, RetryTimeout: 5 * time.Second}
+		sources, stats := fetchSources(config, []Source{{URL: server.URL}})
+		server.Close()
+
+		wantFailed := 1
+		if retry {
+			wantFailed = 0
+		}
+		if stats.Failed != wantFailed || len(sources) != 1-wantFailed {
+			t.Errorf("fetchSources() with retry-failed=%v got %d sources and %d failed, want %d failed", retry, len(sources), stats.Failed, wantFailed)
+		}
+	}
+}
+
+func TestDownloadSourceFeedTooLarge(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<?xml version="1.0"?><rss version="2.0"><channel><title>`))
+		w.Write(bytes.Repeat([]byte("a"), maxFeedBytes))
+		w.Write([]byte(`</title></channel></rss>`))
+	}))
+	defer server.Close()
+
+	if _, err := downloadSourceFeed(Source{URL: server.URL}, nil); err == nil || !strings.Contains(err.Error(), "larger than") {
+		t.Errorf("downloadSourceFeed() of an oversized feed error = %v, want too large", err)
+	}
+}
+
+func TestFetchPassSpread(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(cacheTestFeed))
+	}))
+	defer server.Close()
+
+	entries := []Source{{URL: server.URL + "/a"}, {URL: server.URL + "/b"}}
+	spread := 200 * time.Millisecond
+
+	start := time.Now()
+	sources, failed := fetchPass(&Config{}, entries, spread, "failed")
+	if len(sources) != 2 || len(failed) != 0 {
+		t.Fatalf("fetchPass() got %d sources and %d failures, want 2 and 0", len(sources), len(failed))
+	}
+	if elapsed := time.Since(start); elapsed < spread/2 {
+		t.Errorf("fetchPass() with spread %v took %v, want the second fetch in the second half", spread, elapsed)
+	}
+
+	done := make(chan struct{})
+	close(done)
+	start = time.Now()
+	fetchPass(&Config{done: done}, entries, time.Hour, "failed")
+	if elapsed := time.Since(start); elapsed > time.Minute {
+		t.Errorf("fetchPass() after shutdown took %v, want the spread cut short", elapsed)
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/feeds"
+)
+
+var matrixTxnCounter atomic.Int64
+
+// MatrixNotifier posts new items to a Matrix room through the client-server
+// API.
+type MatrixNotifier struct {
+	Homeserver  string
+	AccessToken string
+	RoomID      string
+}
+
+func (n *MatrixNotifier) Name() string {
+	return "matrix"
+}
+
+func (n *MatrixNotifier) Notify(items []*feeds.Item) error {
+	plain, formatted := matrixMessage(items)
+	body, err := json.Marshal(map[string]string{
+		"msgtype":        "m.text",
+		"body":           plain,
+		"format":         "org.matrix.custom.html",
+		"formatted_body": formatted,
+	})
+	if err != nil {
+		return err
+	}
+
+	txnID := fmt.Sprintf("rss-agg-%d-%d", time.Now().UnixNano(), matrixTxnCounter.Add(1))
+	endpoint := fmt.Sprintf("%s/_matrix/client/v3/rooms/%s/send/m.room.message/%s",
+		strings.TrimSuffix(n.Homeserver, "/"), url.PathEscape(n.RoomID), url.PathEscape(txnID))
+
+	req, err := http.NewRequest(http.MethodPut, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+n.AccessToken)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	return nil
+}
+
+// matrixMessage renders items as a plain-text body and an HTML list.
+func matrixMessage(items []*feeds.Item) (string, string) {
+	var plain, formatted strings.Builder
+	formatted.WriteString("<ul>")
+	for _, item := range items {
+		title := html.EscapeString(item.Title)
+		if item.Link != nil && item.Link.Href != "" {
+			fmt.Fprintf(&plain, "• %s - %s\n", item.Title, item.Link.Href)
+			fmt.Fprintf(&formatted, `<li><a href="%s">%s</a></li>`, html.EscapeString(item.Link.Href), title)
+		} else {
+			fmt.Fprintf(&plain, "• %s\n", item.Title)
+			fmt.Fprintf(&formatted, "<li>%s</li>", title)
+		}
+	}
+	formatted.WriteString("</ul>")
+	return strings.TrimSuffix(plain.String(), "\n"), formatted.String()
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/feeds"
+)
+
+func TestMatrixNotifier(t *testing.T) {
+	var payload map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			t.Errorf("method = %s, want PUT", r.Method)
+		}
+		if !strings.HasPrefix(r.URL.EscapedPath(), "/_matrix/client/v3/rooms/%21room:example.org/send/m.room.message/") {
+			t.Errorf("unexpected path %s", r.URL.EscapedPath())
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer secret" {
+			t.Errorf("Authorization = %q", got)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("invalid Matrix payload: %v", err)
+		}
+		w.Write([]byte(`{"event_id": "$1"}`))
+	}))
+	defer server.Close()
+
+	notifier := &MatrixNotifier{Homeserver: server.URL + "/", AccessToken: "secret", RoomID: "!room:example.org"}
+	items := []*feeds.Item{{Title: "A < B", Link: &feeds.Link{Href: "http://example.com/a"}}}
+	if err := notifier.Notify(items); err != nil {
+		t.Fatalf("Notify() unexpected error = %v", err)
+	}
+
+	if payload["body"] != "• A < B - http://example.com/a" {
+		t.Errorf("body = %q", payload["body"])
+	}
+	if payload["formatted_body"] != `<ul><li><a href="http://example.com/a">A &lt; B</a></li></ul>` {
+		t.Errorf("formatted_body = %q", payload["formatted_body"])
+	}
+}
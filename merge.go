@@ -0,0 +1,74 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"html"
+	"slices"
+	"strings"
+
+	"github.com/gorilla/feeds"
+)
+
+// mergeDuplicates merges items that several sources published for the same
+// story, by link or, lacking one, guid, into the copy published first.
+// Its description and content end with an "Also on" line linking to the
+// other sources, and the other copies are dropped. Items keep their order
+// otherwise.
+func mergeDuplicates(items []*feeds.Item, sources []*SourceFeed) []*feeds.Item {
+	kept := make(map[string]*feeds.Item)
+	for _, item := range items {
+		key := storyKey(item)
+		if key == "" {
+			continue
+		}
+		if first, ok := kept[key]; !ok || item.Created.Before(first.Created) {
+			kept[key] = item
+		}
+	}
+
+	also := make(map[*feeds.Item][]string)
+	merged := make([]*feeds.Item, 0, len(items))
+	for _, item := range items {
+		first, ok := kept[storyKey(item)]
+		if !ok || first == item || linkHref(first.Source) == linkHref(item.Source) {
+			merged = append(merged, item)
+			continue
+		}
+		source := linkHref(item.Source)
+		if !slices.Contains(also[first], source) {
+			also[first] = append(also[first], source)
+		}
+	}
+
+	byURL := make(map[string]*SourceFeed, len(sources))
+	for _, source := range sources {
+		byURL[source.URL] = source
+	}
+	for item, others := range also {
+		var links []string
+		for _, url := range others {
+			name, link := url, url
+			if source, ok := byURL[url]; ok {
+				name = cmp.Or(source.Title, url)
+				link = cmp.Or(source.Link, url)
+			}
+			links = append(links, fmt.Sprintf(`<a href="%s">%s</a>`, html.EscapeString(link), html.EscapeString(name)))
+		}
+		note := "<p>Also on: " + strings.Join(links, ", ") + "</p>"
+		item.Description += note
+		if item.Content != "" {
+			item.Content += note
+		}
+	}
+	return merged
+}
+
+// storyKey identifies the story an item tells across sources: its
+// normalized link, or its guid for items without one.
+func storyKey(item *feeds.Item) string {
+	if item.Link != nil && item.Link.Href != "" {
+		return normalizeURL(item.Link.Href)
+	}
+	return item.Id
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/feeds"
+)
+
+func TestMergeDuplicates(t *testing.T) {
+	now := time.Now()
+	item := func(title, link, source string, age time.Duration) *feeds.Item {
+		return &feeds.Item{
+			Title:       title,
+			Link:        &feeds.Link{Href: link},
+			Source:      &feeds.Link{Href: source},
+			Description: "<p>" + title + "</p>",
+			Created:     now.Add(-age),
+		}
+	}
+	sources := []*SourceFeed{
+		{URL: "https://a.example/feed", Title: "Blog A", Link: "https://a.example/", Items: []*feeds.Item{
+			item("Story", "https://news.example/story", "https://a.example/feed", 2*time.Hour),
+			item("Other", "https://a.example/other", "https://a.example/feed", 3*time.Hour),
+		}},
+		{URL: "https://b.example/feed", Title: "B & Co", Items: []*feeds.Item{
+			item("Story (via B)", "https://NEWS.example/story/", "https://b.example/feed", time.Hour),
+		}},
+		{URL: "https://c.example/feed", Items: []*feeds.Item{
+			item("Story (via C)", "https://news.example/story", "https://c.example/feed", 0),
+		}},
+	}
+
+	feed := buildFeed(&Config{Count: 10, MergeDuplicates: true}, sources)
+	if len(feed.Items) != 2 || feed.Items[0].Title != "Story" || feed.Items[1].Title != "Other" {
+		t.Fatalf("buildFeed() with merge-duplicates items = %v, want the first published story and the other item", feed.Items)
+	}
+	want := `<p>Story</p><p>Also on: <a href="https://c.example/feed">https://c.example/feed</a>, <a href="https://b.example/feed">B &amp; Co</a></p>`
+	if got := feed.Items[0].Description; got != want {
+		t.Errorf("merged description = %q, want %q", got, want)
+	}
+	if strings.Contains(feed.Items[1].Description, "Also on") {
+		t.Errorf("unduplicated item description = %q, want it unchanged", feed.Items[1].Description)
+	}
+
+	if feed := buildFeed(&Config{Count: 10}, sources); len(feed.Items) != 4 {
+		t.Errorf("buildFeed() without merge-duplicates got %d items, want all 4", len(feed.Items))
+	}
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"net/url"
+	"strings"
+)
+
+// normalizeURL returns the form of rawURL used wherever URLs serve as keys,
+// to tell whether two name the same feed or item: scheme and host
+// lowercased, default ports, the fragment and trailing slashes dropped.
+// URLs are still fetched as written, since servers may treat a trailing
+// slash as a different resource. URLs that do not parse as absolute URLs
+// are returned unchanged.
+func normalizeURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || !u.IsAbs() || u.Host == "" {
+		return rawURL
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	if port := u.Port(); (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		u.Host = strings.TrimSuffix(u.Host, ":"+port)
+	}
+	u.Fragment = ""
+	u.RawFragment = ""
+	u.Path = strings.TrimRight(u.Path, "/")
+	u.RawPath = strings.TrimRight(u.RawPath, "/")
+	return u.String()
+}
@@ -0,0 +1,24 @@
+package main
+
+import "testing"
+
+func TestNormalizeURL(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"http://example.com/feed", "http://example.com/feed"},
+		{"HTTP://Example.COM:80/feed/", "http://example.com/feed"},
+		{"https://example.com:443/feed#latest", "https://example.com/feed"},
+		{"https://example.com:8443/feed", "https://example.com:8443/feed"},
+		{"https://example.com/", "https://example.com"},
+		{"https://example.com/Feed?a=1", "https://example.com/Feed?a=1"},
+		{"not a url", "not a url"},
+	}
+
+	for _, tt := range tests {
+		if got := normalizeURL(tt.url); got != tt.want {
+			t.Errorf("normalizeURL(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
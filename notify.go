@@ -0,0 +1,69 @@
+package main
+
+import (
+	"log"
+	"time"
+
+	"github.com/gorilla/feeds"
+)
+
+// Notifier delivers newly seen items somewhere outside the output feed.
+type Notifier interface {
+	Name() string
+	Notify(items []*feeds.Item) error
+}
+
+func (config *Config) notifiers() []Notifier {
+	var notifiers []Notifier
+	if config.SlackWebhook != "" {
+		notifiers = append(notifiers, &SlackNotifier{WebhookURL: config.SlackWebhook})
+	}
+	if config.DiscordWebhook != "" {
+		notifiers = append(notifiers, &DiscordNotifier{WebhookURL: config.DiscordWebhook})
+	}
+	if config.TelegramToken != "" && config.TelegramChat != "" {
+		notifiers = append(notifiers, &TelegramNotifier{Token: config.TelegramToken, ChatID: config.TelegramChat})
+	}
+	if config.MatrixServer != "" {
+		notifiers = append(notifiers, &MatrixNotifier{Homeserver: config.MatrixServer, AccessToken: config.MatrixToken, RoomID: config.MatrixRoom})
+	}
+	if config.NtfyURL != "" {
+		notifiers = append(notifiers, &NtfyNotifier{TopicURL: config.NtfyURL, Priority: config.NtfyPriority, Tags: config.NtfyTags})
+	}
+	if config.WebhookURL != "" {
+		notifiers = append(notifiers, &WebhookNotifier{URL: config.WebhookURL, Secret: config.WebhookSecret})
+	}
+	return notifiers
+}
+
+// updateState records items, with their content hashes from hashes, in the
+// state file and passes the ones not seen in earlier runs to every
+// configured notifier. hashes has every item the sources list, which
+// pruning keeps. The very first run only seeds the state, so that a
+// new install does not announce the whole feed.
+func updateState(config *Config, items []*feeds.Item, hashes map[string]string, now time.Time) error {
+	state, err := loadState(config.StateFile)
+	if err != nil {
+		return err
+	}
+
+	unseen := state.markSeen(items, hashes, now)
+	// hashes covers every item the sources list, not only those output.
+	fetched := make(map[string]bool, len(hashes)+len(items))
+	for key := range hashes {
+		fetched[key] = true
+	}
+	for _, item := range items {
+		fetched[itemKey(item)] = true
+	}
+	state.prune(now, config.Retain, config.RetainMax, fetched)
+	if len(unseen) > 0 && !state.isNew {
+		for _, notifier := range config.notifiers() {
+			if err := notifier.Notify(unseen); err != nil {
+				log.Printf("Warning: %s notification failed: %v", notifier.Name(), err)
+			}
+		}
+	}
+
+	return state.save()
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gorilla/feeds"
+)
+
+var ntfyPriorities = map[string]bool{
+	"1": true, "2": true, "3": true, "4": true, "5": true,
+	"min": true, "low": true, "default": true, "high": true, "max": true, "urgent": true,
+}
+
+// NtfyNotifier publishes one push notification per new item to an ntfy
+// topic URL. An access token for protected topics is read from NTFY_TOKEN.
+type NtfyNotifier struct {
+	TopicURL string
+	Priority string
+	Tags     string
+}
+
+func (n *NtfyNotifier) Name() string {
+	return "ntfy"
+}
+
+func (n *NtfyNotifier) Notify(items []*feeds.Item) error {
+	for _, item := range items {
+		if err := n.publish(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (n *NtfyNotifier) publish(item *feeds.Item) error {
+	message := item.Title
+	description := item.Description
+	if description == "" {
+		description = item.Content
+	}
+	if text, truncated := truncateText(htmlToText(description), 0, 200); text != "" {
+		if truncated {
+			text += "…"
+		}
+		message = text
+	}
+
+	req, err := http.NewRequest(http.MethodPost, n.TopicURL, strings.NewReader(message))
+	if err != nil {
+		return err
+	}
+	// ntfy reads the title from a header, which must not contain newlines.
+	req.Header.Set("Title", strings.Join(strings.Fields(item.Title), " "))
+	if item.Link != nil && item.Link.Href != "" {
+		req.Header.Set("Click", item.Link.Href)
+	}
+	if thumbnail := itemThumbnail(item); thumbnail != "" {
+		req.Header.Set("Attach", thumbnail)
+	}
+	if n.Priority != "" {
+		req.Header.Set("Priority", n.Priority)
+	}
+	if n.Tags != "" {
+		req.Header.Set("Tags", n.Tags)
+	}
+	if token := os.Getenv("NTFY_TOKEN"); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	return nil
+}
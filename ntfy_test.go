@@ -0,0 +1,50 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/feeds"
+)
+
+func TestNtfyNotifier(t *testing.T) {
+	var headers http.Header
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/news" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		headers = r.Header.Clone()
+		data, _ := io.ReadAll(r.Body)
+		body = string(data)
+	}))
+	defer server.Close()
+
+	notifier := &NtfyNotifier{TopicURL: server.URL + "/news", Priority: "high", Tags: "newspaper,go"}
+	item := &feeds.Item{
+		Title:       "Big\nNews",
+		Link:        &feeds.Link{Href: "http://example.com/news"},
+		Description: "<p>Something <i>happened</i>.</p>",
+		Enclosure:   &feeds.Enclosure{Url: "http://example.com/news.jpg", Type: "image/jpeg"},
+	}
+	if err := notifier.Notify([]*feeds.Item{item}); err != nil {
+		t.Fatalf("Notify() unexpected error = %v", err)
+	}
+
+	if body != "Something happened." {
+		t.Errorf("body = %q", body)
+	}
+	for header, want := range map[string]string{
+		"Title":    "Big News",
+		"Click":    "http://example.com/news",
+		"Priority": "high",
+		"Tags":     "newspaper,go",
+		"Attach":   "http://example.com/news.jpg",
+	} {
+		if got := headers.Get(header); got != want {
+			t.Errorf("header %s = %q, want %q", header, got, want)
+		}
+	}
+}
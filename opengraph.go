@@ -0,0 +1,145 @@
+package main
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/feeds"
+)
+
+// openGraph is what an article page says about itself in its OpenGraph
+// tags, or its plain description meta tag.
+type openGraph struct {
+	Description string `json:"description,omitempty"`
+	Image       string `json:"image,omitempty"`
+}
+
+// enrichOpenGraph fetches the link of each item that has neither a
+// description nor content, and fills them in from the page's og:description
+// and, when the item has no thumbnail, og:image, so that feeds listing bare
+// links still give readers something to go on. Pages are cached in
+// config.OpenGraphCacheDir, and offline only uses what was cached.
+func enrichOpenGraph(items []*feeds.Item, config *Config) {
+	sem := make(chan struct{}, max(config.OpenGraphWorkers, 1))
+	var wg sync.WaitGroup
+	for _, item := range items {
+		if item.Description != "" || item.Content != "" || isHeading(item) || item.Link == nil || item.Link.Href == "" {
+			continue
+		}
+		wg.Add(1)
+		go func(item *feeds.Item) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			og, err := fetchOpenGraph(item.Link.Href, config.OpenGraphCacheDir, config.Offline)
+			if err != nil {
+				log.Printf("Warning: failed to fetch OpenGraph tags for %s: %v", item.Link.Href, err)
+				return
+			}
+			if og.Description != "" {
+				item.Description = html.EscapeString(og.Description)
+			}
+			if og.Image != "" && itemThumbnail(item) == "" {
+				item.Enclosure = &feeds.Enclosure{Url: og.Image, Type: imageType(og.Image, "")}
+			}
+		}(item)
+	}
+	wg.Wait()
+}
+
+// fetchOpenGraph returns the OpenGraph tags of the page at link, from
+// cacheDir if it was fetched before. Offline, pages that were not are
+// skipped.
+func fetchOpenGraph(link, cacheDir string, offline bool) (openGraph, error) {
+	var og openGraph
+	var cachePath string
+	if cacheDir != "" {
+		sum := sha256.Sum256([]byte(normalizeURL(link)))
+		cachePath = filepath.Join(cacheDir, hex.EncodeToString(sum[:])+".json")
+		if data, err := os.ReadFile(cachePath); err == nil {
+			if err := json.Unmarshal(data, &og); err == nil {
+				return og, nil
+			}
+		}
+	}
+	if offline {
+		return og, nil
+	}
+
+	resp, err := httpClient.Get(link)
+	if err != nil {
+		return og, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return og, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	og = parseOpenGraph(io.LimitReader(resp.Body, 1<<20), resp.Request.URL)
+
+	if cachePath != "" {
+		data, err := json.Marshal(og)
+		if err != nil {
+			return og, err
+		}
+		if err := os.MkdirAll(cacheDir, 0755); err != nil {
+			return og, fmt.Errorf("error creating cache dir: %v", err)
+		}
+		if err := writeFileAtomic(cachePath, data, 0644); err != nil {
+			return og, fmt.Errorf("error writing cache file: %v", err)
+		}
+	}
+
+	return og, nil
+}
+
+// parseOpenGraph reads the og:description, or else the description, and
+// the og:image of the HTML page r, resolving the image against base.
+func parseOpenGraph(r io.Reader, base *url.URL) openGraph {
+	var og openGraph
+	var description string
+	for _, tag := range headTags(r) {
+		if tag.Name != "meta" {
+			continue
+		}
+		content := strings.TrimSpace(tag.Attrs["content"])
+		// Sites use name as often as property for OpenGraph tags.
+		switch strings.ToLower(cmp.Or(tag.Attrs["property"], tag.Attrs["name"])) {
+		case "og:description":
+			if og.Description == "" {
+				og.Description = content
+			}
+		case "description":
+			if description == "" {
+				description = content
+			}
+		case "og:image", "og:image:url", "og:image:secure_url":
+			if og.Image != "" || content == "" {
+				continue
+			}
+			ref, err := url.Parse(content)
+			if err != nil {
+				continue
+			}
+			if image := base.ResolveReference(ref); image.Scheme == "http" || image.Scheme == "https" {
+				og.Image = image.String()
+			}
+		}
+	}
+	og.Description = cmp.Or(og.Description, description)
+	return og
+}
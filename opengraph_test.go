@@ -0,0 +1,87 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gorilla/feeds"
+)
+
+func TestParseOpenGraph(t *testing.T) {
+	page := `<!DOCTYPE html><html><head>
+<meta name="description" content="Plain description">
+<meta property="og:description" content=" Tom &amp; Jerry return ">
+<meta name="og:image" content="/images/cover.jpg">
+<meta property="og:image" content="https://cdn.example.com/second.jpg">
+</head><body><meta property="og:description" content="Too late"></body></html>`
+	base, _ := url.Parse("https://example.com/posts/1")
+
+	og := parseOpenGraph(strings.NewReader(page), base)
+	want := openGraph{Description: "Tom & Jerry return", Image: "https://example.com/images/cover.jpg"}
+	if og != want {
+		t.Errorf("parseOpenGraph() = %+v, want %+v", og, want)
+	}
+
+	og = parseOpenGraph(strings.NewReader(`<head><meta name="Description" content="Only this"><meta property="og:image" content="javascript:alert(1)"></head>`), base)
+	if want := (openGraph{Description: "Only this"}); og != want {
+		t.Errorf("parseOpenGraph() without OpenGraph tags = %+v, want %+v", og, want)
+	}
+}
+
+func TestEnrichOpenGraph(t *testing.T) {
+	var requests atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/bare", func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Write([]byte(`<html><head><meta property="og:description" content="A &lt;summary&gt;"><meta property="og:image" content="/bare.png"></head></html>`))
+	})
+	mux.HandleFunc("/described", func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		t.Errorf("enrichOpenGraph() fetched an item that has a description")
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	cacheDir, err := os.MkdirTemp("", "rss_opengraph")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(cacheDir)
+
+	newItems := func() []*feeds.Item {
+		return []*feeds.Item{
+			{Title: "Bare", Link: &feeds.Link{Href: server.URL + "/bare"}},
+			{Title: "Described", Link: &feeds.Link{Href: server.URL + "/described"}, Description: "Already here"},
+			{Title: "Missing", Link: &feeds.Link{Href: server.URL + "/missing"}},
+		}
+	}
+	config := &Config{OpenGraph: true, OpenGraphWorkers: 2, OpenGraphCacheDir: cacheDir}
+
+	items := newItems()
+	enrichOpenGraph(items, config)
+	if got, want := items[0].Description, "A &lt;summary&gt;"; got != want {
+		t.Errorf("enrichOpenGraph() description = %q, want %q", got, want)
+	}
+	if got, want := itemThumbnail(items[0]), server.URL+"/bare.png"; got != want {
+		t.Errorf("enrichOpenGraph() thumbnail = %q, want %q", got, want)
+	}
+	if items[1].Description != "Already here" || items[2].Description != "" {
+		t.Errorf("enrichOpenGraph() descriptions = %q, %q, want them unchanged", items[1].Description, items[2].Description)
+	}
+
+	before := requests.Load()
+	items = newItems()
+	config.Offline = true
+	enrichOpenGraph(items, config)
+	if items[0].Description != "A &lt;summary&gt;" {
+		t.Errorf("enrichOpenGraph() offline description = %q, want the cached one", items[0].Description)
+	}
+	if got := requests.Load(); got != before {
+		t.Errorf("enrichOpenGraph() offline made %d requests, want none", got-before)
+	}
+}
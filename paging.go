@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/gorilla/feeds"
+)
+
+// archivePage is one RFC 5005 archive document, keyed by the date of its
+// oldest item.
+type archivePage struct {
+	Key   string
+	Items []*feeds.Item
+}
+
+// pageFeed splits an aggregate built with -page-size into the subscription
+// feed, holding the newest -count items, and RFC 5005 archive pages of
+// -page-size items each. Pages are filled from the oldest, so earlier pages
+// keep their items as newer ones arrive; only the newest page may be partly
+// filled. Since pages are named after their oldest item, a page whose items
+// shift when the oldest ones drop out also moves to a new name, and what
+// readers fetched under the old one stays valid.
+func pageFeed(config *Config, feed *feeds.Feed) (*feeds.Feed, []archivePage) {
+	if config.PageSize <= 0 || len(feed.Items) <= config.Count {
+		return feed, nil
+	}
+
+	current := *feed
+	current.Items = feed.Items[:config.Count]
+
+	older := feed.Items[config.Count:]
+	var pages []archivePage
+	used := make(map[string]int)
+	for end := len(older); end > 0; end -= config.PageSize {
+		items := older[max(0, end-config.PageSize):end]
+		key := items[len(items)-1].Created.UTC().Format("20060102T150405Z")
+		// Items published in the same second may start several pages.
+		if used[key]++; used[key] > 1 {
+			key = fmt.Sprintf("%s-%d", key, used[key])
+		}
+		pages = append(pages, archivePage{Key: key, Items: items})
+	}
+	return &current, pages
+}
+
+// archivePageName returns where the archive page with key of the feed at
+// name lives: feed.xml becomes feed-archive-key.xml. name may be a file, a
+// remote -output or a URL.
+func archivePageName(name string, key string) string {
+	base, query, hasQuery := strings.Cut(name, "?")
+	ext := path.Ext(base)
+	name = fmt.Sprintf("%s-archive-%s%s", strings.TrimSuffix(base, ext), key, ext)
+	if hasQuery {
+		name += "?" + query
+	}
+	return name
+}
+
+// subscriptionOptions links the subscription feed to the newest of pages.
+func subscriptionOptions(config *Config, opts RssOptions, pages []archivePage) RssOptions {
+	if len(pages) > 0 {
+		opts.PrevArchiveURL = archivePageName(config.SelfURL, pages[len(pages)-1].Key)
+	}
+	return opts
+}
+
+// archivePageOptions adjusts opts, those of the subscription feed, for page
+// i of pages.
+func archivePageOptions(config *Config, opts RssOptions, pages []archivePage, i int) RssOptions {
+	opts.HubURL = ""
+	opts.Archive = true
+	opts.CurrentURL = config.SelfURL
+	opts.SelfURL = archivePageName(config.SelfURL, pages[i].Key)
+	opts.PrevArchiveURL = ""
+	if i > 0 {
+		opts.PrevArchiveURL = archivePageName(config.SelfURL, pages[i-1].Key)
+	}
+	if i < len(pages)-1 {
+		opts.NextArchiveURL = archivePageName(config.SelfURL, pages[i+1].Key)
+	}
+	return opts
+}
+
+// writeArchivePages writes every archive page next to the output file and
+// returns the names written.
+func writeArchivePages(config *Config, feed *feeds.Feed, pages []archivePage, opts RssOptions) ([]string, error) {
+	var written []string
+	for i, archive := range pages {
+		page := *feed
+		page.Items = archive.Items
+		output := archivePageName(config.OutputFile, archive.Key)
+		if err := outputFeed(&page, output, config.format(), archivePageOptions(config, opts, pages, i)); err != nil {
+			return written, fmt.Errorf("error writing archive page %s: %v", archive.Key, err)
+		}
+		written = append(written, output)
+	}
+	return written, nil
+}
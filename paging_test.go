@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/feeds"
+)
+
+func TestArchivePageName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"out/feed.xml", "out/feed-archive-20240301T000000Z.xml"},
+		{"https://example.com/feed.xml?lang=en", "https://example.com/feed-archive-20240301T000000Z.xml?lang=en"},
+		{"s3://bucket/feed", "s3://bucket/feed-archive-20240301T000000Z"},
+	}
+
+	for _, tt := range tests {
+		if got := archivePageName(tt.name, "20240301T000000Z"); got != tt.want {
+			t.Errorf("archivePageName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPageFeed(t *testing.T) {
+	feed := &feeds.Feed{Title: "Paged"}
+	published := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	for i := 7; i > 0; i-- {
+		// Items 1 and 2 share a date, and so would their pages' names.
+		created := published.Add(time.Duration(max(i, 2)) * time.Hour)
+		feed.Items = append(feed.Items, &feeds.Item{Title: fmt.Sprint(i), Created: created})
+	}
+
+	current, pages := pageFeed(&Config{Count: 2, PageSize: 1}, feed)
+	if got := itemTitles(current.Items); got != "7 6" {
+		t.Errorf("pageFeed() current = %q, want %q", got, "7 6")
+	}
+	var got []string
+	for _, page := range pages {
+		got = append(got, page.Key+" "+itemTitles(page.Items))
+	}
+	want := []string{
+		"20240301T020000Z 1", "20240301T020000Z-2 2", "20240301T030000Z 3",
+		"20240301T040000Z 4", "20240301T050000Z 5",
+	}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("pageFeed() pages = %q, want %q", got, want)
+	}
+
+	if _, pages := pageFeed(&Config{Count: 10, PageSize: 2}, feed); pages != nil {
+		t.Errorf("pageFeed() with all items current should not page, got %d pages", len(pages))
+	}
+}
+
+func itemTitles(items []*feeds.Item) string {
+	var titles []string
+	for _, item := range items {
+		titles = append(titles, item.Title)
+	}
+	return strings.Join(titles, " ")
+}
+
+func TestPublishFeedArchivePages(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "rss_paging")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	published := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	source := &SourceFeed{URL: "https://example.com/feed"}
+	for i := range 5 {
+		source.Items = append(source.Items, &feeds.Item{
+			Title:   fmt.Sprintf("Item %d", i),
+			Link:    &feeds.Link{Href: fmt.Sprintf("https://example.com/%d", i)},
+			Created: published.Add(time.Duration(i) * time.Hour),
+		})
+	}
+
+	config := &Config{
+		Count:      1,
+		PageSize:   2,
+		OutputFile: filepath.Join(tempDir, "feed.xml"),
+		SelfURL:    "https://agg.example/feed.xml",
+	}
+	if err := publishFeed(config, buildFeed(config, []*SourceFeed{source}), nil); err != nil {
+		t.Fatalf("publishFeed() unexpected error = %v", err)
+	}
+
+	tests := []struct {
+		file string
+		want []string
+	}{
+		{"feed.xml", []string{
+			"<title>Item 4</title>",
+			`<atom:link href="https://agg.example/feed-archive-20240301T020000Z.xml" rel="prev-archive"`,
+		}},
+		{"feed-archive-20240301T000000Z.xml", []string{
+			"<fh:archive></fh:archive>",
+			"<title>Item 1</title>", "<title>Item 0</title>",
+			`<atom:link href="https://agg.example/feed.xml" rel="current"`,
+			`<atom:link href="https://agg.example/feed-archive-20240301T020000Z.xml" rel="next-archive"`,
+		}},
+		{"feed-archive-20240301T020000Z.xml", []string{
+			"<title>Item 3</title>", "<title>Item 2</title>",
+			`<atom:link href="https://agg.example/feed-archive-20240301T000000Z.xml" rel="prev-archive"`,
+		}},
+	}
+
+	for _, tt := range tests {
+		content, err := os.ReadFile(filepath.Join(tempDir, tt.file))
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", tt.file, err)
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(string(content), want) {
+				t.Errorf("%s missing %q:\n%s", tt.file, want, content)
+			}
+		}
+	}
+}
+
+func TestDaemonServesArchivePages(t *testing.T) {
+	config := &Config{Count: 1, PageSize: 1, SelfURL: "https://agg.example/feed.xml"}
+	d := newDaemon(config)
+	published := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	d.feed = &feeds.Feed{Title: "Paged", Link: &feeds.Link{Href: "https://agg.example"}, Created: published}
+	for i := 2; i >= 0; i-- {
+		d.feed.Items = append(d.feed.Items, &feeds.Item{
+			Title:   fmt.Sprintf("Item %d", i),
+			Link:    &feeds.Link{Href: fmt.Sprintf("https://example.com/%d", i)},
+			Created: published.Add(time.Duration(i) * time.Hour),
+		})
+	}
+	handler := d.handler()
+
+	tests := []struct {
+		path       string
+		wantStatus int
+		want       string
+	}{
+		{"/feed.xml", http.StatusOK, `href="https://agg.example/feed-archive-20240301T010000Z.xml" rel="prev-archive"`},
+		{"/feed-archive-20240301T010000Z.xml", http.StatusOK, "<title>Item 1</title>"},
+		{"/feed-archive-20240301T000000Z.xml", http.StatusOK, "<title>Item 0</title>"},
+		{"/feed-archive-20240301T020000Z.xml", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", tt.path, nil))
+		if rec.Code != tt.wantStatus {
+			t.Errorf("GET %s = %d, want %d", tt.path, rec.Code, tt.wantStatus)
+		}
+		if !strings.Contains(rec.Body.String(), tt.want) {
+			t.Errorf("GET %s missing %q:\n%s", tt.path, tt.want, rec.Body.String())
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"net/netip"
+	"time"
+)
+
+// pprofHandler serves the runtime profiles of net/http/pprof under
+// /debug/pprof/, without registering them on http.DefaultServeMux. The
+// command line is left out, as it holds passwords and tokens given as
+// flags.
+func pprofHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	return mux
+}
+
+// loopbackAddress reports whether addr, a host and port to listen on, is
+// only reachable from the machine itself.
+func loopbackAddress(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip, err := netip.ParseAddr(host)
+	return err == nil && ip.IsLoopback()
+}
+
+// servePprof serves pprofHandler on addr, apart from -listen so profiles
+// are never exposed with the feed, until the returned server is closed.
+func servePprof(addr string) (*http.Server, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	// No write timeout: CPU profiles and traces take as long as asked.
+	server := &http.Server{Addr: listener.Addr().String(), Handler: pprofHandler(), ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("Warning: pprof server stopped: %v", err)
+		}
+	}()
+	return server, nil
+}
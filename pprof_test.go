@@ -0,0 +1,64 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestServePprof(t *testing.T) {
+	server, err := servePprof("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("servePprof() unexpected error = %v", err)
+	}
+	defer server.Close()
+	addr := server.Addr
+
+	resp, err := http.Get("http://" + addr + "/debug/pprof/goroutine?debug=1")
+	if err != nil {
+		t.Fatalf("GET /debug/pprof/goroutine failed: %v", err)
+	}
+	body := readBody(t, resp)
+	if resp.StatusCode != http.StatusOK || !strings.Contains(body, "goroutine profile") {
+		t.Errorf("GET /debug/pprof/goroutine = %d: %.100s, want a goroutine profile", resp.StatusCode, body)
+	}
+
+	resp, err = http.Get("http://" + addr + "/debug/pprof/cmdline")
+	if err != nil {
+		t.Fatalf("GET /debug/pprof/cmdline failed: %v", err)
+	}
+	if body := readBody(t, resp); strings.Contains(body, "-test") {
+		t.Errorf("GET /debug/pprof/cmdline = %.100s, want no command line", body)
+	}
+
+	resp, err = http.Get("http://" + addr + "/feed.xml")
+	if err != nil {
+		t.Fatalf("GET /feed.xml failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("GET /feed.xml on the pprof server = %d, want 404", resp.StatusCode)
+	}
+}
+
+func TestLoopbackAddress(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"localhost:6060", true},
+		{"127.0.0.1:6060", true},
+		{"[::1]:6060", true},
+		{":6060", false},
+		{"0.0.0.0:6060", false},
+		{"192.168.1.1:6060", false},
+		{"example.com:6060", false},
+		{"localhost", false},
+	}
+
+	for _, tt := range tests {
+		if got := loopbackAddress(tt.addr); got != tt.want {
+			t.Errorf("loopbackAddress(%q) = %v, want %v", tt.addr, got, tt.want)
+		}
+	}
+}
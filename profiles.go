@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// processOptions apply to the whole process rather than one aggregation,
+// so they are only accepted on the command line, not in a profile.
+var processOptions = []string{"profiles", "listen", "tls-domain", "tls-cache", "rate-limit", "max-connections", "auth-basic", "auth-token", "interval", "schedule", "dns-server", "dns-cache", "otlp-endpoint", "pprof", "offline"}
+
+// validProfileName keeps profile names usable as a path segment.
+var validProfileName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// profileSection is one [name] section of a profiles file, with its
+// option = value lines turned into command-line arguments.
+type profileSection struct {
+	Name string
+	Args []string
+}
+
+// loadProfiles reads the profiles file at path and parses each profile as
+// args, the command line, followed by the profile's own options.
+func loadProfiles(path string, args []string) ([]*Config, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening profiles file: %v", err)
+	}
+	defer file.Close()
+
+	sections, err := readProfiles(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if len(sections) == 0 {
+		return nil, fmt.Errorf("%s: no profiles defined", path)
+	}
+
+	var profiles []*Config
+	outputs := make(map[string]string)
+	states := make(map[string]string)
+	for _, section := range sections {
+		flags := flag.NewFlagSet(section.Name, flag.ContinueOnError)
+		flags.SetOutput(io.Discard)
+		// The trailing -profiles= keeps the profile from loading the
+		// profiles file again.
+		profileArgs := append(slices.Concat(args, section.Args), "-profiles=")
+		profile, err := parseConfig(flags, profileArgs)
+		if err != nil {
+			return nil, fmt.Errorf("profile %s: %v", section.Name, err)
+		}
+		profile.Name = section.Name
+		if profile.WebSubCallback != "" {
+			profile.WebSubCallback = strings.TrimSuffix(profile.WebSubCallback, "/") + "/" + profile.Name
+		}
+
+		if other, ok := outputs[profile.OutputFile]; ok {
+			return nil, fmt.Errorf("profiles %s and %s both write %s", other, profile.Name, profile.OutputFile)
+		}
+		outputs[profile.OutputFile] = profile.Name
+		if profile.StateFile != "" {
+			if other, ok := states[profile.StateFile]; ok {
+				return nil, fmt.Errorf("profiles %s and %s share state file %s", other, profile.Name, profile.StateFile)
+			}
+			states[profile.StateFile] = profile.Name
+		}
+
+		profiles = append(profiles, profile)
+	}
+	return profiles, nil
+}
+
+// readProfiles parses a profiles file: each profile starts with a [name]
+// line, followed by option = value lines naming command-line options
+// without the leading dash. Blank lines and lines starting with # are
+// ignored.
+func readProfiles(r io.Reader) ([]profileSection, error) {
+	var sections []profileSection
+	scanner := bufio.NewScanner(r)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if name, ok := strings.CutPrefix(line, "["); ok {
+			name, ok = strings.CutSuffix(name, "]")
+			name = strings.TrimSpace(name)
+			if !ok || !validProfileName.MatchString(name) {
+				return nil, fmt.Errorf("line %d: invalid profile name %q", lineNum, line)
+			}
+			if slices.ContainsFunc(sections, func(s profileSection) bool { return s.Name == name }) {
+				return nil, fmt.Errorf("line %d: profile %s defined twice", lineNum, name)
+			}
+			sections = append(sections, profileSection{Name: name})
+			continue
+		}
+
+		if len(sections) == 0 {
+			return nil, fmt.Errorf("line %d: option outside a [profile] section", lineNum)
+		}
+		option, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected option = value", lineNum)
+		}
+		option = strings.TrimPrefix(strings.TrimSpace(option), "-")
+		if slices.Contains(processOptions, option) {
+			return nil, fmt.Errorf("line %d: %s applies to all profiles and can only be given on the command line", lineNum, option)
+		}
+		section := &sections[len(sections)-1]
+		section.Args = append(section.Args, "-"+option+"="+strings.TrimSpace(value))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return sections, nil
+}
+
+// profiles returns the configurations to aggregate: each profile, or just
+// config itself without -profiles.
+func (config *Config) profiles() []*Config {
+	if len(config.Profiles) == 0 {
+		return []*Config{config}
+	}
+	return config.Profiles
+}
+
+// logPrefix tags messages with the profile they concern, which would be
+// ambiguous with several profiles.
+func (config *Config) logPrefix() string {
+	if config.Name == "" {
+		return ""
+	}
+	return "[" + config.Name + "] "
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadProfiles(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []profileSection
+		wantErr string
+	}{
+		{
+			name: "sections",
+			input: `# Feeds for work
+[tech]
+input = tech.txt
+-count = 20
+
+[news]
+output = news.xml
+fulltext = true
+`,
+			want: []profileSection{
+				{Name: "tech", Args: []string{"-input=tech.txt", "-count=20"}},
+				{Name: "news", Args: []string{"-output=news.xml", "-fulltext=true"}},
+			},
+		},
+		{name: "option before section", input: "count = 5\n[tech]\n", wantErr: "outside"},
+		{name: "missing value", input: "[tech]\nfulltext\n", wantErr: "option = value"},
+		{name: "bad name", input: "[te/ch]\n", wantErr: "invalid profile name"},
+		{name: "duplicate", input: "[tech]\n[tech]\n", wantErr: "defined twice"},
+		{name: "process option", input: "[tech]\nlisten = :8080\n", wantErr: "command line"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readProfiles(strings.NewReader(tt.input))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("readProfiles() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("readProfiles() unexpected error = %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("readProfiles() = %+v, want %+v", got, tt.want)
+			}
+			for i := range got {
+				if got[i].Name != tt.want[i].Name || !slices.Equal(got[i].Args, tt.want[i].Args) {
+					t.Errorf("readProfiles()[%d] = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestLoadProfiles(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "rss_profiles")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	path := filepath.Join(tempDir, "profiles.ini")
+	writeProfiles := func(content string) {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write profiles file: %v", err)
+		}
+	}
+
+	writeProfiles(`[tech]
+output = tech.xml
+count = 20
+
+[news]
+output = news.xml
+`)
+	inputFile := filepath.Join(tempDir, "feeds.txt")
+	if err := os.WriteFile(inputFile, []byte("https://example.com/feed\n"), 0644); err != nil {
+		t.Fatalf("Failed to write input file: %v", err)
+	}
+	args := []string{"-input", inputFile, "-count", "5", "-websub-callback", "https://agg.example.com/", "-interval", "1h", "-listen", ":8080", "-profiles", path}
+	profiles, err := loadProfiles(path, args)
+	if err != nil {
+		t.Fatalf("loadProfiles() unexpected error = %v", err)
+	}
+	if len(profiles) != 2 {
+		t.Fatalf("loadProfiles() returned %d profiles, want 2", len(profiles))
+	}
+
+	tech, news := profiles[0], profiles[1]
+	if tech.Name != "tech" || tech.OutputFile != "tech.xml" || tech.Count != 20 {
+		t.Errorf("tech profile = %s %s %d, want tech tech.xml 20", tech.Name, tech.OutputFile, tech.Count)
+	}
+	if news.Name != "news" || news.OutputFile != "news.xml" || news.Count != 5 {
+		t.Errorf("news profile = %s %s %d, want news news.xml 5 from the command line", news.Name, news.OutputFile, news.Count)
+	}
+	if !slices.Equal(news.InputFiles, []string{inputFile}) || news.Interval != time.Hour || len(news.Profiles) != 0 {
+		t.Errorf("news profile did not inherit the command line: input %v, interval %v, %d profiles", news.InputFiles, news.Interval, len(news.Profiles))
+	}
+	if tech.WebSubCallback != "https://agg.example.com/tech" {
+		t.Errorf("tech WebSub callback = %q, want https://agg.example.com/tech", tech.WebSubCallback)
+	}
+
+	writeProfiles("[tech]\n[news]\n")
+	if _, err := loadProfiles(path, []string{"-demo"}); err == nil || !strings.Contains(err.Error(), "both write") {
+		t.Errorf("loadProfiles() error = %v, want both write", err)
+	}
+
+	writeProfiles("[tech]\ncount = -1\n")
+	if _, err := loadProfiles(path, []string{"-demo"}); err == nil || !strings.Contains(err.Error(), "profile tech") {
+		t.Errorf("loadProfiles() error = %v, want it to name profile tech", err)
+	}
+}
+
+func TestDaemonsHandlerProfiles(t *testing.T) {
+	tech := newDaemon(&Config{Name: "tech"})
+	news := newDaemon(&Config{Name: "news"})
+	news.lastSuccess = time.Now()
+	server := httptest.NewServer(daemonsHandler([]*daemon{tech, news}))
+	defer server.Close()
+
+	tests := []struct {
+		path string
+		want int
+	}{
+		{"/tech/healthz", http.StatusServiceUnavailable},
+		{"/news/healthz", http.StatusOK},
+		{"/healthz", http.StatusNotFound},
+		{"/sports/healthz", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		resp, err := http.Get(server.URL + tt.path)
+		if err != nil {
+			t.Fatalf("GET %s failed: %v", tt.path, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.want {
+			t.Errorf("GET %s = %d, want %d", tt.path, resp.StatusCode, tt.want)
+		}
+	}
+}
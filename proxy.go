@@ -0,0 +1,201 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	// proxyMaxURLs and proxyMaxCount bound the work one /aggregate request
+	// can cause.
+	proxyMaxURLs     = 20
+	proxyMaxCount    = 200
+	proxyDefaultSize = 20
+	// proxyCacheSize is how many fetched feeds the proxy keeps at most.
+	proxyCacheSize = 500
+)
+
+// specialPrefixes are special-purpose ranges that net/netip does not count
+// as private but that are not reachable from the internet either, or that
+// translate to IPv4 addresses which may not be.
+var specialPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),      // "this network"
+	netip.MustParsePrefix("100.64.0.0/10"),  // carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),   // IETF protocol assignments
+	netip.MustParsePrefix("198.18.0.0/15"),  // benchmarking
+	netip.MustParsePrefix("240.0.0.0/4"),    // reserved
+	netip.MustParsePrefix("64:ff9b::/96"),   // NAT64
+	netip.MustParsePrefix("64:ff9b:1::/48"), // local-use NAT64
+	netip.MustParsePrefix("2002::/16"),      // 6to4
+	netip.MustParsePrefix("2001::/32"),      // Teredo
+}
+
+// feedProxy serves /aggregate, which fetches and merges the feeds given in
+// the request rather than the configured sources. It only connects to
+// public addresses, so it cannot be used to reach the server's own network,
+// and reuses each fetched feed for -cache-ttl.
+type feedProxy struct {
+	config *Config
+	client *http.Client
+
+	mu    sync.Mutex
+	cache map[string]proxyCacheEntry
+}
+
+type proxyCacheEntry struct {
+	source    *SourceFeed
+	fetchedAt time.Time
+}
+
+func newFeedProxy(config *Config) *feedProxy {
+	dialer := &net.Dialer{Timeout: 30 * time.Second, Control: publicAddressesOnly}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &feedProxy{
+		config: config,
+		client: &http.Client{Timeout: httpClient.Timeout, Transport: userAgentTransport{transport}},
+		cache:  make(map[string]proxyCacheEntry),
+	}
+}
+
+// publicAddressesOnly refuses connections to loopback, private, link-local
+// and other addresses that are not reachable from the internet. It runs
+// after name resolution, so host names and redirects resolving to such
+// addresses are refused too.
+func publicAddressesOnly(network, address string, c syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	if !publicAddress(ip) {
+		return fmt.Errorf("%s is not a public address", ip)
+	}
+	return nil
+}
+
+// publicAddress reports whether ip is reachable from the internet. IPv4
+// addresses mapped into IPv6 are judged as the IPv4 address.
+func publicAddress(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	if !ip.IsGlobalUnicast() || ip.IsPrivate() {
+		return false
+	}
+	for _, prefix := range specialPrefixes {
+		if prefix.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// ServeHTTP merges the feeds of the url parameters into one feed of the
+// newest count items, in the daemon's output format.
+func (p *feedProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	urls := query["url"]
+	if len(urls) == 0 || len(urls) > proxyMaxURLs {
+		http.Error(w, fmt.Sprintf("between 1 and %d url parameters required", proxyMaxURLs), http.StatusBadRequest)
+		return
+	}
+	for _, rawURL := range urls {
+		if u, err := url.Parse(rawURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			http.Error(w, fmt.Sprintf("url %q must be an http or https URL", rawURL), http.StatusBadRequest)
+			return
+		}
+	}
+	count := proxyDefaultSize
+	if value := query.Get("count"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > proxyMaxCount {
+			http.Error(w, fmt.Sprintf("count must be between 1 and %d", proxyMaxCount), http.StatusBadRequest)
+			return
+		}
+		count = n
+	}
+
+	sources := make([]*SourceFeed, len(urls))
+	errs := make([]error, len(urls))
+	var wg sync.WaitGroup
+	for i, sourceURL := range urls {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sources[i], errs[i] = p.fetch(sourceURL)
+		}()
+	}
+	wg.Wait()
+
+	var fetched []*SourceFeed
+	for i, err := range errs {
+		if err != nil {
+			log.Printf("Warning: aggregate proxy failed to fetch %s: %v", urls[i], err)
+			continue
+		}
+		fetched = append(fetched, sources[i])
+	}
+	if len(fetched) == 0 {
+		http.Error(w, fmt.Sprintf("error fetching %s: %v", urls[0], errs[0]), http.StatusBadGateway)
+		return
+	}
+
+	aggregatedFeed := buildFeed(&Config{Count: count}, fetched)
+	format := p.config.format()
+	w.Header().Set("Content-Type", format.ContentType)
+	if err := format.Write(w, aggregatedFeed, RssOptions{}); err != nil {
+		log.Printf("Warning: failed to serve aggregate: %v", err)
+	}
+}
+
+// fetch returns the feed at sourceURL from the cache when it was fetched
+// less than -cache-ttl ago, and fetches it otherwise.
+func (p *feedProxy) fetch(sourceURL string) (*SourceFeed, error) {
+	key := normalizeURL(sourceURL)
+	p.mu.Lock()
+	entry, ok := p.cache[key]
+	p.mu.Unlock()
+	if ok && time.Since(entry.fetchedAt) < p.config.CacheTTL {
+		return entry.source, nil
+	}
+
+	source, err := downloadSourceFeedWith(p.client, Source{URL: sourceURL}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.cache) >= proxyCacheSize {
+		p.evict()
+	}
+	p.cache[key] = proxyCacheEntry{source: source, fetchedAt: time.Now()}
+	return source, nil
+}
+
+// evict drops expired feeds, or the oldest one when none has expired.
+// p.mu must be held.
+func (p *feedProxy) evict() {
+	var oldest string
+	for key, entry := range p.cache {
+		if time.Since(entry.fetchedAt) >= p.config.CacheTTL {
+			delete(p.cache, key)
+		} else if oldest == "" || entry.fetchedAt.Before(p.cache[oldest].fetchedAt) {
+			oldest = key
+		}
+	}
+	if len(p.cache) >= proxyCacheSize {
+		delete(p.cache, oldest)
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFeedProxy(t *testing.T) {
+	var hits int32
+	source := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.Write([]byte(cacheTestFeed))
+	}))
+	defer source.Close()
+
+	config := &Config{CacheTTL: time.Hour}
+	proxy := newFeedProxy(config)
+	// The test source listens on a loopback address, which the proxy
+	// refuses; fetch it through the plain client instead.
+	proxy.client = httpClient
+	server := httptest.NewServer(proxy)
+	defer server.Close()
+
+	get := func(query string) (int, string) {
+		t.Helper()
+		resp, err := http.Get(server.URL + "/aggregate?" + query)
+		if err != nil {
+			t.Fatalf("GET /aggregate?%s failed: %v", query, err)
+		}
+		return resp.StatusCode, readBody(t, resp)
+	}
+
+	query := url.Values{"url": {source.URL + "/a", source.URL + "/b"}, "count": {"3"}}.Encode()
+	status, body := get(query)
+	if status != http.StatusOK {
+		t.Fatalf("GET /aggregate = %d, want 200: %s", status, body)
+	}
+	if got := strings.Count(body, "<item>"); got != 3 {
+		t.Errorf("GET /aggregate with count=3 returned %d items, want 3", got)
+	}
+	if !strings.Contains(body, "Newer") {
+		t.Errorf("GET /aggregate body = %s, want the newest items", body)
+	}
+
+	get(query)
+	if got := atomic.LoadInt32(&hits); got != 2 {
+		t.Errorf("sources fetched %d times for two requests, want 2 thanks to the cache", got)
+	}
+
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{"no url", ""},
+		{"not http", url.Values{"url": {"file:///etc/passwd"}}.Encode()},
+		{"count too large", url.Values{"url": {source.URL}, "count": {"1000"}}.Encode()},
+		{"count not a number", url.Values{"url": {source.URL}, "count": {"many"}}.Encode()},
+	}
+	for _, tt := range tests {
+		if status, _ := get(tt.query); status != http.StatusBadRequest {
+			t.Errorf("%s: GET /aggregate = %d, want 400", tt.name, status)
+		}
+	}
+}
+
+func TestFeedProxyRefusesLocalAddresses(t *testing.T) {
+	var hits int32
+	source := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.Write([]byte(cacheTestFeed))
+	}))
+	defer source.Close()
+
+	server := httptest.NewServer(newFeedProxy(&Config{CacheTTL: time.Hour}))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/aggregate?" + url.Values{"url": {source.URL}}.Encode())
+	if err != nil {
+		t.Fatalf("GET /aggregate failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadGateway {
+		t.Errorf("GET /aggregate of a loopback source = %d, want 502", resp.StatusCode)
+	}
+	if got := atomic.LoadInt32(&hits); got != 0 {
+		t.Errorf("loopback source fetched %d times, want 0", got)
+	}
+}
+
+func TestPublicAddress(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"10.1.2.3", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"100.64.0.1", false},
+		{"0.0.0.0", false},
+		{"::1", false},
+		{"fd00::1", false},
+		{"fe80::1", false},
+		{"::ffff:127.0.0.1", false},
+		{"::ffff:10.1.2.3", false},
+		{"::ffff:93.184.216.34", true},
+		{"0.1.2.3", false},
+		{"192.0.0.8", false},
+		{"198.18.0.1", false},
+		{"255.255.255.255", false},
+		{"64:ff9b::a01:203", false},
+		{"2002:a01:203::1", false},
+		{"2001:0:4136:e378::1", false},
+	}
+
+	for _, tt := range tests {
+		if got := publicAddress(netip.MustParseAddr(tt.ip)); got != tt.want {
+			t.Errorf("publicAddress(%s) = %v, want %v", tt.ip, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// rateLimiter allows each client a number of requests per period, as a
+// token bucket that holds a full period's worth of requests and refills
+// continuously, so clients can burst but not exceed the rate for long.
+type rateLimiter struct {
+	mu      sync.Mutex
+	burst   float64
+	perSec  float64
+	clients map[string]*rateBucket
+	pruned  time.Time
+	now     func() time.Time
+}
+
+type rateBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(requests int, period time.Duration) *rateLimiter {
+	return &rateLimiter{
+		burst:   float64(requests),
+		perSec:  float64(requests) / period.Seconds(),
+		clients: make(map[string]*rateBucket),
+		now:     time.Now,
+	}
+}
+
+// allow takes a request from client's bucket. When the bucket is empty it
+// returns false and how long until the next request is allowed.
+func (l *rateLimiter) allow(client string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.prune(now)
+
+	bucket, ok := l.clients[client]
+	if !ok {
+		bucket = &rateBucket{tokens: l.burst, last: now}
+		l.clients[client] = bucket
+	}
+	bucket.tokens = min(l.burst, bucket.tokens+now.Sub(bucket.last).Seconds()*l.perSec)
+	bucket.last = now
+
+	if bucket.tokens < 1 {
+		wait := (1 - bucket.tokens) / l.perSec
+		return false, time.Duration(wait * float64(time.Second))
+	}
+	bucket.tokens--
+	return true, 0
+}
+
+// prune forgets, at most once a minute, the clients whose buckets have
+// refilled, which behave the same as new clients. l.mu must be held.
+func (l *rateLimiter) prune(now time.Time) {
+	if now.Sub(l.pruned) < time.Minute {
+		return
+	}
+	l.pruned = now
+	for client, bucket := range l.clients {
+		if bucket.tokens+now.Sub(bucket.last).Seconds()*l.perSec >= l.burst {
+			delete(l.clients, client)
+		}
+	}
+}
+
+// limitRequests answers 429 Too Many Requests to clients, by IP address,
+// that exceed the limiter's rate.
+func limitRequests(limiter *rateLimiter, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		client, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			client = r.RemoteAddr
+		}
+		if ok, wait := limiter.allow(client); !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			http.Error(w, "too many requests", http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
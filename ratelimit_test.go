@@ -0,0 +1,66 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	limiter := newRateLimiter(3, time.Minute)
+	limiter.now = func() time.Time { return now }
+
+	for i := range 3 {
+		if ok, _ := limiter.allow("192.0.2.1"); !ok {
+			t.Fatalf("allow() request %d of the burst = false, want true", i+1)
+		}
+	}
+	ok, wait := limiter.allow("192.0.2.1")
+	if ok || wait != 20*time.Second {
+		t.Errorf("allow() past the burst = %v, %v, want false, 20s", ok, wait)
+	}
+	if ok, _ := limiter.allow("192.0.2.2"); !ok {
+		t.Errorf("allow() for another client = false, want true")
+	}
+
+	now = now.Add(20 * time.Second)
+	if ok, _ := limiter.allow("192.0.2.1"); !ok {
+		t.Errorf("allow() after refilling one request = false, want true")
+	}
+	if ok, _ := limiter.allow("192.0.2.1"); ok {
+		t.Errorf("allow() right after = true, want false")
+	}
+
+	now = now.Add(2 * time.Minute)
+	limiter.allow("192.0.2.3")
+	if _, ok := limiter.clients["192.0.2.2"]; ok {
+		t.Errorf("client with a full bucket still tracked after pruning")
+	}
+}
+
+func TestLimitRequests(t *testing.T) {
+	handler := limitRequests(newRateLimiter(1, time.Minute), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	tests := []struct {
+		remoteAddr string
+		wantStatus int
+	}{
+		{"192.0.2.1:1234", http.StatusOK},
+		{"192.0.2.1:5678", http.StatusTooManyRequests},
+		{"192.0.2.2:1234", http.StatusOK},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/feed.xml", nil)
+		req.RemoteAddr = tt.remoteAddr
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tt.wantStatus {
+			t.Errorf("request from %s = %d, want %d", tt.remoteAddr, rec.Code, tt.wantStatus)
+		}
+		if rec.Code == http.StatusTooManyRequests && rec.Header().Get("Retry-After") != "60" {
+			t.Errorf("Retry-After = %q, want 60", rec.Header().Get("Retry-After"))
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/gorilla/feeds"
+)
+
+// readerRetention is how long the reader store remembers an item that is
+// no longer in any source, so that a source failing for a while, or
+// dropping an item only to list it again, does not lose its id and flags.
+const readerRetention = 30 * 24 * time.Hour
+
+// readerStore holds what the reader APIs (Fever and Google Reader) need
+// beyond the aggregate: stable numeric ids for items and feeds, and which
+// items were read or saved. Item ids increase in the order items are first
+// seen, as clients fetch new items by id.
+type readerStore struct {
+	mu   sync.Mutex
+	path string
+
+	NextID int64            `json:"next_id"`
+	Items  map[string]int64 `json:"items"`
+	Feeds  map[string]int64 `json:"feeds"`
+	Read   map[int64]bool   `json:"read,omitempty"`
+	Saved  map[int64]bool   `json:"saved,omitempty"`
+	// Gone is when each item last went missing from every source.
+	Gone map[int64]time.Time `json:"gone,omitempty"`
+}
+
+// loadReaderStore reads the store from path, or starts an empty one when
+// path is empty, keeping it in memory only, or does not exist yet.
+func loadReaderStore(path string) (*readerStore, error) {
+	store := &readerStore{
+		path:   path,
+		NextID: 1,
+		Items:  make(map[string]int64),
+		Feeds:  make(map[string]int64),
+		Read:   make(map[int64]bool),
+		Saved:  make(map[int64]bool),
+		Gone:   make(map[int64]time.Time),
+	}
+	if path == "" {
+		return store, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading reader state file: %v", err)
+	}
+	if err := json.Unmarshal(data, store); err != nil {
+		return nil, fmt.Errorf("error parsing reader state file: %v", err)
+	}
+	for _, m := range []*map[int64]bool{&store.Read, &store.Saved} {
+		if *m == nil {
+			*m = make(map[int64]bool)
+		}
+	}
+	if store.Gone == nil {
+		store.Gone = make(map[int64]time.Time)
+	}
+	return store, nil
+}
+
+// save writes the store atomically. It does nothing for a store kept in
+// memory. s.mu must be held.
+func (s *readerStore) save() error {
+	if s.path == "" {
+		return nil
+	}
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	if err := writeFileAtomic(s.path, data, 0600); err != nil {
+		return fmt.Errorf("error writing reader state file: %v", err)
+	}
+	return nil
+}
+
+// readerItem is an item of the aggregate with the ids the reader APIs know
+// it by.
+type readerItem struct {
+	*feeds.Item
+	ID     int64
+	FeedID int64
+	Source *SourceFeed
+}
+
+// sync assigns ids to the items and sources not seen before, oldest first,
+// and forgets items that have been in no source for readerRetention, unless
+// they are saved. Items
+// are only counted missing when prune is set, so that sources not fetched
+// yet after a restart do not look empty. It returns the items in id order.
+// s.mu must be held.
+func (s *readerStore) sync(sources []*SourceFeed, now time.Time, prune bool) []readerItem {
+	var items []readerItem
+	changed := false
+	current := make(map[string]bool)
+	for _, source := range sources {
+		feedKey := normalizeURL(source.URL)
+		if _, ok := s.Feeds[feedKey]; !ok {
+			s.Feeds[feedKey] = s.NextID
+			s.NextID++
+			changed = true
+		}
+		for _, item := range source.Items {
+			key := itemKey(item)
+			if current[key] {
+				continue
+			}
+			current[key] = true
+			items = append(items, readerItem{Item: item, ID: s.Items[key], FeedID: s.Feeds[feedKey], Source: source})
+		}
+	}
+
+	slices.SortStableFunc(items, func(a, b readerItem) int {
+		return a.Created.Compare(b.Created)
+	})
+	for i := range items {
+		if items[i].ID == 0 {
+			items[i].ID = s.NextID
+			s.Items[itemKey(items[i].Item)] = s.NextID
+			s.NextID++
+			changed = true
+		}
+	}
+	slices.SortFunc(items, func(a, b readerItem) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+
+	for key, id := range s.Items {
+		gone, isGone := s.Gone[id]
+		switch {
+		case current[key]:
+			if isGone {
+				delete(s.Gone, id)
+				changed = true
+			}
+		case !prune:
+		case !isGone:
+			s.Gone[id] = now
+			changed = true
+		case now.Sub(gone) >= readerRetention && !s.Saved[id]:
+			delete(s.Items, key)
+			delete(s.Read, id)
+			delete(s.Gone, id)
+			changed = true
+		}
+	}
+
+	if changed {
+		if err := s.save(); err != nil {
+			log.Printf("Warning: failed to save reader state: %v", err)
+		}
+	}
+	return items
+}
+
+// readerItems returns the daemon's current sources and their items, synced
+// with the reader store. Until the first refresh succeeds, the sources may
+// not have been fetched yet, so no item is counted missing.
+func (d *daemon) readerItems() ([]*SourceFeed, []readerItem) {
+	d.mu.Lock()
+	refreshed := !d.lastSuccess.IsZero()
+	sources := make([]*SourceFeed, 0, len(d.order))
+	for _, url := range d.order {
+		if source, ok := d.sources[url]; ok {
+			sources = append(sources, source)
+		}
+	}
+	d.mu.Unlock()
+
+	d.reader.mu.Lock()
+	defer d.reader.mu.Unlock()
+	return sources, d.reader.sync(sources, time.Now(), refreshed)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gorilla/feeds"
+)
+
+func TestReaderStoreSyncRetention(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	item := &feeds.Item{Title: "a", Link: &feeds.Link{Href: "https://example.com/a"}, Created: now}
+	source := &SourceFeed{URL: "https://example.com/feed", Items: []*feeds.Item{item}}
+
+	store, err := loadReaderStore("")
+	if err != nil {
+		t.Fatalf("loadReaderStore() unexpected error = %v", err)
+	}
+	items := store.sync([]*SourceFeed{source}, now, true)
+	if len(items) != 1 {
+		t.Fatalf("sync() = %d items, want 1", len(items))
+	}
+	id := items[0].ID
+	store.Read[id] = true
+
+	// Before the first refresh, sources look empty but nothing is missing.
+	empty := &SourceFeed{URL: source.URL}
+	store.sync([]*SourceFeed{empty}, now.Add(2*readerRetention), false)
+	if _, ok := store.Gone[id]; ok || store.Items[itemKey(item)] != id {
+		t.Errorf("sync() before a refresh marked item %d gone", id)
+	}
+
+	store.sync([]*SourceFeed{empty}, now, true)
+	store.sync([]*SourceFeed{empty}, now.Add(readerRetention-time.Hour), true)
+	if store.Items[itemKey(item)] != id || !store.Read[id] {
+		t.Errorf("sync() forgot item %d before the retention window", id)
+	}
+
+	// An item listed again keeps its id and flags.
+	if items := store.sync([]*SourceFeed{source}, now.Add(readerRetention), true); items[0].ID != id || !store.Read[id] {
+		t.Errorf("sync() after the item came back = id %d, read %v, want %d, true", items[0].ID, store.Read[id], id)
+	}
+	if _, ok := store.Gone[id]; ok {
+		t.Errorf("sync() kept item %d gone after it came back", id)
+	}
+
+	// Saved items are never forgotten.
+	store.Saved[id] = true
+	store.sync([]*SourceFeed{empty}, now, true)
+	store.sync([]*SourceFeed{empty}, now.Add(readerRetention), true)
+	if store.Items[itemKey(item)] != id || !store.Saved[id] {
+		t.Errorf("sync() forgot saved item %d", id)
+	}
+
+	delete(store.Saved, id)
+	store.sync([]*SourceFeed{empty}, now.Add(readerRetention), true)
+	if _, ok := store.Items[itemKey(item)]; ok || store.Read[id] {
+		t.Errorf("sync() kept item %d past the retention window", id)
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+// reportMovedSources logs the sources that permanently redirect elsewhere
+// and, with -fix-redirects, updates their lines in local input files to the
+// new URL, so the list does not keep accumulating stale URLs.
+func reportMovedSources(config *Config, entries []Source, sources []*SourceFeed) {
+	lists := make(map[string]string)
+	for _, entry := range entries {
+		lists[entry.URL] = entry.List
+	}
+
+	moves := make(map[string]map[string]string)
+	for _, source := range sources {
+		if source.MovedTo == "" || source.MovedTo == source.URL {
+			continue
+		}
+		list := lists[source.URL]
+		if !config.FixRedirects || list == "" || list == "-" || isRemoteList(list) {
+			log.Printf("Feed %s has moved permanently to %s; update it in %s or run with -fix-redirects", source.URL, source.MovedTo, list)
+			continue
+		}
+		if moves[list] == nil {
+			moves[list] = make(map[string]string)
+		}
+		moves[list][source.URL] = source.MovedTo
+	}
+
+	for list, moved := range moves {
+		if err := rewriteSourceURLs(list, moved); err != nil {
+			log.Printf("Warning: failed to update moved feeds in %s: %v", list, err)
+			continue
+		}
+		for from, to := range moved {
+			log.Printf("Feed %s has moved permanently; updated %s to %s", from, list, to)
+		}
+	}
+}
+
+// rewriteSourceURLs replaces the URL of each source line in the input file
+// at path that moved lists, keeping its options, comments and layout.
+func rewriteSourceURLs(path string, moved map[string]string) error {
+	return editInputFile(path, func(lines []string) ([]string, error) {
+		for i, line := range lines {
+			url := sourceLineURL(line)
+			if to, ok := moved[url]; ok {
+				lines[i] = strings.Replace(line, url, to, 1)
+			}
+		}
+		return lines, nil
+	})
+}
+
+// sourceLineURL returns the URL of a source line in an input file, or ""
+// for comments, blank lines and @include lines.
+func sourceLineURL(line string) string {
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "" || strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, "@include ") {
+		return ""
+	}
+	return strings.Fields(trimmed)[0]
+}
+
+// editInputFile rewrites the input file at path with the lines, each with
+// its line ending, that edit returns for its current ones.
+func editInputFile(path string, edit func(lines []string) ([]string, error)) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	lines, err := edit(strings.SplitAfter(string(data), "\n"))
+	if err != nil {
+		return err
+	}
+
+	// Replace the file in one step so a daemon watching it never reads a
+	// partial list.
+	if err := writeFileAtomic(path, []byte(strings.Join(lines, "")), info.Mode().Perm()); err != nil {
+		return fmt.Errorf("error writing input file: %v", err)
+	}
+	return nil
+}
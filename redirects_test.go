@@ -0,0 +1,72 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFetchSourcesFixRedirects(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/old", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/new", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/temporary", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/current", http.StatusFound)
+	})
+	for _, path := range []string{"/new", "/current"} {
+		mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(cacheTestFeed))
+		})
+	}
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	tempDir, err := os.MkdirTemp("", "rss_redirects")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	input := filepath.Join(tempDir, "feeds.txt")
+	content := "# moved\n" + server.URL + "/old owner=me\n" + server.URL + "/temporary\n"
+	if err := os.WriteFile(input, []byte(content), 0600); err != nil {
+		t.Fatalf("Failed to write input file: %v", err)
+	}
+
+	for _, fix := range []bool{false, true} {
+		entries, err := readSourcesFromFiles([]string{input})
+		if err != nil {
+			t.Fatalf("readSourcesFromFiles() unexpected error = %v", err)
+		}
+		sources, _ := fetchSources(&Config{FixRedirects: fix}, entries)
+
+		for _, source := range sources {
+			want := ""
+			if source.URL == server.URL+"/old" {
+				want = server.URL + "/new"
+			}
+			if source.MovedTo != want {
+				t.Errorf("fetchSources() %s moved to %q, want %q", source.URL, source.MovedTo, want)
+			}
+		}
+
+		data, err := os.ReadFile(input)
+		if err != nil {
+			t.Fatalf("Failed to read input file: %v", err)
+		}
+		want := content
+		if fix {
+			want = "# moved\n" + server.URL + "/new owner=me\n" + server.URL + "/temporary\n"
+		}
+		if string(data) != want {
+			t.Errorf("input file with fix-redirects=%v = %q, want %q", fix, data, want)
+		}
+	}
+
+	if info, err := os.Stat(input); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("rewritten input file mode = %v, want 0600 kept", info.Mode().Perm())
+	}
+}
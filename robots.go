@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+const (
+	// robotsUserAgent is the product token matched against User-agent
+	// lines, and the one userAgent starts with.
+	robotsUserAgent = "rss-agg"
+	// userAgent is sent with every request that does not set its own, so
+	// that sites can tell what fetches them and address it in robots.txt.
+	userAgent = robotsUserAgent + "/1.0 (+https://github.com/lourencovales/go-rss-agg)"
+)
+
+// userAgentTransport sets the User-Agent header of requests without one.
+type userAgentTransport struct {
+	base http.RoundTripper
+}
+
+func (t userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("User-Agent") == "" {
+		req = req.Clone(req.Context())
+		req.Header.Set("User-Agent", userAgent)
+	}
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return base.RoundTrip(req)
+}
+
+// robotsRule is one Allow or Disallow line of the group that applies to us.
+type robotsRule struct {
+	allow   bool
+	pattern string
+}
+
+// robotsChecker fetches each host's robots.txt once and answers whether a
+// URL may be fetched, following RFC 9309.
+type robotsChecker struct {
+	mu    sync.Mutex
+	hosts map[string]*robotsHost
+}
+
+type robotsHost struct {
+	once  sync.Once
+	rules []robotsRule
+}
+
+func newRobotsChecker() *robotsChecker {
+	return &robotsChecker{hosts: make(map[string]*robotsHost)}
+}
+
+func (c *robotsChecker) allowed(target string) bool {
+	u, err := url.Parse(target)
+	if err != nil || u.Host == "" {
+		return false
+	}
+
+	origin := u.Scheme + "://" + u.Host
+	c.mu.Lock()
+	host, ok := c.hosts[origin]
+	if !ok {
+		host = &robotsHost{}
+		c.hosts[origin] = host
+	}
+	c.mu.Unlock()
+
+	host.once.Do(func() { host.rules = fetchRobotsRules(origin) })
+	return robotsAllowed(host.rules, u.EscapedPath()+queryPart(u))
+}
+
+func queryPart(u *url.URL) string {
+	if u.RawQuery == "" {
+		return ""
+	}
+	return "?" + u.RawQuery
+}
+
+// fetchRobotsRules treats a missing robots.txt as allowing everything and
+// an unreachable one as disallowing everything.
+func fetchRobotsRules(origin string) []robotsRule {
+	disallowAll := []robotsRule{{allow: false, pattern: "/"}}
+
+	resp, err := httpClient.Get(origin + "/robots.txt")
+	if err != nil {
+		return disallowAll
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 500 {
+		return disallowAll
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil
+	}
+	return parseRobots(io.LimitReader(resp.Body, 500<<10), robotsUserAgent)
+}
+
+// parseRobots returns the rules of the group naming agent, or of the "*"
+// group when none does.
+func parseRobots(r io.Reader, agent string) []robotsRule {
+	var specific, general []robotsRule
+	var inSpecific, inGeneral, foundSpecific bool
+	readingAgents := false
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			if !readingAgents {
+				inSpecific, inGeneral = false, false
+				readingAgents = true
+			}
+			if strings.EqualFold(value, agent) {
+				inSpecific, foundSpecific = true, true
+			} else if value == "*" {
+				inGeneral = true
+			}
+		case "allow", "disallow":
+			readingAgents = false
+			if value == "" {
+				continue
+			}
+			rule := robotsRule{allow: key == "allow", pattern: value}
+			if inSpecific {
+				specific = append(specific, rule)
+			}
+			if inGeneral {
+				general = append(general, rule)
+			}
+		default:
+			readingAgents = false
+		}
+	}
+
+	if foundSpecific {
+		return specific
+	}
+	return general
+}
+
+// robotsAllowed applies the most specific matching rule, preferring Allow
+// when an Allow and a Disallow rule are equally specific.
+func robotsAllowed(rules []robotsRule, path string) bool {
+	best, allowed := -1, true
+	for _, rule := range rules {
+		if !robotsMatch(rule.pattern, path) {
+			continue
+		}
+		if len(rule.pattern) > best || (len(rule.pattern) == best && rule.allow) {
+			best, allowed = len(rule.pattern), rule.allow
+		}
+	}
+	return allowed
+}
+
+// robotsMatch matches path against a pattern where * stands for any
+// characters and a trailing $ anchors the end.
+func robotsMatch(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	rest := path[len(parts[0]):]
+	for _, part := range parts[1:] {
+		i := strings.Index(rest, part)
+		if i < 0 {
+			return false
+		}
+		rest = rest[i+len(part):]
+	}
+	if anchored && rest != "" {
+		// The last part must end the path; retry it at the end.
+		last := parts[len(parts)-1]
+		return len(parts) > 1 && strings.HasSuffix(path, last)
+	}
+	return true
+}
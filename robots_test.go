@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/feeds"
+)
+
+func TestParseRobots(t *testing.T) {
+	robots := `# comment
+User-agent: *
+Disallow: /private/
+
+User-agent: Googlebot
+User-agent: RSS-Agg
+Disallow: /
+Allow: /articles/ # ours
+Sitemap: https://example.com/sitemap.xml
+`
+
+	tests := []struct {
+		agent string
+		want  []robotsRule
+	}{
+		{"rss-agg", []robotsRule{{false, "/"}, {true, "/articles/"}}},
+		{"other", []robotsRule{{false, "/private/"}}},
+	}
+
+	for _, tt := range tests {
+		got := parseRobots(strings.NewReader(robots), tt.agent)
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("parseRobots(%q) = %v, want %v", tt.agent, got, tt.want)
+		}
+	}
+}
+
+func TestRobotsAllowed(t *testing.T) {
+	rules := []robotsRule{
+		{false, "/"},
+		{true, "/articles/"},
+		{false, "/articles/*.pdf$"},
+		{true, "/page"},
+		{false, "/page"},
+	}
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"/", false},
+		{"/about", false},
+		{"/articles/one", true},
+		{"/articles/one.pdf", false},
+		{"/articles/one.pdf?x=1", true},
+		{"/page", true},
+	}
+
+	for _, tt := range tests {
+		if got := robotsAllowed(rules, tt.path); got != tt.want {
+			t.Errorf("robotsAllowed(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+
+	if !robotsAllowed(nil, "/anything") {
+		t.Errorf("robotsAllowed(nil) = false, want true")
+	}
+}
+
+func TestRobotsMatch(t *testing.T) {
+	tests := []struct {
+		pattern, path string
+		want          bool
+	}{
+		{"/a", "/a/b", true},
+		{"/a", "/b", false},
+		{"/a$", "/a", true},
+		{"/a$", "/ab", false},
+		{"/*.gif$", "/x/y.gif", true},
+		{"/*.gif$", "/x.gif.html", false},
+		{"/*/c", "/a/b/c", true},
+		{"*", "/anything", true},
+	}
+
+	for _, tt := range tests {
+		if got := robotsMatch(tt.pattern, tt.path); got != tt.want {
+			t.Errorf("robotsMatch(%q, %q) = %v, want %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestEnrichFullTextRobots(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			fmt.Fprint(w, "User-agent: *\nDisallow: /private/\n")
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, articleHTML)
+	}))
+	defer server.Close()
+
+	items := []*feeds.Item{
+		{Title: "Public", Link: &feeds.Link{Href: server.URL + "/public/article"}, Description: "Summary"},
+		{Title: "Private", Link: &feeds.Link{Href: server.URL + "/private/article"}, Description: "Summary"},
+	}
+	enrichFullText(items, &Config{FullTextWorkers: 2, FullTextRobots: true})
+
+	if !strings.Contains(items[0].Content, "The Headline") {
+		t.Errorf("enrichFullText() allowed content = %q, want extracted article", items[0].Content)
+	}
+	if items[1].Content != "" {
+		t.Errorf("enrichFullText() disallowed content = %q, want empty", items[1].Content)
+	}
+}
+
+func TestFetchRobotsRulesStatus(t *testing.T) {
+	tests := []struct {
+		status int
+		want   bool
+	}{
+		{http.StatusNotFound, true},
+		{http.StatusServiceUnavailable, false},
+	}
+
+	for _, tt := range tests {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tt.status)
+		}))
+		if got := newRobotsChecker().allowed(server.URL + "/page"); got != tt.want {
+			t.Errorf("allowed() with robots.txt status %d = %v, want %v", tt.status, got, tt.want)
+		}
+		server.Close()
+	}
+}
+
+func TestUserAgent(t *testing.T) {
+	var got []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.UserAgent())
+	}))
+	defer server.Close()
+
+	if !strings.HasPrefix(userAgent, robotsUserAgent+"/") {
+		t.Errorf("userAgent = %q, want it to start with the robots.txt token %q", userAgent, robotsUserAgent)
+	}
+
+	resp, err := httpClient.Get(server.URL)
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	resp.Body.Close()
+
+	req, _ := http.NewRequest("GET", server.URL, nil)
+	req.Header.Set("User-Agent", "custom/1.0")
+	if resp, err = httpClient.Do(req); err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if want := []string{userAgent, "custom/1.0"}; strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("User-Agent sent = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,385 @@
+package main
+
+import (
+	"bufio"
+	"cmp"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"net/url"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/feeds"
+)
+
+const (
+	contentNamespace     = "http://purl.org/rss/1.0/modules/content/"
+	atomNamespace        = "http://www.w3.org/2005/Atom"
+	syndicationNamespace = "http://purl.org/rss/1.0/modules/syndication/"
+	historyNamespace     = "http://purl.org/syndication/history/1.0"
+)
+
+// RssOptions controls details of the RSS output that gorilla/feeds does not
+// expose.
+type RssOptions struct {
+	// TTL advertises how long readers may cache the feed before polling again.
+	TTL time.Duration
+	// SelfURL is the public URL of the feed itself.
+	SelfURL string
+	// HubURL is the WebSub hub subscribers should use for push updates.
+	HubURL string
+
+	// OmitContent drops content:encoded, leaving only descriptions.
+	OmitContent bool
+	// GUID selects each item's guid: "" or "source" keeps the source's,
+	// "link" uses the item link as a permalink guid, and "none" omits guids.
+	GUID string
+	// LastBuildDate adds the time the aggregate was built as lastBuildDate.
+	LastBuildDate bool
+	// Compact writes the XML without line breaks or indentation.
+	Compact bool
+	// Indent is the indentation per nesting level; empty means two spaces.
+	Indent string
+	// Stylesheet is referenced in an xml-stylesheet processing instruction
+	// so browsers render the feed as a page.
+	Stylesheet string
+
+	// UpdatePeriod and UpdateFrequency are the syndication module hints:
+	// the feed updates UpdateFrequency times per UpdatePeriod.
+	UpdatePeriod    string
+	UpdateFrequency int
+	// SkipHours (0-23, GMT) and SkipDays tell aggregators when not to poll.
+	SkipHours []int
+	SkipDays  []string
+
+	// Archive marks the document as an RFC 5005 archive page, whose
+	// subscription feed is at CurrentURL.
+	Archive    bool
+	CurrentURL string
+	// PrevArchiveURL and NextArchiveURL link to the archive pages holding
+	// the next older and newer items.
+	PrevArchiveURL string
+	NextArchiveURL string
+
+	// Categories are added to items as <category> elements, by the URL of
+	// the source each item came from.
+	Categories map[string][]string
+}
+
+type rssSkipHours struct {
+	XMLName xml.Name `xml:"skipHours"`
+	Hours   []int    `xml:"hour"`
+}
+
+type rssSkipDays struct {
+	XMLName xml.Name `xml:"skipDays"`
+	Days    []string `xml:"day"`
+}
+
+var weekdays = []string{"Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday", "Sunday"}
+
+// parseSkipHours parses a comma-separated list of hours for SkipHours.
+func parseSkipHours(value string) ([]int, error) {
+	var hours []int
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		hour, err := strconv.Atoi(field)
+		if err != nil || hour < 0 || hour > 23 {
+			return nil, fmt.Errorf("%q is not an hour from 0 to 23", field)
+		}
+		hours = append(hours, hour)
+	}
+	return hours, nil
+}
+
+// parseSkipDays parses a comma-separated list of weekday names for SkipDays.
+func parseSkipDays(value string) ([]string, error) {
+	var days []string
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		i := slices.IndexFunc(weekdays, func(day string) bool { return strings.EqualFold(day, field) })
+		if i < 0 {
+			return nil, fmt.Errorf("%q is not a day of the week", field)
+		}
+		days = append(days, weekdays[i])
+	}
+	return days, nil
+}
+
+type atomLink struct {
+	XMLName xml.Name `xml:"atom:link"`
+	Href    string   `xml:"href,attr"`
+	Rel     string   `xml:"rel,attr"`
+	Type    string   `xml:"type,attr,omitempty"`
+}
+
+func (opts RssOptions) atomLinks() []atomLink {
+	var links []atomLink
+	if opts.SelfURL != "" {
+		links = append(links, atomLink{Href: opts.SelfURL, Rel: "self", Type: "application/rss+xml"})
+	}
+	if opts.HubURL != "" {
+		links = append(links, atomLink{Href: opts.HubURL, Rel: "hub"})
+	}
+	if opts.CurrentURL != "" {
+		links = append(links, atomLink{Href: opts.CurrentURL, Rel: "current", Type: "application/rss+xml"})
+	}
+	if opts.PrevArchiveURL != "" {
+		links = append(links, atomLink{Href: opts.PrevArchiveURL, Rel: "prev-archive", Type: "application/rss+xml"})
+	}
+	if opts.NextArchiveURL != "" {
+		links = append(links, atomLink{Href: opts.NextArchiveURL, Rel: "next-archive", Type: "application/rss+xml"})
+	}
+	return links
+}
+
+// writeRss encodes the channel header of feed followed by each of its
+// items, one element at a time, rather than building the whole document as
+// gorilla's ToRss does, so that the options can add elements it lacks.
+func writeRss(w io.Writer, feed *feeds.Feed, opts RssOptions) error {
+	header := *feed
+	header.Items = nil
+	channel := (&feeds.Rss{Feed: &header}).RssFeed()
+	if opts.TTL > 0 {
+		channel.Ttl = int((opts.TTL + time.Minute - 1) / time.Minute)
+	}
+	if opts.LastBuildDate && channel.LastBuildDate == "" && !feed.Created.IsZero() {
+		channel.LastBuildDate = feed.Created.Format(time.RFC1123Z)
+	}
+
+	bw := bufio.NewWriter(w)
+	if _, err := bw.WriteString(xml.Header); err != nil {
+		return err
+	}
+	if opts.Stylesheet != "" {
+		if _, err := bw.WriteString(stylesheetInstruction(opts.Stylesheet)); err != nil {
+			return err
+		}
+	}
+
+	enc := xml.NewEncoder(bw)
+	if !opts.Compact {
+		enc.Indent("", cmp.Or(opts.Indent, "  "))
+	}
+
+	rssStart := xml.StartElement{
+		Name: xml.Name{Local: "rss"},
+		Attr: []xml.Attr{
+			{Name: xml.Name{Local: "version"}, Value: "2.0"},
+		},
+	}
+	if !opts.OmitContent {
+		rssStart.Attr = append(rssStart.Attr, xml.Attr{Name: xml.Name{Local: "xmlns:content"}, Value: contentNamespace})
+	}
+	links := opts.atomLinks()
+	if len(links) > 0 {
+		rssStart.Attr = append(rssStart.Attr, xml.Attr{Name: xml.Name{Local: "xmlns:atom"}, Value: atomNamespace})
+	}
+	if opts.UpdatePeriod != "" || opts.UpdateFrequency > 0 {
+		rssStart.Attr = append(rssStart.Attr, xml.Attr{Name: xml.Name{Local: "xmlns:sy"}, Value: syndicationNamespace})
+	}
+	if opts.Archive {
+		rssStart.Attr = append(rssStart.Attr, xml.Attr{Name: xml.Name{Local: "xmlns:fh"}, Value: historyNamespace})
+	}
+	channelStart := xml.StartElement{Name: xml.Name{Local: "channel"}}
+
+	if err := enc.EncodeToken(rssStart); err != nil {
+		return err
+	}
+	if err := enc.EncodeToken(channelStart); err != nil {
+		return err
+	}
+	if err := encodeChannelHeader(enc, channel); err != nil {
+		return err
+	}
+	for _, link := range links {
+		if err := enc.Encode(link); err != nil {
+			return err
+		}
+	}
+	if err := encodeUpdateHints(enc, opts); err != nil {
+		return err
+	}
+	if opts.Archive {
+		archive := xml.StartElement{Name: xml.Name{Local: "fh:archive"}}
+		if err := enc.EncodeToken(archive); err != nil {
+			return err
+		}
+		if err := enc.EncodeToken(archive.End()); err != nil {
+			return err
+		}
+	}
+
+	for _, item := range feed.Items {
+		if err := enc.Encode(toRssItem(item, opts)); err != nil {
+			return err
+		}
+	}
+
+	if err := enc.EncodeToken(channelStart.End()); err != nil {
+		return err
+	}
+	if err := enc.EncodeToken(rssStart.End()); err != nil {
+		return err
+	}
+	if err := enc.Flush(); err != nil {
+		return err
+	}
+
+	return bw.Flush()
+}
+
+func encodeUpdateHints(enc *xml.Encoder, opts RssOptions) error {
+	if len(opts.SkipHours) > 0 {
+		if err := enc.Encode(rssSkipHours{Hours: opts.SkipHours}); err != nil {
+			return err
+		}
+	}
+	if len(opts.SkipDays) > 0 {
+		if err := enc.Encode(rssSkipDays{Days: opts.SkipDays}); err != nil {
+			return err
+		}
+	}
+	if opts.UpdatePeriod != "" {
+		if err := enc.EncodeElement(opts.UpdatePeriod, xml.StartElement{Name: xml.Name{Local: "sy:updatePeriod"}}); err != nil {
+			return err
+		}
+	}
+	if opts.UpdateFrequency > 0 {
+		if err := enc.EncodeElement(opts.UpdateFrequency, xml.StartElement{Name: xml.Name{Local: "sy:updateFrequency"}}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func stylesheetInstruction(href string) string {
+	styleType := "text/xsl"
+	if u, err := url.Parse(href); err == nil && strings.EqualFold(path.Ext(u.Path), ".css") {
+		styleType = "text/css"
+	}
+	return fmt.Sprintf("<?xml-stylesheet type=%q href=\"%s\"?>\n", styleType, html.EscapeString(href))
+}
+
+func encodeChannelHeader(enc *xml.Encoder, channel *feeds.RssFeed) error {
+	required := []struct{ name, value string }{
+		{"title", channel.Title},
+		{"link", channel.Link},
+		{"description", channel.Description},
+	}
+	for _, field := range required {
+		if err := enc.EncodeElement(field.value, xml.StartElement{Name: xml.Name{Local: field.name}}); err != nil {
+			return err
+		}
+	}
+
+	optional := []struct{ name, value string }{
+		{"language", channel.Language},
+		{"copyright", channel.Copyright},
+		{"managingEditor", channel.ManagingEditor},
+		{"webMaster", channel.WebMaster},
+		{"pubDate", channel.PubDate},
+		{"lastBuildDate", channel.LastBuildDate},
+		{"category", channel.Category},
+		{"generator", channel.Generator},
+		{"docs", channel.Docs},
+		{"cloud", channel.Cloud},
+		{"rating", channel.Rating},
+		{"skipHours", channel.SkipHours},
+		{"skipDays", channel.SkipDays},
+	}
+	if channel.Ttl > 0 {
+		optional = append(optional, struct{ name, value string }{"ttl", strconv.Itoa(channel.Ttl)})
+	}
+	for _, field := range optional {
+		if field.value == "" {
+			continue
+		}
+		if err := enc.EncodeElement(field.value, xml.StartElement{Name: xml.Name{Local: field.name}}); err != nil {
+			return err
+		}
+	}
+
+	if channel.Image != nil {
+		if err := enc.Encode(channel.Image); err != nil {
+			return err
+		}
+	}
+	if channel.TextInput != nil {
+		if err := enc.Encode(channel.TextInput); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// rssItemElement adds repeated categories and the date an item was last
+// updated, which gorilla/feeds cannot express.
+type rssItemElement struct {
+	*feeds.RssItem
+	Categories []string `xml:"category"`
+	Updated    string   `xml:"http://www.w3.org/2005/Atom updated,omitempty"`
+}
+
+// withCategories returns opts with the tags of sources as categories of
+// their items.
+func withCategories(opts RssOptions, sources []*SourceFeed) RssOptions {
+	for _, source := range sources {
+		if len(source.Tags) == 0 {
+			continue
+		}
+		if opts.Categories == nil {
+			opts.Categories = make(map[string][]string)
+		}
+		opts.Categories[source.URL] = source.Tags
+	}
+	return opts
+}
+
+// toRssItem converts a single item using the same mapping gorilla/feeds
+// applies to whole feeds.
+func toRssItem(item *feeds.Item, opts RssOptions) rssItemElement {
+	single := &feeds.Feed{Items: []*feeds.Item{item}}
+	rssItem := (&feeds.Rss{Feed: single}).RssFeed().Items[0]
+	// gorilla/feeds renders <source> without its required url attribute, and
+	// Source is only used internally to remember where an item came from.
+	rssItem.Source = ""
+
+	if opts.OmitContent {
+		rssItem.Content = nil
+	}
+
+	switch opts.GUID {
+	case "link":
+		rssItem.Guid = nil
+		if rssItem.Link != "" {
+			rssItem.Guid = &feeds.RssGuid{Id: rssItem.Link, IsPermaLink: "true"}
+		}
+	case "none":
+		rssItem.Guid = nil
+	}
+
+	var categories []string
+	if item.Source != nil {
+		categories = opts.Categories[item.Source.Href]
+	}
+	// pubDate is the publication date, or the update date for items
+	// without one.
+	var updated string
+	if !item.Created.IsZero() && !item.Updated.IsZero() && !item.Updated.Equal(item.Created) {
+		updated = item.Updated.Format(time.RFC3339)
+	}
+	return rssItemElement{RssItem: rssItem, Categories: categories, Updated: updated}
+}
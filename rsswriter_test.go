@@ -0,0 +1,316 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/feeds"
+)
+
+func TestWriteRssMatchesGorilla(t *testing.T) {
+	created := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	feed := &feeds.Feed{
+		Title:       "Stream Feed",
+		Link:        &feeds.Link{Href: "http://example.com"},
+		Description: "Streamed",
+		Created:     created,
+		Items: []*feeds.Item{
+			{Title: "One", Link: &feeds.Link{Href: "http://example.com/1"}, Description: "first", Created: created, Content: "<b>body</b>"},
+			{Title: "Two", Link: &feeds.Link{Href: "http://example.com/2"}, Description: "second", Created: created},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := writeRss(&buf, feed, RssOptions{}); err != nil {
+		t.Fatalf("writeRss() unexpected error = %v", err)
+	}
+
+	want, err := feed.ToRss()
+	if err != nil {
+		t.Fatalf("ToRss() unexpected error = %v", err)
+	}
+
+	var got, expected feeds.RssFeedXml
+	if err := xml.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("output is not valid XML: %v\n%s", err, buf.String())
+	}
+	if err := xml.Unmarshal([]byte(want), &expected); err != nil {
+		t.Fatalf("gorilla output is not valid XML: %v", err)
+	}
+
+	if got.Channel.Title != expected.Channel.Title || got.Channel.PubDate != expected.Channel.PubDate {
+		t.Errorf("channel header = %+v, want %+v", got.Channel, expected.Channel)
+	}
+	if len(got.Channel.Items) != len(expected.Channel.Items) {
+		t.Fatalf("got %d items, want %d", len(got.Channel.Items), len(expected.Channel.Items))
+	}
+	for i := range got.Channel.Items {
+		if got.Channel.Items[i].Title != expected.Channel.Items[i].Title {
+			t.Errorf("item[%d] title = %q, want %q", i, got.Channel.Items[i].Title, expected.Channel.Items[i].Title)
+		}
+	}
+	if !bytes.Contains(buf.Bytes(), []byte("<content:encoded><![CDATA[<b>body</b>]]></content:encoded>")) {
+		t.Errorf("item content not preserved:\n%s", buf.String())
+	}
+}
+
+func TestWriteRssTTL(t *testing.T) {
+	var buf bytes.Buffer
+	feed := &feeds.Feed{Title: "TTL", Link: &feeds.Link{Href: ""}}
+	if err := writeRss(&buf, feed, RssOptions{TTL: 90*time.Minute + time.Second}); err != nil {
+		t.Fatalf("writeRss() unexpected error = %v", err)
+	}
+
+	if !bytes.Contains(buf.Bytes(), []byte("<ttl>91</ttl>")) {
+		t.Errorf("writeRss() missing rounded-up ttl:\n%s", buf.String())
+	}
+}
+
+func TestWriteRssAtomLinks(t *testing.T) {
+	var buf bytes.Buffer
+	feed := &feeds.Feed{Title: "Links", Link: &feeds.Link{Href: ""}}
+	opts := RssOptions{SelfURL: "https://example.com/feed.xml", HubURL: "https://hub.example.com/"}
+	if err := writeRss(&buf, feed, opts); err != nil {
+		t.Fatalf("writeRss() unexpected error = %v", err)
+	}
+
+	out := buf.String()
+	for _, want := range []string{
+		`xmlns:atom="http://www.w3.org/2005/Atom"`,
+		`<atom:link href="https://example.com/feed.xml" rel="self" type="application/rss+xml"></atom:link>`,
+		`<atom:link href="https://hub.example.com/" rel="hub"></atom:link>`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("writeRss() output missing %s:\n%s", want, out)
+		}
+	}
+}
+
+func TestWriteRssElementOptions(t *testing.T) {
+	built := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	feed := &feeds.Feed{
+		Title:   "Options",
+		Link:    &feeds.Link{Href: ""},
+		Created: built,
+		Items: []*feeds.Item{
+			{Title: "Item", Link: &feeds.Link{Href: "https://example.com/1"}, Id: "tag:example.com,2024:1", Content: "<p>Full</p>"},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		opts    RssOptions
+		want    []string
+		notWant []string
+	}{
+		{
+			name:    "defaults",
+			opts:    RssOptions{},
+			want:    []string{"<content:encoded>", "<guid>tag:example.com,2024:1</guid>"},
+			notWant: []string{"<lastBuildDate>"},
+		},
+		{
+			name:    "omit content",
+			opts:    RssOptions{OmitContent: true},
+			notWant: []string{"content:encoded", "xmlns:content"},
+		},
+		{
+			name:    "link guid",
+			opts:    RssOptions{GUID: "link"},
+			want:    []string{`<guid isPermaLink="true">https://example.com/1</guid>`},
+			notWant: []string{"tag:example.com"},
+		},
+		{
+			name:    "no guid",
+			opts:    RssOptions{GUID: "none"},
+			notWant: []string{"<guid"},
+		},
+		{
+			name: "last build date",
+			opts: RssOptions{LastBuildDate: true},
+			want: []string{"<lastBuildDate>Fri, 01 Mar 2024 12:00:00 +0000</lastBuildDate>"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := writeRss(&buf, feed, tt.opts); err != nil {
+				t.Fatalf("writeRss() unexpected error = %v", err)
+			}
+			out := buf.String()
+			for _, want := range tt.want {
+				if !strings.Contains(out, want) {
+					t.Errorf("writeRss() output missing %s:\n%s", want, out)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(out, notWant) {
+					t.Errorf("writeRss() output should not contain %s:\n%s", notWant, out)
+				}
+			}
+		})
+	}
+}
+
+func TestWriteRssIndentation(t *testing.T) {
+	feed := &feeds.Feed{
+		Title: "Indent",
+		Link:  &feeds.Link{Href: ""},
+		Items: []*feeds.Item{{Title: "Item", Link: &feeds.Link{Href: "https://example.com/1"}}},
+	}
+
+	tests := []struct {
+		name      string
+		opts      RssOptions
+		wantLines int
+		want      string
+	}{
+		{"default two spaces", RssOptions{}, 13, "\n      <title>Item</title>"},
+		{"four spaces", RssOptions{Indent: "    "}, 13, "\n            <title>Item</title>"},
+		{"compact", RssOptions{Compact: true}, 2, "<item><title>Item</title>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := writeRss(&buf, feed, tt.opts); err != nil {
+				t.Fatalf("writeRss() unexpected error = %v", err)
+			}
+			out := buf.String()
+			if got := strings.Count(strings.TrimSpace(out), "\n") + 1; got != tt.wantLines {
+				t.Errorf("writeRss() wrote %d lines, want %d:\n%s", got, tt.wantLines, out)
+			}
+			if !strings.Contains(out, tt.want) {
+				t.Errorf("writeRss() output missing %q:\n%s", tt.want, out)
+			}
+		})
+	}
+}
+
+func TestWriteRssStylesheet(t *testing.T) {
+	tests := []struct {
+		href string
+		want string
+	}{
+		{"/style.xsl", `<?xml-stylesheet type="text/xsl" href="/style.xsl"?>`},
+		{"https://example.com/feed.css?v=1&x=2", `<?xml-stylesheet type="text/css" href="https://example.com/feed.css?v=1&amp;x=2"?>`},
+	}
+
+	feed := &feeds.Feed{Title: "Styled", Link: &feeds.Link{Href: ""}}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := writeRss(&buf, feed, RssOptions{Stylesheet: tt.href}); err != nil {
+			t.Fatalf("writeRss() unexpected error = %v", err)
+		}
+		if !strings.HasPrefix(buf.String(), xml.Header+tt.want+"\n<rss") {
+			t.Errorf("writeRss(%q) should start with the stylesheet instruction, got:\n%s", tt.href, buf.String())
+		}
+	}
+}
+
+func TestWriteRssUpdateHints(t *testing.T) {
+	feed := &feeds.Feed{Title: "Hinted", Link: &feeds.Link{Href: "https://example.com/"}}
+	opts := RssOptions{
+		TTL:             time.Hour,
+		UpdatePeriod:    "hourly",
+		UpdateFrequency: 2,
+		SkipHours:       []int{0, 1},
+		SkipDays:        []string{"Saturday"},
+		Compact:         true,
+	}
+
+	var buf bytes.Buffer
+	if err := writeRss(&buf, feed, opts); err != nil {
+		t.Fatalf("writeRss() unexpected error = %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		`xmlns:sy="` + syndicationNamespace + `"`,
+		"<ttl>60</ttl>",
+		"<skipHours><hour>0</hour><hour>1</hour></skipHours>",
+		"<skipDays><day>Saturday</day></skipDays>",
+		"<sy:updatePeriod>hourly</sy:updatePeriod>",
+		"<sy:updateFrequency>2</sy:updateFrequency>",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("writeRss() output missing %q:\n%s", want, out)
+		}
+	}
+
+	buf.Reset()
+	if err := writeRss(&buf, feed, RssOptions{}); err != nil {
+		t.Fatalf("writeRss() unexpected error = %v", err)
+	}
+	if strings.Contains(buf.String(), "xmlns:sy") {
+		t.Errorf("writeRss() without hints should not declare the syndication namespace:\n%s", buf.String())
+	}
+}
+
+func TestParseSkipHoursAndDays(t *testing.T) {
+	hours, err := parseSkipHours(" 0, 23 ,")
+	if err != nil || !slices.Equal(hours, []int{0, 23}) {
+		t.Errorf("parseSkipHours() = %v, %v, want [0 23]", hours, err)
+	}
+	for _, bad := range []string{"24", "-1", "noon"} {
+		if _, err := parseSkipHours(bad); err == nil {
+			t.Errorf("parseSkipHours(%q) expected error", bad)
+		}
+	}
+
+	days, err := parseSkipDays("saturday,SUNDAY")
+	if err != nil || !slices.Equal(days, []string{"Saturday", "Sunday"}) {
+		t.Errorf("parseSkipDays() = %v, %v, want [Saturday Sunday]", days, err)
+	}
+	if _, err := parseSkipDays("Caturday"); err == nil {
+		t.Errorf("parseSkipDays(%q) expected error", "Caturday")
+	}
+}
+
+func TestWriteRssCategories(t *testing.T) {
+	feed := &feeds.Feed{Title: "Tagged", Link: &feeds.Link{Href: "https://example.com/"}, Items: []*feeds.Item{
+		{Title: "Tagged item", Source: &feeds.Link{Href: "https://a.example/feed"}},
+		{Title: "Untagged item", Source: &feeds.Link{Href: "https://b.example/feed"}},
+	}}
+	sources := []*SourceFeed{
+		{URL: "https://a.example/feed", Tags: []string{"go", "web"}},
+		{URL: "https://b.example/feed"},
+	}
+
+	var buf bytes.Buffer
+	opts := withCategories(RssOptions{Compact: true}, sources)
+	if err := writeRss(&buf, feed, opts); err != nil {
+		t.Fatalf("writeRss() unexpected error = %v", err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, "<title>Tagged item</title>") || !strings.Contains(out, "<category>go</category><category>web</category></item>") {
+		t.Errorf("writeRss() should add the source's tags as categories:\n%s", out)
+	}
+	if strings.Count(out, "<category>") != 2 {
+		t.Errorf("writeRss() added categories to an untagged item:\n%s", out)
+	}
+}
+
+func TestWriteRssUpdated(t *testing.T) {
+	published := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	feed := &feeds.Feed{Title: "Updates", Link: &feeds.Link{Href: "https://example.com/"}, Items: []*feeds.Item{
+		{Title: "Edited", Created: published, Updated: published.Add(48 * time.Hour)},
+		{Title: "Unedited", Created: published},
+	}}
+
+	var buf bytes.Buffer
+	if err := writeRss(&buf, feed, RssOptions{Compact: true}); err != nil {
+		t.Fatalf("writeRss() unexpected error = %v", err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, `<updated xmlns="http://www.w3.org/2005/Atom">2024-01-03T00:00:00Z</updated></item>`) {
+		t.Errorf("writeRss() should add the edited item's update date:\n%s", out)
+	}
+	if strings.Count(out, "<updated") != 1 {
+		t.Errorf("writeRss() dated an unedited item as updated:\n%s", out)
+	}
+}
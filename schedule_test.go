@@ -0,0 +1,60 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSchedule(t *testing.T) {
+	tests := []struct {
+		expr    string
+		wantErr bool
+	}{
+		{"*/20 7-23 * * *", false},
+		{"0 9 * jan-mar mon-fri", false},
+		{"5,35 */2 1-15/2 * 7", false},
+		{"* * * *", true},
+		{"60 * * * *", true},
+		{"*/0 * * * *", true},
+		{"0 23-7 * * *", true},
+		{"0 0 * foo *", true},
+	}
+
+	for _, tt := range tests {
+		if _, err := parseSchedule(tt.expr); (err != nil) != tt.wantErr {
+			t.Errorf("parseSchedule(%q) error = %v, wantErr %v", tt.expr, err, tt.wantErr)
+		}
+	}
+}
+
+func TestScheduleNext(t *testing.T) {
+	// 2024-06-07 is a Friday.
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2024, 6, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		expr string
+		from time.Time
+		want time.Time
+	}{
+		{"*/20 7-23 * * *", at(7, 10, 5), at(7, 10, 20)},
+		{"*/20 7-23 * * *", at(7, 10, 20), at(7, 10, 40)},
+		{"*/20 7-23 * * *", at(7, 23, 45), at(8, 7, 0)},
+		{"0 9 * * mon-fri", at(7, 10, 0), at(10, 9, 0)},
+		{"30 6 * * 7", at(7, 10, 0), at(9, 6, 30)},
+		{"0 0 1 * 1", at(7, 10, 0), at(10, 0, 0)},
+		{"0 0 1 jul *", at(7, 10, 0), time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 30 2 *", at(7, 10, 0), time.Time{}},
+	}
+
+	for _, tt := range tests {
+		schedule, err := parseSchedule(tt.expr)
+		if err != nil {
+			t.Fatalf("parseSchedule(%q) unexpected error = %v", tt.expr, err)
+		}
+		if got := schedule.next(tt.from); !got.Equal(tt.want) {
+			t.Errorf("next(%q, %v) = %v, want %v", tt.expr, tt.from, got, tt.want)
+		}
+	}
+}
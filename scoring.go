@@ -0,0 +1,100 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/feeds"
+)
+
+// Scorer rates items for -select score, which keeps the -count items with
+// the highest scores. source is the feed the item came from, if known.
+type Scorer interface {
+	Score(item *feeds.Item, source *SourceFeed, now time.Time) float64
+}
+
+// keywordScorer is the built-in Scorer. An item scores one point plus the
+// weights of the keywords its title or description contain, times its
+// source's weight, halved for every HalfLife of its age when positive.
+type keywordScorer struct {
+	Keywords map[string]float64
+	HalfLife time.Duration
+}
+
+func (s keywordScorer) Score(item *feeds.Item, source *SourceFeed, now time.Time) float64 {
+	text := strings.ToLower(item.Title + " " + item.Description)
+	score := 1.0
+	for keyword, weight := range s.Keywords {
+		if strings.Contains(text, keyword) {
+			score += weight
+		}
+	}
+	if source != nil && source.Weight > 1 {
+		score *= float64(source.Weight)
+	}
+	// Decaying a negative score would raise it, favouring the oldest of
+	// the items pushed down.
+	if s.HalfLife > 0 && !item.Created.IsZero() && score > 0 {
+		age := max(now.Sub(item.Created), 0)
+		score *= math.Pow(0.5, float64(age)/float64(s.HalfLife))
+	}
+	return score
+}
+
+// parseScoreKeywords parses a comma-separated list of keyword:weight pairs
+// for -score-keywords. Keywords without a weight weigh 1, and negative
+// weights push items down.
+func parseScoreKeywords(value string) (map[string]float64, error) {
+	keywords := make(map[string]float64)
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		keyword, weight := field, 1.0
+		if i := strings.LastIndex(field, ":"); i >= 0 {
+			var err error
+			keyword = strings.TrimSpace(field[:i])
+			if weight, err = strconv.ParseFloat(strings.TrimSpace(field[i+1:]), 64); err != nil {
+				return nil, fmt.Errorf("%q is not a keyword:weight pair", field)
+			}
+		}
+		if keyword == "" {
+			return nil, fmt.Errorf("%q is not a keyword:weight pair", field)
+		}
+		keywords[strings.ToLower(keyword)] = weight
+	}
+	return keywords, nil
+}
+
+// selectScored picks the count items scorer rates highest, ties going to
+// the items first in items. The picked items keep their order in items.
+func selectScored(items []*feeds.Item, count int, scorer Scorer, sources []*SourceFeed, now time.Time) []*feeds.Item {
+	if len(items) <= count {
+		return items
+	}
+
+	byURL := make(map[string]*SourceFeed, len(sources))
+	for _, source := range sources {
+		byURL[source.URL] = source
+	}
+	scores := make(map[*feeds.Item]float64, len(items))
+	for _, item := range items {
+		scores[item] = scorer.Score(item, byURL[linkHref(item.Source)], now)
+	}
+
+	ranked := slices.Clone(items)
+	slices.SortStableFunc(ranked, func(a, b *feeds.Item) int {
+		return cmp.Compare(scores[b], scores[a])
+	})
+	picked := make(map[*feeds.Item]bool, count)
+	for _, item := range ranked[:count] {
+		picked[item] = true
+	}
+	return slices.DeleteFunc(items, func(item *feeds.Item) bool { return !picked[item] })
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/feeds"
+)
+
+func TestKeywordScorer(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	scorer := keywordScorer{Keywords: map[string]float64{"go": 2, "crypto": -1, "sponsored": -3}, HalfLife: 24 * time.Hour}
+
+	tests := []struct {
+		name   string
+		item   *feeds.Item
+		source *SourceFeed
+		want   float64
+	}{
+		{"plain", &feeds.Item{Title: "News", Created: now}, nil, 1},
+		{"keyword", &feeds.Item{Title: "Go 1.23 released", Created: now}, nil, 3},
+		{"keywords", &feeds.Item{Title: "Go", Description: "crypto", Created: now}, nil, 2},
+		{"weighted source", &feeds.Item{Title: "Go", Created: now}, &SourceFeed{Weight: 2}, 6},
+		{"day old", &feeds.Item{Title: "Go", Created: now.Add(-24 * time.Hour)}, nil, 1.5},
+		{"negative", &feeds.Item{Title: "Sponsored", Created: now}, nil, -2},
+		{"day old negative", &feeds.Item{Title: "Sponsored", Created: now.Add(-24 * time.Hour)}, nil, -2},
+		{"undated", &feeds.Item{Title: "News"}, nil, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := scorer.Score(tt.item, tt.source, now); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("Score() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseScoreKeywords(t *testing.T) {
+	got, err := parseScoreKeywords("Go:3, rust , ads:-2.5,")
+	if err != nil {
+		t.Fatalf("parseScoreKeywords() unexpected error = %v", err)
+	}
+	if want := map[string]float64{"go": 3, "rust": 1, "ads": -2.5}; !reflect.DeepEqual(got, want) {
+		t.Errorf("parseScoreKeywords() = %v, want %v", got, want)
+	}
+	for _, value := range []string{"go:lots", ":2"} {
+		if _, err := parseScoreKeywords(value); err == nil {
+			t.Errorf("parseScoreKeywords(%q) expected error", value)
+		}
+	}
+}
+
+// titleScorer scores items by the length of their title.
+type titleScorer struct{}
+
+func (titleScorer) Score(item *feeds.Item, source *SourceFeed, now time.Time) float64 {
+	return float64(len(item.Title))
+}
+
+func TestBuildFeedSelectScore(t *testing.T) {
+	now := time.Now()
+	source := &SourceFeed{URL: "https://example.com/feed"}
+	for i, title := range []string{"a", "bbbb", "cc", "ddd", "e"} {
+		source.Items = append(source.Items, &feeds.Item{Title: title, Source: &feeds.Link{Href: source.URL}, Created: now.Add(-time.Duration(i) * time.Hour)})
+	}
+
+	feed := buildFeed(&Config{Count: 3, Select: "score", Scorer: titleScorer{}}, []*SourceFeed{source})
+	var titles []string
+	for _, item := range feed.Items {
+		titles = append(titles, item.Title)
+	}
+	if got, want := strings.Join(titles, " "), "bbbb cc ddd"; got != want {
+		t.Errorf("buildFeed() with select score = %q, want the top scored items newest first %q", got, want)
+	}
+}
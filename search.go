@@ -0,0 +1,157 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/gorilla/feeds"
+)
+
+// SearchQuery selects archived items. Every term must occur in the title,
+// description or content; Since and Source are ignored when zero.
+type SearchQuery struct {
+	Terms  []string
+	Since  time.Time
+	Source string
+}
+
+func runSearch(args []string) error {
+	flags := flag.NewFlagSet("search", flag.ExitOnError)
+	archiveDir := flags.String("archive", "", "Archive directory written by -archive")
+	since := flags.String("since", "", "Only items from this date or recent period, e.g. 2024-01-01, 30d or 12h")
+	source := flags.String("source", "", "Only items whose source URL contains this, e.g. example.com")
+	format := flags.String("format", "text", "Output format: 'text', 'rss', 'json', 'gemtext', 'ndjson' or 'sqlite'")
+
+	// Allow the query before the flags, as in: search "query" -since 30d.
+	var terms []string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		terms = append(terms, args[0])
+		args = args[1:]
+	}
+	flags.Parse(args)
+	terms = append(terms, flags.Args()...)
+
+	if *archiveDir == "" {
+		return fmt.Errorf("archive directory must be provided")
+	}
+	if len(terms) == 0 {
+		return fmt.Errorf("search query must be provided")
+	}
+	if _, ok := formats[*format]; !ok && *format != "text" {
+		return fmt.Errorf("format must be 'text', 'rss', 'json', 'gemtext', 'ndjson' or 'sqlite'")
+	}
+
+	query := SearchQuery{Terms: strings.Fields(strings.Join(terms, " ")), Source: *source}
+	if *since != "" {
+		t, err := parseSince(*since, time.Now())
+		if err != nil {
+			return fmt.Errorf("since: %v", err)
+		}
+		query.Since = t
+	}
+
+	items, err := searchArchive(*archiveDir, query)
+	if err != nil {
+		return err
+	}
+
+	if *format == "text" {
+		return writeSearchResults(os.Stdout, items)
+	}
+	feed := &feeds.Feed{
+		Title:       "Search results: " + strings.Join(query.Terms, " "),
+		Link:        &feeds.Link{Href: ""},
+		Description: "Archived items matching " + strings.Join(query.Terms, " "),
+		Created:     time.Now(),
+		Items:       items,
+	}
+	return formats[*format].Write(os.Stdout, feed, RssOptions{})
+}
+
+// searchArchive returns the archived items matching query, newest first.
+func searchArchive(dir string, query SearchQuery) ([]*feeds.Item, error) {
+	var matches []*feeds.Item
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || filepath.Ext(path) != ".json" {
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		var archived ItemJSON
+		if err := json.Unmarshal(data, &archived); err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+
+		if !query.matches(archived) {
+			return nil
+		}
+
+		item := &feeds.Item{}
+		applyItemJSON(item, archived)
+		matches = append(matches, item)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error reading archive: %v", err)
+	}
+
+	slices.SortFunc(matches, func(a, b *feeds.Item) int {
+		return cmp.Or(b.Created.Compare(a.Created), strings.Compare(a.Title, b.Title))
+	})
+
+	return matches, nil
+}
+
+// matches reports whether item is selected by query. Terms match
+// case-insensitively.
+func (query SearchQuery) matches(item ItemJSON) bool {
+	if query.Source != "" && !strings.Contains(item.Source, query.Source) {
+		return false
+	}
+	if !query.Since.IsZero() && item.Created.Before(query.Since) {
+		return false
+	}
+
+	text := strings.ToLower(item.Title + " " + htmlToText(item.Description) + " " + htmlToText(item.Content))
+	for _, term := range query.Terms {
+		if !strings.Contains(text, strings.ToLower(term)) {
+			return false
+		}
+	}
+	return true
+}
+
+func writeSearchResults(w io.Writer, items []*feeds.Item) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "DATE\tTITLE\tLINK")
+
+	for _, item := range items {
+		date := ""
+		if !item.Created.IsZero() {
+			date = item.Created.Format("2006-01-02")
+		}
+		link := ""
+		if item.Link != nil {
+			link = item.Link.Href
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", date, item.Title, link)
+	}
+
+	return tw.Flush()
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/feeds"
+)
+
+func TestSearchArchive(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "rss_search")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	now := time.Now()
+	archiveItems(tempDir, []*SourceFeed{
+		{
+			URL: "https://example.com/feed.xml",
+			Items: []*feeds.Item{
+				{Title: "Go 1.24 released", Link: &feeds.Link{Href: "https://example.com/go"}, Source: &feeds.Link{Href: "https://example.com/feed.xml"}, Created: now.Add(-24 * time.Hour)},
+				{Title: "Old news", Description: "<p>Go <b>generics</b> arrive</p>", Link: &feeds.Link{Href: "https://example.com/old"}, Source: &feeds.Link{Href: "https://example.com/feed.xml"}, Created: now.Add(-60 * 24 * time.Hour)},
+			},
+		},
+		{
+			URL: "https://other.org/rss",
+			Items: []*feeds.Item{
+				{Title: "Rust and Go compared", Link: &feeds.Link{Href: "https://other.org/cmp"}, Source: &feeds.Link{Href: "https://other.org/rss"}, Created: now},
+			},
+		},
+	})
+
+	tests := []struct {
+		name  string
+		query SearchQuery
+		want  []string
+	}{
+		{"term in title or description, newest first", SearchQuery{Terms: []string{"go"}}, []string{"Rust and Go compared", "Go 1.24 released", "Old news"}},
+		{"all terms must match", SearchQuery{Terms: []string{"GO", "generics"}}, []string{"Old news"}},
+		{"since", SearchQuery{Terms: []string{"go"}, Since: now.Add(-30 * 24 * time.Hour)}, []string{"Rust and Go compared", "Go 1.24 released"}},
+		{"source", SearchQuery{Terms: []string{"go"}, Source: "example.com"}, []string{"Go 1.24 released", "Old news"}},
+		{"no match", SearchQuery{Terms: []string{"python"}}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, err := searchArchive(tempDir, tt.query)
+			if err != nil {
+				t.Fatalf("searchArchive() unexpected error = %v", err)
+			}
+			var got []string
+			for _, item := range items {
+				got = append(got, item.Title)
+			}
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("searchArchive() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteSearchResults(t *testing.T) {
+	items := []*feeds.Item{
+		{Title: "Go 1.24 released", Link: &feeds.Link{Href: "https://example.com/go"}, Created: time.Date(2025, 2, 11, 0, 0, 0, 0, time.UTC)},
+	}
+
+	var buf bytes.Buffer
+	if err := writeSearchResults(&buf, items); err != nil {
+		t.Fatalf("writeSearchResults() unexpected error = %v", err)
+	}
+	if !strings.Contains(buf.String(), "2025-02-11  Go 1.24 released  https://example.com/go") {
+		t.Errorf("writeSearchResults() = %q", buf.String())
+	}
+}
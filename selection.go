@@ -0,0 +1,97 @@
+package main
+
+import (
+	"math/rand/v2"
+	"slices"
+	"time"
+
+	"github.com/gorilla/feeds"
+)
+
+// orderItems rearranges items, sorted newest first, as -order asks:
+// "asc" puts them oldest first, and "shuffle" in a random order that only
+// depends on seed, or on the time when seed is 0. Selecting -count items
+// afterwards then keeps the oldest or a random sample of them.
+func orderItems(items []*feeds.Item, order string, seed int64) {
+	switch order {
+	case "asc":
+		slices.Reverse(items)
+	case "shuffle":
+		if seed == 0 {
+			seed = time.Now().UnixNano()
+		}
+		r := rand.New(rand.NewPCG(uint64(seed), 0))
+		r.Shuffle(len(items), func(i, j int) {
+			items[i], items[j] = items[j], items[i]
+		})
+	}
+}
+
+// selectRoundRobin picks count items from items, sorted newest first, by
+// taking the newest remaining items of each source in turn, so that sources
+// publishing rarely are represented next to those publishing constantly.
+// Each turn takes as many items as the source's weight, by default one.
+// The picked items keep their order in items.
+func selectRoundRobin(items []*feeds.Item, count int, weights map[string]int) []*feeds.Item {
+	if len(items) <= count {
+		return items
+	}
+
+	var order []string
+	queues := make(map[string][]*feeds.Item)
+	for _, item := range items {
+		key := ""
+		if item.Source != nil {
+			key = item.Source.Href
+		}
+		if _, ok := queues[key]; !ok {
+			order = append(order, key)
+		}
+		queues[key] = append(queues[key], item)
+	}
+
+	picked := make(map[*feeds.Item]bool, count)
+	for len(picked) < count {
+		for _, key := range order {
+			for range max(weights[key], 1) {
+				if len(queues[key]) == 0 || len(picked) == count {
+					break
+				}
+				picked[queues[key][0]] = true
+				queues[key] = queues[key][1:]
+			}
+		}
+	}
+
+	selected := make([]*feeds.Item, 0, count)
+	for _, item := range items {
+		if picked[item] {
+			selected = append(selected, item)
+		}
+	}
+	return selected
+}
+
+// selectDaily picks up to perDay items from each of the last days calendar
+// days, today included, in the time zone of now, dating items by date.
+// Items from before those days are dropped. The picked items keep their
+// order in items, so the first ones of each day in that order are picked.
+func selectDaily(items []*feeds.Item, perDay, days int, now time.Time, date func(*feeds.Item) time.Time) []*feeds.Item {
+	year, month, day := now.Date()
+	since := time.Date(year, month, day-days+1, 0, 0, 0, 0, now.Location())
+
+	picked := make(map[string]int)
+	var selected []*feeds.Item
+	for _, item := range items {
+		itemDate := date(item).In(now.Location())
+		if itemDate.Before(since) {
+			continue
+		}
+		key := itemDate.Format(time.DateOnly)
+		if picked[key] < perDay {
+			picked[key]++
+			selected = append(selected, item)
+		}
+	}
+	return selected
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/feeds"
+)
+
+func TestSelectRoundRobin(t *testing.T) {
+	now := time.Now()
+	var items []*feeds.Item
+	// A firehose source publishing hourly and a quiet one with two
+	// older posts, sorted newest first.
+	for i, title := range []string{"F1", "F2", "F3", "F4", "F5"} {
+		items = append(items, &feeds.Item{Title: title, Source: &feeds.Link{Href: "https://firehose.example/"}, Created: now.Add(-time.Duration(i) * time.Hour)})
+	}
+	for i, title := range []string{"Q1", "Q2"} {
+		items = append(items, &feeds.Item{Title: title, Source: &feeds.Link{Href: "https://quiet.example/"}, Created: now.Add(-time.Duration(i+10) * time.Hour)})
+	}
+
+	tests := []struct {
+		count int
+		want  string
+	}{
+		{1, "F1"},
+		{3, "F1 F2 Q1"},
+		{5, "F1 F2 F3 Q1 Q2"},
+		{6, "F1 F2 F3 F4 Q1 Q2"},
+		{10, "F1 F2 F3 F4 F5 Q1 Q2"},
+	}
+
+	for _, tt := range tests {
+		var titles []string
+		for _, item := range selectRoundRobin(items, tt.count, nil) {
+			titles = append(titles, item.Title)
+		}
+		if got := strings.Join(titles, " "); got != tt.want {
+			t.Errorf("selectRoundRobin(%d) = %q, want %q", tt.count, got, tt.want)
+		}
+	}
+}
+
+func TestSelectRoundRobinWeights(t *testing.T) {
+	now := time.Now()
+	var items []*feeds.Item
+	for i, source := range []string{"a", "b", "a", "b", "a", "b", "a"} {
+		items = append(items, &feeds.Item{
+			Title:   fmt.Sprintf("%s%d", source, i),
+			Source:  &feeds.Link{Href: "https://" + source + ".example/"},
+			Created: now.Add(-time.Duration(i) * time.Hour),
+		})
+	}
+
+	var titles []string
+	for _, item := range selectRoundRobin(items, 4, map[string]int{"https://a.example/": 3}) {
+		titles = append(titles, item.Title)
+	}
+	if got, want := strings.Join(titles, " "), "a0 b1 a2 a4"; got != want {
+		t.Errorf("selectRoundRobin() with weights = %q, want %q", got, want)
+	}
+}
+
+func TestBuildFeedOrder(t *testing.T) {
+	now := time.Now()
+	source := &SourceFeed{URL: "https://example.com/feed"}
+	for i := range 10 {
+		source.Items = append(source.Items, &feeds.Item{Title: fmt.Sprintf("i%d", i), Created: now.Add(-time.Duration(i) * time.Hour)})
+	}
+	titles := func(feed *feeds.Feed) string {
+		var titles []string
+		for _, item := range feed.Items {
+			titles = append(titles, item.Title)
+		}
+		return strings.Join(titles, " ")
+	}
+
+	if got, want := titles(buildFeed(&Config{Count: 3, Order: "asc"}, []*SourceFeed{source})), "i9 i8 i7"; got != want {
+		t.Errorf("buildFeed() with order asc = %q, want the oldest items %q", got, want)
+	}
+
+	shuffled := titles(buildFeed(&Config{Count: 10, Order: "shuffle", Seed: 42}, []*SourceFeed{source}))
+	if again := titles(buildFeed(&Config{Count: 10, Order: "shuffle", Seed: 42}, []*SourceFeed{source})); again != shuffled {
+		t.Errorf("buildFeed() with the same seed shuffled %q, then %q", shuffled, again)
+	}
+	if shuffled == titles(buildFeed(&Config{Count: 10}, []*SourceFeed{source})) {
+		t.Errorf("buildFeed() with order shuffle kept the items in order: %q", shuffled)
+	}
+	if other := titles(buildFeed(&Config{Count: 10, Order: "shuffle", Seed: 7}, []*SourceFeed{source})); other == shuffled {
+		t.Errorf("buildFeed() shuffled the same way with another seed: %q", other)
+	}
+}
+
+func TestSelectDaily(t *testing.T) {
+	now := time.Date(2024, 5, 10, 9, 0, 0, 0, time.UTC)
+	at := func(day, hour int) time.Time { return time.Date(2024, 5, day, hour, 0, 0, 0, time.UTC) }
+	// Newest first: a busy today, a quiet yesterday, a busy day before
+	// and one item from before the last three days.
+	items := []*feeds.Item{
+		{Title: "today3", Created: at(10, 8)},
+		{Title: "today2", Created: at(10, 5)},
+		{Title: "today1", Created: at(10, 1)},
+		{Title: "yesterday", Created: at(9, 12)},
+		{Title: "before3", Created: at(8, 23)},
+		{Title: "before2", Created: at(8, 10)},
+		{Title: "before1", Created: at(8, 0)},
+		{Title: "old", Created: at(7, 23)},
+	}
+
+	var titles []string
+	for _, item := range selectDaily(items, 2, 3, now, func(item *feeds.Item) time.Time { return item.Created }) {
+		titles = append(titles, item.Title)
+	}
+	if got, want := strings.Join(titles, " "), "today3 today2 yesterday before3 before2"; got != want {
+		t.Errorf("selectDaily() = %q, want %q", got, want)
+	}
+
+	// Days start at midnight in now's time zone, where it is still the 9th.
+	titles = nil
+	for _, item := range selectDaily(items, 5, 1, now.In(time.FixedZone("UTC-10", -10*3600)), func(item *feeds.Item) time.Time { return item.Created }) {
+		titles = append(titles, item.Title)
+	}
+	if got, want := strings.Join(titles, " "), "today3 today2 today1 yesterday"; got != want {
+		t.Errorf("selectDaily() in UTC-10 = %q, want %q", got, want)
+	}
+}
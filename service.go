@@ -0,0 +1,43 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+)
+
+// defaultServiceName is the name the daemon is installed under as a
+// Windows service unless -name says otherwise.
+const defaultServiceName = "rss-agg"
+
+// runServiceCommand installs, uninstalls, starts or stops the Windows
+// service that runs the daemon. install takes the daemon's options, which
+// the service is started with.
+func runServiceCommand(args []string) error {
+	flags := flag.NewFlagSet("service", flag.ExitOnError)
+	name := flags.String("name", defaultServiceName, "Name of the service")
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		return fmt.Errorf("command must be install, uninstall, start or stop")
+	}
+	command, options := flags.Arg(0), flags.Args()[1:]
+	if command != "install" && len(options) > 0 {
+		return fmt.Errorf("%s takes no options", command)
+	}
+
+	switch command {
+	case "install":
+		if len(options) == 0 {
+			return fmt.Errorf("install requires the daemon's options, e.g. -input C:\\rss-agg\\feeds.txt -interval 30m")
+		}
+		return installService(*name, options)
+	case "uninstall":
+		return removeService(*name)
+	case "start":
+		return startService(*name)
+	case "stop":
+		return stopService(*name)
+	default:
+		return fmt.Errorf("unknown command %q, must be install, uninstall, start or stop", command)
+	}
+}
@@ -0,0 +1,33 @@
+//go:build !windows
+
+package main
+
+import "errors"
+
+var errServiceUnsupported = errors.New("services are only supported on Windows; elsewhere run the daemon under systemd or another supervisor")
+
+func installService(name string, options []string) error {
+	return errServiceUnsupported
+}
+
+func removeService(name string) error {
+	return errServiceUnsupported
+}
+
+func startService(name string) error {
+	return errServiceUnsupported
+}
+
+func stopService(name string) error {
+	return errServiceUnsupported
+}
+
+// runningAsService reports whether the Windows service control manager
+// started the process, which it never does here.
+func runningAsService() bool {
+	return false
+}
+
+func runAsService(config *Config) error {
+	return errServiceUnsupported
+}
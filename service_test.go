@@ -0,0 +1,28 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRunServiceCommandErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{"no command", nil, "command must be"},
+		{"unknown command", []string{"restart"}, "unknown command"},
+		{"install without options", []string{"install"}, "requires the daemon's options"},
+		{"options to start", []string{"start", "-interval", "30m"}, "takes no options"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := runServiceCommand(tt.args)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("runServiceCommand(%q) error = %v, want %q", tt.args, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,215 @@
+//go:build windows
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/eventlog"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+func installService(name string, options []string) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("error locating executable: %v", err)
+	}
+	exe, err = filepath.Abs(exe)
+	if err != nil {
+		return fmt.Errorf("error locating executable: %v", err)
+	}
+
+	m, err := mgr.Connect()
+	if err != nil {
+		return fmt.Errorf("error connecting to service manager: %v", err)
+	}
+	defer m.Disconnect()
+
+	if s, err := m.OpenService(name); err == nil {
+		s.Close()
+		return fmt.Errorf("service %s already exists", name)
+	}
+
+	s, err := m.CreateService(name, exe, mgr.Config{
+		DisplayName: "RSS aggregator (" + name + ")",
+		Description: "Aggregates RSS feeds into one feed",
+		StartType:   mgr.StartAutomatic,
+	}, options...)
+	if err != nil {
+		return fmt.Errorf("error creating service: %v", err)
+	}
+	defer s.Close()
+
+	if err := eventlog.InstallAsEventCreate(name, eventlog.Error|eventlog.Warning|eventlog.Info); err != nil {
+		s.Delete()
+		return fmt.Errorf("error registering event log source: %v", err)
+	}
+	return nil
+}
+
+func removeService(name string) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return fmt.Errorf("error connecting to service manager: %v", err)
+	}
+	defer m.Disconnect()
+
+	s, err := m.OpenService(name)
+	if err != nil {
+		return fmt.Errorf("service %s is not installed", name)
+	}
+	defer s.Close()
+
+	if err := s.Delete(); err != nil {
+		return fmt.Errorf("error removing service: %v", err)
+	}
+	if err := eventlog.Remove(name); err != nil {
+		return fmt.Errorf("error removing event log source: %v", err)
+	}
+	return nil
+}
+
+func startService(name string) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return fmt.Errorf("error connecting to service manager: %v", err)
+	}
+	defer m.Disconnect()
+
+	s, err := m.OpenService(name)
+	if err != nil {
+		return fmt.Errorf("service %s is not installed", name)
+	}
+	defer s.Close()
+
+	if err := s.Start(); err != nil {
+		return fmt.Errorf("error starting service: %v", err)
+	}
+	return nil
+}
+
+// serviceStopTimeout is how long stopService waits for the daemon to
+// finish its current run and exit.
+const serviceStopTimeout = 30 * time.Second
+
+func stopService(name string) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return fmt.Errorf("error connecting to service manager: %v", err)
+	}
+	defer m.Disconnect()
+
+	s, err := m.OpenService(name)
+	if err != nil {
+		return fmt.Errorf("service %s is not installed", name)
+	}
+	defer s.Close()
+
+	status, err := s.Control(svc.Stop)
+	if err != nil {
+		return fmt.Errorf("error stopping service: %v", err)
+	}
+	deadline := time.Now().Add(serviceStopTimeout)
+	for status.State != svc.Stopped {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("service %s did not stop within %v", name, serviceStopTimeout)
+		}
+		time.Sleep(300 * time.Millisecond)
+		if status, err = s.Query(); err != nil {
+			return fmt.Errorf("error querying service: %v", err)
+		}
+	}
+	return nil
+}
+
+// runningAsService reports whether the Windows service control manager
+// started the process.
+func runningAsService() bool {
+	isService, err := svc.IsWindowsService()
+	return err == nil && isService
+}
+
+// runAsService runs the daemon under the service control manager until it
+// stops the service.
+func runAsService(config *Config) error {
+	return svc.Run(defaultServiceName, &serviceHandler{config: config})
+}
+
+type serviceHandler struct {
+	config *Config
+}
+
+func (h *serviceHandler) Execute(args []string, requests <-chan svc.ChangeRequest, status chan<- svc.Status) (bool, uint32) {
+	status <- svc.Status{State: svc.StartPending}
+
+	// A service has no console, so log to the event log source registered
+	// under the service's name by install.
+	if elog, err := eventlog.Open(args[0]); err == nil {
+		defer elog.Close()
+		log.SetFlags(0)
+		log.SetOutput(eventLogWriter{elog})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		if err := runDaemon(ctx, h.config); err != nil {
+			log.Printf("Error running daemon: %v", err)
+		}
+		close(done)
+	}()
+
+	status <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+	for {
+		select {
+		case <-done:
+			return false, 0
+		case req := <-requests:
+			switch req.Cmd {
+			case svc.Interrogate:
+				status <- req.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				status <- svc.Status{State: svc.StopPending}
+				cancel()
+				<-done
+				return false, 0
+			}
+		}
+	}
+}
+
+// eventLogWriter is a log output that reports each message to the event
+// log, as a warning or error when the message says so.
+type eventLogWriter struct {
+	elog *eventlog.Log
+}
+
+func (w eventLogWriter) Write(p []byte) (int, error) {
+	msg := strings.TrimSuffix(string(p), "\n")
+	// Skip the [profile] tag of messages from a profile.
+	level := msg
+	if strings.HasPrefix(level, "[") {
+		_, level, _ = strings.Cut(level, "] ")
+	}
+	var err error
+	switch {
+	case strings.HasPrefix(level, "Warning:"):
+		err = w.elog.Warning(1, msg)
+	case strings.HasPrefix(level, "Error"):
+		err = w.elog.Error(1, msg)
+	default:
+		err = w.elog.Info(1, msg)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
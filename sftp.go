@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// sshTarget returns the [user@]host, port and remote path of an sftp:// or
+// scp:// output. Paths starting with /~/ are relative to the home directory.
+func sshTarget(dest *url.URL) (host, port, path string, err error) {
+	if dest.Hostname() == "" || dest.Path == "" || dest.Path == "/" {
+		return "", "", "", fmt.Errorf("%s output must be in %s://[user@]host[:port]/path form", dest.Scheme, dest.Scheme)
+	}
+
+	host = dest.Hostname()
+	if dest.User != nil {
+		host = dest.User.Username() + "@" + host
+	}
+	path = dest.Path
+	if rest, ok := strings.CutPrefix(path, "/~/"); ok {
+		path = rest
+	}
+	return host, dest.Port(), path, nil
+}
+
+// sshOptions are shared by sftp and scp. BatchMode rules out password
+// prompts, so authentication must use a key: the one in SFTP_IDENTITY_FILE,
+// the agent, or whatever ~/.ssh/config selects.
+func sshOptions(port string) []string {
+	args := []string{"-o", "BatchMode=yes"}
+	if identity := os.Getenv("SFTP_IDENTITY_FILE"); identity != "" {
+		args = append(args, "-i", identity)
+	}
+	if port != "" {
+		args = append(args, "-P", port)
+	}
+	return args
+}
+
+// uploadSFTP uploads with the OpenSSH sftp client, writing to a temporary
+// name first so readers never see a partially written feed.
+func uploadSFTP(dest *url.URL, data []byte, contentType string) error {
+	host, port, path, err := sshTarget(dest)
+	if err != nil {
+		return err
+	}
+
+	local, err := writeTempOutput(data)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(local)
+
+	// SFTP rename does not replace existing files, so remove the old feed
+	// first; the leading dash ignores the error when there is none.
+	tmp := path + ".tmp"
+	batch := fmt.Sprintf("put %s %s\n-rm %s\nrename %s %s\n",
+		sftpQuote(local), sftpQuote(tmp), sftpQuote(path), sftpQuote(tmp), sftpQuote(path))
+
+	args := append(sshOptions(port), "-b", "-", host)
+	return runSSHCommand("sftp", args, batch)
+}
+
+func sftpQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+func uploadSCP(dest *url.URL, data []byte, contentType string) error {
+	host, port, path, err := sshTarget(dest)
+	if err != nil {
+		return err
+	}
+
+	local, err := writeTempOutput(data)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(local)
+
+	args := append(sshOptions(port), "-q", local, host+":"+path)
+	return runSSHCommand("scp", args, "")
+}
+
+func writeTempOutput(data []byte) (string, error) {
+	file, err := os.CreateTemp("", "rss-agg-*.xml")
+	if err != nil {
+		return "", fmt.Errorf("error creating temp file: %v", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(data); err != nil {
+		os.Remove(file.Name())
+		return "", fmt.Errorf("error writing temp file: %v", err)
+	}
+	return file.Name(), nil
+}
+
+func runSSHCommand(name string, args []string, stdin string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdin = strings.NewReader(stdin)
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s: %v: %s", name, err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	return nil
+}
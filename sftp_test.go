@@ -0,0 +1,80 @@
+package main
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSSHTarget(t *testing.T) {
+	tests := []struct {
+		output   string
+		wantHost string
+		wantPort string
+		wantPath string
+		wantErr  bool
+	}{
+		{"sftp://user@example.com/var/www/feed.xml", "user@example.com", "", "/var/www/feed.xml", false},
+		{"sftp://example.com:2222/~/public_html/feed.xml", "example.com", "2222", "public_html/feed.xml", false},
+		{"scp://user@example.com/feed.xml", "user@example.com", "", "/feed.xml", false},
+		{"sftp://user@example.com/", "", "", "", true},
+		{"sftp:///feed.xml", "", "", "", true},
+	}
+
+	for _, tt := range tests {
+		dest, _ := url.Parse(tt.output)
+		host, port, path, err := sshTarget(dest)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("sshTarget(%q) error = %v, wantErr %v", tt.output, err, tt.wantErr)
+			continue
+		}
+		if host != tt.wantHost || port != tt.wantPort || path != tt.wantPath {
+			t.Errorf("sshTarget(%q) = %q, %q, %q, want %q, %q, %q", tt.output, host, port, path, tt.wantHost, tt.wantPort, tt.wantPath)
+		}
+	}
+}
+
+func TestUploadSFTP(t *testing.T) {
+	binDir, err := os.MkdirTemp("", "rss_sftp")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(binDir)
+
+	// A stand-in sftp client that records its arguments, batch commands and
+	// the file it was asked to put.
+	script := `#!/bin/sh
+echo "$@" > "$(dirname "$0")/args"
+cat > "$(dirname "$0")/batch"
+cp "$(sed -n 's/^put "\([^"]*\)".*/\1/p' "$(dirname "$0")/batch")" "$(dirname "$0")/uploaded"
+`
+	if err := os.WriteFile(filepath.Join(binDir, "sftp"), []byte(script), 0755); err != nil {
+		t.Fatalf("Failed to write fake sftp: %v", err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("SFTP_IDENTITY_FILE", "/keys/id_ed25519")
+
+	dest, upload, _ := remoteOutput("sftp://user@example.com:2222/var/www/feed.xml")
+	if err := upload(dest, []byte("<rss/>"), "application/rss+xml"); err != nil {
+		t.Fatalf("uploadSFTP() unexpected error = %v", err)
+	}
+
+	args, _ := os.ReadFile(filepath.Join(binDir, "args"))
+	if want := "-o BatchMode=yes -i /keys/id_ed25519 -P 2222 -b - user@example.com"; strings.TrimSpace(string(args)) != want {
+		t.Errorf("sftp args = %q, want %q", strings.TrimSpace(string(args)), want)
+	}
+
+	batch, _ := os.ReadFile(filepath.Join(binDir, "batch"))
+	for _, want := range []string{`"/var/www/feed.xml.tmp"`, `-rm "/var/www/feed.xml"`, `rename "/var/www/feed.xml.tmp" "/var/www/feed.xml"`} {
+		if !strings.Contains(string(batch), want) {
+			t.Errorf("sftp batch = %q, missing %q", batch, want)
+		}
+	}
+
+	uploaded, _ := os.ReadFile(filepath.Join(binDir, "uploaded"))
+	if string(uploaded) != "<rss/>" {
+		t.Errorf("uploaded content = %q, want %q", uploaded, "<rss/>")
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package main
+
+import "os"
+
+// Signals that control a running daemon; there are none outside Unix.
+var reloadSignal, refreshSignal os.Signal
+
+func notifyControlSignals(c chan<- os.Signal) {}
@@ -0,0 +1,19 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// Signals that control a running daemon.
+var (
+	reloadSignal  os.Signal = syscall.SIGHUP
+	refreshSignal os.Signal = syscall.SIGUSR1
+)
+
+func notifyControlSignals(c chan<- os.Signal) {
+	signal.Notify(c, reloadSignal, refreshSignal)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/gorilla/feeds"
+)
+
+// Slack rejects very long messages, so large batches are split.
+const slackItemsPerMessage = 20
+
+// SlackNotifier posts new items to a Slack incoming webhook.
+type SlackNotifier struct {
+	WebhookURL string
+}
+
+func (n *SlackNotifier) Name() string {
+	return "slack"
+}
+
+func (n *SlackNotifier) Notify(items []*feeds.Item) error {
+	for start := 0; start < len(items); start += slackItemsPerMessage {
+		end := min(start+slackItemsPerMessage, len(items))
+		if err := n.post(slackMessage(items[start:end])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func slackMessage(items []*feeds.Item) string {
+	var sb strings.Builder
+	for _, item := range items {
+		title := slackEscape(item.Title)
+		if item.Link != nil && item.Link.Href != "" {
+			fmt.Fprintf(&sb, "• <%s|%s>\n", item.Link.Href, title)
+		} else {
+			fmt.Fprintf(&sb, "• %s\n", title)
+		}
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// slackEscape escapes the characters Slack's mrkdwn treats as control
+// characters.
+func slackEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
+func (n *SlackNotifier) post(text string) error {
+	body, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return err
+	}
+
+	resp, err := httpClient.Post(n.WebhookURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	return nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/feeds"
+)
+
+func TestSlackMessage(t *testing.T) {
+	items := []*feeds.Item{
+		{Title: "Q&A <live>", Link: &feeds.Link{Href: "http://example.com/qa"}},
+		{Title: "No link"},
+	}
+
+	want := "• <http://example.com/qa|Q&amp;A &lt;live&gt;>\n• No link"
+	if got := slackMessage(items); got != want {
+		t.Errorf("slackMessage() = %q, want %q", got, want)
+	}
+}
+
+func TestUpdateStateNotifiesSlackOfNewItemsOnly(t *testing.T) {
+	var mu sync.Mutex
+	var messages []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("invalid Slack payload: %v", err)
+		}
+		mu.Lock()
+		messages = append(messages, payload["text"])
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	tempDir, err := os.MkdirTemp("", "rss_slack")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	config := &Config{StateFile: filepath.Join(tempDir, "state.json"), SlackWebhook: server.URL}
+	now := time.Now()
+
+	first := []*feeds.Item{{Title: "Old", Link: &feeds.Link{Href: "http://example.com/old"}}}
+	if err := updateState(config, first, nil, now); err != nil {
+		t.Fatalf("updateState() unexpected error = %v", err)
+	}
+	if len(messages) != 0 {
+		t.Errorf("first run should only seed state, got messages %v", messages)
+	}
+
+	second := []*feeds.Item{
+		{Title: "New", Link: &feeds.Link{Href: "http://example.com/new"}},
+		{Title: "Old", Link: &feeds.Link{Href: "http://example.com/old"}},
+	}
+	if err := updateState(config, second, nil, now); err != nil {
+		t.Fatalf("updateState() unexpected error = %v", err)
+	}
+	if len(messages) != 1 || !strings.Contains(messages[0], "New") || strings.Contains(messages[0], "Old") {
+		t.Errorf("second run messages = %v, want one message about the new item", messages)
+	}
+
+	if err := updateState(config, second, nil, now); err != nil {
+		t.Fatalf("updateState() unexpected error = %v", err)
+	}
+	if len(messages) != 1 {
+		t.Errorf("third run should not repost, got messages %v", messages)
+	}
+}
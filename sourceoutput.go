@@ -0,0 +1,67 @@
+package main
+
+import (
+	"cmp"
+	"log"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/gorilla/feeds"
+)
+
+// writeSourceFeeds writes each source on its own to dir/<source>.<ext>,
+// built from the source's own items, already limited by its count option,
+// through the same templates, transforms and enrichment as the aggregate,
+// and capped at -count; the tool can thus serve as a proxy that cleans up
+// individual feeds. Failures are logged and do not affect the other
+// sources or the aggregate.
+func writeSourceFeeds(config *Config, sources []*SourceFeed) {
+	if _, _, remote := remoteOutput(config.SourceOutputDir); !remote {
+		if err := os.MkdirAll(config.SourceOutputDir, 0755); err != nil {
+			log.Printf("Warning: failed to create source output directory %s: %v", config.SourceOutputDir, err)
+			return
+		}
+	}
+
+	// Each source feed stands alone: its newest items are picked without
+	// headings or archive pages, nothing is archived or remembered for it,
+	// and the aggregate's own links do not apply.
+	sourceConfig := *config
+	sourceConfig.Select = ""
+	sourceConfig.Order = ""
+	sourceConfig.GroupBy = ""
+	sourceConfig.PageSize = 0
+	sourceConfig.MergeDuplicates = false
+	sourceConfig.ArchiveDir = ""
+	sourceConfig.StateFile = ""
+	sourceConfig.OnlyNew = false
+	sourceConfig.SiteURL = ""
+	sourceConfig.SelfURL = ""
+	sourceConfig.WebSubHub = ""
+
+	format := config.format()
+	for _, source := range sources {
+		feed := buildFeed(&sourceConfig, []*SourceFeed{source})
+		feed.Title = cmp.Or(source.Title, source.URL)
+		feed.Link = &feeds.Link{Href: cmp.Or(source.Link, source.URL)}
+		feed.Description = feed.Title
+
+		output := strings.TrimSuffix(config.SourceOutputDir, "/") + "/" + sourceOutputName(source.URL) + format.Extension
+		opts := withCategories(sourceConfig.rssOptions(), []*SourceFeed{source})
+		if err := outputFeed(feed, output, format, opts); err != nil {
+			log.Printf("Warning: failed to write source feed %s: %v", output, err)
+		}
+	}
+}
+
+// sourceOutputName is the source's slug without the extension of its own
+// feed, which the output format's replaces.
+func sourceOutputName(sourceURL string) string {
+	name := sourceSlug(sourceURL)
+	if u, err := url.Parse(sourceURL); err == nil && u.Host != "" {
+		name = strings.TrimSuffix(name, path.Ext(u.Path))
+	}
+	return name
+}
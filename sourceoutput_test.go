@@ -0,0 +1,75 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/feeds"
+)
+
+func TestWriteSourceFeeds(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "rss_sources")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	now := time.Now()
+	sources := []*SourceFeed{
+		{URL: "https://a.example/feed.xml", Title: "Blog A", Link: "https://a.example/", Items: []*feeds.Item{
+			{Title: "A new", Link: &feeds.Link{Href: "https://a.example/2"}, Created: now},
+			{Title: "A old", Link: &feeds.Link{Href: "https://a.example/1"}, Created: now.Add(-time.Hour)},
+		}},
+		{URL: "https://b.example/rss", Items: []*feeds.Item{
+			{Title: "B", Link: &feeds.Link{Href: "https://b.example/1"}, Created: now.Add(-time.Minute)},
+		}},
+	}
+
+	for _, source := range sources {
+		for _, item := range source.Items {
+			item.Source = &feeds.Link{Href: source.URL}
+		}
+	}
+
+	titleTemplate, err := parseItemTemplate("title-template", "{{.Title}}!")
+	if err != nil {
+		t.Fatalf("parseItemTemplate() unexpected error = %v", err)
+	}
+	config := &Config{
+		Count:           2,
+		GroupBy:         "source",
+		SourceOutputDir: filepath.Join(tempDir, "out"),
+		TitleTemplate:   titleTemplate,
+		SelfURL:         "https://agg.example/feed.xml",
+	}
+	writeSourceFeeds(config, sources)
+
+	tests := []struct {
+		file    string
+		want    []string
+		notWant []string
+	}{
+		{"a.example_feed.xml", []string{"<title>Blog A</title>", "<link>https://a.example/</link>", "<title>A new!</title>", "<title>A old!</title>"}, []string{"agg.example"}},
+		{"b.example_rss.xml", []string{"<title>https://b.example/rss</title>", "<title>B!</title>"}, nil},
+	}
+
+	for _, tt := range tests {
+		content, err := os.ReadFile(filepath.Join(config.SourceOutputDir, tt.file))
+		if err != nil {
+			t.Fatalf("writeSourceFeeds() did not write %s: %v", tt.file, err)
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(string(content), want) {
+				t.Errorf("%s missing %q:\n%s", tt.file, want, content)
+			}
+		}
+		for _, notWant := range tt.notWant {
+			if strings.Contains(string(content), notWant) {
+				t.Errorf("%s should not contain %q:\n%s", tt.file, notWant, content)
+			}
+		}
+	}
+}
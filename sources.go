@@ -0,0 +1,425 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/gorilla/feeds"
+)
+
+const reviewDateLayout = "2006-01-02"
+
+// Source is one line of the input file: a feed URL plus optional
+// key=value options, for curation and to tune how the feed is fetched and
+// used.
+type Source struct {
+	URL      string
+	Notes    string
+	Owner    string
+	ReviewBy time.Time
+	// Weight is how many items the source gets per turn of round-robin
+	// selection; zero means one.
+	Weight int
+
+	// Name replaces the title the feed gives itself.
+	Name string
+	Tags []string
+	// Count limits how many of the feed's newest items are used.
+	Count     int
+	Timeout   time.Duration
+	UserAgent string
+	Headers   http.Header
+
+	// Paused sources stay in the list but are not fetched.
+	Paused bool
+
+	// List is the input file or included list the source was read from.
+	List string
+}
+
+func (s Source) dueForReview(now time.Time) bool {
+	return !s.ReviewBy.IsZero() && !s.ReviewBy.After(now)
+}
+
+// readSourcesFromFile reads an input file, standard input for "-", or a
+// list served over HTTP, together with the lists it includes.
+func readSourcesFromFile(filename string) ([]Source, error) {
+	return readSourceList(filename, nil)
+}
+
+// readSourceList reads one list. including holds the lists that led to it
+// through @include lines, to detect cycles.
+func readSourceList(name string, including []string) ([]Source, error) {
+	key := name
+	if !isRemoteList(name) && name != "-" {
+		if abs, err := filepath.Abs(name); err == nil {
+			key = abs
+		}
+	}
+	if slices.Contains(including, key) {
+		return nil, fmt.Errorf("include cycle through %s", name)
+	}
+	including = append(including, key)
+
+	switch {
+	case name == "-":
+		data, err := readStdin()
+		if err != nil {
+			return nil, fmt.Errorf("error reading standard input: %v", err)
+		}
+		return readSources(bytes.NewReader(data), name, including)
+
+	case isRemoteList(name):
+		resp, err := httpClient.Get(name)
+		if err != nil {
+			return nil, fmt.Errorf("error fetching list: %v", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("error fetching list: %s", resp.Status)
+		}
+		return readSources(resp.Body, name, including)
+
+	default:
+		file, err := os.Open(name)
+		if err != nil {
+			return nil, fmt.Errorf("error opening file: %v", err)
+		}
+		defer file.Close()
+
+		return readSources(file, name, including)
+	}
+}
+
+// readStdin reads standard input only once, so a daemon keeps using the
+// list it was started with.
+var readStdin = sync.OnceValues(func() ([]byte, error) {
+	return io.ReadAll(os.Stdin)
+})
+
+func isRemoteList(name string) bool {
+	return strings.HasPrefix(name, "http://") || strings.HasPrefix(name, "https://")
+}
+
+// includedList resolves the target of an @include line in the list name:
+// relative to the including file's directory, or to its URL. A list served
+// over HTTP may only include other lists served over HTTP, so that whoever
+// serves it cannot make the aggregator read local files.
+func includedList(name, target string) (string, error) {
+	switch {
+	case isRemoteList(name):
+		base, err := url.Parse(name)
+		if err != nil {
+			return "", fmt.Errorf("invalid list URL: %v", err)
+		}
+		ref, err := url.Parse(target)
+		if err != nil {
+			return "", fmt.Errorf("invalid include: %v", err)
+		}
+		resolved := base.ResolveReference(ref)
+		if resolved.Scheme != "http" && resolved.Scheme != "https" {
+			return "", fmt.Errorf("a remote list can only include http or https lists")
+		}
+		return resolved.String(), nil
+	case isRemoteList(target) || filepath.IsAbs(target) || name == "-":
+		return target, nil
+	default:
+		return filepath.Join(filepath.Dir(name), target), nil
+	}
+}
+
+func readSources(r io.Reader, name string, including []string) ([]Source, error) {
+	var sources []Source
+	scanner := bufio.NewScanner(r)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if target, ok := strings.CutPrefix(line, "@include "); ok {
+			target, err := includedList(name, strings.TrimSpace(target))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", lineNumber, err)
+			}
+			included, err := readSourceList(target, including)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %s: %v", lineNumber, target, err)
+			}
+			sources = append(sources, included...)
+			continue
+		}
+
+		source, err := parseSourceLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineNumber, err)
+		}
+		source.List = name
+		sources = append(sources, source)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading file: %v", err)
+	}
+
+	return sources, nil
+}
+
+// readSourcesFromFiles reads and merges several input files. A feed listed
+// more than once, even under differently written URLs, is used once, with
+// the options of its first line; the others are reported.
+func readSourcesFromFiles(filenames []string) ([]Source, error) {
+	filenames, err := expandInputGlobs(filenames)
+	if err != nil {
+		return nil, err
+	}
+
+	var sources []Source
+	seen := make(map[string]Source)
+	for _, filename := range filenames {
+		fileSources, err := readSourcesFromFile(filename)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", filename, err)
+		}
+		for _, source := range fileSources {
+			key := normalizeURL(source.URL)
+			if first, ok := seen[key]; ok {
+				log.Printf("Warning: %s in %s duplicates %s in %s, using the first", source.URL, source.List, first.URL, first.List)
+				continue
+			}
+			seen[key] = source
+			sources = append(sources, source)
+		}
+	}
+	return sources, nil
+}
+
+// expandInputGlobs replaces local input names containing glob patterns,
+// such as feeds.d/*.txt, by the files they match in lexical order.
+func expandInputGlobs(names []string) ([]string, error) {
+	var expanded []string
+	for _, name := range names {
+		if name == "-" || isRemoteList(name) || !strings.ContainsAny(name, "*?[") {
+			expanded = append(expanded, name)
+			continue
+		}
+		matches, err := filepath.Glob(name)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("%s: no files match", name)
+		}
+		expanded = append(expanded, matches...)
+	}
+	return expanded, nil
+}
+
+func parseSourceLine(line string) (Source, error) {
+	fields, err := splitSourceFields(line)
+	if err != nil {
+		return Source{}, err
+	}
+	if len(fields) == 0 {
+		return Source{}, fmt.Errorf("source line has no URL")
+	}
+
+	source := Source{URL: fields[0]}
+	for _, field := range fields[1:] {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok {
+			return Source{}, fmt.Errorf("option %q must be in key=value form", field)
+		}
+
+		switch key {
+		case "notes":
+			source.Notes = value
+		case "owner":
+			source.Owner = value
+		case "review-by":
+			reviewBy, err := time.Parse(reviewDateLayout, value)
+			if err != nil {
+				return Source{}, fmt.Errorf("review-by must be a YYYY-MM-DD date: %v", err)
+			}
+			source.ReviewBy = reviewBy
+		case "weight":
+			weight, err := strconv.Atoi(value)
+			if err != nil || weight < 1 {
+				return Source{}, fmt.Errorf("weight must be a positive whole number, got %q", value)
+			}
+			source.Weight = weight
+		case "name":
+			source.Name = value
+		case "tags":
+			for _, tag := range strings.Split(value, ",") {
+				if tag = strings.TrimSpace(tag); tag != "" {
+					source.Tags = append(source.Tags, tag)
+				}
+			}
+		case "count":
+			count, err := strconv.Atoi(value)
+			if err != nil || count < 1 {
+				return Source{}, fmt.Errorf("count must be a positive whole number, got %q", value)
+			}
+			source.Count = count
+		case "timeout":
+			timeout, err := time.ParseDuration(value)
+			if err != nil || timeout <= 0 {
+				return Source{}, fmt.Errorf("timeout must be a positive duration such as 10s, got %q", value)
+			}
+			source.Timeout = timeout
+		case "paused":
+			paused, err := strconv.ParseBool(value)
+			if err != nil {
+				return Source{}, fmt.Errorf("paused must be true or false, got %q", value)
+			}
+			source.Paused = paused
+		case "user-agent":
+			source.UserAgent = value
+		case "header":
+			name, headerValue, ok := strings.Cut(value, ":")
+			if !ok || strings.TrimSpace(name) == "" {
+				return Source{}, fmt.Errorf("header must be in \"Name: value\" form, got %q", value)
+			}
+			if source.Headers == nil {
+				source.Headers = make(http.Header)
+			}
+			source.Headers.Add(strings.TrimSpace(name), strings.TrimSpace(headerValue))
+		default:
+			return Source{}, fmt.Errorf("unknown option %q", key)
+		}
+	}
+
+	return source, nil
+}
+
+// splitSourceFields splits on whitespace, keeping double-quoted values
+// (as in notes="needs a new URL") together and unquoted.
+func splitSourceFields(line string) ([]string, error) {
+	var fields []string
+	var current strings.Builder
+	inQuotes := false
+	hasField := false
+
+	for _, r := range line {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+			hasField = true
+		case !inQuotes && (r == ' ' || r == '\t'):
+			if hasField {
+				fields = append(fields, current.String())
+				current.Reset()
+				hasField = false
+			}
+		default:
+			current.WriteRune(r)
+			hasField = true
+		}
+	}
+
+	if inQuotes {
+		return nil, fmt.Errorf("unterminated quote")
+	}
+	if hasField {
+		fields = append(fields, current.String())
+	}
+
+	return fields, nil
+}
+
+// apply applies the line's options to source, freshly fetched or pushed
+// from its URL.
+func (s Source) apply(source *SourceFeed) {
+	source.Title = cmp.Or(s.Name, source.Title)
+	source.Tags = s.Tags
+	source.Weight = s.Weight
+
+	if s.Count > 0 && len(source.Items) > s.Count {
+		slices.SortStableFunc(source.Items, func(a, b *feeds.Item) int {
+			return b.Created.Compare(a.Created)
+		})
+		source.Items = source.Items[:s.Count]
+	}
+}
+
+// stringList is a flag that may be given several times, one value each.
+// Values are not split on commas, which paths and URLs may contain.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, " ")
+}
+
+func (l *stringList) Set(value string) error {
+	if value = strings.TrimSpace(value); value != "" {
+		*l = append(*l, value)
+	}
+	return nil
+}
+
+// stringsFlag defines a stringList flag on flags, like flags.String.
+func stringsFlag(flags *flag.FlagSet, name, usage string) *[]string {
+	var list stringList
+	flags.Var(&list, name, usage)
+	return (*[]string)(&list)
+}
+
+func runList(args []string) error {
+	flags := flag.NewFlagSet("list", flag.ExitOnError)
+	inputFiles := stringsFlag(flags, "input", "Input file containing RSS feed URLs (one per line); may be repeated")
+	due := flags.Bool("due", false, "Only show feeds whose review-by date has passed")
+	flags.Parse(args)
+
+	if len(*inputFiles) == 0 {
+		return fmt.Errorf("input file must be provided")
+	}
+
+	sources, err := readSourcesFromFiles(*inputFiles)
+	if err != nil {
+		return fmt.Errorf("error reading input file: %v", err)
+	}
+
+	return writeSourceList(os.Stdout, sources, *due, time.Now())
+}
+
+func writeSourceList(w io.Writer, sources []Source, dueOnly bool, now time.Time) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "URL\tOWNER\tREVIEW-BY\tNOTES")
+
+	for _, source := range sources {
+		due := source.dueForReview(now)
+		if dueOnly && !due {
+			continue
+		}
+
+		reviewBy := ""
+		if !source.ReviewBy.IsZero() {
+			reviewBy = source.ReviewBy.Format(reviewDateLayout)
+			if due {
+				reviewBy += " (due)"
+			}
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", source.URL, source.Owner, reviewBy, source.Notes)
+	}
+
+	return tw.Flush()
+}
@@ -0,0 +1,363 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseSourceLine(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    string
+		want    Source
+		wantErr bool
+	}{
+		{
+			name: "bare URL",
+			line: "http://example.com/feed.xml",
+			want: Source{URL: "http://example.com/feed.xml"},
+		},
+		{
+			name: "all metadata",
+			line: `http://example.com/feed.xml owner=alice review-by=2024-06-01 notes="moved to substack?"`,
+			want: Source{
+				URL:      "http://example.com/feed.xml",
+				Owner:    "alice",
+				ReviewBy: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC),
+				Notes:    "moved to substack?",
+			},
+		},
+		{
+			name:    "unknown option",
+			line:    "http://example.com/feed.xml colour=blue",
+			wantErr: true,
+		},
+		{
+			name:    "missing value",
+			line:    "http://example.com/feed.xml owner",
+			wantErr: true,
+		},
+		{
+			name:    "bad date",
+			line:    "http://example.com/feed.xml review-by=soon",
+			wantErr: true,
+		},
+		{
+			name: "paused",
+			line: "http://example.com/feed.xml paused=true",
+			want: Source{URL: "http://example.com/feed.xml", Paused: true},
+		},
+		{
+			name:    "bad paused",
+			line:    "http://example.com/feed.xml paused=maybe",
+			wantErr: true,
+		},
+		{
+			name: "weight",
+			line: "http://example.com/feed.xml weight=3",
+			want: Source{URL: "http://example.com/feed.xml", Weight: 3},
+		},
+		{
+			name: "fetch options",
+			line: `http://example.com/feed.xml name="Example Blog" tags=go,web count=5 timeout=10s user-agent=curl/8 header="Authorization: Bearer abc"`,
+			want: Source{
+				URL:       "http://example.com/feed.xml",
+				Name:      "Example Blog",
+				Tags:      []string{"go", "web"},
+				Count:     5,
+				Timeout:   10 * time.Second,
+				UserAgent: "curl/8",
+				Headers:   http.Header{"Authorization": {"Bearer abc"}},
+			},
+		},
+		{
+			name:    "bad timeout",
+			line:    "http://example.com/feed.xml timeout=soon",
+			wantErr: true,
+		},
+		{
+			name:    "bad header",
+			line:    "http://example.com/feed.xml header=Authorization",
+			wantErr: true,
+		},
+		{
+			name:    "zero weight",
+			line:    "http://example.com/feed.xml weight=0",
+			wantErr: true,
+		},
+		{
+			name:    "empty",
+			line:    "  ",
+			wantErr: true,
+		},
+		{
+			name:    "unterminated quote",
+			line:    `http://example.com/feed.xml notes="oops`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseSourceLine(tt.line)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("parseSourceLine() expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseSourceLine() unexpected error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseSourceLine() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteSourceList(t *testing.T) {
+	now := time.Date(2024, 6, 15, 0, 0, 0, 0, time.UTC)
+	sources := []Source{
+		{URL: "http://a.example/feed", Owner: "alice", ReviewBy: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)},
+		{URL: "http://b.example/feed", Owner: "bob", ReviewBy: time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC)},
+		{URL: "http://c.example/feed", Notes: "no review date"},
+	}
+
+	var all bytes.Buffer
+	if err := writeSourceList(&all, sources, false, now); err != nil {
+		t.Fatalf("writeSourceList() unexpected error = %v", err)
+	}
+	for _, want := range []string{"http://a.example/feed", "2024-06-01 (due)", "http://b.example/feed", "no review date"} {
+		if !strings.Contains(all.String(), want) {
+			t.Errorf("writeSourceList() output missing %q:\n%s", want, all.String())
+		}
+	}
+	if strings.Contains(all.String(), "2024-12-01 (due)") {
+		t.Errorf("writeSourceList() marked a future review date as due:\n%s", all.String())
+	}
+
+	var due bytes.Buffer
+	if err := writeSourceList(&due, sources, true, now); err != nil {
+		t.Fatalf("writeSourceList() unexpected error = %v", err)
+	}
+	if !strings.Contains(due.String(), "http://a.example/feed") {
+		t.Errorf("due list missing overdue feed:\n%s", due.String())
+	}
+	if strings.Contains(due.String(), "http://b.example/feed") || strings.Contains(due.String(), "http://c.example/feed") {
+		t.Errorf("due list contains feeds not due for review:\n%s", due.String())
+	}
+}
+
+func TestFetchSourceFeedOptions(t *testing.T) {
+	rssContent := `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0">
+<channel>
+<title>Test Feed</title>
+<link>http://example.com</link>
+<item><title>Older</title><link>http://example.com/1</link><pubDate>Wed, 01 Jan 2020 00:00:00 GMT</pubDate></item>
+<item><title>Newer</title><link>http://example.com/2</link><pubDate>Thu, 02 Jan 2020 00:00:00 GMT</pubDate></item>
+</channel>
+</rss>`
+
+	var gotAgent, gotAuth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAgent, gotAuth = r.Header.Get("User-Agent"), r.Header.Get("Authorization")
+		w.Write([]byte(rssContent))
+	}))
+	defer server.Close()
+
+	source, err := fetchSourceFeed(Source{
+		URL:       server.URL,
+		Name:      "Renamed",
+		Tags:      []string{"news"},
+		Count:     1,
+		Timeout:   5 * time.Second,
+		UserAgent: "test-agent",
+		Headers:   http.Header{"Authorization": {"Bearer abc"}},
+	})
+	if err != nil {
+		t.Fatalf("fetchSourceFeed() unexpected error = %v", err)
+	}
+
+	if gotAgent != "test-agent" || gotAuth != "Bearer abc" {
+		t.Errorf("fetchSourceFeed() sent User-Agent %q and Authorization %q", gotAgent, gotAuth)
+	}
+	if source.Title != "Renamed" || !reflect.DeepEqual(source.Tags, []string{"news"}) {
+		t.Errorf("fetchSourceFeed() title = %q, tags = %v, want the configured name and tags", source.Title, source.Tags)
+	}
+	if len(source.Items) != 1 || source.Items[0].Title != "Newer" {
+		t.Errorf("fetchSourceFeed() with count=1 got %d items, want only the newest", len(source.Items))
+	}
+}
+
+func TestReadSourcesFromFiles(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "rss_inputs")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	work := filepath.Join(tempDir, "work.txt")
+	personal := filepath.Join(tempDir, "personal.txt")
+	os.WriteFile(work, []byte("https://a.example/feed owner=work\nhttps://b.example/feed\n"), 0644)
+	os.WriteFile(personal, []byte("https://b.example/feed owner=me\nhttps://c.example/feed\n"), 0644)
+
+	var inputs stringList
+	inputs.Set(work)
+	inputs.Set(" " + personal + " ")
+
+	sources, err := readSourcesFromFiles(inputs)
+	if err != nil {
+		t.Fatalf("readSourcesFromFiles() unexpected error = %v", err)
+	}
+	want := []Source{
+		{URL: "https://a.example/feed", Owner: "work", List: work},
+		{URL: "https://b.example/feed", List: work},
+		{URL: "https://c.example/feed", List: personal},
+	}
+	if !reflect.DeepEqual(sources, want) {
+		t.Errorf("readSourcesFromFiles() = %+v, want %+v", sources, want)
+	}
+
+	// Commas belong to the value, as in list URLs with query parameters.
+	var urls stringList
+	urls.Set("https://lists.example/feeds?tags=a,b")
+	if want := (stringList{"https://lists.example/feeds?tags=a,b"}); !reflect.DeepEqual(urls, want) {
+		t.Errorf("stringList.Set() = %q, want %q", urls, want)
+	}
+
+	if _, err := readSourcesFromFiles([]string{work, filepath.Join(tempDir, "missing.txt")}); err == nil {
+		t.Errorf("readSourcesFromFiles() expected error for a missing file")
+	}
+}
+
+func TestReadSourcesFromURL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/feeds.txt":
+			w.Write([]byte("# team feeds\nhttps://a.example/feed owner=team\n"))
+		case "/local.txt":
+			w.Write([]byte("@include file:///etc/passwd\n"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	sources, err := readSourcesFromFile(server.URL + "/feeds.txt")
+	if err != nil {
+		t.Fatalf("readSourcesFromFile() unexpected error = %v", err)
+	}
+	if want := []Source{{URL: "https://a.example/feed", Owner: "team", List: server.URL + "/feeds.txt"}}; !reflect.DeepEqual(sources, want) {
+		t.Errorf("readSourcesFromFile() = %+v, want %+v", sources, want)
+	}
+
+	if _, err := readSourcesFromFile(server.URL + "/missing.txt"); err == nil {
+		t.Errorf("readSourcesFromFile() expected error for a missing list")
+	}
+
+	if _, err := readSourcesFromFile(server.URL + "/local.txt"); err == nil || !strings.Contains(err.Error(), "only include http") {
+		t.Errorf("readSourcesFromFile() error = %v, want a remote list refused a local include", err)
+	}
+}
+
+func TestReadSourcesFromFileIncludes(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "rss_include")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	os.Mkdir(filepath.Join(tempDir, "topics"), 0755)
+	files := map[string]string{
+		"feeds.txt":        "https://a.example/feed\n@include topics/go.txt\n",
+		"topics/go.txt":    "https://go.example/feed owner=gophers\n",
+		"cycle.txt":        "@include topics/cycle.txt\n",
+		"topics/cycle.txt": "@include ../cycle.txt\n",
+	}
+	for name, content := range files {
+		os.WriteFile(filepath.Join(tempDir, name), []byte(content), 0644)
+	}
+
+	sources, err := readSourcesFromFile(filepath.Join(tempDir, "feeds.txt"))
+	if err != nil {
+		t.Fatalf("readSourcesFromFile() unexpected error = %v", err)
+	}
+	want := []Source{
+		{URL: "https://a.example/feed", List: filepath.Join(tempDir, "feeds.txt")},
+		{URL: "https://go.example/feed", Owner: "gophers", List: filepath.Join(tempDir, "topics", "go.txt")},
+	}
+	if !reflect.DeepEqual(sources, want) {
+		t.Errorf("readSourcesFromFile() = %+v, want %+v", sources, want)
+	}
+
+	_, err = readSourcesFromFile(filepath.Join(tempDir, "cycle.txt"))
+	if err == nil || !strings.Contains(err.Error(), "include cycle") {
+		t.Errorf("readSourcesFromFile() error = %v, want an include cycle", err)
+	}
+}
+
+func TestIncludedList(t *testing.T) {
+	tests := []struct {
+		name    string
+		target  string
+		want    string
+		wantErr bool
+	}{
+		{"lists/feeds.txt", "go.txt", filepath.Join("lists", "go.txt"), false},
+		{"lists/feeds.txt", "/etc/feeds.txt", "/etc/feeds.txt", false},
+		{"https://example.com/lists/feeds.txt", "go.txt", "https://example.com/lists/go.txt", false},
+		{"https://example.com/lists/feeds.txt", "https://other.example/x.txt", "https://other.example/x.txt", false},
+		{"https://example.com/lists/feeds.txt", "/etc/feeds.txt", "https://example.com/etc/feeds.txt", false},
+		{"https://example.com/lists/feeds.txt", "file:///etc/passwd", "", true},
+		{"https://example.com/lists/feeds.txt", "ftp://example.com/x.txt", "", true},
+		{"-", "go.txt", "go.txt", false},
+	}
+
+	for _, tt := range tests {
+		got, err := includedList(tt.name, tt.target)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("includedList(%q, %q) error = %v, wantErr %v", tt.name, tt.target, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("includedList(%q, %q) = %q, want %q", tt.name, tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestReadSourcesFromFilesGlob(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "rss_glob")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	os.WriteFile(filepath.Join(tempDir, "b-news.txt"), []byte("https://b.example/feed\n"), 0644)
+	os.WriteFile(filepath.Join(tempDir, "a-tech.txt"), []byte("https://a.example/feed\n"), 0644)
+	os.WriteFile(filepath.Join(tempDir, "README.md"), []byte("not a list\n"), 0644)
+
+	sources, err := readSourcesFromFiles([]string{filepath.Join(tempDir, "*.txt")})
+	if err != nil {
+		t.Fatalf("readSourcesFromFiles() unexpected error = %v", err)
+	}
+	want := []Source{
+		{URL: "https://a.example/feed", List: filepath.Join(tempDir, "a-tech.txt")},
+		{URL: "https://b.example/feed", List: filepath.Join(tempDir, "b-news.txt")},
+	}
+	if !reflect.DeepEqual(sources, want) {
+		t.Errorf("readSourcesFromFiles() = %+v, want %+v", sources, want)
+	}
+
+	if _, err := readSourcesFromFiles([]string{filepath.Join(tempDir, "*.opml")}); err == nil {
+		t.Errorf("readSourcesFromFiles() expected error when a pattern matches nothing")
+	}
+}
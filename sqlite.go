@@ -0,0 +1,120 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/gorilla/feeds"
+	_ "modernc.org/sqlite"
+)
+
+// sqliteSchema is the documented layout of -format sqlite output. Times are
+// RFC 3339 strings, and items.position is the item's rank in the aggregate.
+const sqliteSchema = `
+CREATE TABLE feeds (
+	id  INTEGER PRIMARY KEY,
+	url TEXT NOT NULL UNIQUE
+);
+CREATE TABLE items (
+	id          INTEGER PRIMARY KEY,
+	feed_id     INTEGER REFERENCES feeds(id),
+	position    INTEGER NOT NULL,
+	guid        TEXT,
+	title       TEXT NOT NULL,
+	link        TEXT,
+	author      TEXT,
+	description TEXT,
+	content     TEXT,
+	published   TEXT,
+	updated     TEXT
+);
+CREATE INDEX items_feed_id ON items(feed_id);
+CREATE INDEX items_published ON items(published);
+`
+
+// writeSQLite builds a database in a temporary file, since SQLite cannot
+// write to a stream, and copies it to w.
+func writeSQLite(w io.Writer, feed *feeds.Feed, opts RssOptions) error {
+	tmp, err := os.CreateTemp("", "rss-agg-*.db")
+	if err != nil {
+		return fmt.Errorf("error creating temp database: %v", err)
+	}
+	tmp.Close()
+	defer os.Remove(tmp.Name())
+
+	if err := fillSQLite(tmp.Name(), feed); err != nil {
+		return err
+	}
+
+	db, err := os.Open(tmp.Name())
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	_, err = io.Copy(w, db)
+	return err
+}
+
+func fillSQLite(path string, feed *feeds.Feed) error {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return fmt.Errorf("error opening database: %v", err)
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("error opening database: %v", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(sqliteSchema); err != nil {
+		return fmt.Errorf("error creating schema: %v", err)
+	}
+
+	feedIDs := make(map[string]int64)
+	for i, item := range feed.Items {
+		var feedID sql.NullInt64
+		if item.Source != nil && item.Source.Href != "" {
+			id, ok := feedIDs[item.Source.Href]
+			if !ok {
+				result, err := tx.Exec(`INSERT INTO feeds (url) VALUES (?)`, item.Source.Href)
+				if err != nil {
+					return fmt.Errorf("error inserting feed: %v", err)
+				}
+				if id, err = result.LastInsertId(); err != nil {
+					return err
+				}
+				feedIDs[item.Source.Href] = id
+			}
+			feedID = sql.NullInt64{Int64: id, Valid: true}
+		}
+
+		row := toItemJSON(item)
+		_, err := tx.Exec(`INSERT INTO items
+			(feed_id, position, guid, title, link, author, description, content, published, updated)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			feedID, i+1, nullString(row.ID), row.Title, nullString(row.Link), nullString(row.Author),
+			nullString(row.Description), nullString(row.Content), nullTime(row.Created), nullTime(row.Updated))
+		if err != nil {
+			return fmt.Errorf("error inserting item: %v", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+func nullTime(t time.Time) sql.NullString {
+	if t.IsZero() {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: t.UTC().Format(time.RFC3339), Valid: true}
+}
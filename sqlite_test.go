@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gorilla/feeds"
+)
+
+func TestWriteSQLite(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "rss_sqlite")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	feed := &feeds.Feed{
+		Title: "RSS Aggregator Feed",
+		Items: []*feeds.Item{
+			{Title: "A", Link: &feeds.Link{Href: "https://a.example/1"}, Source: &feeds.Link{Href: "https://a.example/feed"}, Created: time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC)},
+			{Title: "B", Link: &feeds.Link{Href: "https://b.example/1"}, Source: &feeds.Link{Href: "https://b.example/feed"}},
+			{Title: "C", Content: "<p>Body</p>", Source: &feeds.Link{Href: "https://a.example/feed"}},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := writeSQLite(&buf, feed, RssOptions{}); err != nil {
+		t.Fatalf("writeSQLite() unexpected error = %v", err)
+	}
+	path := filepath.Join(tempDir, "feed.db")
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatalf("Failed to write database: %v", err)
+	}
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("sql.Open() unexpected error = %v", err)
+	}
+	defer db.Close()
+
+	var feedCount int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM feeds`).Scan(&feedCount); err != nil {
+		t.Fatalf("query feeds: %v", err)
+	}
+	if feedCount != 2 {
+		t.Errorf("feeds table has %d rows, want 2", feedCount)
+	}
+
+	var title, url, published string
+	err = db.QueryRow(`SELECT items.title, feeds.url, items.published FROM items
+		JOIN feeds ON feeds.id = items.feed_id WHERE items.position = 1`).Scan(&title, &url, &published)
+	if err != nil {
+		t.Fatalf("query items: %v", err)
+	}
+	if title != "A" || url != "https://a.example/feed" || published != "2024-03-02T00:00:00Z" {
+		t.Errorf("first item = %q, %q, %q", title, url, published)
+	}
+
+	var content string
+	if err := db.QueryRow(`SELECT content FROM items WHERE title = 'C'`).Scan(&content); err != nil {
+		t.Fatalf("query content: %v", err)
+	}
+	if content != "<p>Body</p>" {
+		t.Errorf("content = %q, want %q", content, "<p>Body</p>")
+	}
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/feeds"
+)
+
+// SeenItem records when an item was first encountered, the hash of its
+// content and when that content last changed.
+type SeenItem struct {
+	FirstSeen time.Time `json:"first_seen"`
+	Hash      string    `json:"hash,omitempty"`
+	Updated   time.Time `json:"updated,omitzero"`
+}
+
+// State is the persistent record of items seen in previous runs, of how
+// fetching each source went, and of the operator alerts in effect.
+type State struct {
+	Items   map[string]SeenItem      `json:"items"`
+	Sources map[string]*SourceHealth `json:"sources,omitempty"`
+	// Alerts holds the operator alerts sent and not yet cleared, by key.
+	Alerts map[string]OperatorAlert `json:"alerts,omitempty"`
+
+	path  string
+	isNew bool
+}
+
+func loadState(path string) (*State, error) {
+	state := &State{path: path}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		state.Items = make(map[string]SeenItem)
+		state.isNew = true
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading state file: %v", err)
+	}
+
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("error parsing state file: %v", err)
+	}
+	// Source URLs were recorded as written before they were normalized.
+	for url, health := range state.Sources {
+		if key := normalizeURL(url); key != url {
+			if _, ok := state.Sources[key]; !ok {
+				state.Sources[key] = health
+			}
+			delete(state.Sources, url)
+		}
+	}
+	// Items is written even when empty once a run has recorded them, so
+	// only a state file holding nothing but source health, saved with null
+	// items, has not seen any yet.
+	if state.Items == nil {
+		state.Items = make(map[string]SeenItem)
+		state.isNew = true
+	}
+
+	return state, nil
+}
+
+// save writes the state atomically so an interrupted write never leaves a
+// truncated state file behind.
+func (s *State) save() error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := writeFileAtomic(s.path, data, 0600); err != nil {
+		return fmt.Errorf("error writing state file: %v", err)
+	}
+	return nil
+}
+
+// markSeen records items with their content hashes from hashes, and
+// returns those not seen before, in their original order. An item whose
+// content changed is recorded as updated at its Updated date, which
+// markUpdated set when the run noticed the change, or else now.
+func (s *State) markSeen(items []*feeds.Item, hashes map[string]string, now time.Time) []*feeds.Item {
+	var unseen []*feeds.Item
+	for _, item := range items {
+		key, seen, ok := s.lookup(item)
+		hash := hashes[key]
+		if ok {
+			if seen.changed(hash) {
+				seen.Updated = now
+				if item.Updated.After(seen.FirstSeen) && !item.Updated.After(now) {
+					seen.Updated = item.Updated
+				}
+			}
+			seen.Hash = cmp.Or(hash, seen.Hash)
+			s.Items[key] = seen
+			continue
+		}
+		s.Items[key] = SeenItem{FirstSeen: now, Hash: hash}
+		unseen = append(unseen, item)
+	}
+	return unseen
+}
+
+// prune forgets items first seen longer than maxAge ago, then the oldest
+// items beyond maxItems. Zero disables either limit. Items whose keys are
+// in fetched, those the sources still list, are always kept, since
+// forgetting them would make them new again next run even when the output
+// left them out.
+func (s *State) prune(now time.Time, maxAge time.Duration, maxItems int, fetched map[string]bool) {
+	keep := fetched
+
+	if maxAge > 0 {
+		cutoff := now.Add(-maxAge)
+		for key, seen := range s.Items {
+			if !keep[key] && seen.FirstSeen.Before(cutoff) {
+				delete(s.Items, key)
+			}
+		}
+	}
+
+	if maxItems > 0 && len(s.Items) > maxItems {
+		var keys []string
+		for key := range s.Items {
+			if !keep[key] {
+				keys = append(keys, key)
+			}
+		}
+		slices.SortFunc(keys, func(a, b string) int {
+			return s.Items[a].FirstSeen.Compare(s.Items[b].FirstSeen)
+		})
+		for _, key := range keys[:min(len(keys), len(s.Items)-maxItems)] {
+			delete(s.Items, key)
+		}
+	}
+}
+
+// parseDays parses a Go duration, or a whole number of days such as "90d",
+// as accepted by -retain and search -since.
+func parseDays(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("%q is not a duration such as 720h or 90d", value)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("%q is not a duration such as 720h or 90d", value)
+	}
+	return d, nil
+}
+
+// unseenItems returns the items not recorded in the state file at path, in
+// their original order. Every item is unseen when there is no state file yet.
+func unseenItems(path string, items []*feeds.Item) ([]*feeds.Item, error) {
+	state, err := loadState(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var unseen []*feeds.Item
+	for _, item := range items {
+		if _, _, ok := state.lookup(item); !ok {
+			unseen = append(unseen, item)
+		}
+	}
+	return unseen, nil
+}
+
+// lookup returns the key of item and what was recorded about it, if
+// anything. An item recorded under its legacy key is moved to its current
+// one, so state files from before links were normalized keep working.
+func (s *State) lookup(item *feeds.Item) (string, SeenItem, bool) {
+	key := itemKey(item)
+	if seen, ok := s.Items[key]; ok {
+		return key, seen, true
+	}
+	if legacy := legacyItemKey(item); legacy != key {
+		if seen, ok := s.Items[legacy]; ok {
+			delete(s.Items, legacy)
+			s.Items[key] = seen
+			return key, seen, true
+		}
+	}
+	return key, SeenItem{}, false
+}
+
+// itemKey identifies an item across runs: its guid if it has one, otherwise
+// its normalized link, otherwise its source and title.
+func itemKey(item *feeds.Item) string {
+	if item.Id != "" {
+		return item.Id
+	}
+	if item.Link != nil && item.Link.Href != "" {
+		return normalizeURL(item.Link.Href)
+	}
+	source := ""
+	if item.Source != nil {
+		source = normalizeURL(item.Source.Href)
+	}
+	return source + "\n" + item.Title
+}
+
+// legacyItemKey is what itemKey returned before links were normalized.
+func legacyItemKey(item *feeds.Item) string {
+	if item.Id != "" {
+		return item.Id
+	}
+	if item.Link != nil && item.Link.Href != "" {
+		return item.Link.Href
+	}
+	source := ""
+	if item.Source != nil {
+		source = item.Source.Href
+	}
+	return source + "\n" + item.Title
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/gorilla/feeds"
+)
+
+func TestStateMarkSeenPersists(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "rss_state")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	path := filepath.Join(tempDir, "state.json")
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	state, err := loadState(path)
+	if err != nil {
+		t.Fatalf("loadState() unexpected error = %v", err)
+	}
+	if !state.isNew {
+		t.Errorf("loadState() of missing file should report a new state")
+	}
+
+	first := []*feeds.Item{
+		{Title: "A", Link: &feeds.Link{Href: "http://example.com/a"}},
+		{Title: "B", Link: &feeds.Link{Href: "http://example.com/b"}},
+	}
+	if unseen := state.markSeen(first, nil, now); len(unseen) != 2 {
+		t.Errorf("markSeen() returned %d unseen items, want 2", len(unseen))
+	}
+	if err := state.save(); err != nil {
+		t.Fatalf("save() unexpected error = %v", err)
+	}
+
+	reloaded, err := loadState(path)
+	if err != nil {
+		t.Fatalf("loadState() unexpected error = %v", err)
+	}
+	if reloaded.isNew {
+		t.Errorf("loadState() of existing file should not report a new state")
+	}
+
+	second := []*feeds.Item{
+		{Title: "B", Link: &feeds.Link{Href: "http://example.com/b"}},
+		{Title: "C", Link: &feeds.Link{Href: "http://example.com/c"}},
+	}
+	unseen := reloaded.markSeen(second, nil, now.Add(time.Hour))
+	if len(unseen) != 1 || unseen[0].Title != "C" {
+		t.Errorf("markSeen() after reload = %+v, want only C", unseen)
+	}
+	if got := reloaded.Items["http://example.com/a"].FirstSeen; !got.Equal(now) {
+		t.Errorf("first seen time = %v, want %v", got, now)
+	}
+}
+
+func TestItemKey(t *testing.T) {
+	tests := []struct {
+		name string
+		item *feeds.Item
+		want string
+	}{
+		{"guid wins", &feeds.Item{Id: "guid-1", Link: &feeds.Link{Href: "http://x/1"}}, "guid-1"},
+		{"link", &feeds.Item{Link: &feeds.Link{Href: "http://x/1"}}, "http://x/1"},
+		{"normalized link", &feeds.Item{Link: &feeds.Link{Href: "HTTP://X:80/1/#comments"}}, "http://x/1"},
+		{"source and title", &feeds.Item{Title: "T", Source: &feeds.Link{Href: "http://x/feed"}}, "http://x/feed\nT"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := itemKey(tt.item); got != tt.want {
+				t.Errorf("itemKey() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildFeedOnlyNew(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "rss_state")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	sources, err := demoSources()
+	if err != nil {
+		t.Fatalf("demoSources() unexpected error = %v", err)
+	}
+
+	config := &Config{
+		Count:      2,
+		OutputFile: filepath.Join(tempDir, "out.xml"),
+		StateFile:  filepath.Join(tempDir, "state.json"),
+		OnlyNew:    true,
+	}
+
+	// Each run should output the next batch of unseen items until none are
+	// left, never repeating one.
+	seen := make(map[string]bool)
+	for run := 0; ; run++ {
+		feed := buildFeed(config, sources)
+		if len(feed.Items) == 0 {
+			break
+		}
+		for _, item := range feed.Items {
+			if seen[itemKey(item)] {
+				t.Errorf("run %d repeated item %q", run, item.Title)
+			}
+			seen[itemKey(item)] = true
+		}
+		if err := publishFeed(config, feed, sources); err != nil {
+			t.Fatalf("publishFeed() unexpected error = %v", err)
+		}
+		if run > 10 {
+			t.Fatalf("buildFeed() with only-new never ran out of items")
+		}
+	}
+
+	total := 0
+	for _, source := range sources {
+		total += len(source.Items)
+	}
+	if len(seen) != total {
+		t.Errorf("only-new runs output %d distinct items, want %d", len(seen), total)
+	}
+}
+
+func TestStateLegacyKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	firstSeen := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	legacy := `{"items": {"HTTPS://Example.com/a/": {"first_seen": "2024-01-01T00:00:00Z", "hash": "h"}},
+		"sources": {"HTTPS://Example.com:443/feed": {"failure_streak": 2}}}`
+	if err := os.WriteFile(path, []byte(legacy), 0644); err != nil {
+		t.Fatalf("Failed to write state: %v", err)
+	}
+
+	state, err := loadState(path)
+	if err != nil {
+		t.Fatalf("loadState() unexpected error = %v", err)
+	}
+	if _, ok := state.Sources["https://example.com/feed"]; !ok || len(state.Sources) != 1 {
+		t.Errorf("loadState() sources = %v, want them under normalized URLs", state.Sources)
+	}
+
+	item := &feeds.Item{Title: "A", Link: &feeds.Link{Href: "HTTPS://Example.com/a/"}}
+	if unseen := state.markSeen([]*feeds.Item{item}, nil, firstSeen.Add(time.Hour)); len(unseen) != 0 {
+		t.Errorf("markSeen() = %v, want the item recorded under its legacy key seen", unseen)
+	}
+	if seen, ok := state.Items[itemKey(item)]; !ok || !seen.FirstSeen.Equal(firstSeen) || len(state.Items) != 1 {
+		t.Errorf("markSeen() items = %v, want the record moved to %q", state.Items, itemKey(item))
+	}
+}
+
+func TestLoadStateIsNew(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// Source health alone leaves the state new.
+	recordSourceHealth(&Config{StateFile: path}, &RunStats{Results: map[string]SourceResult{"https://a.example/feed": {Items: 1}}}, now)
+	if state, err := loadState(path); err != nil || !state.isNew {
+		t.Errorf("loadState() after recording health = %+v, %v, want a new state", state, err)
+	}
+
+	// A first run that saw no items still counts.
+	if err := updateState(&Config{StateFile: path}, nil, nil, now); err != nil {
+		t.Fatalf("updateState() unexpected error = %v", err)
+	}
+	if state, err := loadState(path); err != nil || state.isNew {
+		t.Errorf("loadState() after a run without items = %+v, %v, want a state that is not new", state, err)
+	}
+}
+
+func TestStatePrune(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	newState := func() *State {
+		return &State{Items: map[string]SeenItem{
+			"old":     {FirstSeen: now.Add(-100 * day)},
+			"current": {FirstSeen: now.Add(-100 * day)},
+			"month":   {FirstSeen: now.Add(-30 * day)},
+			"week":    {FirstSeen: now.Add(-7 * day)},
+			"today":   {FirstSeen: now},
+		}}
+	}
+	fetched := map[string]bool{"current": true}
+
+	tests := []struct {
+		name     string
+		maxAge   time.Duration
+		maxItems int
+		want     []string
+	}{
+		{"no limits", 0, 0, []string{"current", "month", "old", "today", "week"}},
+		{"max age", 90 * day, 0, []string{"current", "month", "today", "week"}},
+		{"max items drops oldest", 0, 3, []string{"current", "today", "week"}},
+		{"both", 10 * day, 2, []string{"current", "today"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := newState()
+			state.prune(now, tt.maxAge, tt.maxItems, fetched)
+
+			var got []string
+			for key := range state.Items {
+				got = append(got, key)
+			}
+			slices.Sort(got)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("prune() kept %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateStateKeepsFetchedItems(t *testing.T) {
+	config := &Config{StateFile: filepath.Join(t.TempDir(), "state.json"), RetainMax: 1}
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	// Both items are still in the sources, but only the newer is output.
+	hashes := map[string]string{"old": "a", "new": "b"}
+	if err := updateState(config, []*feeds.Item{{Id: "old"}}, hashes, now); err != nil {
+		t.Fatalf("updateState() unexpected error = %v", err)
+	}
+	if err := updateState(config, []*feeds.Item{{Id: "new"}}, hashes, now.Add(time.Hour)); err != nil {
+		t.Fatalf("updateState() unexpected error = %v", err)
+	}
+
+	state, err := loadState(config.StateFile)
+	if err != nil {
+		t.Fatalf("loadState() unexpected error = %v", err)
+	}
+	if _, ok := state.Items["old"]; !ok {
+		t.Errorf("updateState() pruned an item its source still lists")
+	}
+}
+
+func TestParseDays(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"", 0, false},
+		{"90d", 90 * 24 * time.Hour, false},
+		{"720h", 720 * time.Hour, false},
+		{"-1d", 0, true},
+		{"soon", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseDays(tt.value)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseDays(%q) = %v, %v, want %v, wantErr %v", tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,558 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// uploader publishes the rendered feed to a remote -output destination.
+type uploader func(dest *url.URL, data []byte, contentType string) error
+
+// uploaders maps -output URL schemes to the function publishing there.
+// Outputs with any other scheme are written as local files.
+var uploaders = map[string]uploader{
+	"s3":   uploadS3,
+	"gs":   uploadGCS,
+	"az":   uploadAzure,
+	"sftp": uploadSFTP,
+	"scp":  uploadSCP,
+}
+
+// remoteOutput reports whether outputFile names a remote destination, and
+// how to publish to it.
+func remoteOutput(outputFile string) (*url.URL, uploader, bool) {
+	dest, err := url.Parse(outputFile)
+	if err != nil {
+		return nil, nil, false
+	}
+	upload, ok := uploaders[dest.Scheme]
+	return dest, upload, ok
+}
+
+// checkRemoteOutput validates the form of a remote destination up front, so
+// mistakes surface as configuration errors rather than on the first upload.
+func checkRemoteOutput(dest *url.URL) error {
+	var err error
+	switch dest.Scheme {
+	case "sftp", "scp":
+		_, _, _, err = sshTarget(dest)
+	default:
+		_, _, err = bucketObject(dest)
+	}
+	return err
+}
+
+// bucketObject splits s3://bucket/key style URLs.
+func bucketObject(dest *url.URL) (bucket, key string, err error) {
+	bucket = dest.Host
+	key = strings.TrimPrefix(dest.Path, "/")
+	if bucket == "" || key == "" {
+		return "", "", fmt.Errorf("%s output must be in %s://bucket/key form", dest.Scheme, dest.Scheme)
+	}
+	return bucket, key, nil
+}
+
+func putObject(req *http.Request) error {
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("unexpected status %s: %s", resp.Status, bytes.TrimSpace(body))
+	}
+
+	return nil
+}
+
+var awsMetadataBase = "http://169.254.169.254"
+
+type awsCredentials struct {
+	AccessKeyID     string `json:"AccessKeyId"`
+	SecretAccessKey string `json:"SecretAccessKey"`
+	SessionToken    string `json:"Token"`
+}
+
+func uploadS3(dest *url.URL, data []byte, contentType string) error {
+	bucket, key, err := bucketObject(dest)
+	if err != nil {
+		return err
+	}
+
+	creds, err := loadAWSCredentials()
+	if err != nil {
+		return fmt.Errorf("error loading AWS credentials: %v", err)
+	}
+
+	region := cmp.Or(os.Getenv("AWS_REGION"), os.Getenv("AWS_DEFAULT_REGION"), "us-east-1")
+	objectURL := fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", bucket, region, awsURIEncode(key, false))
+	if endpoint := cmp.Or(os.Getenv("AWS_ENDPOINT_URL_S3"), os.Getenv("AWS_ENDPOINT_URL")); endpoint != "" {
+		objectURL = strings.TrimSuffix(endpoint, "/") + "/" + bucket + "/" + awsURIEncode(key, false)
+	}
+
+	req, err := http.NewRequest(http.MethodPut, objectURL, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	sum := sha256.Sum256(data)
+	signAWSv4(req, hex.EncodeToString(sum[:]), creds, region, "s3", time.Now())
+
+	return putObject(req)
+}
+
+// loadAWSCredentials reads credentials from the standard environment
+// variables, falling back to the EC2 instance metadata service.
+func loadAWSCredentials() (awsCredentials, error) {
+	if id := os.Getenv("AWS_ACCESS_KEY_ID"); id != "" {
+		return awsCredentials{
+			AccessKeyID:     id,
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		}, nil
+	}
+
+	tokenReq, err := http.NewRequest(http.MethodPut, awsMetadataBase+"/latest/api/token", nil)
+	if err != nil {
+		return awsCredentials{}, err
+	}
+	tokenReq.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", "300")
+	token, err := metadataGet(tokenReq)
+	if err != nil {
+		return awsCredentials{}, fmt.Errorf("no AWS_ACCESS_KEY_ID and instance metadata unavailable: %v", err)
+	}
+
+	get := func(path string) ([]byte, error) {
+		req, err := http.NewRequest(http.MethodGet, awsMetadataBase+"/latest/meta-data/iam/security-credentials/"+path, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("X-aws-ec2-metadata-token", string(token))
+		return metadataGet(req)
+	}
+
+	role, err := get("")
+	if err != nil {
+		return awsCredentials{}, err
+	}
+	body, err := get(strings.TrimSpace(strings.SplitN(string(role), "\n", 2)[0]))
+	if err != nil {
+		return awsCredentials{}, err
+	}
+
+	var creds awsCredentials
+	if err := json.Unmarshal(body, &creds); err != nil {
+		return awsCredentials{}, fmt.Errorf("error decoding instance credentials: %v", err)
+	}
+	return creds, nil
+}
+
+// signAWSv4 adds a Signature Version 4 Authorization header covering the
+// Host header and every header already set on req.
+func signAWSv4(req *http.Request, payloadHash string, creds awsCredentials, region, service string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if creds.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	query := req.URL.Query()
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	var canonicalQuery []string
+	for _, key := range keys {
+		for _, value := range query[key] {
+			canonicalQuery = append(canonicalQuery, awsURIEncode(key, true)+"="+awsURIEncode(value, true))
+		}
+	}
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		cmp.Or(req.URL.EscapedPath(), "/"),
+		strings.Join(canonicalQuery, "&"),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := []byte("AWS4" + creds.SecretAccessKey)
+	for _, part := range []string{date, region, service, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		creds.AccessKeyID, scope, signedHeaders, signature))
+}
+
+// awsURIEncode percent-encodes everything but unreserved characters, and
+// slashes too unless encodeSlash is false.
+func awsURIEncode(s string, encodeSlash bool) string {
+	var sb strings.Builder
+	for _, b := range []byte(s) {
+		switch {
+		case 'A' <= b && b <= 'Z', 'a' <= b && b <= 'z', '0' <= b && b <= '9',
+			b == '-', b == '.', b == '_', b == '~', b == '/' && !encodeSlash:
+			sb.WriteByte(b)
+		default:
+			fmt.Fprintf(&sb, "%%%02X", b)
+		}
+	}
+	return sb.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func metadataGet(req *http.Request) ([]byte, error) {
+	client := &http.Client{Timeout: 2 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s from %s", resp.Status, req.URL)
+	}
+
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
+
+var gcpMetadataBase = "http://metadata.google.internal"
+
+func uploadGCS(dest *url.URL, data []byte, contentType string) error {
+	bucket, key, err := bucketObject(dest)
+	if err != nil {
+		return err
+	}
+
+	base := "https://storage.googleapis.com"
+	emulator := os.Getenv("STORAGE_EMULATOR_HOST")
+	if emulator != "" {
+		base = strings.TrimSuffix(emulator, "/")
+		if !strings.Contains(base, "://") {
+			base = "http://" + base
+		}
+	}
+	objectURL := base + "/upload/storage/v1/b/" + url.PathEscape(bucket) + "/o?" +
+		url.Values{"uploadType": {"media"}, "name": {key}}.Encode()
+
+	req, err := http.NewRequest(http.MethodPost, objectURL, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	if emulator == "" {
+		token, err := loadGCPToken()
+		if err != nil {
+			return fmt.Errorf("error loading Google Cloud credentials: %v", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	return putObject(req)
+}
+
+// loadGCPToken uses GOOGLE_OAUTH_ACCESS_TOKEN if set, then the credentials
+// file GOOGLE_APPLICATION_CREDENTIALS names, and otherwise asks the metadata
+// server for the default service account's token.
+func loadGCPToken() (string, error) {
+	if token := os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN"); token != "" {
+		return token, nil
+	}
+	if path := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS"); path != "" {
+		return gcpCredentialsToken(path, time.Now())
+	}
+
+	req, err := http.NewRequest(http.MethodGet, gcpMetadataBase+"/computeMetadata/v1/instance/service-accounts/default/token", nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	body, err := metadataGet(req)
+	if err != nil {
+		return "", fmt.Errorf("no GOOGLE_OAUTH_ACCESS_TOKEN or GOOGLE_APPLICATION_CREDENTIALS and metadata server unavailable: %v", err)
+	}
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.Unmarshal(body, &token); err != nil {
+		return "", fmt.Errorf("error decoding metadata token: %v", err)
+	}
+	return token.AccessToken, nil
+}
+
+const (
+	gcpTokenURL     = "https://oauth2.googleapis.com/token"
+	gcpStorageScope = "https://www.googleapis.com/auth/devstorage.read_write"
+)
+
+// gcpCredentials is what a Google credentials file holds: either a service
+// account key, or the user credentials that gcloud auth
+// application-default login writes.
+type gcpCredentials struct {
+	Type         string `json:"type"`
+	ClientEmail  string `json:"client_email"`
+	PrivateKey   string `json:"private_key"`
+	TokenURI     string `json:"token_uri"`
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// gcpCredentialsToken exchanges the credentials file at path for an access
+// token: a service account's with a JWT signed by its key, a user's with
+// their refresh token.
+func gcpCredentialsToken(path string, now time.Time) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("error reading credentials file: %v", err)
+	}
+	var creds gcpCredentials
+	if err := json.Unmarshal(data, &creds); err != nil {
+		return "", fmt.Errorf("error parsing credentials file: %v", err)
+	}
+
+	tokenURL := cmp.Or(creds.TokenURI, gcpTokenURL)
+	var form url.Values
+	switch creds.Type {
+	case "service_account":
+		assertion, err := creds.assertion(tokenURL, now)
+		if err != nil {
+			return "", err
+		}
+		form = url.Values{"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"}, "assertion": {assertion}}
+	case "authorized_user":
+		form = url.Values{
+			"grant_type":    {"refresh_token"},
+			"client_id":     {creds.ClientID},
+			"client_secret": {creds.ClientSecret},
+			"refresh_token": {creds.RefreshToken},
+		}
+	default:
+		return "", fmt.Errorf("unsupported credentials type %q", creds.Type)
+	}
+
+	resp, err := httpClient.PostForm(tokenURL, form)
+	if err != nil {
+		return "", fmt.Errorf("error requesting token: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("error requesting token: %s", resp.Status)
+	}
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&token); err != nil {
+		return "", fmt.Errorf("error decoding token: %v", err)
+	}
+	if token.AccessToken == "" {
+		return "", fmt.Errorf("token response has no access_token")
+	}
+	return token.AccessToken, nil
+}
+
+// assertion returns a JWT for aud, signed with the service account's key,
+// asking for an hour's access to Cloud Storage.
+func (c gcpCredentials) assertion(aud string, now time.Time) (string, error) {
+	block, _ := pem.Decode([]byte(c.PrivateKey))
+	if block == nil {
+		return "", fmt.Errorf("credentials file has no PEM private key")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return "", fmt.Errorf("error parsing private key: %v", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return "", fmt.Errorf("private key is not an RSA key")
+	}
+
+	claims, err := json.Marshal(map[string]any{
+		"iss":   c.ClientEmail,
+		"scope": gcpStorageScope,
+		"aud":   aud,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+	enc := base64.RawURLEncoding
+	signed := enc.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`)) + "." + enc.EncodeToString(claims)
+	sum := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(nil, key, crypto.SHA256, sum[:])
+	if err != nil {
+		return "", fmt.Errorf("error signing token request: %v", err)
+	}
+	return signed + "." + enc.EncodeToString(signature), nil
+}
+
+const azureStorageVersion = "2021-08-06"
+
+var (
+	azureBlobEndpoint = "https://%s.blob.core.windows.net"
+	azureMetadataBase = "http://169.254.169.254"
+)
+
+// uploadAzure publishes to az://container/blob in the storage account named
+// by AZURE_STORAGE_ACCOUNT.
+func uploadAzure(dest *url.URL, data []byte, contentType string) error {
+	container, blob, err := bucketObject(dest)
+	if err != nil {
+		return err
+	}
+
+	account := os.Getenv("AZURE_STORAGE_ACCOUNT")
+	if account == "" {
+		return fmt.Errorf("AZURE_STORAGE_ACCOUNT must be set for az:// output")
+	}
+
+	path := "/" + container + "/" + blob
+	blobURL := fmt.Sprintf(azureBlobEndpoint, account) + (&url.URL{Path: path}).EscapedPath()
+	if sas := os.Getenv("AZURE_STORAGE_SAS_TOKEN"); sas != "" {
+		blobURL += "?" + strings.TrimPrefix(sas, "?")
+	}
+
+	req, err := http.NewRequest(http.MethodPut, blobURL, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("x-ms-blob-type", "BlockBlob")
+	req.Header.Set("x-ms-version", azureStorageVersion)
+	req.Header.Set("x-ms-date", time.Now().UTC().Format(http.TimeFormat))
+
+	switch {
+	case os.Getenv("AZURE_STORAGE_SAS_TOKEN") != "":
+	case os.Getenv("AZURE_STORAGE_KEY") != "":
+		key, err := base64.StdEncoding.DecodeString(os.Getenv("AZURE_STORAGE_KEY"))
+		if err != nil {
+			return fmt.Errorf("AZURE_STORAGE_KEY must be base64: %v", err)
+		}
+		req.Header.Set("Authorization", "SharedKey "+account+":"+azureSharedKeySignature(req, account, path, len(data), key))
+	default:
+		token, err := loadAzureToken()
+		if err != nil {
+			return fmt.Errorf("error loading Azure credentials: %v", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	return putObject(req)
+}
+
+func azureSharedKeySignature(req *http.Request, account, path string, contentLength int, key []byte) string {
+	length := ""
+	if contentLength > 0 {
+		length = strconv.Itoa(contentLength)
+	}
+
+	var msHeaders []string
+	for name, values := range req.Header {
+		name = strings.ToLower(name)
+		if strings.HasPrefix(name, "x-ms-") {
+			msHeaders = append(msHeaders, name+":"+strings.Join(values, ","))
+		}
+	}
+	slices.Sort(msHeaders)
+
+	stringToSign := strings.Join([]string{
+		req.Method,
+		req.Header.Get("Content-Encoding"),
+		req.Header.Get("Content-Language"),
+		length,
+		req.Header.Get("Content-MD5"),
+		req.Header.Get("Content-Type"),
+		"", // Date, superseded by x-ms-date
+		req.Header.Get("If-Modified-Since"),
+		req.Header.Get("If-Match"),
+		req.Header.Get("If-None-Match"),
+		req.Header.Get("If-Unmodified-Since"),
+		req.Header.Get("Range"),
+		strings.Join(msHeaders, "\n"),
+		"/" + account + path,
+	}, "\n")
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(stringToSign))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// loadAzureToken asks the instance metadata service for a managed identity
+// token for Azure Storage.
+func loadAzureToken() (string, error) {
+	req, err := http.NewRequest(http.MethodGet, azureMetadataBase+"/metadata/identity/oauth2/token?"+
+		url.Values{"api-version": {"2018-02-01"}, "resource": {"https://storage.azure.com/"}}.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata", "true")
+	body, err := metadataGet(req)
+	if err != nil {
+		return "", fmt.Errorf("no AZURE_STORAGE_KEY or AZURE_STORAGE_SAS_TOKEN and managed identity unavailable: %v", err)
+	}
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.Unmarshal(body, &token); err != nil {
+		return "", fmt.Errorf("error decoding managed identity token: %v", err)
+	}
+	return token.AccessToken, nil
+}
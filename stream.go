@@ -0,0 +1,137 @@
+package main
+
+import (
+	"cmp"
+	"container/heap"
+	"slices"
+	"sync"
+
+	"github.com/gorilla/feeds"
+)
+
+// itemStream keeps the newest -count items of a run as sources are
+// fetched, in a heap with the oldest kept item on top, so that a run over
+// thousands of sources holds O(count) items at a time rather than every
+// item of every source.
+type itemStream struct {
+	count int
+	order map[string]int
+
+	mu      sync.Mutex
+	heap    itemHeap
+	results map[string]SourceResult
+	owners  map[string]*SourceFeed
+}
+
+// itemHeap orders items oldest first, by newer.
+type itemHeap struct {
+	items []*feeds.Item
+	newer func(a, b *feeds.Item) bool
+}
+
+func (h itemHeap) Len() int           { return len(h.items) }
+func (h itemHeap) Less(i, j int) bool { return h.newer(h.items[j], h.items[i]) }
+func (h itemHeap) Swap(i, j int)      { h.items[i], h.items[j] = h.items[j], h.items[i] }
+func (h *itemHeap) Push(x any)        { h.items = append(h.items, x.(*feeds.Item)) }
+func (h *itemHeap) Pop() any {
+	last := h.items[len(h.items)-1]
+	h.items[len(h.items)-1] = nil
+	h.items = h.items[:len(h.items)-1]
+	return last
+}
+
+// streamable reports whether only the newest -count items of a run can end
+// up in its output, so that older ones need not be kept while fetching. The
+// daemon keeps every source to rebuild from, and round-robin and daily
+// selection, -order other than desc, archive pages, -only-new,
+// -merge-duplicates, -languages, -transform-cmd, -archive,
+// -source-output-dir and -state, which must not forget the content hashes
+// of items still listed, all look beyond the newest items.
+func (config *Config) streamable() bool {
+	return !config.daemon() && cmp.Or(config.Select, "newest") == "newest" &&
+		cmp.Or(config.Order, "desc") == "desc" && config.PageSize == 0 && !config.OnlyNew &&
+		!config.MergeDuplicates && len(config.Languages) == 0 && config.CheckLinks != "drop" && config.TransformCmd == "" &&
+		config.ArchiveDir == "" && config.SourceOutputDir == "" && config.StateFile == ""
+}
+
+// newItemStream returns a stream for a run over entries, whose order
+// decides which of two sources that turn out to be the same feed is kept,
+// as in dropDuplicateSources.
+func newItemStream(config *Config, entries []Source) *itemStream {
+	order := make(map[string]int, len(entries))
+	for i, entry := range entries {
+		order[entry.URL] = i
+	}
+	return &itemStream{
+		count:   config.Count,
+		order:   order,
+		heap:    itemHeap{newer: config.newer()},
+		results: make(map[string]SourceResult),
+		owners:  make(map[string]*SourceFeed),
+	}
+}
+
+// add moves source's items into the stream, keeping those among the
+// newest so far and dropping the rest, along with any kept items they
+// displace. The items of a source that is the same feed as one listed
+// earlier are dropped before they can take any place, and those of one
+// listed later are taken out again. The source is left without items until
+// finish.
+func (s *itemStream) add(source *SourceFeed) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.results[source.URL] = fetchResult(source)
+	items := source.Items
+	source.Items = nil
+
+	key := normalizeURL(source.feedURL())
+	if owner, ok := s.owners[key]; ok {
+		if s.order[owner.URL] <= s.order[source.URL] {
+			return
+		}
+		s.remove(owner.URL)
+	}
+	s.owners[key] = source
+
+	for _, item := range items {
+		if s.heap.Len() < s.count {
+			heap.Push(&s.heap, item)
+		} else if s.count > 0 && s.heap.newer(item, s.heap.items[0]) {
+			s.heap.items[0] = item
+			heap.Fix(&s.heap, 0)
+		}
+	}
+}
+
+// remove drops the kept items of the source with url.
+func (s *itemStream) remove(url string) {
+	s.heap.items = slices.DeleteFunc(s.heap.items, func(item *feeds.Item) bool {
+		return item.Source != nil && item.Source.Href == url
+	})
+	heap.Init(&s.heap)
+}
+
+// finish gives each of sources back those of its items that were kept,
+// newest first, and records how fetching each went in stats. Kept items of
+// sources dropped since they were added are dropped too.
+func (s *itemStream) finish(sources []*SourceFeed, stats *RunStats) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	bySource := make(map[string]*SourceFeed, len(sources))
+	for _, source := range sources {
+		bySource[source.URL] = source
+		stats.record(source.URL, s.results[source.URL])
+	}
+	for s.heap.Len() > 0 {
+		item := heap.Pop(&s.heap).(*feeds.Item)
+		if item.Source == nil {
+			continue
+		}
+		if source, ok := bySource[item.Source.Href]; ok {
+			source.Items = append(source.Items, item)
+		}
+	}
+	for _, source := range sources {
+		slices.Reverse(source.Items)
+	}
+}
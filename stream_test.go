@@ -0,0 +1,177 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/feeds"
+)
+
+func TestItemStream(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	newSource := func(url string, hours ...int) *SourceFeed {
+		source := &SourceFeed{URL: url}
+		for _, h := range hours {
+			source.Items = append(source.Items, &feeds.Item{
+				Title:   fmt.Sprintf("%s %d", url, h),
+				Source:  &feeds.Link{Href: url},
+				Created: base.Add(time.Duration(h) * time.Hour),
+			})
+		}
+		return source
+	}
+	a := newSource("a", 1, 5, 9)
+	b := newSource("b", 8, 2, 7, 3)
+	c := newSource("c", 4)
+
+	stream := newItemStream(&Config{Count: 3}, nil)
+	for _, source := range []*SourceFeed{a, b, c} {
+		stream.add(source)
+		if source.Items != nil {
+			t.Errorf("add() left %d items on source %s, want none", len(source.Items), source.URL)
+		}
+	}
+	stats := &RunStats{}
+	stream.finish([]*SourceFeed{a, b, c}, stats)
+
+	titles := func(source *SourceFeed) string {
+		var titles []string
+		for _, item := range source.Items {
+			titles = append(titles, item.Title)
+		}
+		return fmt.Sprint(titles)
+	}
+	tests := []struct {
+		source    *SourceFeed
+		want      string
+		wantItems int
+	}{
+		{a, "[a 9]", 3},
+		{b, "[b 8 b 7]", 4},
+		{c, "[]", 1},
+	}
+	for _, tt := range tests {
+		if got := titles(tt.source); got != tt.want {
+			t.Errorf("source %s items after finish() = %s, want %s", tt.source.URL, got, tt.want)
+		}
+		if got := stats.Results[tt.source.URL].Items; got != tt.wantItems {
+			t.Errorf("source %s recorded %d items, want all %d fetched", tt.source.URL, got, tt.wantItems)
+		}
+	}
+}
+
+func TestItemStreamTies(t *testing.T) {
+	published := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	source := &SourceFeed{URL: "a"}
+	for _, title := range []string{"d", "b", "a", "c"} {
+		source.Items = append(source.Items, &feeds.Item{
+			Title:   title,
+			Link:    &feeds.Link{Href: "https://example.com/" + title},
+			Source:  &feeds.Link{Href: source.URL},
+			Created: published,
+		})
+	}
+
+	stream := newItemStream(&Config{Count: 2}, nil)
+	stream.add(source)
+	stream.finish([]*SourceFeed{source}, &RunStats{})
+	if len(source.Items) != 2 || source.Items[0].Title != "a" || source.Items[1].Title != "b" {
+		t.Errorf("stream kept %v, want the items a and b sorted first", source.Items)
+	}
+}
+
+func TestItemStreamDuplicates(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	newSource := func(url, finalURL string, hours ...int) *SourceFeed {
+		source := &SourceFeed{URL: url, FinalURL: finalURL}
+		for _, h := range hours {
+			source.Items = append(source.Items, &feeds.Item{
+				Title:   fmt.Sprintf("%s %d", url, h),
+				Source:  &feeds.Link{Href: url},
+				Created: base.Add(time.Duration(h) * time.Hour),
+			})
+		}
+		return source
+	}
+	entries := []Source{{URL: "https://a.example/feed"}, {URL: "https://b.example/feed"}, {URL: "https://old.example/feed"}}
+	tests := []struct {
+		name  string
+		added []int
+	}{
+		{"duplicate added last", []int{0, 1, 2}},
+		{"duplicate added first", []int{2, 0, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// old.example has moved to a.example, listed first.
+			sources := []*SourceFeed{
+				newSource(entries[0].URL, "", 9, 8),
+				newSource(entries[1].URL, "", 5, 4),
+				newSource(entries[2].URL, entries[0].URL, 9, 8),
+			}
+			stream := newItemStream(&Config{Count: 4}, entries)
+			for _, i := range tt.added {
+				stream.add(sources[i])
+			}
+			kept := dropDuplicateSources(sources)
+			stream.finish(kept, &RunStats{})
+
+			var titles []string
+			for _, source := range kept {
+				for _, item := range source.Items {
+					titles = append(titles, item.Title)
+				}
+			}
+			want := "[https://a.example/feed 9 https://a.example/feed 8 https://b.example/feed 5 https://b.example/feed 4]"
+			if got := fmt.Sprint(titles); got != want {
+				t.Errorf("stream kept %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestFetchSourcesStreams(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(cacheTestFeed))
+	}))
+	defer server.Close()
+
+	entries := []Source{{URL: server.URL + "/a"}, {URL: server.URL + "/b"}}
+	config := &Config{Count: 1}
+	sources, stats := fetchSources(config, entries)
+
+	kept := 0
+	for _, source := range sources {
+		kept += len(source.Items)
+		if got := stats.Results[source.URL].Items; got != 2 {
+			t.Errorf("source %s recorded %d items, want 2", source.URL, got)
+		}
+	}
+	if kept != 1 {
+		t.Errorf("fetchSources() kept %d items with -count 1, want 1", kept)
+	}
+
+	config.PageSize = 5
+	sources, _ = fetchSources(config, entries)
+	kept = 0
+	for _, source := range sources {
+		kept += len(source.Items)
+	}
+	if kept != 4 {
+		t.Errorf("fetchSources() with -page-size kept %d items, want all 4", kept)
+	}
+
+	config.PageSize = 0
+	config.StateFile = "state.json"
+	sources, _ = fetchSources(config, entries)
+	kept = 0
+	for _, source := range sources {
+		kept += len(source.Items)
+	}
+	if kept != 4 {
+		t.Errorf("fetchSources() with -state kept %d items, want all 4", kept)
+	}
+}
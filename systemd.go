@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+// systemdListen is the -listen value that serves on the socket systemd
+// passes to the service (socket activation).
+const systemdListen = "systemd"
+
+// sdNotify sends state, such as "READY=1", to systemd when running as a
+// Type=notify service. It does nothing elsewhere.
+func sdNotify(state string) error {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return nil
+	}
+	// A leading @ names a socket in the abstract namespace.
+	if socket[0] == '@' {
+		socket = "\x00" + socket[1:]
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = conn.Write([]byte(state))
+	return err
+}
+
+// sdWatchdog pings systemd's watchdog at half the interval it asks for in
+// WATCHDOG_USEC, until ctx is cancelled, as long as healthy reports the
+// daemon keeps refreshing; otherwise systemd restarts it. It returns at
+// once when the watchdog is not enabled for this process.
+func sdWatchdog(ctx context.Context, healthy func() bool) {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return
+	}
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return
+	}
+
+	ticker := time.NewTicker(time.Duration(usec) * time.Microsecond / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if healthy() {
+				sdNotify("WATCHDOG=1")
+			}
+		}
+	}
+}
+
+// systemdListener returns the first socket passed by systemd socket
+// activation, as described by LISTEN_PID and LISTEN_FDS.
+func systemdListener() (net.Listener, error) {
+	if os.Getenv("LISTEN_PID") != strconv.Itoa(os.Getpid()) {
+		return nil, fmt.Errorf("no socket passed by systemd (LISTEN_PID is not this process)")
+	}
+	fds, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || fds < 1 {
+		return nil, fmt.Errorf("no socket passed by systemd (LISTEN_FDS is not set)")
+	}
+
+	// Passed sockets start at file descriptor 3.
+	file := os.NewFile(3, "systemd-socket")
+	defer file.Close()
+	return net.FileListener(file)
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSdNotify(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "rss_systemd")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	socket := filepath.Join(tempDir, "notify")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		t.Skipf("unixgram sockets unavailable: %v", err)
+	}
+	defer conn.Close()
+
+	t.Setenv("NOTIFY_SOCKET", "")
+	if err := sdNotify("READY=1"); err != nil {
+		t.Errorf("sdNotify() without NOTIFY_SOCKET unexpected error = %v", err)
+	}
+
+	t.Setenv("NOTIFY_SOCKET", socket)
+	if err := sdNotify("READY=1"); err != nil {
+		t.Fatalf("sdNotify() unexpected error = %v", err)
+	}
+
+	buf := make([]byte, 64)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, err := conn.Read(buf)
+	if err != nil || string(buf[:n]) != "READY=1" {
+		t.Errorf("systemd received %q (%v), want READY=1", buf[:n], err)
+	}
+}
+
+func TestSdWatchdog(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "rss_systemd")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	socket := filepath.Join(tempDir, "notify")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		t.Skipf("unixgram sockets unavailable: %v", err)
+	}
+	defer conn.Close()
+
+	t.Setenv("NOTIFY_SOCKET", socket)
+	t.Setenv("WATCHDOG_USEC", "20000")
+	t.Setenv("WATCHDOG_PID", strconv.Itoa(os.Getpid()))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var healthy atomic.Bool
+	go sdWatchdog(ctx, healthy.Load)
+
+	// An unhealthy daemon is not pinged for.
+	conn.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+	if n, err := conn.Read(make([]byte, 64)); err == nil {
+		t.Errorf("systemd received %d bytes while unhealthy, want none", n)
+	}
+	healthy.Store(true)
+
+	buf := make([]byte, 64)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, err := conn.Read(buf)
+	if err != nil || string(buf[:n]) != "WATCHDOG=1" {
+		t.Errorf("systemd received %q (%v), want WATCHDOG=1", buf[:n], err)
+	}
+}
+
+func TestDaemonListenerSystemd(t *testing.T) {
+	t.Setenv("LISTEN_PID", "")
+	if _, err := daemonListener(systemdListen); err == nil {
+		t.Errorf("daemonListener(systemd) expected error without socket activation")
+	}
+
+	listener, err := daemonListener("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("daemonListener() unexpected error = %v", err)
+	}
+	defer listener.Close()
+
+	go http.Serve(listener, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	resp, err := http.Get("http://" + listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Get() from daemonListener() unexpected error = %v", err)
+	}
+	resp.Body.Close()
+}
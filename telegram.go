@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/feeds"
+)
+
+const telegramAPI = "https://api.telegram.org"
+
+// TelegramNotifier sends each new item as a bot message, letting Telegram
+// render a preview of the item link.
+type TelegramNotifier struct {
+	Token  string
+	ChatID string
+
+	apiBase string
+}
+
+func (n *TelegramNotifier) Name() string {
+	return "telegram"
+}
+
+func (n *TelegramNotifier) Notify(items []*feeds.Item) error {
+	for _, item := range items {
+		if err := n.send(telegramMessage(item)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func telegramMessage(item *feeds.Item) string {
+	title := html.EscapeString(item.Title)
+	if item.Link == nil || item.Link.Href == "" {
+		return "<b>" + title + "</b>"
+	}
+	link := html.EscapeString(item.Link.Href)
+	return fmt.Sprintf("<b>%s</b>\n<a href=\"%s\">%s</a>", title, link, link)
+}
+
+func (n *TelegramNotifier) send(text string) error {
+	base := n.apiBase
+	if base == "" {
+		base = telegramAPI
+	}
+
+	body, err := json.Marshal(map[string]any{
+		"chat_id":    n.ChatID,
+		"text":       text,
+		"parse_mode": "HTML",
+	})
+	if err != nil {
+		return err
+	}
+
+	for retried := false; ; retried = true {
+		resp, err := httpClient.Post(base+"/bot"+n.Token+"/sendMessage", "application/json", bytes.NewReader(body))
+		if err != nil {
+			// The token is part of the URL; keep it out of logs.
+			return fmt.Errorf("sending message failed")
+		}
+
+		var result struct {
+			OK          bool   `json:"ok"`
+			Description string `json:"description"`
+			Parameters  struct {
+				RetryAfter int `json:"retry_after"`
+			} `json:"parameters"`
+		}
+		data, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		json.Unmarshal(data, &result)
+
+		if resp.StatusCode == http.StatusTooManyRequests && !retried {
+			time.Sleep(time.Duration(max(result.Parameters.RetryAfter, 1)) * time.Second)
+			continue
+		}
+		if !result.OK {
+			return fmt.Errorf("telegram error: %s %s", resp.Status, result.Description)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/feeds"
+)
+
+func TestTelegramNotifier(t *testing.T) {
+	var payloads []map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/botTOKEN/sendMessage" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		var payload map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("invalid Telegram payload: %v", err)
+		}
+		payloads = append(payloads, payload)
+		fmt.Fprint(w, `{"ok": true}`)
+	}))
+	defer server.Close()
+
+	notifier := &TelegramNotifier{Token: "TOKEN", ChatID: "@news", apiBase: server.URL}
+	items := []*feeds.Item{
+		{Title: "Fish & Chips", Link: &feeds.Link{Href: "http://example.com/fish"}},
+		{Title: "Second"},
+	}
+	if err := notifier.Notify(items); err != nil {
+		t.Fatalf("Notify() unexpected error = %v", err)
+	}
+
+	if len(payloads) != 2 {
+		t.Fatalf("got %d messages, want 2", len(payloads))
+	}
+	if payloads[0]["chat_id"] != "@news" || payloads[0]["parse_mode"] != "HTML" {
+		t.Errorf("unexpected payload %v", payloads[0])
+	}
+	want := "<b>Fish &amp; Chips</b>\n<a href=\"http://example.com/fish\">http://example.com/fish</a>"
+	if payloads[0]["text"] != want {
+		t.Errorf("text = %q, want %q", payloads[0]["text"], want)
+	}
+}
+
+func TestTelegramNotifierError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"ok": false, "description": "chat not found"}`)
+	}))
+	defer server.Close()
+
+	notifier := &TelegramNotifier{Token: "TOKEN", ChatID: "1", apiBase: server.URL}
+	if err := notifier.Notify([]*feeds.Item{{Title: "x"}}); err == nil {
+		t.Errorf("Notify() expected error for failed API call")
+	}
+}
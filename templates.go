@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/gorilla/feeds"
+)
+
+// itemTemplateData is what -title-template and -description-template see.
+type itemTemplateData struct {
+	Title       string
+	Link        string
+	Description string
+	Content     string
+	Author      string
+	Created     time.Time
+	Updated     time.Time
+	Source      sourceTemplateData
+}
+
+type sourceTemplateData struct {
+	Title string
+	URL   string
+	Link  string
+	Tags  []string
+}
+
+func parseItemTemplate(name, text string) (*template.Template, error) {
+	if text == "" {
+		return nil, nil
+	}
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", name, err)
+	}
+	return tmpl, nil
+}
+
+func applyItemTemplates(source *SourceFeed, titleTemplate, descriptionTemplate *template.Template) {
+	for _, item := range source.Items {
+		data := newItemTemplateData(item, source)
+
+		if titleTemplate != nil {
+			if title, err := executeItemTemplate(titleTemplate, data); err != nil {
+				log.Printf("Warning: title-template failed for %q: %v", item.Title, err)
+			} else {
+				item.Title = title
+			}
+		}
+
+		if descriptionTemplate != nil {
+			if description, err := executeItemTemplate(descriptionTemplate, data); err != nil {
+				log.Printf("Warning: description-template failed for %q: %v", item.Title, err)
+			} else {
+				item.Description = description
+			}
+		}
+	}
+}
+
+func newItemTemplateData(item *feeds.Item, source *SourceFeed) itemTemplateData {
+	data := itemTemplateData{
+		Title:       item.Title,
+		Description: item.Description,
+		Content:     item.Content,
+		Created:     item.Created,
+		Updated:     item.Updated,
+		Source: sourceTemplateData{
+			Title: source.Title,
+			URL:   source.URL,
+			Link:  source.Link,
+			Tags:  source.Tags,
+		},
+	}
+	if item.Link != nil {
+		data.Link = item.Link.Href
+	}
+	if item.Author != nil {
+		data.Author = item.Author.Name
+	}
+	return data
+}
+
+func executeItemTemplate(tmpl *template.Template, data itemTemplateData) (string, error) {
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, data); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/gorilla/feeds"
+)
+
+func TestApplyItemTemplates(t *testing.T) {
+	titleTemplate, err := parseItemTemplate("title-template", "{{.Source.Title}}: {{.Title}}")
+	if err != nil {
+		t.Fatalf("parseItemTemplate() unexpected error = %v", err)
+	}
+	descriptionTemplate, err := parseItemTemplate("description-template", `{{.Description}} <a href="{{.Link}}">via {{.Source.URL}}</a>`)
+	if err != nil {
+		t.Fatalf("parseItemTemplate() unexpected error = %v", err)
+	}
+
+	source := &SourceFeed{
+		URL:   "http://example.com/feed.xml",
+		Title: "Example Blog",
+		Items: []*feeds.Item{
+			{Title: "Hello", Link: &feeds.Link{Href: "http://example.com/hello"}, Description: "Greetings."},
+		},
+	}
+
+	applyItemTemplates(source, titleTemplate, descriptionTemplate)
+
+	item := source.Items[0]
+	if item.Title != "Example Blog: Hello" {
+		t.Errorf("title = %q, want %q", item.Title, "Example Blog: Hello")
+	}
+	if want := `Greetings. <a href="http://example.com/hello">via http://example.com/feed.xml</a>`; item.Description != want {
+		t.Errorf("description = %q, want %q", item.Description, want)
+	}
+}
+
+func TestParseItemTemplate(t *testing.T) {
+	if tmpl, err := parseItemTemplate("title-template", ""); tmpl != nil || err != nil {
+		t.Errorf("parseItemTemplate(\"\") = %v, %v, want nil, nil", tmpl, err)
+	}
+	if _, err := parseItemTemplate("title-template", "{{.Title"); err == nil {
+		t.Errorf("parseItemTemplate() expected error for malformed template")
+	}
+}
+
+func TestApplyItemTemplatesBadFieldKeepsOriginal(t *testing.T) {
+	titleTemplate, err := parseItemTemplate("title-template", "{{.Nope}}")
+	if err != nil {
+		t.Fatalf("parseItemTemplate() unexpected error = %v", err)
+	}
+
+	source := &SourceFeed{Items: []*feeds.Item{{Title: "Original"}}}
+	applyItemTemplates(source, titleTemplate, nil)
+
+	if source.Items[0].Title != "Original" {
+		t.Errorf("title = %q, want original title kept", source.Items[0].Title)
+	}
+}
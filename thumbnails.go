@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"html"
+	"mime"
+	"path"
+	"strings"
+
+	"github.com/gorilla/feeds"
+)
+
+// mediaNamespace is the Media RSS namespace of media:thumbnail and
+// media:content.
+const mediaNamespace = "http://search.yahoo.com/mrss/"
+
+// parseThumbnails reads the image each item or entry of a fetched feed
+// gives as its thumbnail, in document order: its first media:thumbnail, or
+// else its first image media:content, RSS enclosure or Atom enclosure link.
+// Items without one get nil. The feed parser keeps none of these.
+func parseThumbnails(body []byte) []*feeds.Enclosure {
+	var thumbnails, enclosures []*feeds.Enclosure
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	decoder.Strict = false
+	inItem := false
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			break
+		}
+		switch token := token.(type) {
+		case xml.StartElement:
+			name := token.Name.Local
+			if name == "item" || name == "entry" {
+				inItem = true
+				thumbnails = append(thumbnails, nil)
+				enclosures = append(enclosures, nil)
+				continue
+			}
+			if !inItem {
+				continue
+			}
+			attrs := make(map[string]string, len(token.Attr))
+			for _, attr := range token.Attr {
+				attrs[attr.Name.Local] = attr.Value
+			}
+			media := token.Name.Space == mediaNamespace || token.Name.Space == "media"
+			last := len(thumbnails) - 1
+			switch {
+			case media && name == "thumbnail":
+				if thumbnails[last] == nil && attrs["url"] != "" {
+					thumbnails[last] = &feeds.Enclosure{Url: attrs["url"], Type: imageType(attrs["url"], "")}
+				}
+			case media && name == "content":
+				if attrs["medium"] == "image" || strings.HasPrefix(attrs["type"], "image/") {
+					setImageEnclosure(&enclosures[last], attrs["url"], attrs["type"], attrs["fileSize"])
+				}
+			case name == "enclosure":
+				if strings.HasPrefix(attrs["type"], "image/") {
+					setImageEnclosure(&enclosures[last], attrs["url"], attrs["type"], attrs["length"])
+				}
+			case name == "link" && attrs["rel"] == "enclosure":
+				if strings.HasPrefix(attrs["type"], "image/") {
+					setImageEnclosure(&enclosures[last], attrs["href"], attrs["type"], attrs["length"])
+				}
+			}
+		case xml.EndElement:
+			if token.Name.Local == "item" || token.Name.Local == "entry" {
+				inItem = false
+			}
+		}
+	}
+
+	for i, thumbnail := range thumbnails {
+		if thumbnail == nil {
+			thumbnails[i] = enclosures[i]
+		}
+	}
+	return thumbnails
+}
+
+func setImageEnclosure(enclosure **feeds.Enclosure, url, mediaType, length string) {
+	if *enclosure == nil && url != "" {
+		*enclosure = &feeds.Enclosure{Url: url, Type: imageType(url, mediaType), Length: length}
+	}
+}
+
+// imageType is mediaType, or the image type url's extension suggests when
+// the feed gives none, assuming JPEG, the usual format of thumbnails.
+func imageType(url, mediaType string) string {
+	if mediaType != "" {
+		return mediaType
+	}
+	if guessed := mime.TypeByExtension(path.Ext(strings.SplitN(url, "?", 2)[0])); strings.HasPrefix(guessed, "image/") {
+		return guessed
+	}
+	return "image/jpeg"
+}
+
+// readThumbnails gives the items of source the thumbnails body, the
+// document it was parsed from, names for them, as image enclosures. As with
+// applyItemDates, items are left alone when the parser dropped some.
+// Thumbnails without a length, which RSS requires of enclosures, are not
+// written to the output feed, only offered by itemThumbnail.
+func (source *SourceFeed) readThumbnails(body []byte) {
+	thumbnails := parseThumbnails(body)
+	if len(thumbnails) != len(source.Items) {
+		return
+	}
+	for i, item := range source.Items {
+		if thumbnails[i] != nil {
+			item.Enclosure = thumbnails[i]
+		}
+	}
+}
+
+// itemThumbnail returns the item's image enclosure or the first <img> in its
+// content or description.
+func itemThumbnail(item *feeds.Item) string {
+	if item.Enclosure != nil && strings.HasPrefix(item.Enclosure.Type, "image/") {
+		return item.Enclosure.Url
+	}
+	for _, fragment := range []string{item.Content, item.Description} {
+		if match := imgSrcPattern.FindStringSubmatch(fragment); match != nil {
+			src := html.UnescapeString(match[2][1 : len(match[2])-1])
+			if !strings.HasPrefix(src, "data:") {
+				return resolveImageURL(src, item)
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/gorilla/feeds"
+)
+
+func TestParseThumbnails(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []*feeds.Enclosure
+	}{
+		{
+			name: "rss",
+			body: `<rss xmlns:media="http://search.yahoo.com/mrss/"><channel><title>Blog</title>
+<item><title>Thumbnail</title><enclosure url="https://example.com/big.png" type="image/png" length="2048"/><media:thumbnail url="https://example.com/thumb.png?w=200"/></item>
+<item><title>Enclosure</title><enclosure url="https://example.com/episode.mp3" type="audio/mpeg" length="1"/><enclosure url="https://example.com/photo.jpg" type="image/jpeg" length="4096"/></item>
+<item><title>Media content</title><media:group><media:content url="https://example.com/cover" medium="image"/></media:group></item>
+<item><title>None</title><description>&lt;img src="inline.png"&gt;</description></item>
+</channel></rss>`,
+			want: []*feeds.Enclosure{
+				{Url: "https://example.com/thumb.png?w=200", Type: "image/png"},
+				{Url: "https://example.com/photo.jpg", Type: "image/jpeg", Length: "4096"},
+				{Url: "https://example.com/cover", Type: "image/jpeg"},
+				nil,
+			},
+		},
+		{
+			name: "atom",
+			body: `<feed xmlns="http://www.w3.org/2005/Atom"><title>Blog</title>
+<entry><title>Linked</title><link rel="alternate" href="https://example.com/post"/><link rel="enclosure" type="image/webp" href="https://example.com/cover.webp" length="512"/><content type="html">text</content></entry>
+</feed>`,
+			want: []*feeds.Enclosure{
+				{Url: "https://example.com/cover.webp", Type: "image/webp", Length: "512"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseThumbnails([]byte(tt.body))
+			if len(got) != len(tt.want) {
+				t.Fatalf("parseThumbnails() = %d items, want %d", len(got), len(tt.want))
+			}
+			for i, want := range tt.want {
+				if (got[i] == nil) != (want == nil) || got[i] != nil && *got[i] != *want {
+					t.Errorf("parseThumbnails()[%d] = %+v, want %+v", i, got[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestItemThumbnail(t *testing.T) {
+	tests := []struct {
+		name string
+		item *feeds.Item
+		want string
+	}{
+		{
+			name: "image enclosure",
+			item: &feeds.Item{Enclosure: &feeds.Enclosure{Url: "https://example.com/thumb.png", Type: "image/png"}, Content: `<img src="https://example.com/other.png">`},
+			want: "https://example.com/thumb.png",
+		},
+		{
+			name: "content image",
+			item: &feeds.Item{Link: &feeds.Link{Href: "https://example.com/posts/1"}, Enclosure: &feeds.Enclosure{Url: "https://example.com/a.mp3", Type: "audio/mpeg"}, Description: `<p><img alt="" src='../img/a.png?x=1&amp;y=2'></p>`},
+			want: "https://example.com/img/a.png?x=1&y=2",
+		},
+		{
+			name: "inline image",
+			item: &feeds.Item{Content: `<img src="data:image/png;base64,AAAA">`},
+			want: "",
+		},
+	}
+
+	for _, tt := range tests {
+		if got := itemThumbnail(tt.item); got != tt.want {
+			t.Errorf("itemThumbnail() with %s = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
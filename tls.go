@@ -0,0 +1,58 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// parseTLSDomains parses the comma-separated host names of -tls-domain.
+func parseTLSDomains(value string) ([]string, error) {
+	var domains []string
+	for _, field := range strings.Split(value, ",") {
+		field = strings.ToLower(strings.TrimSpace(field))
+		if field == "" {
+			continue
+		}
+		if strings.ContainsAny(field, ":/ ") || !strings.Contains(field, ".") {
+			return nil, fmt.Errorf("%q is not a host name such as feed.example.com", field)
+		}
+		domains = append(domains, field)
+	}
+	return domains, nil
+}
+
+// tlsCacheDir is where certificates are kept between restarts: -tls-cache,
+// or an autocert directory in the user's cache directory.
+func (config *Config) tlsCacheDir() (string, error) {
+	if config.TLSCache != "" {
+		return config.TLSCache, nil
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("error finding a certificate cache directory, set -tls-cache: %v", err)
+	}
+	return filepath.Join(dir, "rss-agg", "autocert"), nil
+}
+
+// tlsListener terminates TLS on listener with certificates for
+// -tls-domain, obtained from Let's Encrypt on the first connection for each
+// domain and renewed before they expire. The TLS-ALPN-01 challenge is
+// answered on the same listener, so it must be reachable on port 443.
+func tlsListener(config *Config, listener net.Listener) (net.Listener, error) {
+	dir, err := config.tlsCacheDir()
+	if err != nil {
+		return nil, err
+	}
+	manager := &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: autocert.HostWhitelist(config.TLSDomains...),
+		Cache:      autocert.DirCache(dir),
+	}
+	return tls.NewListener(listener, manager.TLSConfig()), nil
+}
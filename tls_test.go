@@ -0,0 +1,72 @@
+package main
+
+import (
+	"crypto/tls"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseTLSDomains(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    []string
+		wantErr bool
+	}{
+		{"", nil, false},
+		{"feed.example.com", []string{"feed.example.com"}, false},
+		{" Feed.Example.com, www.example.com ,", []string{"feed.example.com", "www.example.com"}, false},
+		{"https://feed.example.com", nil, true},
+		{"feed.example.com:443", nil, true},
+		{"localhost", nil, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseTLSDomains(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseTLSDomains(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseTLSDomains(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestTLSListenerRejectsOtherHosts(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "rss_tls")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	config := &Config{TLSDomains: []string{"feed.example.com"}, TLSCache: filepath.Join(tempDir, "certs")}
+	listener, err = tlsListener(config, listener)
+	if err != nil {
+		t.Fatalf("tlsListener() unexpected error = %v", err)
+	}
+	defer listener.Close()
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		conn.(*tls.Conn).Handshake()
+		conn.Close()
+	}()
+
+	// The host policy refuses the handshake before any certificate is
+	// requested, so this needs no network access.
+	conn, err := tls.Dial("tcp", listener.Addr().String(), &tls.Config{ServerName: "other.example.com"})
+	if err == nil {
+		conn.Close()
+		t.Fatalf("TLS handshake for a host outside -tls-domain succeeded, want an error")
+	}
+}
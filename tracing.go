@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer starts the spans of aggregation runs. They go nowhere unless
+// -otlp-endpoint installs an exporter.
+var tracer = otel.Tracer("go-rss-agg")
+
+// setupTracing exports spans to the OTLP/HTTP collector at endpoint. The
+// returned function flushes the spans not yet sent and must be called before
+// exiting.
+func setupTracing(endpoint string) (func(context.Context) error, error) {
+	exporter, err := otlptracehttp.New(context.Background(), otlptracehttp.WithEndpointURL(endpoint))
+	if err != nil {
+		return nil, fmt.Errorf("error creating OTLP exporter: %v", err)
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", "rss-agg"))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+func validateOTLPEndpoint(endpoint string) error {
+	if endpoint == "" {
+		return nil
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("otlp-endpoint must be an http or https URL")
+	}
+	return nil
+}
+
+// startRun starts the span of one run, named name, and returns a copy of
+// config under which the run's other spans are started.
+func startRun(config *Config, name string) (*Config, trace.Span) {
+	ctx, span := tracer.Start(context.Background(), name)
+	if config.Name != "" {
+		span.SetAttributes(attribute.String("profile", config.Name))
+	}
+	runConfig := *config
+	runConfig.traceCtx = ctx
+	return &runConfig, span
+}
+
+// startSpan starts a span named name within config's run.
+func (config *Config) startSpan(name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	ctx := config.traceCtx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// endSpan marks span as failed with err, if any, and ends it.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// traceDownload adds the download and parse of a source to ctx's span,
+// after the fact, from what metrics measured.
+func traceDownload(ctx context.Context, metrics *FetchMetrics) {
+	downloaded := metrics.Start.Add(metrics.Duration)
+	_, span := tracer.Start(ctx, "download", trace.WithTimestamp(metrics.Start),
+		trace.WithAttributes(attribute.Int("bytes", metrics.Bytes)))
+	span.End(trace.WithTimestamp(downloaded))
+	_, span = tracer.Start(ctx, "parse", trace.WithTimestamp(downloaded))
+	span.End(trace.WithTimestamp(downloaded.Add(metrics.Parse)))
+}
+
+// fetchTraced is fetchCachedSource within a span of its own.
+func fetchTraced(config *Config, entry Source) (*SourceFeed, error) {
+	ctx, span := config.startSpan("fetch", attribute.String("url", entry.URL))
+	source, err := fetchCachedSource(config, entry)
+	if err == nil {
+		span.SetAttributes(attribute.Int("items", len(source.Items)), attribute.Bool("cached", source.Metrics == nil))
+		if source.Metrics != nil {
+			traceDownload(ctx, source.Metrics)
+		}
+	}
+	endSpan(span, err)
+	return source, err
+}
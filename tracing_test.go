@@ -0,0 +1,86 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestRunTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(cacheTestFeed))
+	}))
+	defer server.Close()
+
+	tempDir, err := os.MkdirTemp("", "rss_tracing")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	config := &Config{Mode: "single", SingleURL: server.URL, Count: 10, OutputFile: filepath.Join(tempDir, "out.xml")}
+	if _, err := run(config); err != nil {
+		t.Fatalf("run() unexpected error = %v", err)
+	}
+
+	spans := make(map[string]sdktrace.ReadOnlySpan)
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+	parents := map[string]string{
+		"fetch":    "run",
+		"download": "fetch",
+		"parse":    "fetch",
+		"build":    "run",
+		"render":   "run",
+		"publish":  "run",
+	}
+	root, ok := spans["run"]
+	if !ok {
+		t.Fatalf("run() recorded no run span, got %v", spans)
+	}
+	if root.Parent().IsValid() {
+		t.Errorf("run span has parent %v, want none", root.Parent())
+	}
+	for name, parent := range parents {
+		span, ok := spans[name]
+		if !ok {
+			t.Errorf("run() recorded no %s span", name)
+			continue
+		}
+		if span.Parent().SpanID() != spans[parent].SpanContext().SpanID() {
+			t.Errorf("%s span is not a child of the %s span", name, parent)
+		}
+	}
+	if download, ok := spans["download"]; ok && download.EndTime().Before(download.StartTime()) {
+		t.Errorf("download span ends at %v, before it starts at %v", download.EndTime(), download.StartTime())
+	}
+}
+
+func TestValidateOTLPEndpoint(t *testing.T) {
+	tests := []struct {
+		endpoint string
+		wantErr  bool
+	}{
+		{"", false},
+		{"http://localhost:4318", false},
+		{"https://otlp.example.com/v1/traces", false},
+		{"localhost:4318", true},
+		{"grpc://localhost:4317", true},
+	}
+
+	for _, tt := range tests {
+		if err := validateOTLPEndpoint(tt.endpoint); (err != nil) != tt.wantErr {
+			t.Errorf("validateOTLPEndpoint(%q) error = %v, wantErr %v", tt.endpoint, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os/exec"
+	"runtime"
+	"time"
+
+	"github.com/gorilla/feeds"
+)
+
+// transformTimeout bounds the single run of -transform-cmd over all items.
+const transformTimeout = 2 * time.Minute
+
+// ItemJSON is the JSON shape of an item exchanged with external programs.
+type ItemJSON struct {
+	Title       string    `json:"title"`
+	Link        string    `json:"link,omitempty"`
+	Source      string    `json:"source,omitempty"`
+	Author      string    `json:"author,omitempty"`
+	Description string    `json:"description,omitempty"`
+	Content     string    `json:"content,omitempty"`
+	Thumbnail   string    `json:"thumbnail,omitempty"`
+	ID          string    `json:"id,omitempty"`
+	Created     time.Time `json:"created,omitzero"`
+	Updated     time.Time `json:"updated,omitzero"`
+}
+
+func toItemJSON(item *feeds.Item) ItemJSON {
+	out := ItemJSON{
+		Title:       item.Title,
+		Description: item.Description,
+		Content:     item.Content,
+		Thumbnail:   itemThumbnail(item),
+		ID:          item.Id,
+		Created:     item.Created,
+		Updated:     item.Updated,
+	}
+	if item.Link != nil {
+		out.Link = item.Link.Href
+	}
+	if item.Source != nil {
+		out.Source = item.Source.Href
+	}
+	if item.Author != nil {
+		out.Author = item.Author.Name
+	}
+	return out
+}
+
+// applyItemJSON copies the exchanged fields back onto item, leaving fields
+// that have no JSON representation untouched.
+func applyItemJSON(item *feeds.Item, in ItemJSON) {
+	// A changed thumbnail replaces the image enclosure it came from.
+	if in.Thumbnail != itemThumbnail(item) {
+		item.Enclosure = nil
+		if in.Thumbnail != "" {
+			item.Enclosure = &feeds.Enclosure{Url: in.Thumbnail, Type: imageType(in.Thumbnail, "")}
+		}
+	}
+
+	item.Title = in.Title
+	item.Description = in.Description
+	item.Content = in.Content
+	item.Id = in.ID
+	item.Created = in.Created
+	item.Updated = in.Updated
+
+	item.Link = nil
+	if in.Link != "" {
+		item.Link = &feeds.Link{Href: in.Link}
+	}
+	item.Source = nil
+	if in.Source != "" {
+		item.Source = &feeds.Link{Href: in.Source}
+	}
+	item.Author = nil
+	if in.Author != "" {
+		item.Author = &feeds.Author{Name: in.Author}
+	}
+}
+
+// transformItems pipes all items through one run of command, as one JSON
+// object per line, and reads back one JSON value per item, in the same
+// order. An item whose value is null is dropped, and the fields an object
+// leaves out are kept; if the command fails or prints a different number of
+// values, every item is kept unchanged.
+func transformItems(items []*feeds.Item, command string) []*feeds.Item {
+	if len(items) == 0 {
+		return items
+	}
+	transformed, err := runTransform(items, command)
+	if err != nil {
+		log.Printf("Warning: transform-cmd failed, keeping %d items unchanged: %v", len(items), err)
+		return items
+	}
+
+	var kept []*feeds.Item
+	for i, item := range items {
+		if transformed[i] == nil {
+			continue
+		}
+		applyItemJSON(item, *transformed[i])
+		kept = append(kept, item)
+	}
+	return kept
+}
+
+// runTransform returns what command made of each of items, nil for those
+// it dropped. Each value printed is decoded over the item's own JSON, so
+// that it only replaces the fields it has.
+func runTransform(items []*feeds.Item, command string) ([]*ItemJSON, error) {
+	var input bytes.Buffer
+	encoder := json.NewEncoder(&input)
+	for _, item := range items {
+		if err := encoder.Encode(toItemJSON(item)); err != nil {
+			return nil, err
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), transformTimeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := shellCommand(ctx, command)
+	cmd.Stdin = &input
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%v: %s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+
+	var values []json.RawMessage
+	decoder := json.NewDecoder(&stdout)
+	for decoder.More() {
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			return nil, fmt.Errorf("invalid JSON output: %v", err)
+		}
+		values = append(values, value)
+	}
+	if len(values) != len(items) {
+		return nil, fmt.Errorf("printed %d items for %d", len(values), len(items))
+	}
+
+	transformed := make([]*ItemJSON, len(items))
+	for i, value := range values {
+		if string(value) == "null" {
+			continue
+		}
+		item := toItemJSON(items[i])
+		if err := json.Unmarshal(value, &item); err != nil {
+			return nil, fmt.Errorf("invalid JSON output: %v", err)
+		}
+		transformed[i] = &item
+	}
+	return transformed, nil
+}
+
+// shellCommand runs command with the system's shell: sh, or cmd on Windows.
+func shellCommand(ctx context.Context, command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.CommandContext(ctx, "cmd", "/C", command)
+	}
+	return exec.CommandContext(ctx, "sh", "-c", command)
+}
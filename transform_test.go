@@ -0,0 +1,115 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/feeds"
+)
+
+func TestItemJSONRoundTrip(t *testing.T) {
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	item := &feeds.Item{
+		Title:     "Title",
+		Link:      &feeds.Link{Href: "http://example.com/a"},
+		Source:    &feeds.Link{Href: "http://example.com/feed"},
+		Author:    &feeds.Author{Name: "Ann"},
+		Content:   "<p>body</p>",
+		Created:   created,
+		Enclosure: &feeds.Enclosure{Url: "http://example.com/a.png", Type: "image/png", Length: "100"},
+	}
+
+	roundTripped := &feeds.Item{}
+	applyItemJSON(roundTripped, toItemJSON(item))
+
+	if roundTripped.Title != item.Title || roundTripped.Link.Href != item.Link.Href ||
+		roundTripped.Source.Href != item.Source.Href || roundTripped.Author.Name != item.Author.Name ||
+		roundTripped.Content != item.Content || !roundTripped.Created.Equal(created) ||
+		itemThumbnail(roundTripped) != "http://example.com/a.png" {
+		t.Errorf("round trip = %+v, want %+v", roundTripped, item)
+	}
+}
+
+func TestTransformItems(t *testing.T) {
+	items := []*feeds.Item{
+		{Title: "keep me", Link: &feeds.Link{Href: "http://example.com/1"}},
+		{Title: "drop me", Link: &feeds.Link{Href: "http://example.com/2"}},
+	}
+
+	// Uppercase the first item's title via sed and drop the second, in one
+	// run over both lines.
+	command := `sed -e 's/keep me/KEPT/' -e '/drop me/c\
+null'`
+	got := transformItems(items, command)
+
+	if len(got) != 1 {
+		t.Fatalf("transformItems() kept %d items, want 1", len(got))
+	}
+	if got[0].Title != "KEPT" {
+		t.Errorf("transformItems() title = %q, want %q", got[0].Title, "KEPT")
+	}
+	if got[0].Link == nil || got[0].Link.Href != "http://example.com/1" {
+		t.Errorf("transformItems() lost the link: %+v", got[0].Link)
+	}
+}
+
+func TestTransformItemsPartialOutput(t *testing.T) {
+	items := []*feeds.Item{{
+		Title:  "original",
+		Link:   &feeds.Link{Href: "http://example.com/1"},
+		Source: &feeds.Link{Href: "http://example.com/feed"},
+		Author: &feeds.Author{Name: "Ann"},
+	}}
+
+	// The command prints only the field it changes.
+	got := transformItems(items, `echo '{"title": "retitled"}'`)
+
+	if len(got) != 1 || got[0].Title != "retitled" {
+		t.Fatalf("transformItems() = %+v, want the item retitled", got)
+	}
+	if got[0].Link == nil || got[0].Source == nil || got[0].Author == nil {
+		t.Errorf("transformItems() cleared fields the output left out: link %+v, source %+v, author %+v", got[0].Link, got[0].Source, got[0].Author)
+	}
+}
+
+func TestTransformItemsCommandFailureKeepsItem(t *testing.T) {
+	items := []*feeds.Item{{Title: "original"}}
+
+	got := transformItems(items, "exit 3")
+
+	if len(got) != 1 || got[0].Title != "original" {
+		t.Errorf("transformItems() = %+v, want original item kept", got)
+	}
+}
+
+func TestTransformItemsCountMismatchKeepsItems(t *testing.T) {
+	items := []*feeds.Item{{Title: "one"}, {Title: "two"}}
+
+	got := transformItems(items, "head -n 1")
+
+	if len(got) != 2 || got[0].Title != "one" || got[1].Title != "two" {
+		t.Errorf("transformItems() = %+v, want both items kept unchanged", got)
+	}
+}
+
+func TestTransformItemsSingleRun(t *testing.T) {
+	dir := t.TempDir()
+	items := []*feeds.Item{{Title: "a"}, {Title: "b"}, {Title: "c"}}
+
+	// Each run appends a line to runs, and passes its input through.
+	command := "echo run >> " + filepath.Join(dir, "runs") + "; cat"
+	if got := transformItems(items, command); len(got) != 3 {
+		t.Fatalf("transformItems() kept %d items, want 3", len(got))
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "runs"))
+	if err != nil {
+		t.Fatalf("Failed to read runs: %v", err)
+	}
+	if runs := strings.Count(string(data), "run"); runs != 1 {
+		t.Errorf("transform-cmd ran %d times, want 1", runs)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gorilla/feeds"
+)
+
+// truncateDescriptions replaces each item's description with a plain-text
+// excerpt of at most maxWords words and maxChars characters (0 means no
+// limit) followed by a "Read more" link, and drops the full content.
+func truncateDescriptions(items []*feeds.Item, maxWords, maxChars int) {
+	for _, item := range items {
+		source := item.Description
+		if source == "" {
+			source = item.Content
+		}
+
+		text, truncated := truncateText(htmlToText(source), maxWords, maxChars)
+		description := html.EscapeString(text)
+		if truncated {
+			description += "…"
+			if item.Link != nil && item.Link.Href != "" {
+				description += fmt.Sprintf(` <a href="%s">Read more</a>`, html.EscapeString(item.Link.Href))
+			}
+		}
+
+		item.Description = description
+		item.Content = ""
+	}
+}
+
+func truncateText(text string, maxWords, maxChars int) (string, bool) {
+	words := strings.Fields(text)
+	truncated := false
+
+	if maxWords > 0 && len(words) > maxWords {
+		words = words[:maxWords]
+		truncated = true
+	}
+
+	if maxChars > 0 {
+		length := 0
+		for i, word := range words {
+			wordLength := utf8.RuneCountInString(word)
+			if i > 0 {
+				wordLength++
+			}
+			if length+wordLength > maxChars {
+				words = words[:i]
+				truncated = true
+				break
+			}
+			length += wordLength
+		}
+	}
+
+	result := strings.Join(words, " ")
+	if !truncated {
+		return result, false
+	}
+
+	// Prefer ending on a full sentence if one ends in the second half.
+	if i := strings.LastIndexAny(result, ".!?"); i >= len(result)/2 {
+		result = result[:i+1]
+	}
+
+	return result, true
+}
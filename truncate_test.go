@@ -0,0 +1,81 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gorilla/feeds"
+)
+
+func TestTruncateText(t *testing.T) {
+	tests := []struct {
+		name          string
+		text          string
+		maxWords      int
+		maxChars      int
+		want          string
+		wantTruncated bool
+	}{
+		{
+			name: "under limits",
+			text: "Short text.", maxWords: 10, maxChars: 100,
+			want: "Short text.",
+		},
+		{
+			name: "word limit",
+			text: "one two three four five six", maxWords: 3,
+			want: "one two three", wantTruncated: true,
+		},
+		{
+			name: "char limit cuts at word boundary",
+			text: "alpha beta gamma delta", maxChars: 13,
+			want: "alpha beta", wantTruncated: true,
+		},
+		{
+			name: "prefers sentence boundary",
+			text: "First sentence here. Second sentence goes on and on", maxWords: 5,
+			want: "First sentence here.", wantTruncated: true,
+		},
+		{
+			name: "no limits",
+			text: "anything goes",
+			want: "anything goes",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, truncated := truncateText(tt.text, tt.maxWords, tt.maxChars)
+			if got != tt.want || truncated != tt.wantTruncated {
+				t.Errorf("truncateText() = %q, %v, want %q, %v", got, truncated, tt.want, tt.wantTruncated)
+			}
+		})
+	}
+}
+
+func TestTruncateDescriptions(t *testing.T) {
+	items := []*feeds.Item{
+		{
+			Link:        &feeds.Link{Href: "http://example.com/a?x=1&y=2"},
+			Description: "<p>One <b>two</b> three four</p>",
+			Content:     "<p>Full body</p>",
+		},
+		{
+			Link:    &feeds.Link{Href: "http://example.com/b"},
+			Content: "<p>Only content here</p>",
+		},
+	}
+
+	truncateDescriptions(items, 2, 0)
+
+	want := `One two… <a href="http://example.com/a?x=1&amp;y=2">Read more</a>`
+	if items[0].Description != want {
+		t.Errorf("description = %q, want %q", items[0].Description, want)
+	}
+	if items[0].Content != "" {
+		t.Errorf("content should be dropped, got %q", items[0].Content)
+	}
+	if !strings.HasPrefix(items[1].Description, "Only content…") {
+		t.Errorf("content-only item description = %q, want excerpt of content", items[1].Description)
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/gorilla/feeds"
+)
+
+// updatedMarker starts the titles of updated items with -mark-updated.
+const updatedMarker = "[updated] "
+
+// contentHash identifies the content of an item as fetched, so that a
+// source editing an item it already published can be noticed.
+func contentHash(item *feeds.Item) string {
+	sum := sha256.Sum256([]byte(item.Title + "\x00" + item.Description + "\x00" + item.Content))
+	return hex.EncodeToString(sum[:16])
+}
+
+// contentHashes maps the keys of sources' items to their content hashes,
+// before templates, enrichment or anything else changes them.
+func contentHashes(sources []*SourceFeed) map[string]string {
+	hashes := make(map[string]string)
+	for _, source := range sources {
+		for _, item := range source.Items {
+			hashes[itemKey(item)] = contentHash(item)
+		}
+	}
+	return hashes
+}
+
+// itemUpdates returns when the content of each of items last changed after
+// it was first seen, according to the state file at path; items changing
+// in this run changed now. Items never seen before, or never changed since,
+// are left out.
+func itemUpdates(path string, items []*feeds.Item, hashes map[string]string, now time.Time) (map[string]time.Time, error) {
+	state, err := loadState(path)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := make(map[string]time.Time)
+	for _, item := range items {
+		key, seen, ok := state.lookup(item)
+		if !ok {
+			continue
+		}
+		if seen.changed(hashes[key]) {
+			updates[key] = now
+		} else if !seen.Updated.IsZero() {
+			updates[key] = seen.Updated
+		}
+	}
+	return updates, nil
+}
+
+// changed reports whether hash differs from the recorded one. Items seen
+// before hashes were recorded have none and never count as changed.
+func (seen SeenItem) changed(hash string) bool {
+	return seen.Hash != "" && hash != "" && hash != seen.Hash
+}
+
+// markUpdated dates items by the last change to their content from
+// updates and, with marker, flags their titles with updatedMarker. Only
+// items with a guid or link get the marker, since the title identifies the
+// others across runs.
+func markUpdated(items []*feeds.Item, updates map[string]time.Time, marker bool) {
+	for _, item := range items {
+		updated, ok := updates[itemKey(item)]
+		if !ok {
+			continue
+		}
+		if updated.After(item.Updated) {
+			item.Updated = updated
+		}
+		if marker && (item.Id != "" || item.Link != nil && item.Link.Href != "") {
+			item.Title = updatedMarker + item.Title
+		}
+	}
+}
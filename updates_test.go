@@ -0,0 +1,108 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/feeds"
+)
+
+func TestUpdatedItems(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "rss_updates")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	config := &Config{Count: 10, StateFile: filepath.Join(tempDir, "state.json"), MarkUpdated: true}
+	published := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	newSource := func(edited string) *SourceFeed {
+		source := &SourceFeed{URL: "https://example.com/feed"}
+		for _, title := range []string{"Edited", "Unchanged"} {
+			description := "Original"
+			if title == "Edited" {
+				description = edited
+			}
+			source.Items = append(source.Items, &feeds.Item{
+				Title:       title,
+				Link:        &feeds.Link{Href: "https://example.com/" + title},
+				Source:      &feeds.Link{Href: "https://example.com/feed"},
+				Description: description,
+				Created:     published,
+			})
+		}
+		return source
+	}
+	// run builds and records one run, returning its items by link.
+	run := func(edited string) map[string]*feeds.Item {
+		t.Helper()
+		sources := []*SourceFeed{newSource(edited)}
+		feed := buildFeed(config, sources)
+		if err := updateState(config, feed.Items, contentHashes(sources), time.Now()); err != nil {
+			t.Fatalf("updateState() unexpected error = %v", err)
+		}
+		items := make(map[string]*feeds.Item)
+		for _, item := range feed.Items {
+			items[strings.TrimPrefix(item.Link.Href, "https://example.com/")] = item
+		}
+		return items
+	}
+
+	run("Original")
+	items := run("Original")
+	if items["Edited"].Title != "Edited" || !items["Edited"].Updated.IsZero() {
+		t.Errorf("unchanged item = %q updated %v, want it untouched", items["Edited"].Title, items["Edited"].Updated)
+	}
+
+	before := time.Now()
+	items = run("Fixed a typo")
+	edit := items["Edited"].Updated
+	if items["Edited"].Title != "[updated] Edited" || edit.Before(before) {
+		t.Errorf("edited item = %q updated %v, want it marked and dated now", items["Edited"].Title, edit)
+	}
+	if items["Unchanged"].Title != "Unchanged" || !items["Unchanged"].Updated.IsZero() {
+		t.Errorf("unchanged item = %q updated %v, want it untouched", items["Unchanged"].Title, items["Unchanged"].Updated)
+	}
+
+	items = run("Fixed a typo")
+	if items["Edited"].Title != "[updated] Edited" || !items["Edited"].Updated.Equal(edit) {
+		t.Errorf("item edited in an earlier run = %q updated %v, want it still marked and dated %v", items["Edited"].Title, items["Edited"].Updated, edit)
+	}
+	if len(items) != 2 {
+		t.Errorf("run returned %d items, want the edited item once and the unchanged one", len(items))
+	}
+
+	config.OnlyNew = true
+	items = run("Fixed another typo")
+	if _, ok := items["Edited"]; !ok || len(items) != 1 {
+		t.Errorf("-only-new run returned %v, want only the item edited since the last run", items)
+	}
+}
+
+func TestMarkSeenRecordsHashes(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	item := &feeds.Item{Title: "A", Link: &feeds.Link{Href: "http://example.com/a"}}
+	key := itemKey(item)
+
+	// Items recorded before hashes were have none, and are not updated
+	// when one is first recorded.
+	state := &State{Items: map[string]SeenItem{key: {FirstSeen: now}}}
+	state.markSeen([]*feeds.Item{item}, map[string]string{key: "first"}, now.Add(time.Hour))
+	if seen := state.Items[key]; seen.Hash != "first" || !seen.Updated.IsZero() {
+		t.Errorf("markSeen() of an item without a hash = %+v, want its hash recorded", seen)
+	}
+
+	state.markSeen([]*feeds.Item{item}, map[string]string{key: "second"}, now.Add(2*time.Hour))
+	if seen := state.Items[key]; seen.Hash != "second" || !seen.Updated.Equal(now.Add(2*time.Hour)) {
+		t.Errorf("markSeen() of an edited item = %+v, want the new hash and an update", seen)
+	}
+
+	// Items without a hash, such as group headings, keep their record.
+	state.markSeen([]*feeds.Item{item}, nil, now.Add(3*time.Hour))
+	if seen := state.Items[key]; seen.Hash != "second" || !seen.Updated.Equal(now.Add(2*time.Hour)) {
+		t.Errorf("markSeen() without a hash = %+v, want the record unchanged", seen)
+	}
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// inputSettleDelay is how long the input must stay unchanged before a
+// reload, so that an editor saving in several steps causes a single one.
+var inputSettleDelay = 500 * time.Millisecond
+
+// watchInputFiles signals on the returned channel when any local input file
+// is written, created, replaced or removed, including files matching a glob
+// pattern that are added later, even in new directories, and the local
+// lists they @include. The set
+// of watched files is worked out again after each change, so includes added
+// or removed by an edit are followed. Standard input and remote lists are
+// not watched; without local files the channel is nil.
+func watchInputFiles(ctx context.Context, files []string) (<-chan struct{}, error) {
+	var inputs []string
+	for _, file := range files {
+		if file == "-" || isRemoteList(file) {
+			continue
+		}
+		abs, err := filepath.Abs(file)
+		if err != nil {
+			return nil, err
+		}
+		inputs = append(inputs, abs)
+	}
+	if len(inputs) == 0 {
+		return nil, nil
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	// Editors often replace files rather than write them in place, which
+	// only the containing directory sees.
+	dirs := make(map[string]bool)
+	watch := func() ([]string, error) {
+		watched := watchedInputs(inputs)
+		wanted := make(map[string]bool)
+		for _, file := range watched {
+			for _, dir := range parentDirs(file) {
+				wanted[dir] = true
+			}
+		}
+		for dir := range wanted {
+			if !dirs[dir] {
+				if err := watcher.Add(dir); err != nil {
+					return watched, err
+				}
+				dirs[dir] = true
+			}
+		}
+		for dir := range dirs {
+			if !wanted[dir] {
+				watcher.Remove(dir)
+				delete(dirs, dir)
+			}
+		}
+		return watched, nil
+	}
+	watched, err := watch()
+	if err != nil {
+		watcher.Close()
+		return nil, err
+	}
+
+	changes := make(chan struct{}, 1)
+	go func() {
+		defer watcher.Close()
+
+		settle := time.NewTimer(0)
+		<-settle.C
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event := <-watcher.Events:
+				if matchesAny(watched, event.Name) && event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename|fsnotify.Remove) != 0 {
+					settle.Reset(inputSettleDelay)
+				}
+			case err := <-watcher.Errors:
+				log.Printf("Warning: watching input files: %v", err)
+			case <-settle.C:
+				if watched, err = watch(); err != nil {
+					log.Printf("Warning: watching input files: %v", err)
+				}
+				select {
+				case changes <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+
+	return changes, nil
+}
+
+// watchedInputs returns the absolute input files and patterns in inputs,
+// followed by every local list their files @include. A pattern with
+// wildcards in its directories, such as feeds.d/*/list.txt, also brings the
+// patterns of those directories, feeds.d/* here, since a directory showing
+// up there may hold new matches.
+func watchedInputs(inputs []string) []string {
+	watched := slices.Clone(inputs)
+	for _, input := range inputs {
+		for dir := filepath.Dir(input); hasGlobMeta(dir); dir = filepath.Dir(dir) {
+			watched = append(watched, dir)
+		}
+	}
+	seen := make(map[string]bool)
+	for _, input := range inputs {
+		matches, _ := filepath.Glob(input)
+		for _, match := range matches {
+			watched = appendIncludes(watched, match, seen)
+		}
+	}
+	return watched
+}
+
+// appendIncludes appends to watched the local lists name includes, directly
+// or through other lists, skipping those in seen. Lists that cannot be read
+// are left for the reload to report.
+func appendIncludes(watched []string, name string, seen map[string]bool) []string {
+	if seen[name] {
+		return watched
+	}
+	seen[name] = true
+
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return watched
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		target, ok := strings.CutPrefix(strings.TrimSpace(line), "@include ")
+		if !ok {
+			continue
+		}
+		target, err := includedList(name, strings.TrimSpace(target))
+		if err != nil || isRemoteList(target) {
+			continue
+		}
+		if abs, err := filepath.Abs(target); err == nil {
+			watched = append(watched, abs)
+			watched = appendIncludes(watched, abs, seen)
+		}
+	}
+	return watched
+}
+
+// parentDirs returns the existing directories whose events tell about
+// files matching pattern: its directory, or for a pattern with wildcards in
+// its directories, their nearest parent without any and the directories
+// they currently match.
+func parentDirs(pattern string) []string {
+	dir := filepath.Dir(pattern)
+	if !hasGlobMeta(dir) {
+		return []string{dir}
+	}
+	base := dir
+	for hasGlobMeta(base) {
+		base = filepath.Dir(base)
+	}
+	dirs := []string{base}
+	matches, _ := filepath.Glob(dir)
+	for _, match := range matches {
+		if info, err := os.Stat(match); err == nil && info.IsDir() {
+			dirs = append(dirs, match)
+		}
+	}
+	return dirs
+}
+
+func hasGlobMeta(path string) bool {
+	return strings.ContainsAny(path, "*?[")
+}
+
+func matchesAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := filepath.Match(pattern, filepath.Clean(name)); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatchInputFiles(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "rss_watch")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	input := filepath.Join(tempDir, "feeds.txt")
+	other := filepath.Join(tempDir, "notes.txt")
+	os.WriteFile(input, []byte("https://a.example/feed\n"), 0644)
+
+	defer func(delay time.Duration) { inputSettleDelay = delay }(inputSettleDelay)
+	inputSettleDelay = 10 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changes, err := watchInputFiles(ctx, []string{input, "-", "https://example.com/feeds.txt"})
+	if err != nil {
+		t.Fatalf("watchInputFiles() unexpected error = %v", err)
+	}
+
+	os.WriteFile(other, []byte("unrelated"), 0644)
+	select {
+	case <-changes:
+		t.Fatalf("watchInputFiles() signalled a change to another file")
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	// Replace the file the way editors do.
+	tmp := input + ".tmp"
+	os.WriteFile(tmp, []byte("https://b.example/feed\n"), 0644)
+	os.Rename(tmp, input)
+	select {
+	case <-changes:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("watchInputFiles() did not signal the replaced input file")
+	}
+
+	// Files added to a watched pattern count as changes too.
+	changes, err = watchInputFiles(ctx, []string{filepath.Join(tempDir, "*.list")})
+	if err != nil {
+		t.Fatalf("watchInputFiles() unexpected error = %v", err)
+	}
+	os.WriteFile(filepath.Join(tempDir, "news.list"), []byte("https://c.example/feed\n"), 0644)
+	select {
+	case <-changes:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("watchInputFiles() did not signal a file added to the pattern")
+	}
+
+	// So are files in directories added to a pattern's wildcard directory.
+	topics := filepath.Join(tempDir, "topics")
+	os.MkdirAll(filepath.Join(topics, "tech"), 0755)
+	changes, err = watchInputFiles(ctx, []string{filepath.Join(topics, "*", "feeds.txt")})
+	if err != nil {
+		t.Fatalf("watchInputFiles() unexpected error = %v", err)
+	}
+	for _, file := range []string{
+		filepath.Join(topics, "tech", "feeds.txt"),
+		filepath.Join(topics, "music", "feeds.txt"),
+	} {
+		os.MkdirAll(filepath.Dir(file), 0755)
+		select {
+		case <-changes:
+		case <-time.After(time.Second):
+		}
+		os.WriteFile(file, []byte("https://g.example/feed\n"), 0644)
+		select {
+		case <-changes:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("watchInputFiles() did not signal %s", file)
+		}
+	}
+
+	// Included lists are watched, also those an edit starts including.
+	includes := filepath.Join(tempDir, "includes")
+	os.Mkdir(includes, 0755)
+	team := filepath.Join(includes, "team.txt")
+	extra := filepath.Join(includes, "extra.txt")
+	os.WriteFile(team, []byte("https://d.example/feed\n"), 0644)
+	os.WriteFile(extra, []byte("https://e.example/feed\n"), 0644)
+	os.WriteFile(input, []byte("@include includes/team.txt\n"), 0644)
+	changes, err = watchInputFiles(ctx, []string{input})
+	if err != nil {
+		t.Fatalf("watchInputFiles() unexpected error = %v", err)
+	}
+	for _, step := range []struct {
+		file, content string
+	}{
+		{team, "https://d.example/feed\n@include extra.txt\n"},
+		{extra, "https://f.example/feed\n"},
+	} {
+		os.WriteFile(step.file, []byte(step.content), 0644)
+		select {
+		case <-changes:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("watchInputFiles() did not signal a change to included %s", step.file)
+		}
+	}
+
+	if changes, err := watchInputFiles(ctx, []string{"-"}); changes != nil || err != nil {
+		t.Errorf("watchInputFiles() without local files = %v, %v, want nil, nil", changes, err)
+	}
+}
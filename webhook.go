@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/feeds"
+)
+
+const webhookSignatureHeader = "X-Signature-256"
+
+// WebhookNotifier POSTs each batch of new items as JSON. When Secret is set
+// the body is signed with HMAC-SHA256 and the signature sent as
+// "X-Signature-256: sha256=<hex>".
+type WebhookNotifier struct {
+	URL    string
+	Secret string
+}
+
+type webhookPayload struct {
+	Items []ItemJSON `json:"items"`
+}
+
+func (n *WebhookNotifier) Name() string {
+	return "webhook"
+}
+
+func (n *WebhookNotifier) Notify(items []*feeds.Item) error {
+	payload := webhookPayload{Items: make([]ItemJSON, 0, len(items))}
+	for _, item := range items {
+		payload.Items = append(payload.Items, toItemJSON(item))
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, n.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if n.Secret != "" {
+		req.Header.Set(webhookSignatureHeader, "sha256="+signWebhookBody(n.Secret, body))
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	return nil
+}
+
+func signWebhookBody(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
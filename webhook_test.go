@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/feeds"
+)
+
+func TestWebhookNotifier(t *testing.T) {
+	var signature string
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		signature = r.Header.Get(webhookSignatureHeader)
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer server.Close()
+
+	notifier := &WebhookNotifier{URL: server.URL, Secret: "s3cret"}
+	items := []*feeds.Item{
+		{Title: "One", Link: &feeds.Link{Href: "http://example.com/1"}},
+		{Title: "Two"},
+	}
+	if err := notifier.Notify(items); err != nil {
+		t.Fatalf("Notify() unexpected error = %v", err)
+	}
+
+	var payload webhookPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		t.Fatalf("invalid webhook payload: %v", err)
+	}
+	if len(payload.Items) != 2 || payload.Items[0].Title != "One" || payload.Items[0].Link != "http://example.com/1" {
+		t.Errorf("payload = %+v", payload)
+	}
+
+	if want := "sha256=" + signWebhookBody("s3cret", body); signature != want {
+		t.Errorf("signature = %q, want %q", signature, want)
+	}
+}
+
+func TestWebhookNotifierUnsigned(t *testing.T) {
+	var signature string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		signature = r.Header.Get(webhookSignatureHeader)
+	}))
+	defer server.Close()
+
+	notifier := &WebhookNotifier{URL: server.URL}
+	if err := notifier.Notify([]*feeds.Item{{Title: "One"}}); err != nil {
+		t.Fatalf("Notify() unexpected error = %v", err)
+	}
+	if signature != "" {
+		t.Errorf("unsigned webhook sent signature %q", signature)
+	}
+}
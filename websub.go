@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"hash"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// pingWebSubHub tells hub that topic has new content so it can push the
+// update to subscribers.
+func pingWebSubHub(hub, topic string) error {
+	resp, err := httpClient.PostForm(hub, url.Values{
+		"hub.mode":  {"publish"},
+		"hub.url":   {topic},
+		"hub.topic": {topic},
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	return nil
+}
+
+var linkHeaderPattern = regexp.MustCompile(`<([^>]*)>\s*;[^,]*?\brel="?([^",;]*)"?`)
+
+// discoverWebSub looks for rel="hub" and rel="self" links in a fetched feed,
+// both in the document (RSS atom:link and Atom link elements) and in HTTP
+// Link headers.
+func discoverWebSub(body []byte, header http.Header) (hub, self string) {
+	for _, value := range header.Values("Link") {
+		for _, match := range linkHeaderPattern.FindAllStringSubmatch(value, -1) {
+			switch match[2] {
+			case "hub":
+				hub = cmp.Or(hub, match[1])
+			case "self":
+				self = cmp.Or(self, match[1])
+			}
+		}
+	}
+
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	decoder.Strict = false
+	for hub == "" || self == "" {
+		token, err := decoder.Token()
+		if err != nil {
+			break
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "link" {
+			continue
+		}
+
+		var rel, href string
+		for _, attr := range start.Attr {
+			switch attr.Name.Local {
+			case "rel":
+				rel = attr.Value
+			case "href":
+				href = attr.Value
+			}
+		}
+		switch rel {
+		case "hub":
+			hub = cmp.Or(hub, href)
+		case "self":
+			self = cmp.Or(self, href)
+		}
+	}
+
+	return hub, self
+}
+
+const webSubLease = 7 * 24 * time.Hour
+
+// webSubSubscription is a subscription this process requested from a
+// source's hub. It is active once the hub has verified it.
+type webSubSubscription struct {
+	ID        string
+	SourceURL string
+	Hub       string
+	Topic     string
+	Secret    string
+	Active    bool
+	Expires   time.Time
+}
+
+func newWebSubSubscription(source *SourceFeed) *webSubSubscription {
+	sum := sha256.Sum256([]byte(source.URL))
+	secret := make([]byte, 16)
+	rand.Read(secret)
+
+	return &webSubSubscription{
+		ID:        hex.EncodeToString(sum[:8]),
+		SourceURL: source.URL,
+		Hub:       source.WebSubHub,
+		Topic:     source.WebSubTopic,
+		Secret:    hex.EncodeToString(secret),
+	}
+}
+
+func (s *webSubSubscription) request(mode, callbackBase string) error {
+	resp, err := httpClient.PostForm(s.Hub, url.Values{
+		"hub.mode":          {mode},
+		"hub.topic":         {s.Topic},
+		"hub.callback":      {strings.TrimSuffix(callbackBase, "/") + webSubCallbackPath + s.ID},
+		"hub.secret":        {s.Secret},
+		"hub.lease_seconds": {strconv.Itoa(int(webSubLease / time.Second))},
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	return nil
+}
+
+// verifySignature checks the X-Hub-Signature header of a content
+// distribution request against the subscription secret.
+func (s *webSubSubscription) verifySignature(signature string, body []byte) bool {
+	method, digest, ok := strings.Cut(signature, "=")
+	if !ok {
+		return false
+	}
+
+	var newHash func() hash.Hash
+	switch method {
+	case "sha1":
+		newHash = sha1.New
+	case "sha256":
+		newHash = sha256.New
+	case "sha512":
+		newHash = sha512.New
+	default:
+		return false
+	}
+
+	expected, err := hex.DecodeString(digest)
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(newHash, []byte(s.Secret))
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
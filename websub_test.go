@@ -0,0 +1,93 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPingWebSubHub(t *testing.T) {
+	var mode, topic string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		mode = r.PostForm.Get("hub.mode")
+		topic = r.PostForm.Get("hub.url")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	if err := pingWebSubHub(server.URL, "https://example.com/feed.xml"); err != nil {
+		t.Fatalf("pingWebSubHub() unexpected error = %v", err)
+	}
+	if mode != "publish" || topic != "https://example.com/feed.xml" {
+		t.Errorf("hub received mode=%q url=%q", mode, topic)
+	}
+}
+
+func TestDiscoverWebSub(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		header   http.Header
+		wantHub  string
+		wantSelf string
+	}{
+		{
+			name:     "rss atom links",
+			body:     `<rss xmlns:atom="http://www.w3.org/2005/Atom"><channel><atom:link rel="hub" href="https://hub.example.com/"/><atom:link rel="self" href="https://example.com/feed"/></channel></rss>`,
+			wantHub:  "https://hub.example.com/",
+			wantSelf: "https://example.com/feed",
+		},
+		{
+			name:     "link header wins over document",
+			body:     `<feed><link rel="hub" href="https://other.example.com/"/></feed>`,
+			header:   http.Header{"Link": {`<https://hub.example.com/>; rel="hub", <https://example.com/feed>; rel="self"`}},
+			wantHub:  "https://hub.example.com/",
+			wantSelf: "https://example.com/feed",
+		},
+		{
+			name: "no hub",
+			body: `<rss><channel><link>https://example.com/</link></channel></rss>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hub, self := discoverWebSub([]byte(tt.body), tt.header)
+			if hub != tt.wantHub || self != tt.wantSelf {
+				t.Errorf("discoverWebSub() = %q, %q, want %q, %q", hub, self, tt.wantHub, tt.wantSelf)
+			}
+		})
+	}
+}
+
+func TestWebSubVerifySignature(t *testing.T) {
+	sub := &webSubSubscription{Secret: "secret"}
+	body := []byte("<rss/>")
+
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write(body)
+	valid := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	tests := []struct {
+		name      string
+		signature string
+		want      bool
+	}{
+		{"valid", valid, true},
+		{"wrong digest", "sha256=00", false},
+		{"unknown method", "md5=00", false},
+		{"missing", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sub.verifySignature(tt.signature, body); got != tt.want {
+				t.Errorf("verifySignature(%q) = %v, want %v", tt.signature, got, tt.want)
+			}
+		})
+	}
+}
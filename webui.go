@@ -0,0 +1,178 @@
+package main
+
+import (
+	"cmp"
+	"html/template"
+	"log"
+	"net/http"
+	"time"
+)
+
+// webUIPolicy keeps scripts in item content from running in the reader,
+// while still showing its images and media. The sandbox gives the page an
+// origin of its own, so that content cannot reach the daemon's cookies or
+// credentials, and forms and <base> in content are ignored, so they cannot
+// phish or redirect the page's links.
+const webUIPolicy = "default-src 'none'; img-src http: https: data:; media-src http: https:; style-src 'unsafe-inline'; " +
+	"sandbox allow-popups allow-popups-to-escape-sandbox; form-action 'none'; base-uri 'none'"
+
+var webUITemplate = template.Must(template.New("webui").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<link rel="alternate" type="{{.FeedType}}" href="feed.xml">
+<style>
+body { max-width: 48rem; margin: 0 auto; padding: 1rem; font-family: system-ui, sans-serif; line-height: 1.5; }
+ol { list-style: none; padding: 0; }
+li { border-bottom: 1px solid #ddd; padding: 0.5rem 0; }
+h2 { font-size: 1.125rem; margin: 1rem 0 0; }
+summary { cursor: pointer; }
+.meta { color: #666; font-size: 0.875rem; }
+.content { padding: 0.5rem 0 0 1rem; overflow-wrap: anywhere; }
+.content img { max-width: 100%; height: auto; }
+.icon { vertical-align: -0.125rem; }
+.thumbnail { float: right; width: 4rem; height: 4rem; object-fit: cover; margin-left: 0.5rem; }
+mark { background: #ffe58a; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+{{with .Description}}<p>{{.}}</p>{{end}}
+<p class="meta"><a href="feed.xml">Feed</a>{{with .Updated}} · updated <time datetime="{{.Format "2006-01-02T15:04:05Z07:00"}}">{{.Format "2 Jan 2006 15:04"}}</time>{{end}}</p>
+<ol>
+{{- range .Items}}
+{{- if .Heading}}
+<li><h2>{{with .Icon}}<img class="icon" src="{{.}}" alt="" width="16" height="16"> {{end}}{{if .Link}}<a href="{{.Link}}">{{.Title}}</a>{{else}}{{.Title}}{{end}}</h2></li>
+{{- else}}
+<li><details>
+<summary>{{with .Thumbnail}}<img class="thumbnail" src="{{.}}" alt="">{{end}}{{if .Link}}<a href="{{.Link}}">{{.Title}}</a>{{else}}{{.Title}}{{end}}
+<span class="meta">{{with .Icon}}<img class="icon" src="{{.}}" alt="" width="16" height="16"> {{end}}{{.Source}}{{if not .Date.IsZero}} · <time datetime="{{.Date.Format "2006-01-02T15:04:05Z07:00"}}">{{.Date.Format "2 Jan 2006 15:04"}}</time>{{end}}</span></summary>
+<div class="content">{{.Content}}</div>
+</details></li>
+{{- end}}
+{{- else}}
+<li>No items yet.</li>
+{{- end}}
+</ol>
+</body>
+</html>
+`))
+
+var webUIIndexTemplate = template.Must(template.New("index").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Feeds</title>
+<style>body { max-width: 48rem; margin: 0 auto; padding: 1rem; font-family: system-ui, sans-serif; }</style>
+</head>
+<body>
+<h1>Feeds</h1>
+<ul>
+{{- range .}}
+<li><a href="{{.}}/">{{.}}</a> (<a href="{{.}}/feed.xml">feed</a>)</li>
+{{- end}}
+</ul>
+</body>
+</html>
+`))
+
+type webUIPage struct {
+	Title       string
+	Description string
+	FeedType    string
+	Updated     time.Time
+	Items       []webUIItem
+}
+
+type webUIItem struct {
+	// Title is the item's title, with the terms -score-keywords favours
+	// marked.
+	Title  template.HTML
+	Link   string
+	Source string
+	Date   time.Time
+	// Thumbnail is the item's image, from itemThumbnail.
+	Thumbnail string
+	// Icon is the favicon of the item's source, as a data URI, with
+	// -favicons.
+	Icon template.URL
+	// Heading introduces the items of a -group-by group.
+	Heading bool
+	// Content is the item's HTML as published; webUIPolicy keeps it from
+	// running scripts.
+	Content template.HTML
+}
+
+// serveWebUI renders the current aggregate as a simple HTML page, for
+// checking the output without a feed reader. Each item expands to show its
+// content.
+func (d *daemon) serveWebUI(w http.ResponseWriter, r *http.Request) {
+	d.mu.Lock()
+	aggregatedFeed := d.feed
+	titles := make(map[string]string)
+	icons := make(map[string]template.URL)
+	for url, source := range d.sources {
+		titles[url] = source.Title
+		if icon := d.favicons[siteOrigin(source)]; icon != "" {
+			// Favicons are data URIs fetchDataURI made of images.
+			icons[url] = template.URL(icon)
+		}
+	}
+	d.mu.Unlock()
+
+	if aggregatedFeed == nil {
+		http.Error(w, "feed not ready yet", http.StatusServiceUnavailable)
+		return
+	}
+
+	page := webUIPage{
+		Title:       aggregatedFeed.Title,
+		Description: aggregatedFeed.Description,
+		FeedType:    d.config.format().ContentType,
+		Updated:     aggregatedFeed.Updated,
+	}
+	terms := d.config.highlightTerms()
+	now := d.config.now()
+	for i, item := range aggregatedFeed.Items {
+		webItem := webUIItem{
+			Title:     highlightText(item.Title, terms),
+			Date:      item.Created,
+			Content:   highlightHTML(cmp.Or(item.Content, item.Description), terms),
+			Source:    itemSourceHost(item),
+			Thumbnail: itemThumbnail(item),
+			Heading:   isHeading(item),
+		}
+		// Date headings are dated in the feed, which may be read long after
+		// it is written; the page is rendered as it is viewed, so it can say
+		// "Today".
+		if period := d.config.GroupBy; webItem.Heading && (period == "day" || period == "week") && i+1 < len(aggregatedFeed.Items) {
+			start := periodStart(d.config.itemDate(aggregatedFeed.Items[i+1]).In(now.Location()), period)
+			webItem.Title = template.HTML(template.HTMLEscapeString(relativePeriodTitle(start, periodStart(now, period), period)))
+		}
+		if item.Link != nil {
+			webItem.Link = item.Link.Href
+		}
+		if item.Source != nil {
+			webItem.Source = cmp.Or(titles[item.Source.Href], webItem.Source)
+			webItem.Icon = icons[item.Source.Href]
+		}
+		page.Items = append(page.Items, webItem)
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Security-Policy", webUIPolicy)
+	if err := webUITemplate.Execute(w, page); err != nil {
+		log.Printf("Warning: failed to serve web UI: %v", err)
+	}
+}
+
+// serveWebUIIndex links to the web UI of each profile.
+func serveWebUIIndex(w http.ResponseWriter, names []string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := webUIIndexTemplate.Execute(w, names); err != nil {
+		log.Printf("Warning: failed to serve web UI: %v", err)
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/feeds"
+)
+
+func TestDaemonWebUI(t *testing.T) {
+	d := newDaemon(&Config{})
+	server := httptest.NewServer(d.handler())
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/")
+	if err != nil {
+		t.Fatalf("GET / failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("GET / before the first update = %d, want 503", resp.StatusCode)
+	}
+
+	d.sources["https://example.com/feed"] = &SourceFeed{URL: "https://example.com/feed", Title: "Example Blog"}
+	d.feed = &feeds.Feed{
+		Title: "Aggregate",
+		Items: []*feeds.Item{
+			{Title: "Today", Source: &feeds.Link{Rel: headingRel}},
+			{
+				Title:     "First <post>",
+				Link:      &feeds.Link{Href: "https://example.com/1"},
+				Source:    &feeds.Link{Href: "https://example.com/feed"},
+				Created:   time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+				Content:   "<p>Hello <b>world</b></p>",
+				Enclosure: &feeds.Enclosure{Url: "https://example.com/1.png", Type: "image/png"},
+			},
+			{
+				Title:       "Second",
+				Link:        &feeds.Link{Href: "javascript:alert(1)"},
+				Source:      &feeds.Link{Href: "https://other.example.org/rss"},
+				Description: "Just a summary",
+			},
+		},
+	}
+
+	resp, err = http.Get(server.URL + "/")
+	if err != nil {
+		t.Fatalf("GET / failed: %v", err)
+	}
+	body := readBody(t, resp)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET / = %d, want 200", resp.StatusCode)
+	}
+	for _, directive := range []string{"default-src 'none'", "sandbox", "form-action 'none'", "base-uri 'none'"} {
+		if !strings.Contains(resp.Header.Get("Content-Security-Policy"), directive) {
+			t.Errorf("GET / Content-Security-Policy = %q, want %s", resp.Header.Get("Content-Security-Policy"), directive)
+		}
+	}
+
+	for _, want := range []string{
+		`<a href="https://example.com/1">First &lt;post&gt;</a>`,
+		"Example Blog",
+		"1 Mar 2024 12:00",
+		"<p>Hello <b>world</b></p>",
+		"other.example.org",
+		"Just a summary",
+		"<li><h2>Today</h2></li>",
+		`<img class="thumbnail" src="https://example.com/1.png" alt="">`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("GET / page does not contain %q", want)
+		}
+	}
+	if strings.Contains(body, "javascript:") {
+		t.Errorf("GET / page links to a javascript: URL")
+	}
+
+	resp, err = http.Get(server.URL + "/missing")
+	if err != nil {
+		t.Fatalf("GET /missing failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("GET /missing = %d, want 404", resp.StatusCode)
+	}
+}
+
+func TestDaemonWebUIHighlights(t *testing.T) {
+	d := newDaemon(&Config{Scorer: keywordScorer{Keywords: map[string]float64{"world": 1}}})
+	server := httptest.NewServer(d.handler())
+	defer server.Close()
+
+	d.feed = &feeds.Feed{Title: "Aggregate", Items: []*feeds.Item{
+		{Title: "Hello world", Content: `<p class="world">Hello <b>World</b></p>`},
+	}}
+
+	resp, err := http.Get(server.URL + "/")
+	if err != nil {
+		t.Fatalf("GET / failed: %v", err)
+	}
+	body := readBody(t, resp)
+	for _, want := range []string{
+		"Hello <mark>world</mark>",
+		`<p class="world">Hello <b><mark>World</mark></b></p>`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("GET / page does not contain %q", want)
+		}
+	}
+}
+
+func TestDaemonWebUIRelativeDates(t *testing.T) {
+	d := newDaemon(&Config{GroupBy: "day"})
+	server := httptest.NewServer(d.handler())
+	defer server.Close()
+
+	now := time.Now()
+	items := []*feeds.Item{
+		{Title: "today", Created: now},
+		{Title: "yesterday", Created: now.AddDate(0, 0, -1)},
+		{Title: "older", Created: time.Date(2024, 3, 1, 12, 0, 0, 0, now.Location())},
+	}
+	d.feed = &feeds.Feed{Title: "Aggregate", Items: groupByDate(items, "day", now, d.config.itemDate)}
+
+	resp, err := http.Get(server.URL + "/")
+	if err != nil {
+		t.Fatalf("GET / failed: %v", err)
+	}
+	body := readBody(t, resp)
+	for _, want := range []string{"<h2>Today</h2>", "<h2>Yesterday</h2>", "<h2>Friday, 1 March 2024</h2>"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("GET / page does not contain %q", want)
+		}
+	}
+}
+
+func TestDaemonWebUIFavicons(t *testing.T) {
+	d := newDaemon(&Config{})
+	server := httptest.NewServer(d.handler())
+	defer server.Close()
+
+	d.sources["https://example.com/feed"] = &SourceFeed{URL: "https://example.com/feed", Link: "https://example.com/"}
+	d.favicons = map[string]string{"https://example.com": "data:image/png;base64,cG5n"}
+	d.feed = &feeds.Feed{Title: "Aggregate", Items: []*feeds.Item{
+		{Title: "With icon", Source: &feeds.Link{Href: "https://example.com/feed"}},
+		{Title: "Without icon", Source: &feeds.Link{Href: "https://other.example.org/rss"}},
+	}}
+
+	resp, err := http.Get(server.URL + "/")
+	if err != nil {
+		t.Fatalf("GET / failed: %v", err)
+	}
+	body := readBody(t, resp)
+	if got := strings.Count(body, `<img class="icon" src="data:image/png;base64,cG5n"`); got != 1 {
+		t.Errorf("GET / page shows the favicon %d times, want once", got)
+	}
+}
+
+func TestDaemonsHandlerIndex(t *testing.T) {
+	server := httptest.NewServer(daemonsHandler([]*daemon{
+		newDaemon(&Config{Name: "tech"}),
+		newDaemon(&Config{Name: "news"}),
+	}))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/")
+	if err != nil {
+		t.Fatalf("GET / failed: %v", err)
+	}
+	body := readBody(t, resp)
+	if !strings.Contains(body, `href="tech/"`) || !strings.Contains(body, `href="news/feed.xml"`) {
+		t.Errorf("GET / index = %q, want links to each profile", body)
+	}
+}